@@ -44,7 +44,7 @@ var otherCmd = &cobra.Command{
             case "nat":
                 _ = pool.Submit(func() {
                     defer wg.Done()
-                    traversal.NginxAlias(target, "", "")
+                    traversal.NginxAlias(target, "", "", client)
                 })
             
             case "swagger":
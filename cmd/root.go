@@ -1,16 +1,14 @@
 package cmd
 
 import (
-    "bufio"
     "fmt"
     "github.com/logrusorgru/aurora"
     "github.com/spf13/cobra"
     "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/logging"
-    "io"
     "os"
-    "strings"
 )
 
 /**
@@ -31,9 +29,9 @@ var rootCmd = &cobra.Command{
     Short: "A Powerful security assessment and utilization tools",
     PersistentPreRun: func(cmd *cobra.Command, args []string) {
         // PersistentPreRun 在每个子命令执行之前都会执行
-        initTargetList()
-        // 初始化日志
+        // 初始化日志，解析目标时会输出格式错误的目标
         logging.Logger = logging.New(conf.GlobalConfig.Debug, "", "Jie", true)
+        initTargetList()
         // 配置文件生成
         conf.Init()
         if proxy != "" {
@@ -53,11 +51,12 @@ func init() {
     fmt.Println(aurora.Red("Developers assume no liability and are not responsible for any misuse or damage.").String() + "\n")
     
     rootCmd.CompletionOptions.DisableDefaultCmd = true
-    rootCmd.PersistentFlags().StringVarP(&conf.GlobalConfig.Options.Target, "target", "t", "", "target\r\n主动扫描目标，被动下不需要指定")
-    rootCmd.PersistentFlags().StringVarP(&conf.GlobalConfig.Options.TargetFile, "file", "f", "", "target file\r\n主动扫描目标列表，每行一个")
+    rootCmd.PersistentFlags().StringVarP(&conf.GlobalConfig.Options.Target, "target", "t", "", "target, supports url, host:port, CIDR and ip range (example: 10.0.0.0/24:80,8080)\r\n主动扫描目标，被动下不需要指定")
+    rootCmd.PersistentFlags().StringVarP(&conf.GlobalConfig.Options.TargetFile, "file", "f", "", "target file, also supports nmap/masscan xml, masscan json, - for stdin\r\n主动扫描目标列表，每行一个，支持 CIDR、ip 段、host:端口范围，也支持 nmap/masscan 的扫描结果，- 表示从标准输入读取")
     rootCmd.PersistentFlags().StringVarP(&conf.GlobalConfig.Options.Output, "out", "o", "", "output report file(eg:vulnerability_report.html)\r\n漏洞结果报告保存地址")
     rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "proxy, (example: --proxy http://127.0.0.1:8080)\r\n指定 http/https 代理")
     rootCmd.PersistentFlags().BoolVar(&conf.GlobalConfig.Debug, "debug", false, "debug")
+    rootCmd.PersistentFlags().BoolVar(&conf.SafeMode, "safe", false, "safe mode, skip destructive and lockout-risk checks, refuse requests that may modify data.\r\n安全模式，不运行会写入文件、爆破类的检测，拒绝发送 PUT、DELETE 等可能修改数据的请求")
     // rootCmd.MarkPersistentFlagRequired("target")
     
     webScanCmdInit()
@@ -77,30 +76,27 @@ func Execute() {
     }
 }
 
+// initTargetList 解析目标，-t 和 -f 都支持 CIDR、ip 段、端口列表，-f 还支持 nmap/masscan 的结果，-f - 从标准输入读取
+// 指定了目标但是一个都解析不出来时直接退出
 func initTargetList() {
+    if conf.GlobalConfig.Options.Target == "" && conf.GlobalConfig.Options.TargetFile == "" {
+        return
+    }
+    var targets []string
     if conf.GlobalConfig.Options.Target != "" {
-        conf.GlobalConfig.Options.Targets = append(conf.GlobalConfig.Options.Targets, conf.GlobalConfig.Options.Target)
+        targets = append(targets, input.ParseTargets([]string{conf.GlobalConfig.Options.Target})...)
     }
     if conf.GlobalConfig.Options.TargetFile != "" {
-        file, err := os.Open(conf.GlobalConfig.Options.TargetFile)
+        res, err := input.ReadTargets(conf.GlobalConfig.Options.TargetFile)
         if err != nil {
-            fmt.Println("文件不存在", err)
-            return
-        }
-        buffer := bufio.NewReader(file)
-        
-        for {
-            line, err := buffer.ReadString('\n')
-            line = strings.TrimSpace(line)
-            if err != nil {
-                if err == io.EOF {
-                    return
-                }
-                fmt.Println("未知错误", err)
-                return
-            }
-            conf.GlobalConfig.Options.Targets = append(conf.GlobalConfig.Options.Targets, line)
+            logging.Logger.Errorln("read target file failed:", err)
+            os.Exit(1)
         }
+        targets = append(targets, res...)
+    }
+    if len(targets) == 0 {
+        logging.Logger.Errorln("no valid target")
+        os.Exit(1)
     }
-    return
+    conf.GlobalConfig.Options.Targets = append(conf.GlobalConfig.Options.Targets, input.Dedupe(targets)...)
 }
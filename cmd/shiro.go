@@ -22,6 +22,9 @@ var shiroCmd = &cobra.Command{
     Use:   "shiro",
     Short: "Shiro scan && exp",
     Run: func(cmd *cobra.Command, args []string) {
+        if conf.SafeMode && conf.GlobalConfig.Options.Shiro.Mode == "exp" {
+            logging.Logger.Fatalln("exp mode is not allowed in safe mode")
+        }
         for _, target := range conf.GlobalConfig.Options.Targets {
             switch conf.GlobalConfig.Options.Shiro.Mode {
             case "burp":
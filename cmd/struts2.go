@@ -5,6 +5,7 @@ import (
     "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/scan/Pocs/java/struts2"
+    "github.com/yhy0/logging"
 )
 
 /**
@@ -17,6 +18,9 @@ var struts2Cmd = &cobra.Command{
     Use:   "s2",
     Short: "Struts2 scan && exp",
     Run: func(cmd *cobra.Command, args []string) {
+        if conf.SafeMode && conf.GlobalConfig.Options.S2.Mode == "exec" {
+            logging.Logger.Fatalln("exec mode is not allowed in safe mode")
+        }
         struts2.S2(conf.GlobalConfig.Options, httpx.NewClient(nil))
     },
 }
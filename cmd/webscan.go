@@ -7,8 +7,10 @@ import (
     "github.com/yhy0/Jie/crawler"
     "github.com/yhy0/Jie/pkg/mode"
     "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/PerFile/domxss"
     "github.com/yhy0/logging"
     "strings"
+    "time"
 )
 
 /**
@@ -24,12 +26,23 @@ var (
     noPlugins  bool
     allPlugins bool
     copilot    bool
+    
+    concurrency    int
+    targetTimeout  int
+    targetRequests int
 )
 
 var webScanCmd = &cobra.Command{
     Use:   "web",
     Short: "Run a web scan task",
     Run: func(cmd *cobra.Command, args []string) {
+        // 扫描策略在加载配置文件时已经应用，这里只检查命令行指定的策略是否存在
+        if conf.PolicyName != "" {
+            if _, ok := conf.GetPolicy(conf.PolicyName); !ok {
+                logging.Logger.Fatalf("policy %s not found, available: %s", conf.PolicyName, strings.Join(conf.PolicyNames(), ", "))
+            }
+        }
+        
         if !noPlugins {
             // 如果没有禁用插件，并且没有指定插件，则按照配置文件默认插件
             if plugins != nil {
@@ -82,9 +95,15 @@ var webScanCmd = &cobra.Command{
                 crawler.NewCrawlergo(show)
             }
             
-            for _, target := range conf.GlobalConfig.Options.Targets {
-                mode.Active(target, nil)
-            }
+            // 多个目标同时扫描，每个目标单独限制扫描时间和请求数
+            summaries := mode.ActiveBatch(conf.GlobalConfig.Options.Targets, mode.BatchOptions{
+                Concurrency: concurrency,
+                Timeout:     time.Duration(targetTimeout) * time.Minute,
+                MaxRequests: targetRequests,
+            })
+            mode.PrintSummary(summaries)
+            // 扫描结束，关闭 dom xss 检测单独启动的浏览器
+            domxss.Close()
             
             if copilot { // 阻塞，不退出
                 logging.Logger.Infoln("Scan complete. Blocking program, go to the default port 9088 to view detailed scan information")
@@ -99,6 +118,8 @@ func webScanCmdInit() {
     // 设置需要开启的插件
     webScanCmd.Flags().StringSliceVarP(&plugins, "plugin", "p", nil, "Vulnerable Plugin, (example: --plugin xss,csrf,sql,dir ...)\r\n指定开启的插件，当指定 all 时开启全部插件")
     webScanCmd.Flags().BoolVar(&noPlugins, "np", false, "not run plugin.\r\n禁用所有的插件")
+    // 扫描策略
+    webScanCmd.Flags().StringVar(&conf.PolicyName, "policy", "", "scan policy, built-in: quick, standard, deep, passive-safe, or defined in Jie_config.yaml (example: --policy deep).\r\n扫描策略，会覆盖配置文件中的插件开关，--plugin 指定的插件优先级更高")
     
     // 是否显示无头浏览器
     webScanCmd.Flags().BoolVar(&show, "show", false, "specifies whether the show the browser in headless mode.\r\n主动扫描下是否显示浏览器")
@@ -107,6 +128,11 @@ func webScanCmdInit() {
     webScanCmd.Flags().StringSliceVar(&Poc, "poc", nil, "specify the nuclei poc to run, separated by ','(example: test.yml,./test/*).\r\n自定义的nuclei 漏洞模板地址")
     webScanCmd.Flags().StringVarP(&craw, "craw", "c", "k", "Select crawler:c or k or kh. (c:Crawlergo, k:Katana Standard Mode(default), kh:(Katana Headless Mode))\r\n选择哪一个爬虫，c:Crawlergo, k:Katana 标准模式(default),kh: Katana无头模式")
     
+    // 批量主动扫描
+    webScanCmd.Flags().IntVar(&concurrency, "concurrency", 5, "number of targets to crawl and scan concurrently in active mode.\r\n主动扫描时同时扫描的目标数")
+    webScanCmd.Flags().IntVar(&targetTimeout, "target-timeout", 0, "max scan time per target in minutes, 0 means unlimited.\r\n每个目标的最长扫描时间(分钟)，0 为不限制")
+    webScanCmd.Flags().IntVar(&targetRequests, "target-requests", 0, "max requests per target, 0 means unlimited.\r\n每个目标最多发送的请求数，0 为不限制")
+    
     webScanCmd.Flags().BoolVar(&conf.GlobalConfig.NoPortScan, "nps", false, "No port scanning(false).\r\n不进行端口扫描就不会检测 nmap、masscan 是否存在，默认 false")
     
     // 被动监听，收集流量 Security Copilot mode
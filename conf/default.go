@@ -28,6 +28,23 @@ var (
         "bbscan":                false,
         "archive":               false,
         "nginx-alias-traversal": false,
+        "smuggling":             false,
+        "cors":                  false,
+        "redirect":              false,
+        "ssti":                  false,
+        "lfi":                   false,
+        "nosql":                 false,
+        "graphql":               false,
+        "websocket":             false,
+        "upload":                false,
+        "authz":                 false,
+        "csrf":                  false,
+        "ssl":                   false,
+        "deserialization":       false,
+        "viewstate":             false,
+        "prototype":             false,
+        "blindxss":              false,
+        "domxss":                false,
     }
 )
 
@@ -1,6 +1,7 @@
 package conf
 
 import (
+    "bytes"
     "github.com/fsnotify/fsnotify"
     "github.com/spf13/viper"
     "github.com/yhy0/Jie/pkg/util"
@@ -22,6 +23,25 @@ var defaultConfigYaml = []byte(`version: ` + Version + `
 
 parallel: 10                            # 同时扫描的最大 url 个数
 
+# 扫描策略，内置 quick、standard、deep、passive-safe，为空则按照下方 plugins 中的配置运行，命令行 --policy 优先级更高
+policy: ""
+
+# 自定义扫描策略，与内置策略同名时会覆盖内置策略
+policies:
+  custom:
+    description: "自定义策略示例"
+    plugins:                            # 开启的插件，all 代表全部开启
+      - xss
+      - sql
+      - bbscan
+    payloadLevel: 2                     # payload 深度 1: quick 2: standard 3: deep
+    maxRequests:                        # 每个插件对单个网站最多发送的请求数，0 或不配置表示不限制
+      xss: 200
+      sql: 200
+      bbscan: 500
+    templateTags: []                    # nuclei 模板标签，为空则只按照指纹选择模板
+    bbscanRules: []                     # bbscan 使用的规则集(rules 目录下的文件名，不带后缀)，为空则全部使用
+
 # 全局 http 发包配置
 http:
   proxy: ""                             # 漏洞扫描时使用的代理，如: http://127.0.0.1:8080
@@ -48,6 +68,7 @@ plugins:
   xss:
     enabled: true
     detectXssInCookie: true             # 是否探测入口点在 cookie 中的 xss
+    browserVerify: false                # 在浏览器中验证反射型 xss，确认执行的为 High，没有确认的降为 Low，需要启动浏览器
   sql:
     enabled: true
     booleanBasedDetection: true         # 是否检测布尔盲注
@@ -85,6 +106,52 @@ plugins:
     enabled: false
   portScan:
     enabled: false
+  domXss:                               # dom xss 污点传播分析，爬到的、被动代理的 html/js 响应在浏览器中插桩重新渲染
+    enabled: false
+    tabs: 4                             # 同时渲染的标签页数量，没有启动爬虫的浏览器时会单独启动一个浏览器
+  blindXss:                             # 盲打 xss，注入加载回连脚本的 payload，Jie 启动回连服务收集触发的页面信息
+    enabled: false
+    listen: ""                          # 回连服务的监听地址，如 0.0.0.0:8899，为空时不检测
+    url: ""                             # 目标浏览器访问回连服务使用的地址，如 http://1.2.3.4:8899，为空时使用 http://监听地址
+  prototype:                            # Node.js 服务端原型链污染检测(JSON 请求体)
+    enabled: false
+  viewstate:                            # ASP.NET ViewState 分析，MAC/加密检测，公开 machineKey 离线爆破
+    enabled: false
+    machineKeys: ""                     # 内置 key 之外的 machineKey 文件，如 Blacklist3r 的 MachineKeys.txt，每行 validationKey,decryptionKey
+  deserialization:                      # Java 反序列化检测，被动识别序列化数据，URLDNS、FindClassByDNS 确认
+    enabled: false
+  ssl:                                  # TLS 协议版本、加密套件和证书检测
+    enabled: false
+  csrf:                                 # CSRF 检测，去掉 token、Referer/Origin、修改 Content-Type 后重放，生成 PoC
+    enabled: false
+  authz:                                # 越权检测，使用其他身份、未登录状态重放请求，替换对象 id
+    enabled: false
+    unsafeMethods: false                # 默认只重放 GET、HEAD 请求，开启后 POST、PUT、DELETE 等请求也会以其他身份、替换 id 后重放(会修改数据，安全模式下不生效)
+    identities:                         # 其他用户的身份，带有认证信息的请求会使用这些身份和未登录状态重放
+    #  - name: user-b
+    #    headers:
+    #      Cookie: "session=xxxx"
+    #      Authorization: "Bearer xxxx"
+  upload:                               # 文件上传检测，重放 multipart 上传请求，扩展名、MIME、文件头绕过，会在目标上写入文件
+    enabled: false
+    htaccess: false                     # 上传 .htaccess 让自定义扩展名按照 php 解析，会改变目标整个目录的解析方式且无法自动清理
+  websocket:                            # WebSocket 检测，跨站 WebSocket 劫持，消息中的字段交给 xss/sql/cmd 插件检测
+    enabled: false
+  graphql:                              # GraphQL 检测，内省、字段建议、批量查询、CSRF，变量交给 sql/xss/ssrf/cmd 插件检测
+    enabled: false
+  nosql:                                # NoSQL(MongoDB) 操作符注入检测
+    enabled: false
+  lfi:                                  # 路径穿越、本地文件包含检测
+    enabled: false
+  ssti:                                 # 服务端模板注入检测，识别模板引擎
+    enabled: false
+  redirect:                             # 开放重定向检测
+    enabled: false
+  cors:                                 # CORS 配置错误检测
+    enabled: false
+  smuggling:                            # HTTP 请求走私 CL.TE、TE.CL、TE.TE、H2.CL、H2.TE
+    enabled: false
+    differential: false                 # 基于时间检测到后，走私一个指向不存在路径的请求确认，请求会到达后端，安全模式下不生效
 
 # 反连平台配置
 # 注意: 默认配置为 dig.pm, 可以使用 https://github.com/yumusb/DNSLog-Platform-Golang 自行搭建，后续看需求要不要支持别的 dnslog 平台
@@ -279,6 +346,18 @@ func Init() {
     HotConf()
 }
 
+// InitDefault 加载内置的默认配置，不读写配置文件，嵌入使用时由 jie.Init 调用，之后可以直接修改 GlobalConfig
+func InitDefault() {
+    viper.SetConfigType("yaml")
+    if err := viper.ReadConfig(bytes.NewReader(defaultConfigYaml)); err != nil {
+        logging.Logger.Fatalf("Fail to read default config: %+v", err)
+    }
+    if err := viper.Unmarshal(&GlobalConfig); err != nil {
+        logging.Logger.Fatalf("Fail to parse default config: %+v", err)
+    }
+    ReadPlugin()
+}
+
 // WriteYamlConfig 生成写入默认配置文件, 这里就不通过 viper 写入了， viper 写入的没有注释
 func WriteYamlConfig() error {
     // 判断文件夹是否存在
@@ -313,6 +392,20 @@ func ReadYamlConfig() {
         logging.Logger.Fatalf("Fail to parse '%s', check format: %+v", ConfigFile, err)
     }
     ReadPlugin()
+    
+    // 指定了扫描策略时，使用策略覆盖 plugins 中的配置
+    ActivePolicy = Policy{}
+    policy := PolicyName
+    if policy == "" {
+        policy = GlobalConfig.Policy
+    }
+    if policy != "" {
+        if err = ApplyPolicy(policy); err != nil {
+            logging.Logger.Errorln(err)
+        } else {
+            logging.Logger.Infoln("Scan policy:", policy)
+        }
+    }
 }
 
 // ReadPlugin 插件读取出来方便使用，之后所有的插件运行都是看 Plugin 中对应的是否开启
@@ -410,4 +503,72 @@ func ReadPlugin() {
     if GlobalConfig.Plugins.NginxAliasTraversal.Enabled {
         Plugin["nginx-alias-traversal"] = true
     }
+    
+    if GlobalConfig.Plugins.Smuggling.Enabled {
+        Plugin["smuggling"] = true
+    }
+    
+    if GlobalConfig.Plugins.Cors.Enabled {
+        Plugin["cors"] = true
+    }
+    
+    if GlobalConfig.Plugins.Redirect.Enabled {
+        Plugin["redirect"] = true
+    }
+    
+    if GlobalConfig.Plugins.Ssti.Enabled {
+        Plugin["ssti"] = true
+    }
+    
+    if GlobalConfig.Plugins.Lfi.Enabled {
+        Plugin["lfi"] = true
+    }
+    
+    if GlobalConfig.Plugins.Nosql.Enabled {
+        Plugin["nosql"] = true
+    }
+    
+    if GlobalConfig.Plugins.Graphql.Enabled {
+        Plugin["graphql"] = true
+    }
+    
+    if GlobalConfig.Plugins.WebSocket.Enabled {
+        Plugin["websocket"] = true
+    }
+    
+    if GlobalConfig.Plugins.Upload.Enabled {
+        Plugin["upload"] = true
+    }
+    
+    if GlobalConfig.Plugins.Authz.Enabled {
+        Plugin["authz"] = true
+    }
+    
+    if GlobalConfig.Plugins.Csrf.Enabled {
+        Plugin["csrf"] = true
+    }
+    
+    if GlobalConfig.Plugins.Ssl.Enabled {
+        Plugin["ssl"] = true
+    }
+    
+    if GlobalConfig.Plugins.Deserialization.Enabled {
+        Plugin["deserialization"] = true
+    }
+    
+    if GlobalConfig.Plugins.Viewstate.Enabled {
+        Plugin["viewstate"] = true
+    }
+    
+    if GlobalConfig.Plugins.Prototype.Enabled {
+        Plugin["prototype"] = true
+    }
+    
+    if GlobalConfig.Plugins.BlindXss.Enabled {
+        Plugin["blindxss"] = true
+    }
+    
+    if GlobalConfig.Plugins.DomXss.Enabled {
+        Plugin["domxss"] = true
+    }
 }
@@ -0,0 +1,145 @@
+package conf
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/12
+   @desc 扫描策略，将开启的插件、payload 深度、每个插件的请求数限制、nuclei 模板标签打包成一个命名的策略
+        内置 quick、standard、deep、passive-safe 四种，也可以在配置文件 policies 中自定义，同名的会覆盖内置策略
+**/
+
+// payload 深度，目前 sql(闭合方式、时间盲注)、lfi(payload 数量)、cmd(命令分隔符) 会根据深度调整 payload，其他插件不受影响
+const (
+    PayloadQuick    = 1 // 只发送最常用的 payload
+    PayloadStandard = 2 // 默认
+    PayloadDeep     = 3 // 发送全部 payload 变体，包括时间盲注这类耗时的检测
+)
+
+// Policy 扫描策略
+type Policy struct {
+    Description  string         `json:"description"`
+    Plugins      []string       `json:"plugins"`      // 开启的插件，对应 Plugin 中的 key，all 表示全部开启
+    PayloadLevel int            `json:"payloadLevel"` // payload 深度 1: quick 2: standard 3: deep
+    MaxRequests  map[string]int `json:"maxRequests"`  // 每个插件对单个网站最多发送的请求数，0 或不配置表示不限制
+    TemplateTags []string       `json:"templateTags"` // nuclei 模板标签，为空则只按照指纹选择模板
+    BBscanRules  []string       `json:"bbscanRules"`  // bbscan 使用的规则集(rules 目录下的文件名，不带后缀)，为空则全部使用
+}
+
+// PolicyName 命令行中指定的策略，优先级高于配置文件中的 policy
+var PolicyName string
+
+// ActivePolicy 当前生效的策略，没有指定策略时为空，各插件按照默认逻辑运行
+var ActivePolicy = Policy{}
+
+// BuiltinPolicies 内置的扫描策略
+var BuiltinPolicies = map[string]Policy{
+    "quick": {
+        Description:  "只运行高收益的插件，每种漏洞只发送最常用的 payload",
+        Plugins:      []string{"xss", "sql", "cmd", "jsonp", "crlf", "bbscan", "nginx-alias-traversal"},
+        PayloadLevel: PayloadQuick,
+        MaxRequests: map[string]int{
+            "xss":    100,
+            "sql":    100,
+            "cmd":    50,
+            "bbscan": 300,
+        },
+        BBscanRules: []string{"git_and_svn", "config_file", "sensitive_url", "springboot", "go_pprof_debug", "druid"},
+    },
+    "standard": {
+        Description:  "默认策略，与默认配置文件中开启的插件一致",
+        Plugins:      []string{"xss", "sql", "cmd", "xxe", "ssrf", "bbscan", "jsonp", "log4j", "bypass403", "fastjson", "archive", "nginx-alias-traversal", "crlf"},
+        PayloadLevel: PayloadStandard,
+    },
+    // 不包含 upload、prototype、csrf 这类会修改服务端状态的插件和 brute、hydra 这类可能锁定账号的爆破插件，需要时在自定义策略中显式开启
+    "deep": {
+        Description: "开启除破坏性、爆破类以外的全部插件，发送全部 payload 变体，不限制请求数",
+        Plugins: []string{
+            "xss", "sql", "sqlmapApi", "cmd", "xxe", "ssrf", "bypass403", "jsonp", "crlf", "log4j", "fastjson",
+            "portScan", "poc", "nuclei", "bbscan", "archive", "nginx-alias-traversal", "smuggling", "cors", "redirect",
+            "ssti", "lfi", "nosql", "graphql", "websocket", "authz", "ssl", "deserialization", "viewstate", "blindxss", "domxss",
+        },
+        PayloadLevel: PayloadDeep,
+    },
+    "passive-safe": {
+        Description:  "只进行不发送攻击 payload 的检测，适合对线上业务的被动扫描",
+        Plugins:      []string{"jsonp", "archive", "bbscan"},
+        PayloadLevel: PayloadQuick,
+        MaxRequests: map[string]int{
+            "bbscan": 100,
+        },
+        BBscanRules: []string{"git_and_svn", "config_file", "sensitive_url"},
+    },
+}
+
+// GetPolicy 根据名字获取策略，配置文件中自定义的策略优先
+// viper 读取配置时会把 map 的 key 转为小写，所以这里统一按小写查找
+func GetPolicy(name string) (Policy, bool) {
+    name = strings.ToLower(name)
+    if p, ok := GlobalConfig.Policies[name]; ok {
+        return p, true
+    }
+    p, ok := BuiltinPolicies[name]
+    return p, ok
+}
+
+// PolicyNames 返回所有可用的策略名字，用于提示
+func PolicyNames() []string {
+    var names []string
+    for name := range BuiltinPolicies {
+        names = append(names, name)
+    }
+    for name := range GlobalConfig.Policies {
+        if _, ok := BuiltinPolicies[name]; !ok {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// ApplyPolicy 应用策略，会覆盖配置文件中各个插件的开关
+func ApplyPolicy(name string) error {
+    policy, ok := GetPolicy(name)
+    if !ok {
+        return fmt.Errorf("policy %s not found, available: %s", name, strings.Join(PolicyNames(), ", "))
+    }
+    
+    for k := range Plugin {
+        Plugin[k] = false
+    }
+    for _, plugin := range policy.Plugins {
+        if plugin == "all" {
+            for k := range Plugin {
+                Plugin[k] = true
+            }
+            break
+        }
+        for k := range Plugin {
+            if strings.EqualFold(k, plugin) {
+                Plugin[k] = true
+            }
+        }
+    }
+    
+    if policy.PayloadLevel == 0 {
+        policy.PayloadLevel = PayloadStandard
+    }
+    
+    ActivePolicy = policy
+    return nil
+}
+
+// PayloadLevel 当前策略的 payload 深度
+func PayloadLevel() int {
+    return (*Scope)(nil).PayloadLevel()
+}
+
+// MaxRequests 当前策略下插件对单个网站允许发送的最大请求数，0 表示不限制
+func MaxRequests(plugin string) int {
+    return (*Scope)(nil).MaxRequests(plugin)
+}
@@ -0,0 +1,23 @@
+package conf
+
+/**
+   @author yhy
+   @since 2024/6/14
+   @desc 插件、poc 的风险等级，安全模式(--safe)下只运行不会对目标造成破坏的检测
+**/
+
+// 风险等级
+const (
+    RiskReadOnly    = "read-only"    // 只读取目标的数据，不发送攻击 payload
+    RiskIntrusive   = "intrusive"    // 发送攻击 payload，但不会修改目标的数据
+    RiskDestructive = "destructive"  // 会在目标上写入文件、修改数据
+    RiskLockout     = "lockout-risk" // 爆破类，可能导致账号被锁定
+)
+
+// SafeMode 安全模式，开启后 destructive、lockout-risk 等级的检测不会运行，httpx 也会拒绝发送会修改数据的请求
+var SafeMode bool
+
+// RiskAllowed 判断当前模式下该风险等级的检测是否允许运行
+func RiskAllowed(risk string) bool {
+    return (*Scope)(nil).RiskAllowed(risk)
+}
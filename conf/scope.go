@@ -0,0 +1,71 @@
+package conf
+
+import "strings"
+
+/**
+   @author yhy
+   @since 2024/6/16
+   @desc 单个扫描任务的安全模式和扫描策略，嵌入使用时每个扫描器有自己的 Scope，同一个进程中的扫描互不影响
+        Scope 为空时使用全局的 SafeMode 和 ActivePolicy，命令行模式下都是空的
+**/
+
+// Scope 扫描任务的安全模式和扫描策略
+type Scope struct {
+    SafeMode     bool   // 安全模式
+    Policy       Policy // 扫描策略，为空时各插件按照默认逻辑运行
+    Differential *bool  // 请求走私是否使用差异响应确认，为空时使用配置文件中的 plugins.smuggling.differential
+}
+
+// Safe 是否开启了安全模式
+func (s *Scope) Safe() bool {
+    if s == nil {
+        return SafeMode
+    }
+    return s.SafeMode
+}
+
+// RiskAllowed 判断该风险等级的检测是否允许运行
+func (s *Scope) RiskAllowed(risk string) bool {
+    if !s.Safe() {
+        return true
+    }
+    return risk == RiskReadOnly || risk == RiskIntrusive
+}
+
+// SmugglingDifferential 请求走私是否使用差异响应确认，走私的请求会到达后端，安全模式下不确认
+func (s *Scope) SmugglingDifferential() bool {
+    if s.Safe() {
+        return false
+    }
+    if s == nil || s.Differential == nil {
+        return GlobalConfig.Plugins.Smuggling.Differential
+    }
+    return *s.Differential
+}
+
+// ActivePolicy 生效的扫描策略
+func (s *Scope) ActivePolicy() Policy {
+    if s == nil {
+        return ActivePolicy
+    }
+    return s.Policy
+}
+
+// PayloadLevel 扫描策略的 payload 深度
+func (s *Scope) PayloadLevel() int {
+    if level := s.ActivePolicy().PayloadLevel; level != 0 {
+        return level
+    }
+    return PayloadStandard
+}
+
+// MaxRequests 扫描策略中插件对单个网站允许发送的最大请求数，0 表示不限制
+func (s *Scope) MaxRequests(plugin string) int {
+    // viper 读取配置文件时会把 map 的 key 转为小写，这里不区分大小写
+    for k, v := range s.ActivePolicy().MaxRequests {
+        if strings.EqualFold(k, plugin) {
+            return v
+        }
+    }
+    return 0
+}
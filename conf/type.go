@@ -7,17 +7,19 @@ package conf
 **/
 
 type Config struct {
-    Debug      bool       `json:"debug"`
-    Options    Options    `json:"options"`
-    Passive    Passive    `json:"passive"`
-    Http       Http       `json:"http"`
-    Plugins    Plugins    `json:"plugins"`
-    WebScan    WebScan    `json:"webScan"`
-    NoPortScan bool       `json:"no_port_scan"`
-    Reverse    Reverse    `json:"reverse"`
-    SqlmapApi  Sqlmap     `json:"sqlmapApi"`
-    Mitmproxy  Mitmproxy  `json:"mitmproxy"`
-    Collection Collection `json:"collection"`
+    Debug      bool              `json:"debug"`
+    Options    Options           `json:"options"`
+    Passive    Passive           `json:"passive"`
+    Http       Http              `json:"http"`
+    Plugins    Plugins           `json:"plugins"`
+    WebScan    WebScan           `json:"webScan"`
+    NoPortScan bool              `json:"no_port_scan"`
+    Reverse    Reverse           `json:"reverse"`
+    SqlmapApi  Sqlmap            `json:"sqlmapApi"`
+    Mitmproxy  Mitmproxy         `json:"mitmproxy"`
+    Collection Collection        `json:"collection"`
+    Policy     string            `json:"policy"`   // 使用的扫描策略，为空则按照 plugins 中的配置
+    Policies   map[string]Policy `json:"policies"` // 自定义扫描策略，同名会覆盖内置策略
 }
 
 type WebScan struct {
@@ -94,6 +96,7 @@ type Plugins struct {
     XSS struct {
         Enabled           bool `json:"enabled"`
         DetectXssInCookie bool `json:"detectXssInCookie"`
+        BrowserVerify     bool `json:"browserVerify"` // 在浏览器中验证反射型 xss，需要启动浏览器
     } `json:"xss"`
     
     Sql struct {
@@ -157,6 +160,82 @@ type Plugins struct {
     PortScan struct {
         Enabled bool `json:"enabled"`
     } `json:"portScan"`
+    
+    Smuggling struct {
+        Enabled      bool `json:"enabled"`
+        Differential bool `json:"differential"` // 基于时间检测到后，走私请求确认，请求会到达后端
+    } `json:"smuggling"`
+    
+    Cors struct {
+        Enabled bool `json:"enabled"`
+    } `json:"cors"`
+    
+    Redirect struct {
+        Enabled bool `json:"enabled"`
+    } `json:"redirect"`
+    
+    Ssti struct {
+        Enabled bool `json:"enabled"`
+    } `json:"ssti"`
+    
+    Lfi struct {
+        Enabled bool `json:"enabled"`
+    } `json:"lfi"`
+    
+    Nosql struct {
+        Enabled bool `json:"enabled"`
+    } `json:"nosql"`
+    
+    Graphql struct {
+        Enabled bool `json:"enabled"`
+    } `json:"graphql"`
+    
+    WebSocket struct {
+        Enabled bool `json:"enabled"`
+    } `json:"websocket"`
+    
+    Upload struct {
+        Enabled  bool `json:"enabled"`
+        Htaccess bool `json:"htaccess"` // 上传 .htaccess 让自定义扩展名按照 php 解析，会改变目标整个目录的解析方式
+    } `json:"upload"`
+    
+    Authz struct {
+        Enabled       bool       `json:"enabled"`
+        Identities    []Identity `json:"identities"`    // 其他用户的身份
+        UnsafeMethods bool       `json:"unsafeMethods"` // 重放 POST、PUT、DELETE 等会修改数据的请求
+    } `json:"authz"`
+    
+    Csrf struct {
+        Enabled bool `json:"enabled"`
+    } `json:"csrf"`
+    
+    Ssl struct {
+        Enabled bool `json:"enabled"`
+    } `json:"ssl"`
+    
+    Deserialization struct {
+        Enabled bool `json:"enabled"`
+    } `json:"deserialization"`
+    
+    Viewstate struct {
+        Enabled     bool   `json:"enabled"`
+        MachineKeys string `json:"machineKeys"` // 额外的 machineKey 文件，每行 validationKey,decryptionKey
+    } `json:"viewstate"`
+    
+    Prototype struct {
+        Enabled bool `json:"enabled"`
+    } `json:"prototype"`
+    
+    BlindXss struct {
+        Enabled bool   `json:"enabled"`
+        Listen  string `json:"listen"` // 回连服务的监听地址，如 0.0.0.0:8899
+        Url     string `json:"url"`    // 目标浏览器访问回连服务使用的地址，如 http://1.2.3.4:8899，为空时使用 http://监听地址
+    } `json:"blindXss"`
+    
+    DomXss struct {
+        Enabled bool `json:"enabled"`
+        Tabs    int  `json:"tabs"` // 同时渲染的标签页数量
+    } `json:"domXss"`
 }
 
 // Reverse dnslog 配置，使用 dig.pm https://github.com/yumusb/DNSLog-Platform-Golang
@@ -173,6 +252,12 @@ type Sqlmap struct {
     Password string `json:"password"` // SQLMap API 密码
 }
 
+// Identity 越权检测时使用的其他用户身份，请求头会替换原始请求中的认证信息
+type Identity struct {
+    Name    string            `json:"name"`
+    Headers map[string]string `json:"headers"`
+}
+
 // Collection 信息收集中的正则
 type Collection struct {
     Domain              []string `json:"domain"`
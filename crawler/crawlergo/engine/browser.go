@@ -104,6 +104,19 @@ func (bro *Browser) NewTab(timeout time.Duration) (context.Context, context.Canc
     return tCtx, cancel
 }
 
+// NewTabContext 和 NewTab 一样，parent 取消时同时关闭标签页，扫描任务取消后不再继续渲染
+func (bro *Browser) NewTabContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+    ctx, cancel := bro.NewTab(timeout)
+    if parent == nil {
+        return ctx, cancel
+    }
+    stop := context.AfterFunc(parent, cancel)
+    return ctx, func() {
+        stop()
+        cancel()
+    }
+}
+
 func (bro *Browser) Close() {
     for _, cancel := range bro.tabCancels {
         cancel()
@@ -194,7 +194,7 @@ func (tab *Tab) ParseResponseURL(target string, v *network.EventResponseReceived
     resStr := string(res)
     
     // 这里获取了 body, 这里进行敏感信息检测
-    go sensitive.Detection(target, "", resStr)
+    go sensitive.Detection(target, "", resStr, tab.config.Sink)
     
     urlRegex := regexp.MustCompile(config.SuspectURLRegex)
     urlList := urlRegex.FindAllString(resStr, -1)
@@ -6,6 +6,7 @@ import (
     "errors"
     "fmt"
     regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
     cconfig "github.com/yhy0/Jie/crawler/crawlergo/config"
     "github.com/yhy0/Jie/crawler/crawlergo/js"
     "github.com/yhy0/Jie/crawler/crawlergo/model"
@@ -63,6 +64,7 @@ type TabConfig struct {
     Proxy                   string
     CustomFormValues        map[string]string
     CustomFormKeywordValues map[string]string
+    Sink                    *output.Sink // 爬虫中发现的漏洞的接收者，为空时发送到 output.OutChannel
 }
 
 type bindingCallPayload struct {
@@ -197,19 +199,9 @@ func NewTab(browser *Browser, navigateReq model.Request, config TabConfig) *Tab
                     logging.Logger.Errorln("[dom-based] json.Unmarshal error:", err)
                     return
                 }
-                for _, point := range points {
-                    output.OutChannel <- output.VulMessage{
-                        DataType: "web_vul",
-                        Plugin:   "XSS",
-                        VulnData: output.VulnData{
-                            CreateTime: time.Now().Format("2006-01-02 15:04:05"),
-                            Target:     point.Url,
-                            VulnType:   "Dom XSS",
-                            Method:     "GET",
-                            Payload:    fmt.Sprintf("Source:%v \t Sink:%v\n", point.Source, point.Sink),
-                        },
-                        Level: output.Medium,
-                    }
+                // 开启 domxss 插件时，爬到的页面会由插件重新渲染并确认，这里不再重复输出
+                if !conf.Plugin["domxss"] {
+                    go xss.Report(points, nil, tab.config.Sink)
                 }
             }
             
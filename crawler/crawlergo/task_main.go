@@ -205,6 +205,10 @@ func (t *CrawlerTask) Run() {
 添加之前实时过滤
 */
 func (t *CrawlerTask) addTask2Pool(req *model.Request) {
+    if t.Config.Ctx != nil && t.Config.Ctx.Err() != nil {
+        return
+    }
+    
     t.taskCountLock.Lock()
     if t.crawledCount >= t.Config.MaxCrawlCount {
         t.taskCountLock.Unlock()
@@ -255,6 +259,7 @@ func (t *tabTask) Task() {
         IgnoreKeywords:          t.crawlerTask.Config.IgnoreKeywords,
         CustomFormValues:        t.crawlerTask.Config.CustomFormValues,
         CustomFormKeywordValues: t.crawlerTask.Config.CustomFormKeywordValues,
+        Sink:                    t.crawlerTask.Config.Sink,
     })
     tab.Start()
 
@@ -1,6 +1,10 @@
 package crawlergo
 
-import "time"
+import (
+    "context"
+    "github.com/yhy0/Jie/pkg/output"
+    "time"
+)
 
 type TaskConfig struct {
     MaxCrawlCount           int    // 最大爬取的数量
@@ -27,6 +31,8 @@ type TaskConfig struct {
     CustomFormValues        map[string]string // 自定义表单填充参数
     CustomFormKeywordValues map[string]string // 自定义表单关键词填充内容
     MaxRunTime              int64             // 最大爬取时间(单位秒），超时则结束任务，平滑结束（比如某个url还未处理完不能结束，需要一次req完成后才可以结束整个任务）
+    Ctx                     context.Context   // 取消后不再打开新的标签页，为空时不能取消
+    Sink                    *output.Sink      // 爬虫中发现的漏洞的接收者，为空时发送到 output.OutChannel
 }
 
 type TaskConfigOptFunc func(*TaskConfig)
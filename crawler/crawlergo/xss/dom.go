@@ -16,7 +16,8 @@ import (
 /**
   @author: yhy
   @since: 2023/8/3
-  @desc: //TODO
+  @desc: dom 型 xss 污点传播分析，preload.js 中 hook 了 source、sink，HookParse 将页面中的 js 转换为调用 hook 的形式
+        sink 收到被污染的数据时通过 EventPushVul 绑定回传 VulPoint
 **/
 
 const (
@@ -51,20 +52,11 @@ func ParseDomHookResponseJs(ctx context.Context, event *fetch.EventRequestPaused
     }
     switch event.ResourceType {
     case network.ResourceTypeDocument:
-        ss := scriptContentRex.FindAllSubmatch(resBody, -1)
-        for i := range ss {
-            convedBody, err := dom.HookParse(util.BytesToString(ss[i][1]))
-            if err != nil {
-                logrus.Errorf("[dom-based] body hookconv %s error: %s\n", event.Request.URL, err)
-                continue
-            }
-            resBody = bytes.Replace(resBody, ss[i][1], util.StringToBytes(convedBody), 1)
-        }
-        return fetch.FulfillRequest(event.RequestID, event.ResponseStatusCode).WithBody(string(resBody)).Do(ctx)
+        resBody = HookDocument(resBody, event.Request.URL)
+        return fetch.FulfillRequest(event.RequestID, event.ResponseStatusCode).WithBody(base64.StdEncoding.EncodeToString(resBody)).Do(ctx)
     case network.ResourceTypeScript:
-        convertedResBody, err := dom.HookParse(util.BytesToString(resBody))
+        convertedResBody, err := HookScript(resBody, event.Request.URL)
         if err != nil {
-            logrus.Errorf("[dom-based] script hookconv %s error: %s\n", event.Request.URL, err)
             return err
         }
         return fetch.FulfillRequest(event.RequestID, event.ResponseStatusCode).WithBody(base64.StdEncoding.EncodeToString(
@@ -72,3 +64,27 @@ func ParseDomHookResponseJs(ctx context.Context, event *fetch.EventRequestPaused
     }
     return nil
 }
+
+// HookDocument 转换 html 中所有内联的 script，转换失败的保持原样
+func HookDocument(body []byte, u string) []byte {
+    ss := scriptContentRex.FindAllSubmatch(body, -1)
+    for i := range ss {
+        convedBody, err := dom.HookParse(util.BytesToString(ss[i][1]))
+        if err != nil {
+            logrus.Errorf("[dom-based] body hookconv %s error: %s\n", u, err)
+            continue
+        }
+        body = bytes.Replace(body, ss[i][1], util.StringToBytes(convedBody), 1)
+    }
+    return body
+}
+
+// HookScript 转换 js 文件
+func HookScript(body []byte, u string) (string, error) {
+    convertedResBody, err := dom.HookParse(util.BytesToString(body))
+    if err != nil {
+        logrus.Errorf("[dom-based] script hookconv %s error: %s\n", u, err)
+        return "", err
+    }
+    return convertedResBody, nil
+}
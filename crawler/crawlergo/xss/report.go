@@ -0,0 +1,151 @@
+package xss
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/util"
+    "net/url"
+    "strings"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/6
+   @desc 将污点传播的结果输出为漏洞，根据 source、sink 生成最小的触发 payload
+        source 为地址时生成可以直接打开的 poc 地址，交给 confirm 在浏览器中确认，确认的为 High，否则为 Medium
+        document.cookie、document.referrer、window.name、storage 等无法通过地址触发，只在描述中说明利用方式
+**/
+
+// Poc 触发 payload 的地址，Marker 为 payload 中 alert 的参数
+type Poc struct {
+    Url    string
+    Marker string
+}
+
+// Report 输出 VulPoint，confirm 为空时不确认(如爬虫中发现的)，sink 为空时发送到 output.OutChannel
+// 同一个扫描(sink)、同一个路径(爬虫、插件)中页面(不含参数) + source + sink 只输出一次，爬虫中发现的不会确认，不能影响插件中的确认
+func Report(points []VulPoint, confirm func(poc Poc) bool, sink *output.Sink) {
+    for _, point := range points {
+        page := point.Url
+        if u, err := url.Parse(point.Url); err == nil {
+            u.RawQuery, u.Fragment = "", ""
+            page = u.String()
+        }
+        if !sink.Once(fmt.Sprintf("domxss|%t|%s|%s|%s", confirm != nil, page, point.Source.Label, point.Sink.Label)) {
+            continue
+        }
+        report(point, confirm, sink)
+    }
+}
+
+func report(point VulPoint, confirm func(poc Poc) bool, sink *output.Sink) {
+    pocs := Pocs(point)
+    level, payload, confirmed := output.Medium, "", false
+    if len(pocs) > 0 {
+        payload = pocs[0].Url
+    }
+    if confirm != nil {
+        for _, poc := range pocs {
+            if confirm(poc) {
+                level, payload, confirmed = output.High, poc.Url, true
+                break
+            }
+        }
+    }
+    
+    description := fmt.Sprintf("DOM XSS: data from the source %s flows into the sink %s without sanitization.\nSource stack: %s\nSink stack: %s\n",
+        point.Source.Label, point.Sink.Label, point.Source.stack(), point.Sink.stack())
+    switch {
+    case confirmed:
+        description += "Confirmed in browser: opening the payload url executes alert."
+    case len(pocs) > 0:
+        description += "Not confirmed automatically, the flow may need user interaction or a different payload, try opening the payload url."
+    default:
+        payload = sinkPayload(point.Sink.Label, "1")
+        description += fmt.Sprintf("The source %s can not be controlled through the url, set it to %s to trigger the sink (e.g. via a page that sets window.name, a cookie injection or a stored value).", point.Source.Label, payload)
+    }
+    
+    evidence, _ := json.MarshalIndent(point, "", "  ")
+    sink.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "DOM XSS",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            Target:      point.Url,
+            VulnType:    "Dom XSS",
+            Method:      "GET",
+            Param:       point.Source.Label,
+            Payload:     payload,
+            Response:    string(evidence),
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (t TrackChain) stack() string {
+    var frames []string
+    for _, s := range t.Stacktrace {
+        frames = append(frames, fmt.Sprintf("%s:%s:%s", s.Url, s.Line, s.Column))
+    }
+    if len(frames) == 0 {
+        return "unknown"
+    }
+    return strings.Join(frames, " <- ")
+}
+
+// sinkPayload 根据 sink 的类型生成 payload
+func sinkPayload(sink, marker string) string {
+    switch {
+    case strings.HasPrefix(sink, "eval") || strings.HasPrefix(sink, "new Function") || strings.HasPrefix(sink, "setTimeout") ||
+        strings.Contains(sink, "('on") || (strings.HasPrefix(sink, "HTMLScriptElement.") && !strings.Contains(sink, "src")):
+        return fmt.Sprintf("alert(%s)", marker)
+    case strings.Contains(sink, "HTMLScriptElement") && strings.Contains(sink, "src"):
+        return fmt.Sprintf("data:,alert(%s)", marker)
+    case strings.Contains(sink, "href") || strings.Contains(sink, "action") || strings.Contains(sink, "formAction") ||
+        strings.Contains(sink, "formaction") || strings.Contains(sink, "src") || strings.Contains(sink, "data") || strings.Contains(sink, "click"):
+        return fmt.Sprintf("javascript:alert(%s)", marker)
+    }
+    // innerHTML、outerHTML、document.write、createContextualFragment 等 html sink
+    return fmt.Sprintf("<img src=x onerror=alert(%s)>", marker)
+}
+
+// Pocs 根据 source 将 payload 放到地址中，source 无法通过地址控制时返回空
+func Pocs(point VulPoint) []Poc {
+    u, err := url.Parse(point.Url)
+    if err != nil {
+        return nil
+    }
+    marker := util.RandomFromChoices(9, "123456789")
+    payload := sinkPayload(point.Sink.Label, marker)
+    
+    var pocs []Poc
+    switch point.Source.Label {
+    case "window.location", "window.location.href", "window.location.hash", "document.URL", "document.documentURI", "document.baseURI":
+        // fragment 不会发送到服务端，整个地址作为 source 时 payload 在最后
+        p := *u
+        p.Fragment = ""
+        pocs = append(pocs, Poc{Url: p.String() + "#" + payload, Marker: marker})
+        if point.Source.Label == "window.location.hash" {
+            break
+        }
+        fallthrough
+    case "window.location.search":
+        query := u.Query()
+        for name := range query {
+            p := *u
+            q := u.Query()
+            q.Set(name, payload)
+            p.RawQuery = q.Encode()
+            pocs = append(pocs, Poc{Url: p.String(), Marker: marker})
+        }
+        if len(query) == 0 {
+            p := *u
+            p.RawQuery = url.Values{"q": []string{payload}}.Encode()
+            pocs = append(pocs, Poc{Url: p.String(), Marker: marker})
+        }
+    }
+    return pocs
+}
@@ -0,0 +1,70 @@
+package xss
+
+import (
+    "github.com/yhy0/Jie/pkg/output"
+    "net/url"
+    "strings"
+    "testing"
+)
+
+func point(u, source, sink string) VulPoint {
+    return VulPoint{Url: u, Source: TrackChain{Label: source}, Sink: TrackChain{Label: sink}}
+}
+
+// 爬虫中未确认的结果不能阻止插件确认，不同扫描互不影响
+func TestReportDedupe(t *testing.T) {
+    var levels []string
+    sink := &output.Sink{OnFinding: func(msg output.VulMessage) { levels = append(levels, msg.Level) }}
+    points := []VulPoint{
+        point("http://example.com/a?x=1", "window.location.search", "Element.innerHTML"),
+        point("http://example.com/a?x=2", "window.location.search", "Element.innerHTML"),
+    }
+    
+    Report(points, nil, sink)
+    Report(points, func(poc Poc) bool { return true }, sink)
+    Report(points, func(poc Poc) bool { return true }, sink)
+    if len(levels) != 2 || levels[0] != output.Medium || levels[1] != output.High {
+        t.Errorf("levels = %v, want [Medium High]", levels)
+    }
+    
+    var other int
+    Report(points, nil, &output.Sink{OnFinding: func(msg output.VulMessage) { other++ }})
+    if other != 1 {
+        t.Errorf("another scan got %d findings, want 1", other)
+    }
+}
+
+func TestPocs(t *testing.T) {
+    pocs := Pocs(point("http://example.com/a?x=1#top", "window.location.hash", "Element.innerHTML"))
+    if len(pocs) != 1 || !strings.HasPrefix(pocs[0].Url, "http://example.com/a?x=1#<img src=x onerror=alert(") {
+        t.Errorf("hash pocs = %v", pocs)
+    }
+    
+    pocs = Pocs(point("http://example.com/a?x=1", "window.location.search", "eval"))
+    if len(pocs) != 1 {
+        t.Fatalf("search pocs = %v", pocs)
+    }
+    u, _ := url.Parse(pocs[0].Url)
+    if u.Query().Get("x") != "alert("+pocs[0].Marker+")" {
+        t.Errorf("search poc = %s", pocs[0].Url)
+    }
+    
+    if pocs = Pocs(point("http://example.com/a", "document.cookie", "eval")); len(pocs) != 0 {
+        t.Errorf("cookie source should have no url poc: %v", pocs)
+    }
+}
+
+func TestSinkPayload(t *testing.T) {
+    tests := map[string]string{
+        "eval":                     "alert(1)",
+        "HTMLScriptElement.src":    "data:,alert(1)",
+        "HTMLAnchorElement.href":   "javascript:alert(1)",
+        "Element.innerHTML":        "<img src=x onerror=alert(1)>",
+        "Element.setAttribute('on": "alert(1)",
+    }
+    for sink, want := range tests {
+        if got := sinkPayload(sink, "1"); got != want {
+            t.Errorf("sinkPayload(%q) = %q, want %q", sink, got, want)
+        }
+    }
+}
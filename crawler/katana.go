@@ -1,6 +1,8 @@
 package crawler
 
 import (
+    "context"
+    "github.com/projectdiscovery/fastdialer/fastdialer"
     "github.com/projectdiscovery/katana/pkg/engine"
     "github.com/projectdiscovery/katana/pkg/engine/hybrid"
     "github.com/projectdiscovery/katana/pkg/engine/standard"
@@ -9,6 +11,8 @@ import (
     "github.com/yhy0/Jie/conf"
     "github.com/yhy0/logging"
     "math"
+    "net"
+    "syscall"
 )
 
 /**
@@ -28,19 +32,27 @@ var ExtensionFilter = []string{
     ".flv", ".mpeg", ".dat", ".xsl", ".csv", ".cab", ".exif", ".wps", ".m4v", ".rmvb",
 }
 
-func Katana(target string, headless bool, show bool, out func(result output.Result)) {
+// Katana 爬取 target，ctx 取消后不再建立新的连接、不再输出结果并立即返回，katana 没有提供传入 ctx 的方法，剩余的队列在后台结束
+func Katana(ctx context.Context, target string, headless bool, show bool, out func(result output.Result)) {
+    if ctx == nil {
+        ctx = context.Background()
+    }
     // todo 作为库，还有 bug，这里有的参数根本不起作用，先自行处理
     options := &types.Options{
-        MaxDepth:        3,             // Maximum depth to crawl
-        FieldScope:      "fqdn",        //  rdn: 爬取范围为根域名和所有子域(默认), dn:搜索范围为域名关键字 fqdn:爬取范围为给定子(域)
-        BodyReadSize:    math.MaxInt,   // Maximum response size to read
-        Timeout:         10,            // Timeout is the time to wait for request in seconds
-        Concurrency:     10,            // Concurrency is the number of concurrent crawling goroutines
-        Parallelism:     10,            // Parallelism is the number of urls processing goroutines
-        Delay:           0,             // Delay is the delay between each crawl requests in seconds
-        RateLimit:       150,           // Maximum requests to send per second
-        Strategy:        "depth-first", // Visit strategy (depth-first, breadth-first)
-        OnResult:        out,
+        MaxDepth:     3,             // Maximum depth to crawl
+        FieldScope:   "fqdn",        //  rdn: 爬取范围为根域名和所有子域(默认), dn:搜索范围为域名关键字 fqdn:爬取范围为给定子(域)
+        BodyReadSize: math.MaxInt,   // Maximum response size to read
+        Timeout:      10,            // Timeout is the time to wait for request in seconds
+        Concurrency:  10,            // Concurrency is the number of concurrent crawling goroutines
+        Parallelism:  10,            // Parallelism is the number of urls processing goroutines
+        Delay:        0,             // Delay is the delay between each crawl requests in seconds
+        RateLimit:    150,           // Maximum requests to send per second
+        Strategy:     "depth-first", // Visit strategy (depth-first, breadth-first)
+        OnResult: func(result output.Result) {
+            if ctx.Err() == nil {
+                out(result)
+            }
+        },
         Headless:        headless,
         Proxy:           conf.GlobalConfig.Http.Proxy,
         ExtensionFilter: ExtensionFilter,
@@ -54,7 +66,22 @@ func Katana(target string, headless bool, show bool, out func(result output.Resu
     if err != nil {
         logging.Logger.Fatal(err.Error())
     }
-    defer crawlerOptions.Close()
+    
+    // 取消后拒绝建立新的连接，队列中剩余的请求很快失败，爬虫随之结束
+    dialerOpts := fastdialer.DefaultOptions
+    dialerOpts.Dialer = &net.Dialer{
+        Timeout:   dialerOpts.DialerTimeout,
+        KeepAlive: dialerOpts.DialerKeepAlive,
+        Control: func(network, address string, c syscall.RawConn) error {
+            return ctx.Err()
+        },
+    }
+    if dialer, err := fastdialer.NewDialer(dialerOpts); err == nil {
+        crawlerOptions.Dialer.Close()
+        crawlerOptions.Dialer = dialer
+    } else {
+        logging.Logger.Warnln("could not create dialer:", err)
+    }
     
     var crawler engine.Engine
     
@@ -66,13 +93,23 @@ func Katana(target string, headless bool, show bool, out func(result output.Resu
     }
     
     if err != nil {
+        crawlerOptions.Close()
         logging.Logger.Fatal("could not create standard crawler", err.Error())
     }
     
-    defer crawler.Close()
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        defer crawlerOptions.Close()
+        defer crawler.Close()
+        
+        if err := crawler.Crawl(target); err != nil {
+            logging.Logger.Warnf("Could not crawl %s: %s", target, err.Error())
+        }
+    }()
     
-    err = crawler.Crawl(target)
-    if err != nil {
-        logging.Logger.Warnf("Could not crawl %s: %s", target, err.Error())
+    select {
+    case <-done:
+    case <-ctx.Done():
     }
 }
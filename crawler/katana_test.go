@@ -1,6 +1,7 @@
 package crawler
 
 import (
+    "context"
     "github.com/projectdiscovery/katana/pkg/output"
     "github.com/yhy0/logging"
     "testing"
@@ -22,5 +23,5 @@ func TestKatana(t *testing.T) {
         logging.Logger.Infoln(result.Request.URL)
     }
     
-    Katana("https://www.baidu.com", true, true, out)
+    Katana(context.Background(), "https://www.baidu.com", true, true, out)
 }
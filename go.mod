@@ -15,7 +15,7 @@ require (
 	github.com/lestrrat-go/file-rotatelogs v2.4.0+incompatible // indirect
 	github.com/logrusorgru/aurora v2.0.3+incompatible
 	github.com/mgutz/ansi v0.0.0-20200706080929-d51e80ef957d // indirect
-	github.com/projectdiscovery/fastdialer v0.0.71 // indirect
+	github.com/projectdiscovery/fastdialer v0.0.71
 	github.com/projectdiscovery/goflags v0.1.52 // indirect
 	github.com/projectdiscovery/hmap v0.0.42 // indirect
 	github.com/projectdiscovery/ratelimit v0.0.41
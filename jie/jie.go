@@ -0,0 +1,264 @@
+package jie
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/crawler"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/mode"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/task"
+    "github.com/yhy0/Jie/scan"
+    "github.com/yhy0/Jie/scan/PerFile/blindxss"
+    "github.com/yhy0/Jie/scan/PerFile/domxss"
+    "github.com/yhy0/logging"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/16
+   @desc 嵌入使用的接口，每个 Scanner 有自己的插件实例、插件开关、http 配置、扫描策略、安全模式和回调，同一个进程中可以同时运行多个扫描
+        发现的漏洞和网站信息只会交给对应的 Scanner，反连平台等仍然是进程级别的，由 Init 初始化一次
+**/
+
+// Options 扫描器配置
+type Options struct {
+    Targets      []string       // 扫描目标
+    Crawler      string         // 爬虫 c: Crawlergo k: Katana kh: Katana 无头模式，默认 k
+    Plugins      []string       // 开启的插件，all 表示全部开启，为空时使用配置文件中的插件开关
+    Parallelism  int            // 同时扫描的最大 url 个数，默认 conf.Parallelism
+    Http         *httpx.Options // http 配置，为空时使用配置文件中的配置
+    Policy       string         // 扫描策略，Plugins 为空时使用策略中的插件
+    SafeMode     bool           // 安全模式，跳过会写入文件、爆破类的检测，拒绝发送可能修改数据的请求
+    Differential *bool          // 请求走私检测到后是否走私请求确认，为空时使用配置文件中的配置，安全模式下不生效
+    
+    OnFinding  func(v output.VulMessage)   // 发现漏洞时回调
+    OnCrawl    func(in *input.CrawlResult) // 每个要扫描的请求分发前回调
+    OnProgress func(p Progress)            // 扫描进度，每秒回调一次，扫描结束时再回调一次
+}
+
+// Progress 扫描进度
+type Progress struct {
+    Target   string // 当前扫描的目标
+    Targets  int    // 目标总数
+    Finished int    // 已经扫描完的目标数
+    Total    int64  // 已分发的请求数
+    Done     int64  // 已扫描完的请求数
+}
+
+// Scanner 扫描器，通过 New 创建
+type Scanner struct {
+    opts    Options
+    task    *task.Task
+    sink    *output.Sink
+    cancel  context.CancelFunc
+    done    chan struct{}
+    running int32
+    
+    target   atomic.Value // 当前扫描的目标
+    finished int32
+}
+
+// ErrRunning 扫描器已经启动过
+var ErrRunning = errors.New("scanner is already running")
+
+var (
+    initOnce    sync.Once
+    browserOnce sync.Once
+)
+
+// Init 初始化日志，加载内置的默认配置，不会读写配置文件，所有 Scanner 共享，只会执行一次，New 时会自动调用
+func Init(debug bool) {
+    initOnce.Do(func() {
+        if logging.Logger == nil {
+            logging.Logger = logging.New(debug, "", "Jie", true)
+        }
+        conf.InitDefault()
+        // Scanner 的结果都通过自己的 Sink 输出，这里只是防止没有使用 Sink 的检测阻塞
+        go func() {
+            for v := range output.OutChannel {
+                logging.Logger.Warnln("finding without a scanner:", v.Plugin, v.VulnData.Target)
+            }
+        }()
+    })
+}
+
+// Close 释放所有 Scanner 共享的资源(dom xss 检测单独启动的浏览器、盲打 xss 的回连服务)，所有扫描结束、程序退出前调用
+// 盲打 xss 的回连可能在扫描结束很久之后才触发，需要等待回连时延后调用
+func Close() {
+    domxss.Close()
+    blindxss.Close()
+}
+
+// New 创建扫描器
+func New(opts Options) (*Scanner, error) {
+    if len(opts.Targets) == 0 {
+        return nil, errors.New("targets must be set")
+    }
+    
+    Init(false)
+    
+    if opts.Crawler == "" {
+        opts.Crawler = "k"
+    }
+    if opts.Parallelism <= 0 {
+        opts.Parallelism = conf.Parallelism
+    }
+    
+    scope := &conf.Scope{SafeMode: opts.SafeMode, Differential: opts.Differential}
+    if opts.Policy != "" {
+        policy, ok := conf.GetPolicy(opts.Policy)
+        if !ok {
+            return nil, fmt.Errorf("policy %s not found, available: %s", opts.Policy, strings.Join(conf.PolicyNames(), ", "))
+        }
+        scope.Policy = policy
+        if len(opts.Plugins) == 0 {
+            opts.Plugins = policy.Plugins
+        }
+    }
+    
+    s := &Scanner{
+        opts: opts,
+        sink: &output.Sink{OnFinding: opts.OnFinding},
+        done: make(chan struct{}),
+    }
+    
+    s.task = &task.Task{
+        Parallelism: opts.Parallelism,
+        ScanTask:    make(map[string]*task.ScanTask),
+        Plugins:     scan.NewPluginSet(),
+        Enabled:     enabled(opts.Plugins),
+        HttpOptions: opts.Http,
+        OnCrawl:     opts.OnCrawl,
+        Sink:        s.sink,
+        Scope:       scope,
+    }
+    s.target.Store("")
+    return s, nil
+}
+
+// enabled 根据指定的插件生成插件开关，为空时使用配置文件中的插件开关
+func enabled(plugins []string) map[string]bool {
+    if len(plugins) == 0 {
+        return nil
+    }
+    
+    res := make(map[string]bool)
+    for k := range conf.Plugin {
+        res[k] = false
+    }
+    for _, plugin := range plugins {
+        if plugin == "all" {
+            for k := range res {
+                res[k] = true
+            }
+            break
+        }
+        for k := range res {
+            if strings.EqualFold(k, plugin) {
+                res[k] = true
+            }
+        }
+    }
+    return res
+}
+
+// Start 开始扫描，不会阻塞，ctx 取消或者调用 Stop 后停止扫描
+func (s *Scanner) Start(ctx context.Context) error {
+    if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+        return ErrRunning
+    }
+    
+    if s.opts.Crawler == "c" {
+        browserOnce.Do(func() {
+            crawler.NewCrawlergo(false)
+        })
+    }
+    
+    ctx, s.cancel = context.WithCancel(ctx)
+    s.task.Ctx = ctx
+    
+    go s.progress(ctx)
+    go s.run(ctx)
+    return nil
+}
+
+func (s *Scanner) run(ctx context.Context) {
+    defer func() {
+        s.cancel()
+        if s.opts.OnProgress != nil {
+            s.opts.OnProgress(s.Progress())
+        }
+        close(s.done)
+    }()
+    
+    for _, target := range s.opts.Targets {
+        if ctx.Err() != nil {
+            return
+        }
+        s.target.Store(target)
+        if _, _, err := mode.ActiveTask(ctx, s.task, target, s.opts.Crawler, nil); err != nil {
+            logging.Logger.Warnln(target, err)
+        }
+        atomic.AddInt32(&s.finished, 1)
+    }
+}
+
+func (s *Scanner) progress(ctx context.Context) {
+    if s.opts.OnProgress == nil {
+        return
+    }
+    ticker := time.NewTicker(time.Second)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            s.opts.OnProgress(s.Progress())
+        }
+    }
+}
+
+// Progress 获取当前的扫描进度
+func (s *Scanner) Progress() Progress {
+    return Progress{
+        Target:   s.target.Load().(string),
+        Targets:  len(s.opts.Targets),
+        Finished: int(atomic.LoadInt32(&s.finished)),
+        Total:    atomic.LoadInt64(&s.task.Total),
+        Done:     atomic.LoadInt64(&s.task.Done),
+    }
+}
+
+// Stop 停止扫描，爬虫不再爬取新的页面，插件之后的请求直接返回 context.Canceled，Wait 等待已经发出的请求结束
+func (s *Scanner) Stop() {
+    if s.cancel != nil {
+        s.cancel()
+    }
+}
+
+// Wait 等待扫描结束
+func (s *Scanner) Wait() {
+    if atomic.LoadInt32(&s.running) == 0 {
+        return
+    }
+    <-s.done
+}
+
+// Done 扫描结束后关闭
+func (s *Scanner) Done() <-chan struct{} {
+    return s.done
+}
+
+// SCopilot 获取扫描过程中收集的网站信息，key 为 host
+func (s *Scanner) SCopilot() map[string]*output.SCopilotData {
+    return s.sink.Messages()
+}
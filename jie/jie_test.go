@@ -0,0 +1,137 @@
+package jie
+
+import (
+    "context"
+    "errors"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/logging"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "sync/atomic"
+    "testing"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "jie", false)
+    os.Exit(m.Run())
+}
+
+func TestEnabled(t *testing.T) {
+    Init(false)
+    if enabled(nil) != nil {
+        t.Error("empty plugins should use the global switches")
+    }
+    res := enabled([]string{"XSS", "sql"})
+    if !res["xss"] || !res["sql"] || res["cmd"] {
+        t.Errorf("enabled = %v", res)
+    }
+    for k, v := range enabled([]string{"all"}) {
+        if !v {
+            t.Errorf("%s not enabled by all", k)
+        }
+    }
+}
+
+func TestNew(t *testing.T) {
+    if _, err := New(Options{}); err == nil {
+        t.Error("no targets accepted")
+    }
+    if _, err := New(Options{Targets: []string{"http://127.0.0.1:1"}, Policy: "nonexistent"}); err == nil {
+        t.Error("unknown policy accepted")
+    }
+    
+    s, err := New(Options{Targets: []string{"http://127.0.0.1:1"}, Policy: "quick", SafeMode: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !s.task.Enable("xss") || s.task.Enable("hydra") {
+        t.Errorf("policy plugins not used: %v", s.task.Enabled)
+    }
+    if !s.task.Scope.Safe() || s.task.Scope.PayloadLevel() != conf.PayloadQuick || s.task.Scope.MaxRequests("xss") != 100 {
+        t.Errorf("scope = %+v", s.task.Scope)
+    }
+    // 扫描器的配置不影响全局配置
+    if conf.SafeMode || conf.ActivePolicy.PayloadLevel != 0 {
+        t.Error("scanner options leaked into the global config")
+    }
+}
+
+// deep 策略不开启破坏性和爆破类插件
+func TestDeepPolicy(t *testing.T) {
+    s, err := New(Options{Targets: []string{"http://127.0.0.1:1"}, Policy: "Deep"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, name := range []string{"upload", "prototype", "csrf", "brute", "hydra"} {
+        if s.task.Enable(name) {
+            t.Errorf("%s enabled by deep", name)
+        }
+    }
+    if !s.task.Enable("xss") || !s.task.Enable("domxss") || s.task.Scope.PayloadLevel() != conf.PayloadDeep {
+        t.Errorf("deep policy = %v", s.task.Enabled)
+    }
+}
+
+func TestStartTwice(t *testing.T) {
+    s, err := New(Options{Targets: []string{"http://127.0.0.1:1"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if err = s.Start(ctx); err != nil {
+        t.Fatal(err)
+    }
+    if err = s.Start(context.Background()); !errors.Is(err, ErrRunning) {
+        t.Errorf("second Start = %v, want ErrRunning", err)
+    }
+    s.Wait()
+}
+
+// 插件通过 client 输出的漏洞只交给创建这个 client 的扫描器
+func TestSinkIsolation(t *testing.T) {
+    var a, b int32
+    sa, _ := New(Options{Targets: []string{"http://example.com"}, OnFinding: func(v output.VulMessage) { atomic.AddInt32(&a, 1) }})
+    sb, _ := New(Options{Targets: []string{"http://example.com"}, OnFinding: func(v output.VulMessage) { atomic.AddInt32(&b, 1) }})
+    
+    sa.task.NewClient().Report(output.VulMessage{Plugin: "test", VulnData: output.VulnData{Target: "http://example.com/"}})
+    sa.task.NewClient().SCopilot("example.com:80", output.SCopilotData{Target: "example.com", SiteMap: []string{"http://example.com/"}})
+    
+    if atomic.LoadInt32(&a) != 1 || atomic.LoadInt32(&b) != 0 {
+        t.Errorf("findings: a=%d b=%d, want 1 0", a, b)
+    }
+    if len(sa.SCopilot()) != 1 || sa.SCopilot()["example.com"] == nil || len(sb.SCopilot()) != 0 {
+        t.Errorf("SCopilot: a=%v b=%v", sa.SCopilot(), sb.SCopilot())
+    }
+    if _, ok := output.SCopilotMessage["example.com"]; ok {
+        t.Error("site info leaked into the global SCopilotMessage")
+    }
+}
+
+// Stop 之后插件的请求不再发送
+func TestStopCancelsRequests(t *testing.T) {
+    var hits int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&hits, 1)
+    }))
+    defer server.Close()
+    
+    s, err := New(Options{Targets: []string{"http://127.0.0.1:1"}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err = s.Start(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    s.Stop()
+    s.Wait()
+    
+    if _, err = s.task.NewClient().Request(server.URL, "GET", "", nil); !errors.Is(err, context.Canceled) {
+        t.Errorf("request after Stop = %v, want context.Canceled", err)
+    }
+    if atomic.LoadInt32(&hits) != 0 {
+        t.Errorf("%d requests sent after Stop", hits)
+    }
+}
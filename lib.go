@@ -1,82 +1,42 @@
 package main
 
 import (
+    "context"
     "github.com/logrusorgru/aurora"
-    "github.com/yhy0/Jie/SCopilot"
-    "github.com/yhy0/Jie/conf"
-    "github.com/yhy0/Jie/crawler"
-    "github.com/yhy0/Jie/pkg/mode"
+    "github.com/yhy0/Jie/jie"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/logging"
-    "net/url"
 )
 
 /**
   @author: yhy
   @since: 2023/12/28
-  @desc: //TODO
+  @desc: 作为库使用的示例，详见 jie 包
 **/
 
 func lib() {
-    logging.Logger = logging.New(conf.GlobalConfig.Debug, "", "Jie", true)
-    conf.Init()
-    conf.GlobalConfig.Http.Proxy = ""
-    conf.GlobalConfig.WebScan.Craw = "k"
-    conf.GlobalConfig.WebScan.Poc = nil
-    conf.GlobalConfig.Reverse.Host = "https://dig.pm/"
-    conf.GlobalConfig.Passive.WebPort = "9088"
-    conf.GlobalConfig.Passive.WebUser = "yhy"
-    conf.GlobalConfig.Passive.WebPass = "123456" // 注意修改为强口令
+    jie.Init(false)
+    defer jie.Close()
     
-    conf.Preparations()
-    
-    // 全部插件开启
-    for k := range conf.Plugin {
-        // if k == "nuclei" || k == "poc" {
-        //     continue
-        // }
-        conf.Plugin[k] = true
+    scanner, err := jie.New(jie.Options{
+        Targets: []string{"http://testphp.vulnweb.com/"},
+        Crawler: "k",
+        Plugins: []string{"all"}, // 全部插件开启
+        OnFinding: func(v output.VulMessage) {
+            logging.Logger.Infoln(aurora.Red(v.PrintScreen()).String())
+        },
+        OnProgress: func(p jie.Progress) {
+            logging.Logger.Debugf("%s %d/%d", p.Target, p.Done, p.Total)
+        },
+    })
+    if err != nil {
+        logging.Logger.Errorln(err)
+        return
     }
     
-    if conf.GlobalConfig.Passive.WebPort != "" {
-        go SCopilot.Init()
+    if err = scanner.Start(context.Background()); err != nil {
+        logging.Logger.Errorln(err)
+        return
     }
-    
-    // 初始化爬虫
-    crawler.NewCrawlergo(false)
-    
-    go func() {
-        for v := range output.OutChannel {
-            // SCopilot 显示
-            if conf.GlobalConfig.Passive.WebPort != "" {
-                parse, err := url.Parse(v.VulnData.Target)
-                if err != nil {
-                    logging.Logger.Errorln(err)
-                    continue
-                }
-                msg := output.SCopilotData{
-                    Target: v.VulnData.Target,
-                }
-                
-                if v.Level == "Low" {
-                    msg.InfoMsg = []output.PluginMsg{
-                        {
-                            Url:      v.VulnData.Target,
-                            Plugin:   v.Plugin,
-                            Result:   []string{v.VulnData.Payload},
-                            Request:  v.VulnData.Request,
-                            Response: v.VulnData.Response,
-                        },
-                    }
-                } else {
-                    msg.VulMessage = append(msg.VulMessage, v)
-                }
-                output.SCopilot(parse.Host, msg)
-                logging.Logger.Infoln(aurora.Red(v.PrintScreen()).String())
-            }
-            logging.Logger.Infoln(aurora.Red(v.PrintScreen()).String())
-        }
-    }()
-    
-    mode.Active("http://testphp.vulnweb.com/", nil)
+    scanner.Wait()
 }
@@ -35,4 +35,10 @@ type CrawlResult struct {
     ParamNames   []string          `json:"param_names"` // 请求中的参数名  user,password，
     Waf          []string          `json:"waf"`         // 是否存在 waf
     Archive      map[string]string `json:"archive"`     // 从 web.archive.org 获取到的历史 url
+    WebSocket    []string          `json:"websocket"`   // WebSocket 握手请求时，连接中客户端发送的文本消息
+    
+    // Rewrite 插件展开的请求，发送前需要还原为原始的格式，例如 GraphQL 的变量需要放回 variables 中
+    Rewrite func(target, method, body string) (string, string, string) `json:"-"`
+    // RoundTrip 插件展开的请求不通过 http 发送时使用，例如放回 WebSocket 消息中发送
+    RoundTrip func(c *httpx.Client, target, method, body string, header map[string]string) (*httpx.Response, error) `json:"-"`
 }
@@ -0,0 +1,417 @@
+package input
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/binary"
+    "encoding/json"
+    "encoding/xml"
+    "fmt"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/PerServer/portScan/masscan"
+    "github.com/yhy0/logging"
+    "io"
+    "net"
+    "net/url"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/18
+   @desc 目标解析，支持 url、host、host:port、端口列表/范围、CIDR、ip 段，以及 nmap/masscan 的 xml、masscan 的 json 结果
+        最后统一转换为 url，并和其他地方一样把 :80、:443 去掉后去重
+**/
+
+// maxExpand 单个 CIDR、ip 段、端口范围最多展开的目标数，防止写错导致展开出几百万个目标
+const maxExpand = 65536
+
+// 没有服务识别结果时(masscan 默认就没有)，这些端口认为是 web 服务
+var webPorts = map[int]bool{
+    80: true, 81: true, 443: true, 591: true, 2082: true, 2083: true, 2086: true, 2087: true, 3000: true, 5000: true,
+    7001: true, 7443: true, 8000: true, 8001: true, 8008: true, 8080: true, 8081: true, 8088: true, 8443: true, 8888: true,
+    9000: true, 9090: true, 9443: true,
+}
+
+// 这些端口默认使用 https
+var httpsPorts = map[int]bool{
+    443: true, 2083: true, 2087: true, 7443: true, 8443: true, 9443: true,
+}
+
+var (
+    portSpecRegex = regexp.MustCompile(`^[0-9][0-9,\-]*$`)
+    cidrRegex     = regexp.MustCompile(`^[0-9a-fA-F.:]+/[0-9]{1,3}(:[0-9,\-]+)?$`)
+)
+
+// ReadTargets 读取目标文件，自动识别 nmap/masscan 的 xml、masscan 的 json 和每行一个目标的文本，path 为 - 时从标准输入读取
+func ReadTargets(path string) ([]string, error) {
+    var data []byte
+    var err error
+    if path == "-" {
+        data, err = io.ReadAll(os.Stdin)
+    } else {
+        data, err = os.ReadFile(path)
+    }
+    if err != nil {
+        return nil, err
+    }
+    
+    data = bytes.TrimSpace(data)
+    switch {
+    case bytes.HasPrefix(data, []byte("<")):
+        return ParseNmapXML(data)
+    case bytes.HasPrefix(data, []byte("[")) || bytes.HasPrefix(data, []byte("{")):
+        return ParseMasscanJSON(data)
+    }
+    
+    var lines []string
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+    for scanner.Scan() {
+        lines = append(lines, scanner.Text())
+    }
+    return ParseTargets(lines), nil
+}
+
+// ParseTargets 解析每行一个的目标，空行和 # 开头的注释会被忽略，格式错误的行输出警告后跳过
+func ParseTargets(lines []string) []string {
+    var targets []string
+    for _, line := range lines {
+        line = strings.TrimSpace(line)
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        res, err := expandTarget(line)
+        if err != nil {
+            logging.Logger.Warnln("skip target", line, err)
+            continue
+        }
+        targets = append(targets, res...)
+    }
+    return Dedupe(targets)
+}
+
+// expandTarget 展开单个目标
+func expandTarget(target string) ([]string, error) {
+    // 带协议的直接当做 url
+    if strings.Contains(target, "://") {
+        return []string{NormalizeTarget(target)}, nil
+    }
+    
+    // 拆分出路径，CIDR 中的 / 不算
+    var path string
+    if i := strings.Index(target, "/"); i >= 0 && !cidrRegex.MatchString(target) {
+        target, path = target[:i], target[i:]
+    }
+    
+    hostPart, portPart, err := splitHostPort(target)
+    if err != nil {
+        return nil, err
+    }
+    
+    hosts, err := expandHosts(hostPart)
+    if err != nil {
+        return nil, err
+    }
+    
+    var ports []int
+    if portPart != "" {
+        ports, err = expandPorts(portPart)
+        if err != nil {
+            return nil, err
+        }
+    }
+    
+    if len(hosts)*max(len(ports), 1) > maxExpand {
+        return nil, fmt.Errorf("too many targets, more than %d", maxExpand)
+    }
+    
+    var targets []string
+    for _, host := range hosts {
+        if len(ports) == 0 {
+            if strings.Contains(host, ":") { // ipv6
+                targets = append(targets, NormalizeTarget("http://["+host+"]"+path))
+                continue
+            }
+            targets = append(targets, NormalizeTarget("http://"+host+path))
+            continue
+        }
+        for _, port := range ports {
+            targets = append(targets, NormalizeTarget(schemeOf(port)+"://"+net.JoinHostPort(host, strconv.Itoa(port))+path))
+        }
+    }
+    return targets, nil
+}
+
+// splitHostPort 拆分 host 和端口列表，ipv6 带端口时需要写成 [::1]:8080，不带方括号的 ipv6 认为没有端口
+func splitHostPort(target string) (string, string, error) {
+    if strings.HasPrefix(target, "[") {
+        i := strings.Index(target, "]")
+        if i < 0 {
+            return "", "", fmt.Errorf("missing ']' in address")
+        }
+        host, rest := target[1:i], target[i+1:]
+        if rest == "" {
+            return host, "", nil
+        }
+        if !strings.HasPrefix(rest, ":") || !portSpecRegex.MatchString(rest[1:]) {
+            return "", "", fmt.Errorf("invalid port %s", rest)
+        }
+        return host, rest[1:], nil
+    }
+    
+    if strings.Count(target, ":") != 1 {
+        return target, "", nil
+    }
+    i := strings.Index(target, ":")
+    if !portSpecRegex.MatchString(target[i+1:]) {
+        return "", "", fmt.Errorf("invalid port %s", target[i+1:])
+    }
+    return target[:i], target[i+1:], nil
+}
+
+// expandHosts 展开 CIDR(10.0.0.0/24)、ip 段(10.0.0.1-10.0.0.20、10.0.0.1-20)，其他的原样返回
+func expandHosts(host string) ([]string, error) {
+    if strings.Contains(host, "/") {
+        // util.Cidr2IPs 不处理错误的 CIDR，这里先校验
+        _, ipNet, err := net.ParseCIDR(host)
+        if err != nil {
+            return nil, err
+        }
+        ones, bits := ipNet.Mask.Size()
+        if bits-ones > 16 {
+            return nil, fmt.Errorf("cidr too large, at most /%d", bits-16)
+        }
+        return util.Cidr2IPs(host), nil
+    }
+    
+    if i := strings.Index(host, "-"); i > 0 {
+        start := net.ParseIP(host[:i]).To4()
+        if start == nil {
+            return []string{host}, nil
+        }
+        endStr := host[i+1:]
+        // 10.0.0.1-20 这种只写了最后一段
+        if !strings.Contains(endStr, ".") {
+            endStr = host[:strings.LastIndex(host[:i], ".")+1] + endStr
+        }
+        end := net.ParseIP(endStr).To4()
+        if end == nil {
+            return nil, fmt.Errorf("invalid ip range")
+        }
+        s, e := binary.BigEndian.Uint32(start), binary.BigEndian.Uint32(end)
+        if s > e {
+            return nil, fmt.Errorf("invalid ip range")
+        }
+        if e-s >= maxExpand {
+            return nil, fmt.Errorf("ip range too large, more than %d", maxExpand)
+        }
+        var ips []string
+        for n := s; n <= e; n++ {
+            ip := make(net.IP, 4)
+            binary.BigEndian.PutUint32(ip, n)
+            ips = append(ips, ip.String())
+        }
+        return ips, nil
+    }
+    
+    return []string{host}, nil
+}
+
+// expandPorts 展开端口列表 80,443,8000-8010
+func expandPorts(spec string) ([]int, error) {
+    var ports []int
+    for _, p := range strings.Split(spec, ",") {
+        if p == "" {
+            continue
+        }
+        start, end := p, p
+        if i := strings.Index(p, "-"); i >= 0 {
+            start, end = p[:i], p[i+1:]
+        }
+        s, err := strconv.Atoi(start)
+        if err != nil {
+            return nil, err
+        }
+        e, err := strconv.Atoi(end)
+        if err != nil {
+            return nil, err
+        }
+        if s < 1 || e > 65535 || s > e {
+            return nil, fmt.Errorf("invalid port %s", p)
+        }
+        for port := s; port <= e; port++ {
+            ports = append(ports, port)
+        }
+    }
+    return ports, nil
+}
+
+func schemeOf(port int) string {
+    if httpsPorts[port] {
+        return "https"
+    }
+    return "http"
+}
+
+// NormalizeTarget 统一目标格式，没有协议时按照端口推断，去掉默认的 :80、:443 端口
+func NormalizeTarget(target string) string {
+    if !strings.Contains(target, "://") {
+        scheme := "http"
+        if strings.Contains(target, ":443") {
+            scheme = "https"
+        }
+        target = scheme + "://" + target
+    }
+    u, err := url.Parse(target)
+    if err != nil {
+        return target
+    }
+    u.Scheme = strings.ToLower(u.Scheme)
+    u.Host = strings.ToLower(u.Host)
+    if u.Scheme == "http" && u.Port() == "80" || u.Scheme == "https" && u.Port() == "443" {
+        u.Host = u.Hostname()
+        if strings.Contains(u.Host, ":") { // ipv6
+            u.Host = "[" + u.Host + "]"
+        }
+    }
+    if u.Path == "/" && u.RawQuery == "" {
+        u.Path = ""
+    }
+    return u.String()
+}
+
+// Dedupe 去重，保持原有顺序
+func Dedupe(targets []string) []string {
+    seen := make(map[string]bool)
+    var res []string
+    for _, t := range targets {
+        if seen[t] {
+            continue
+        }
+        seen[t] = true
+        res = append(res, t)
+    }
+    return res
+}
+
+// ParseNmapXML 解析 nmap/masscan 的 xml 结果，只保留开放的 web 服务
+func ParseNmapXML(data []byte) ([]string, error) {
+    var run masscan.Nmaprun
+    if err := xml.Unmarshal(data, &run); err != nil {
+        return nil, err
+    }
+    
+    var targets []string
+    for _, host := range run.Hosts {
+        var addr string
+        for _, a := range host.Addresses {
+            if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+                addr = a.Addr
+                break
+            }
+        }
+        // 扫描时指定的是域名的话，使用域名，防止虚拟主机访问不到
+        for _, h := range host.Hostnames {
+            if h.Type == "user" {
+                addr = h.Name
+                break
+            }
+        }
+        if addr == "" {
+            continue
+        }
+        
+        for _, port := range host.Ports {
+            if port.Protocol != "tcp" || port.State.State != "open" {
+                continue
+            }
+            portId, err := strconv.Atoi(port.Portid)
+            if err != nil {
+                continue
+            }
+            scheme, ok := webService(portId, port.Service.Name, port.Service.Tunnel)
+            if !ok {
+                continue
+            }
+            targets = append(targets, NormalizeTarget(scheme+"://"+net.JoinHostPort(addr, port.Portid)))
+        }
+    }
+    return Dedupe(targets), nil
+}
+
+type masscanRecord struct {
+    Ip    string `json:"ip"`
+    Ports []struct {
+        Port    int    `json:"port"`
+        Proto   string `json:"proto"`
+        Status  string `json:"status"`
+        Service struct {
+            Name string `json:"name"`
+        } `json:"service"`
+    } `json:"ports"`
+}
+
+// ParseMasscanJSON 解析 masscan -oJ、-oD 的结果，只保留开放的 web 服务
+// masscan 的 json 经常不规范(最后一条记录后面带逗号)，解析失败时逐行解析
+func ParseMasscanJSON(data []byte) ([]string, error) {
+    var records []masscanRecord
+    if err := json.Unmarshal(data, &records); err != nil {
+        records = nil
+        scanner := bufio.NewScanner(bytes.NewReader(data))
+        scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+        for scanner.Scan() {
+            line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ",")
+            if !strings.HasPrefix(line, "{") {
+                continue
+            }
+            var record masscanRecord
+            if json.Unmarshal([]byte(line), &record) == nil {
+                records = append(records, record)
+            }
+        }
+        if len(records) == 0 {
+            return nil, err
+        }
+    }
+    
+    var targets []string
+    for _, record := range records {
+        if record.Ip == "" {
+            continue
+        }
+        for _, port := range record.Ports {
+            if port.Proto != "" && port.Proto != "tcp" || port.Status != "" && port.Status != "open" {
+                continue
+            }
+            scheme, ok := webService(port.Port, port.Service.Name, "")
+            if !ok {
+                continue
+            }
+            targets = append(targets, NormalizeTarget(scheme+"://"+net.JoinHostPort(record.Ip, strconv.Itoa(port.Port))))
+        }
+    }
+    return Dedupe(targets), nil
+}
+
+// webService 根据服务识别结果判断是不是 web 服务，并推断协议
+func webService(port int, name, tunnel string) (string, bool) {
+    name = strings.ToLower(name)
+    if name == "" {
+        if !webPorts[port] {
+            return "", false
+        }
+        return schemeOf(port), true
+    }
+    
+    if !strings.Contains(name, "http") && !(name == "ssl" && webPorts[port]) {
+        return "", false
+    }
+    if strings.Contains(name, "https") || strings.HasPrefix(name, "ssl") || tunnel == "ssl" || httpsPorts[port] && name != "http" {
+        return "https", true
+    }
+    return "http", true
+}
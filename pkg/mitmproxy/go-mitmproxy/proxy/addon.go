@@ -44,6 +44,15 @@ type Addon interface {
 
 	// onAccessProxyServer
 	AccessProxyServer(req *http.Request, res http.ResponseWriter)
+
+	// A WebSocket handshake has completed, the connection is upgraded.
+	WebSocketStart(*WebSocketFlow)
+
+	// A complete WebSocket message has been received from the client or the server.
+	WebSocketMessage(*WebSocketFlow)
+
+	// A WebSocket connection has been closed.
+	WebSocketEnd(*WebSocketFlow)
 }
 
 // BaseAddon do nothing
@@ -61,6 +70,9 @@ func (addon *BaseAddon) Response(*Flow)
 func (addon *BaseAddon) StreamRequestModifier(f *Flow, in io.Reader) io.Reader        { return in }
 func (addon *BaseAddon) StreamResponseModifier(f *Flow, in io.Reader) io.Reader       { return in }
 func (addon *BaseAddon) AccessProxyServer(req *http.Request, res http.ResponseWriter) {}
+func (addon *BaseAddon) WebSocketStart(*WebSocketFlow)                                {}
+func (addon *BaseAddon) WebSocketMessage(*WebSocketFlow)                              {}
+func (addon *BaseAddon) WebSocketEnd(*WebSocketFlow)                                  {}
 
 // LogAddon log connection and flow
 type LogAddon struct {
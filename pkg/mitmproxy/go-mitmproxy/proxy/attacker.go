@@ -7,7 +7,6 @@ import (
     "io"
     "net"
     "net/http"
-    "strings"
     
     log "github.com/sirupsen/logrus"
     "github.com/yhy0/Jie/pkg/mitmproxy/go-mitmproxy/cert"
@@ -132,9 +131,9 @@ func (a *attacker) serveConn(clientTlsConn *tls.Conn, connCtx *ConnContext) {
 }
 
 func (a *attacker) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-    if strings.EqualFold(req.Header.Get("Connection"), "Upgrade") && strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+    if isWebSocket(req) {
         // wss
-        defaultWebSocket.wss(res, req)
+        (&webSocket{proxy: a.proxy}).wss(res, req)
         return
     }
     
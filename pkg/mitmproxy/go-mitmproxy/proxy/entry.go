@@ -204,6 +204,12 @@ func (e *entry) ServeHTTP(res http.ResponseWriter, req *http.Request) {
         }
     }
     
+    // ws
+    if isWebSocket(req) {
+        (&webSocket{proxy: proxy}).ws(res, req)
+        return
+    }
+    
     // http proxy
     proxy.attacker.initHttpDialFn(req)
     proxy.attacker.attack(res, req)
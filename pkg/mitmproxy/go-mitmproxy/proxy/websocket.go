@@ -1,36 +1,116 @@
 package proxy
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"sync"
+	"time"
 
+	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
 
-// 当前仅做了转发 websocket 流量
+// 转发 websocket 流量，同时按帧解析，把握手和消息记录到 WebSocketFlow 中交给插件
 
-type webSocket struct{}
+// websocket 帧的 opcode
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+)
+
+// 单个连接最多记录的消息数，超过后只转发不记录
+const maxWebSocketMessages = 200
+
+// 单个消息最多记录的字节数，超过后只转发不记录
+const maxWebSocketMessageSize = 1024 * 1024
+
+// WebSocketMessage websocket 连接中的一条完整消息(多个分片帧合并后)
+type WebSocketMessage struct {
+	FromClient bool
+	Type       int // 1 文本 2 二进制
+	Content    []byte
+	Time       time.Time
+}
 
-var defaultWebSocket webSocket
+func (m *WebSocketMessage) MarshalJSON() ([]byte, error) {
+	j := make(map[string]interface{})
+	j["fromClient"] = m.FromClient
+	j["type"] = m.Type
+	j["content"] = string(m.Content)
+	j["time"] = m.Time.UnixMilli()
+	return json.Marshal(j)
+}
+
+// WebSocketFlow 一个 websocket 连接，Request、Response 为握手请求和响应
+type WebSocketFlow struct {
+	Id       uuid.UUID
+	Request  *Request
+	Response *Response
 
-// func (s *webSocket) ws(conn net.Conn, host string) {
-// 	log := log.WithField("in", "webSocket.ws").WithField("host", host)
+	mu       sync.Mutex
+	messages []*WebSocketMessage
+}
+
+// Messages 已经记录的消息
+func (f *WebSocketFlow) Messages() []*WebSocketMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*WebSocketMessage{}, f.messages...)
+}
+
+func (f *WebSocketFlow) addMessage(m *WebSocketMessage) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.messages) >= maxWebSocketMessages {
+		return false
+	}
+	f.messages = append(f.messages, m)
+	return true
+}
+
+func (f *WebSocketFlow) MarshalJSON() ([]byte, error) {
+	j := make(map[string]interface{})
+	j["id"] = f.Id
+	j["request"] = f.Request
+	j["response"] = f.Response
+	j["messages"] = f.Messages()
+	return json.Marshal(j)
+}
+
+func isWebSocket(req *http.Request) bool {
+	return strings.Contains(strings.ToLower(req.Header.Get("Connection")), "upgrade") && strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+type webSocket struct {
+	proxy *Proxy
+}
 
-// 	defer conn.Close()
-// 	remoteConn, err := net.Dial("tcp", host)
-// 	if err != nil {
-// 		logErr(log, err)
-// 		return
-// 	}
-// 	defer remoteConn.Close()
-// 	transfer(log, conn, remoteConn)
-// }
+// ws http 代理中的明文 websocket
+func (s *webSocket) ws(res http.ResponseWriter, req *http.Request) {
+	s.handle(res, req, false)
+}
 
+// wss 中间人解密后的 websocket
 func (s *webSocket) wss(res http.ResponseWriter, req *http.Request) {
-	log := log.WithField("in", "webSocket.wss").WithField("host", req.Host)
+	s.handle(res, req, true)
+}
+
+func (s *webSocket) handle(res http.ResponseWriter, req *http.Request, secure bool) {
+	log := log.WithField("in", "webSocket.handle").WithField("host", req.Host)
 
+	// 去掉压缩扩展，保证帧中的数据是明文，便于记录
+	req.Header.Del("Sec-WebSocket-Extensions")
+	// http 代理中的请求行是完整的 url，转发给服务端时使用路径
+	req.RequestURI = req.URL.RequestURI()
 	upgradeBuf, err := httputil.DumpRequest(req, false)
 	if err != nil {
 		log.Errorf("DumpRequest: %v\n", err)
@@ -46,21 +126,237 @@ func (s *webSocket) wss(res http.ResponseWriter, req *http.Request) {
 	}
 	defer cconn.Close()
 
-	host := req.Host
-	if !strings.Contains(host, ":") {
-		host = host + ":443"
-	}
-	conn, err := tls.Dial("tcp", host, nil)
+	conn, err := s.dial(req, secure)
 	if err != nil {
-		log.Errorf("tls.Dial: %v\n", err)
+		log.Errorf("dial: %v\n", err)
 		return
 	}
 	defer conn.Close()
 
 	_, err = conn.Write(upgradeBuf)
 	if err != nil {
-		log.Errorf("wss upgrade: %v\n", err)
+		log.Errorf("ws upgrade: %v\n", err)
 		return
 	}
-	transfer(log, conn, cconn)
+
+	// 读取握手响应，原样返回给客户端
+	server := bufio.NewReader(conn)
+	raw, resp, err := readHandshake(server, req)
+	if err != nil {
+		log.Errorf("ws handshake: %v\n", err)
+		return
+	}
+	if _, err = cconn.Write(raw); err != nil {
+		logErr(log, err)
+		return
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		transfer(log, &bufferedConn{Conn: conn, r: server}, cconn)
+		return
+	}
+
+	// 中间人解密后的请求 url 中没有 scheme 和 host
+	if req.URL.Host == "" {
+		req.URL.Host = req.Host
+	}
+	if req.URL.Scheme == "" {
+		req.URL.Scheme = "http"
+		if secure {
+			req.URL.Scheme = "https"
+		}
+	}
+	f := &WebSocketFlow{
+		Id:      uuid.NewV4(),
+		Request: newRequest(req),
+		Response: &Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+		},
+	}
+	for _, addon := range s.proxy.Addons {
+		addon.WebSocketStart(f)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.relay(log, f, conn, cconn, true)
+		conn.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		s.relay(log, f, cconn, server, false)
+		cconn.Close()
+	}()
+	wg.Wait()
+
+	for _, addon := range s.proxy.Addons {
+		addon.WebSocketEnd(f)
+	}
+}
+
+// dial 连接服务端，和 http、https 请求一样经过上游代理
+func (s *webSocket) dial(req *http.Request, secure bool) (net.Conn, error) {
+	host := req.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := "80"
+		if secure {
+			port = "443"
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	dialReq := req.Clone(req.Context())
+	dialReq.Host = host
+	conn, err := s.proxy.getUpstreamConn(req.Context(), dialReq)
+	if err != nil || !secure {
+		return conn, err
+	}
+
+	serverName, _, _ := net.SplitHostPort(host)
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: s.proxy.Opts.SslInsecure, ServerName: serverName})
+	if err = tlsConn.HandshakeContext(req.Context()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// readHandshake 读取握手响应头，返回原始数据和解析后的响应
+func readHandshake(r *bufio.Reader, req *http.Request) ([]byte, *http.Response, error) {
+	var raw bytes.Buffer
+	for {
+		line, err := r.ReadSlice('\n')
+		raw.Write(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw.Bytes())), req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw.Bytes(), resp, nil
+}
+
+// bufferedConn 握手时 bufio 中可能已经缓存了后续的数据
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// relay 按帧转发 src 到 dst，帧原样转发，同时解析出完整的消息交给插件
+func (s *webSocket) relay(log *log.Entry, f *WebSocketFlow, dst io.Writer, src io.Reader, fromClient bool) {
+	var (
+		message  *WebSocketMessage
+		tooLarge bool
+	)
+	for {
+		fin, opcode, payload, err := copyFrame(dst, src)
+		if err != nil {
+			if err != io.EOF {
+				logErr(log, err)
+			}
+			return
+		}
+
+		switch opcode {
+		case opText, opBinary:
+			message = &WebSocketMessage{FromClient: fromClient, Type: int(opcode), Time: time.Now()}
+			tooLarge = false
+		case opContinuation:
+			if message == nil {
+				continue
+			}
+		default:
+			// ping、pong、close 只转发，close 之后对端会关闭连接
+			continue
+		}
+
+		if payload == nil || len(message.Content)+len(payload) > maxWebSocketMessageSize {
+			tooLarge = true
+		} else if !tooLarge {
+			message.Content = append(message.Content, payload...)
+		}
+
+		if fin {
+			if !tooLarge && f.addMessage(message) {
+				for _, addon := range s.proxy.Addons {
+					addon.WebSocketMessage(f)
+				}
+			}
+			message = nil
+		}
+	}
+}
+
+// copyFrame 复制一个帧，返回解除掩码后的数据，数据过大时只复制不返回
+func copyFrame(dst io.Writer, src io.Reader) (bool, byte, []byte, error) {
+	head := make([]byte, 2, 14)
+	if _, err := io.ReadFull(src, head); err != nil {
+		return false, 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7f)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(src, ext); err != nil {
+			return false, 0, nil, err
+		}
+		head = append(head, ext...)
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(src, ext); err != nil {
+			return false, 0, nil, err
+		}
+		head = append(head, ext...)
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	var key []byte
+	if masked {
+		key = make([]byte, 4)
+		if _, err := io.ReadFull(src, key); err != nil {
+			return false, 0, nil, err
+		}
+		head = append(head, key...)
+	}
+	if _, err := dst.Write(head); err != nil {
+		return false, 0, nil, err
+	}
+
+	if length > maxWebSocketMessageSize {
+		if _, err := io.CopyN(dst, src, int64(length)); err != nil {
+			return false, 0, nil, err
+		}
+		return fin, opcode, nil, nil
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(src, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if _, err := dst.Write(payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		unmasked := make([]byte, length)
+		for i := range payload {
+			unmasked[i] = payload[i] ^ key[i%4]
+		}
+		payload = unmasked
+	}
+	return fin, opcode, payload, nil
 }
@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testFrame 构造一个 websocket 帧，mask 为 true 时使用固定的掩码(客户端发送的帧)
+func testFrame(fin bool, opcode byte, payload []byte, mask bool) []byte {
+	var buf bytes.Buffer
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	buf.WriteByte(b0)
+
+	var b1 byte
+	if mask {
+		b1 = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		buf.WriteByte(b1 | byte(len(payload)))
+	case len(payload) <= 0xffff:
+		buf.WriteByte(b1 | 126)
+		binary.Write(&buf, binary.BigEndian, uint16(len(payload)))
+	default:
+		buf.WriteByte(b1 | 127)
+		binary.Write(&buf, binary.BigEndian, uint64(len(payload)))
+	}
+
+	if !mask {
+		buf.Write(payload)
+		return buf.Bytes()
+	}
+	key := []byte{0x12, 0x34, 0x56, 0x78}
+	buf.Write(key)
+	for i, b := range payload {
+		buf.WriteByte(b ^ key[i%4])
+	}
+	return buf.Bytes()
+}
+
+func TestCopyFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		fin     bool
+		opcode  byte
+		payload []byte
+		mask    bool
+	}{
+		{"text", true, opText, []byte("hello"), false},
+		{"masked", true, opText, []byte(`{"id":1}`), true},
+		{"empty", true, opBinary, []byte{}, true},
+		{"16-bit length", false, opBinary, bytes.Repeat([]byte("a"), 300), true},
+		{"64-bit length", true, opBinary, bytes.Repeat([]byte("b"), 70000), false},
+	}
+	for _, tt := range tests {
+		raw := testFrame(tt.fin, tt.opcode, tt.payload, tt.mask)
+		var dst bytes.Buffer
+		fin, opcode, payload, err := copyFrame(&dst, bytes.NewReader(raw))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if fin != tt.fin || opcode != tt.opcode || !bytes.Equal(payload, tt.payload) {
+			t.Errorf("%s: got fin=%v opcode=%d payload=%d bytes", tt.name, fin, opcode, len(payload))
+		}
+		// 帧原样转发，包括掩码
+		if !bytes.Equal(dst.Bytes(), raw) {
+			t.Errorf("%s: forwarded frame differs from the original", tt.name)
+		}
+	}
+}
+
+// 超过大小限制的帧只转发不返回数据
+func TestCopyFrameOversized(t *testing.T) {
+	raw := testFrame(true, opBinary, bytes.Repeat([]byte("c"), maxWebSocketMessageSize+1), true)
+	var dst bytes.Buffer
+	fin, opcode, payload, err := copyFrame(&dst, bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fin || opcode != opBinary || payload != nil {
+		t.Errorf("got fin=%v opcode=%d payload=%d bytes, want no payload", fin, opcode, len(payload))
+	}
+	if !bytes.Equal(dst.Bytes(), raw) {
+		t.Error("oversized frame not forwarded as is")
+	}
+}
+
+func TestCopyFrameTruncated(t *testing.T) {
+	raw := testFrame(true, opText, []byte("hello"), true)
+	if _, _, _, err := copyFrame(&bytes.Buffer{}, bytes.NewReader(raw[:len(raw)-2])); err == nil {
+		t.Error("truncated frame accepted")
+	}
+}
+
+type testWebSocketAddon struct {
+	BaseAddon
+	calls int
+}
+
+func (a *testWebSocketAddon) WebSocketMessage(*WebSocketFlow) {
+	a.calls++
+}
+
+// 分片的消息合并为一条，中间插入的控制帧不影响合并，超过大小的消息不记录
+func TestRelay(t *testing.T) {
+	var src bytes.Buffer
+	src.Write(testFrame(false, opText, []byte(`{"a":`), true))
+	src.Write(testFrame(true, 0x9, []byte("ping"), true))
+	src.Write(testFrame(false, opContinuation, []byte(`"b"`), true))
+	src.Write(testFrame(true, opContinuation, []byte(`}`), true))
+	src.Write(testFrame(true, opContinuation, []byte("orphan"), true))
+	src.Write(testFrame(false, opBinary, bytes.Repeat([]byte("x"), maxWebSocketMessageSize), false))
+	src.Write(testFrame(true, opContinuation, []byte("y"), false))
+	src.Write(testFrame(true, opText, []byte("bye"), false))
+	raw := append([]byte{}, src.Bytes()...)
+
+	addon := &testWebSocketAddon{}
+	s := &webSocket{proxy: &Proxy{Addons: []Addon{addon}}}
+	f := &WebSocketFlow{}
+	var dst bytes.Buffer
+	s.relay(nil, f, &dst, &src, true)
+
+	if !bytes.Equal(dst.Bytes(), raw) {
+		t.Error("frames not forwarded as is")
+	}
+	messages := f.Messages()
+	if len(messages) != 2 || addon.calls != 2 {
+		t.Fatalf("got %d messages, %d addon calls, want 2", len(messages), addon.calls)
+	}
+	if string(messages[0].Content) != `{"a":"b"}` || messages[0].Type != opText || !messages[0].FromClient {
+		t.Errorf("first message = %+v", messages[0])
+	}
+	if string(messages[1].Content) != "bye" {
+		t.Errorf("second message = %q", messages[1].Content)
+	}
+}
+
+// 明文 websocket 同样经过上游代理
+func TestWebSocketDialUpstream(t *testing.T) {
+	connected := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			connected <- r.Host
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	s := &webSocket{proxy: &Proxy{Opts: &Options{Upstream: upstream.URL}}}
+	req := httptest.NewRequest("GET", "http://example.com/ws", nil)
+	conn, err := s.dial(req, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if host := <-connected; host != "example.com:80" {
+		t.Errorf("CONNECT %s, want example.com:80", host)
+	}
+}
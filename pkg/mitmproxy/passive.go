@@ -59,3 +59,50 @@ func judge(f *proxy.Flow) {
         }
     }
 }
+
+// 每个 WebSocket 连接中客户端发送了这么多条消息后分发扫描，不够的在连接关闭时分发
+const webSocketMessages = 10
+
+// WebSocketMessage 客户端消息数达到 webSocketMessages 时分发扫描
+func (pa *PassiveAddon) WebSocketMessage(f *proxy.WebSocketFlow) {
+    messages := f.Messages()
+    if !messages[len(messages)-1].FromClient || clientMessages(messages) != webSocketMessages {
+        return
+    }
+    if intercept(f.Request.URL.Host) {
+        distributeWebSocket(f)
+    }
+}
+
+// WebSocketEnd 连接关闭时还没有分发的进行分发
+func (pa *PassiveAddon) WebSocketEnd(f *proxy.WebSocketFlow) {
+    if clientMessages(f.Messages()) >= webSocketMessages {
+        return
+    }
+    if intercept(f.Request.URL.Host) {
+        distributeWebSocket(f)
+    }
+}
+
+func clientMessages(messages []*proxy.WebSocketMessage) int {
+    count := 0
+    for _, m := range messages {
+        if m.FromClient {
+            count++
+        }
+    }
+    return count
+}
+
+// intercept 按照配置的 include、exclude 判断是否扫描
+func intercept(host string) bool {
+    if len(conf.GlobalConfig.Mitmproxy.Exclude) > 0 && !(len(conf.GlobalConfig.Mitmproxy.Exclude) == 1 && conf.GlobalConfig.Mitmproxy.Exclude[0] == "") {
+        if util.RegexpStr(conf.GlobalConfig.Mitmproxy.Exclude, host) {
+            return false
+        }
+    }
+    if len(conf.GlobalConfig.Mitmproxy.Include) > 0 && !(len(conf.GlobalConfig.Mitmproxy.Include) == 1 && conf.GlobalConfig.Mitmproxy.Include[0] == "") {
+        return util.RegexpStr(conf.GlobalConfig.Mitmproxy.Include, host)
+    }
+    return true
+}
@@ -1,7 +1,9 @@
 package mitmproxy
 
 import (
+    "fmt"
     "github.com/yhy0/Jie/pkg/mitmproxy/go-mitmproxy/proxy"
+    "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/util"
     "github.com/yhy0/logging"
     "net/url"
@@ -84,3 +86,81 @@ func distribution(f *proxy.Flow) {
     }()
     // t.Distribution(in)
 }
+
+// 握手请求中由浏览器生成的请求头，重放时由 websocket 库重新生成
+var webSocketHeaders = []string{"Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions"}
+
+// distributeWebSocket 分发 WebSocket 连接，握手请求作为扫描目标，客户端发送的文本消息交给 websocket 插件检测
+func distributeWebSocket(f *proxy.WebSocketFlow) {
+    parseUrl, err := url.Parse(f.Request.URL.String())
+    if err != nil {
+        logging.Logger.Errorln(err)
+        return
+    }
+    
+    var host string
+    port := strings.Split(parseUrl.Host, ":")
+    if len(port) > 1 && (port[1] == "443" || port[1] == "80") {
+        host = strings.Split(parseUrl.Host, ":")[0]
+    } else {
+        host = parseUrl.Host
+    }
+    
+    headerMap := make(map[string]string)
+    for key, values := range f.Request.Header {
+        if util.InCaseFoldSlice(webSocketHeaders, key) {
+            continue
+        }
+        headerMap[key] = strings.Join(values, ",")
+    }
+    
+    var messages []string
+    for _, m := range f.Messages() {
+        if m.FromClient && m.Type == 1 {
+            messages = append(messages, string(m.Content))
+        }
+    }
+    
+    in := &input.CrawlResult{
+        Target:   f.Request.URL.Host,
+        Url:      f.Request.URL.String(),
+        Host:     host,
+        ParseUrl: parseUrl,
+        UniqueId: util.MD5("websocket" + util.UniqueId(f.Request)),
+        Method:   "GET",
+        Headers:  headerMap,
+        Resp: &httpx.Response{
+            Status:     strconv.Itoa(f.Response.StatusCode),
+            StatusCode: f.Response.StatusCode,
+            Header:     f.Response.Header,
+        },
+        RawRequest:  requestDump(f.Request),
+        RawResponse: webSocketDump(f),
+        Source:      "websocket",
+        WebSocket:   messages,
+    }
+    
+    // 握手和消息在 SCopilot 中展示
+    output.SCopilot(host, output.SCopilotData{
+        Target:  host,
+        SiteMap: []string{in.Url},
+        InfoMsg: []output.PluginMsg{
+            {
+                Url:      in.Url,
+                Plugin:   "WebSocket",
+                Result:   []string{fmt.Sprintf("%d messages, %d sent by client", len(f.Messages()), len(messages))},
+                Request:  in.RawRequest,
+                Response: in.RawResponse,
+            },
+        },
+    })
+    
+    t.WG.Add(1)
+    go func() {
+        err := t.Pool.Submit(t.Distribution(in))
+        if err != nil {
+            t.WG.Done()
+            logging.Logger.Errorf("add distribution err:%v, crawlResult:%v", err, in)
+        }
+    }()
+}
@@ -69,3 +69,28 @@ func canPrint(content []byte) bool {
     }
     return true
 }
+
+// webSocketDump 握手响应和连接中的消息，> 为客户端发送的消息，< 为服务端发送的消息
+func webSocketDump(f *proxy.WebSocketFlow) string {
+    buf := bytes.NewBuffer(make([]byte, 0))
+    fmt.Fprintf(buf, "%v %v %v\r\n", f.Request.Proto, f.Response.StatusCode, http.StatusText(f.Response.StatusCode))
+    err := f.Response.Header.WriteSubset(buf, nil)
+    if err != nil {
+        logging.Logger.Error(err)
+    }
+    buf.WriteString("\r\n")
+    for _, m := range f.Messages() {
+        if m.FromClient {
+            buf.WriteString("> ")
+        } else {
+            buf.WriteString("< ")
+        }
+        if m.Type == 1 || canPrint(m.Content) {
+            buf.Write(m.Content)
+        } else {
+            fmt.Fprintf(buf, "[binary %d bytes]", len(m.Content))
+        }
+        buf.WriteString("\n")
+    }
+    return buf.String()
+}
@@ -1,7 +1,9 @@
 package mode
 
 import (
+    "context"
     "encoding/json"
+    "errors"
     "fmt"
     "github.com/panjf2000/ants/v2"
     "github.com/projectdiscovery/katana/pkg/output"
@@ -38,11 +40,27 @@ var extensionFilter = []string{
     ".ico", ".ttf",
 }
 
+// ErrUnreachable 爬虫前的连接性检测失败
+var ErrUnreachable = errors.New("target unreachable")
+
 // Active 主动扫描 调用爬虫扫描, 只会输入一个域名
 func Active(target string, fingerprint []string) ([]string, []string) {
+    t := &task.Task{
+        Parallelism: conf.Parallelism,
+    }
+    subdomains, fingerprints, _ := ActiveTask(nil, t, target, conf.GlobalConfig.WebScan.Craw, fingerprint)
+    return subdomains, fingerprints
+}
+
+// ActiveTask 使用传入的 task 进行主动扫描，嵌入使用时每个扫描器通过 task 使用自己的配置
+// ctx 不为空时作为任务的 ctx，取消后爬虫和插件的请求都会停止，返回时扫描的协程都已经结束
+func ActiveTask(ctx context.Context, t *task.Task, target string, craw string, fingerprint []string) ([]string, []string, error) {
     if target == "" {
         logging.Logger.Errorln("target must be set")
-        return nil, nil
+        return nil, nil, errors.New("target must be set")
+    }
+    if ctx != nil {
+        t.Ctx = ctx
     }
     
     // 判断是否以 http https 开头
@@ -59,7 +77,7 @@ func Active(target string, fingerprint []string) ([]string, []string) {
     parseUrl, err := url.Parse(target)
     if err != nil {
         logging.Logger.Errorln(err)
-        return nil, nil
+        return nil, nil, err
     }
     var host string
     // 有的会带80、443端口号，导致    example.com 和 example.com:80、example.com:443被认为是不同的网站
@@ -69,12 +87,11 @@ func Active(target string, fingerprint []string) ([]string, []string) {
         host = parseUrl.Host
     }
     
-    t := &task.Task{
-        Parallelism: conf.Parallelism,
-        ScanTask:    make(map[string]*task.ScanTask),
+    if t.ScanTask == nil {
+        t.ScanTask = make(map[string]*task.ScanTask)
     }
     
-    client := httpx.NewClient(nil)
+    client := t.NewClient()
     t.ScanTask[host] = &task.ScanTask{
         PerServer: make(map[string]bool),
         PerFolder: make(map[string]bool),
@@ -92,7 +109,7 @@ func Active(target string, fingerprint []string) ([]string, []string) {
     resp, err := client.Request(target, "GET", "", nil)
     if err != nil {
         logging.Logger.Errorln("End: ", err)
-        return nil, nil
+        return nil, nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
     }
     
     technologies := fingprints.Identify([]byte(resp.Body), resp.Header)
@@ -101,12 +118,12 @@ func Active(target string, fingerprint []string) ([]string, []string) {
     
     var subdomains []string
     // 爬虫的同时进行指纹识别
-    if conf.GlobalConfig.WebScan.Craw == "c" {
+    if craw == "c" {
         logging.Logger.Infoln("Crawling with Crawlergo.")
         subdomains = Crawlergo(target, wafs, t, fingerprint)
     } else {
         logging.Logger.Infoln("Crawling with Katana.")
-        subdomains = Katana(target, wafs, t, fingerprint)
+        subdomains = Katana(target, craw, wafs, t, fingerprint)
     }
     
     t.WG.Wait()
@@ -115,10 +132,10 @@ func Active(target string, fingerprint []string) ([]string, []string) {
     
     t.Fingerprints = funk.UniqString(append(t.Fingerprints, technologies...))
     
-    return subdomains, t.Fingerprints
+    return subdomains, t.Fingerprints, nil
 }
 
-func Katana(target string, waf []string, t *task.Task, fingerprint []string) []string {
+func Katana(target string, craw string, waf []string, t *task.Task, fingerprint []string) []string {
     parseUrl, err := url.Parse(target)
     if err != nil {
         logging.Logger.Errorln(err)
@@ -126,9 +143,22 @@ func Katana(target string, waf []string, t *task.Task, fingerprint []string) []s
     }
     rootHostname := parseUrl.Host
     
+    // 爬虫自己的请求也计入任务的请求数限制，用完后停止爬虫
+    ctx, stop := context.WithCancel(t.Context())
+    defer stop()
+    
     i := 0
     now := time.Now()
     out := func(result output.Result) { // Callback function to execute for result
+        // 任务取消后，不再处理爬虫结果
+        if t.Canceled() || ctx.Err() != nil {
+            return
+        }
+        if t.Spend() != nil {
+            logging.Logger.Infoln("request budget exhausted, stop crawling", target)
+            stop()
+            return
+        }
         curl := strings.ReplaceAll(result.Request.URL, "\\n", "")
         curl = strings.ReplaceAll(curl, "\\t", "")
         curl = strings.ReplaceAll(curl, "\\n", "")
@@ -205,10 +235,10 @@ func Katana(target string, waf []string, t *task.Task, fingerprint []string) []s
         _ = t.Pool.Submit(t.Distribution(crawlResult))
     }
     
-    if conf.GlobalConfig.WebScan.Craw == "k" {
-        crawler.Katana(target, false, false, out)
+    if craw == "k" {
+        crawler.Katana(ctx, target, false, false, out)
     } else {
-        crawler.Katana(target, true, conf.GlobalConfig.WebScan.Show, out)
+        crawler.Katana(ctx, target, true, conf.GlobalConfig.WebScan.Show, out)
     }
     
     logging.Logger.Infof("Task finished, %d results, %d subdomains found, runtime: %d s", i, 0, time.Now().Unix()-now.Unix())
@@ -242,8 +272,20 @@ func Crawlergo(target string, waf []string, t *task.Task, fingerprint []string)
         logging.Logger.Info("request with proxy: ", crawler.TaskConfig.Proxy)
     }
     
+    // 爬虫自己的请求也计入任务的请求数限制，用完后停止爬虫
+    ctx, stop := context.WithCancel(t.Context())
+    defer stop()
+    
     // 实时获取结果
     onResult := func(result *crawlergo.OutResult) {
+        if t.Canceled() || ctx.Err() != nil {
+            return
+        }
+        if t.Spend() != nil {
+            logging.Logger.Infoln("request budget exhausted, stop crawling", target)
+            stop()
+            return
+        }
         // 不对这些进行漏扫
         for _, suffix := range extensionFilter {
             if strings.HasSuffix(result.ReqList.URL.Path, suffix) {
@@ -301,7 +343,11 @@ func Crawlergo(target string, waf []string, t *task.Task, fingerprint []string)
     }
     
     // 开始爬虫任务
-    crawlerTask, err := crawlergo.NewCrawlerTask(targets, crawler.TaskConfig, onResult)
+    // 任务取消后爬虫不再打开新的页面，爬虫中发现的漏洞输出到任务的 Sink
+    taskConfig := crawler.TaskConfig
+    taskConfig.Ctx = ctx
+    taskConfig.Sink = t.Sink
+    crawlerTask, err := crawlergo.NewCrawlerTask(targets, taskConfig, onResult)
     if err != nil {
         logging.Logger.Error("create crawler task failed.")
         return nil
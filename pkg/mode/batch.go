@@ -0,0 +1,169 @@
+package mode
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/task"
+    "github.com/yhy0/logging"
+    "runtime"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/17
+   @desc 批量主动扫描，同时对多个目标进行爬虫、扫描
+        每个目标有单独的 task、协程池、时间和请求数限制，一个目标无法访问、超时或者出错不会影响其他目标
+**/
+
+// 目标的扫描状态
+const (
+    StatusFinished    = "finished"
+    StatusUnreachable = "unreachable"
+    StatusTimeout     = "timeout"
+    StatusBudget      = "budget exhausted"
+    StatusError       = "error"
+)
+
+// BatchOptions 批量扫描的配置
+type BatchOptions struct {
+    Concurrency int           // 同时扫描的目标数
+    Timeout     time.Duration // 每个目标的最长扫描时间，0 为不限制
+    MaxRequests int           // 每个目标最多发送的请求数，0 为不限制
+}
+
+// TargetSummary 单个目标的扫描结果汇总
+type TargetSummary struct {
+    Target       string
+    Status       string
+    Error        string
+    Duration     time.Duration
+    Urls         int64    // 扫描的链接数
+    Requests     int64    // 发送的请求数，只有限制了请求数时才会统计
+    Findings     int64    // 发现的漏洞数
+    Fingerprints []string // 识别到的指纹
+}
+
+// ActiveBatch 批量主动扫描，返回每个目标的扫描结果汇总，顺序和传入的目标一致
+func ActiveBatch(targets []string, opts BatchOptions) []*TargetSummary {
+    if opts.Concurrency <= 0 {
+        opts.Concurrency = 1
+    }
+    
+    summaries := make([]*TargetSummary, len(targets))
+    
+    var wg sync.WaitGroup
+    limit := make(chan struct{}, opts.Concurrency)
+    for i, target := range targets {
+        wg.Add(1)
+        limit <- struct{}{}
+        go func(i int, target string) {
+            defer func() {
+                <-limit
+                wg.Done()
+            }()
+            summaries[i] = activeTarget(target, opts)
+        }(i, target)
+    }
+    wg.Wait()
+    
+    return summaries
+}
+
+// activeTarget 扫描单个目标，超时后取消爬虫和插件的请求，等待扫描的协程都结束后才返回并释放并发数
+func activeTarget(target string, opts BatchOptions) *TargetSummary {
+    summary := &TargetSummary{
+        Target: target,
+    }
+    start := time.Now()
+    
+    ctx := context.Background()
+    var cancel context.CancelFunc
+    if opts.Timeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+    } else {
+        ctx, cancel = context.WithCancel(ctx)
+    }
+    defer cancel()
+    
+    t := &task.Task{
+        Parallelism: conf.Parallelism,
+        MaxRequests: opts.MaxRequests,
+    }
+    
+    type result struct {
+        fingerprints []string
+        err          error
+    }
+    done := make(chan result, 1)
+    
+    go func() {
+        // 一个目标出现 panic 不影响其他目标
+        defer func() {
+            if err := recover(); err != nil {
+                buf := make([]byte, 4096)
+                buf = buf[:runtime.Stack(buf, false)]
+                logging.Logger.Errorf("%s scan panic: %v\n%s", target, err, buf)
+                done <- result{err: fmt.Errorf("panic: %v", err)}
+            }
+        }()
+        _, fingerprints, err := ActiveTask(ctx, t, target, conf.GlobalConfig.WebScan.Craw, nil)
+        done <- result{fingerprints: fingerprints, err: err}
+    }()
+    
+    // 超时后 ActiveTask 会很快结束，这里等待它结束，防止超时的目标在后台继续占用资源
+    res := <-done
+    summary.Fingerprints = res.fingerprints
+    switch {
+    case ctx.Err() == context.DeadlineExceeded:
+        summary.Status = StatusTimeout
+    case res.err == nil:
+        summary.Status = StatusFinished
+    case errors.Is(res.err, ErrUnreachable):
+        summary.Status = StatusUnreachable
+        summary.Error = res.err.Error()
+    default:
+        summary.Status = StatusError
+        summary.Error = res.err.Error()
+    }
+    
+    used, max := t.Budget()
+    if max > 0 && used >= max && summary.Status == StatusFinished {
+        summary.Status = StatusBudget
+    }
+    
+    summary.Duration = time.Since(start).Round(time.Second)
+    summary.Urls = atomic.LoadInt64(&t.Done)
+    summary.Requests = used
+    summary.Findings = output.FindingCount(target)
+    return summary
+}
+
+// PrintSummary 打印每个目标的扫描结果汇总
+func PrintSummary(summaries []*TargetSummary) {
+    var msg strings.Builder
+    msg.WriteString(fmt.Sprintf("Scan summary, %d targets:\n", len(summaries)))
+    for _, s := range summaries {
+        if s == nil {
+            continue
+        }
+        msg.WriteString(fmt.Sprintf("\t%-40s %-16s %8s  urls: %-6d findings: %-4d", s.Target, s.Status, s.Duration, s.Urls, s.Findings))
+        if s.Requests > 0 {
+            msg.WriteString(fmt.Sprintf(" requests: %-6d", s.Requests))
+        }
+        if len(s.Fingerprints) > 0 {
+            msg.WriteString(" fingerprints: " + strings.Join(s.Fingerprints, ","))
+        }
+        if s.Error != "" {
+            msg.WriteString(" error: " + s.Error)
+        }
+        msg.WriteString("\n")
+    }
+    logging.Logger.Infoln(msg.String())
+}
@@ -0,0 +1,68 @@
+package mode
+
+import (
+    "errors"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/task"
+    "github.com/yhy0/logging"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "testing"
+    "time"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "mode", false)
+    conf.InitDefault()
+    os.Exit(m.Run())
+}
+
+// 超时后取消正在发送的请求，等待扫描结束后才返回
+func TestActiveTargetTimeout(t *testing.T) {
+    release := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-release:
+        case <-r.Context().Done():
+        }
+    }))
+    defer server.Close()
+    defer close(release)
+    
+    start := time.Now()
+    summary := activeTarget(server.URL, BatchOptions{Timeout: 300 * time.Millisecond})
+    if summary.Status != StatusTimeout {
+        t.Errorf("status = %s, want %s", summary.Status, StatusTimeout)
+    }
+    if elapsed := time.Since(start); elapsed > 5*time.Second {
+        t.Errorf("activeTarget returned after %s", elapsed)
+    }
+}
+
+func TestActiveTargetUnreachable(t *testing.T) {
+    summary := activeTarget("http://127.0.0.1:1", BatchOptions{Timeout: 10 * time.Second})
+    if summary.Status != StatusUnreachable {
+        t.Errorf("status = %s, want %s", summary.Status, StatusUnreachable)
+    }
+}
+
+// 爬虫自己的请求也计入目标的请求数限制
+func TestCrawlerBudget(t *testing.T) {
+    tk := &task.Task{MaxRequests: 2}
+    for i := 0; i < 2; i++ {
+        if err := tk.Spend(); err != nil {
+            t.Fatalf("spend %d: %v", i, err)
+        }
+    }
+    if err := tk.Spend(); !errors.Is(err, httpx.ErrBudgetExhausted) {
+        t.Errorf("spend over budget = %v", err)
+    }
+    if used, max := tk.Budget(); used != 2 || max != 2 {
+        t.Errorf("budget = %d/%d, want 2/2", used, max)
+    }
+    if err := (&task.Task{}).Spend(); err != nil {
+        t.Errorf("spend without budget = %v", err)
+    }
+}
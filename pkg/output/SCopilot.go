@@ -24,64 +24,12 @@ var DataUpdated = make(chan struct{})
 
 // SCopilot 将数据存储到 SCopilotMessage 中
 func SCopilot(host string, data SCopilotData) {
-    _host := strings.Split(host, ":")
-    if len(_host) > 1 {
-        if _host[1] == "80" {
-            host = _host[0]
-        }
-    }
+    host = scopilotHost(host)
     
     lock.Lock()
     defer lock.Unlock()
     // 判断 map 中是否存在，存在的话就 append，不存在的话就创建
-    if _, ok := SCopilotMessage[host]; ok {
-        // 合并去重
-        SCopilotMessage[host].SiteMap = funk.UniqString(append(SCopilotMessage[host].SiteMap, data.SiteMap...))
-        // 对 sitemap 链接进行排序
-        sort.SliceStable(SCopilotMessage[host].SiteMap, func(i, j int) bool {
-            return compareLinks(SCopilotMessage[host].SiteMap[i], SCopilotMessage[host].SiteMap[j])
-        })
-        
-        SCopilotMessage[host].Fingerprints = funk.UniqString(append(SCopilotMessage[host].Fingerprints, data.Fingerprints...))
-        
-        for _, v := range data.VulMessage {
-            if funk.Contains(SCopilotMessage[host].VulMessage, v) {
-                continue
-            }
-            SCopilotMessage[host].VulMessage = append(SCopilotMessage[host].VulMessage, v)
-            
-            if SCopilotMessage[host].VulPlugin == nil {
-                SCopilotMessage[host].VulPlugin = make(map[string]int)
-            }
-            if SCopilotMessage[host].VulPlugin[v.Plugin] > 0 {
-                SCopilotMessage[host].VulPlugin[v.Plugin] = SCopilotMessage[host].VulPlugin[v.Plugin] + 1
-            } else {
-                SCopilotMessage[host].VulPlugin[v.Plugin] = 1
-            }
-        }
-        
-        for _, v := range data.InfoMsg {
-            if funk.Contains(SCopilotMessage[host].InfoMsg, v) {
-                continue
-            }
-            SCopilotMessage[host].InfoMsg = append(SCopilotMessage[host].InfoMsg, v)
-            if SCopilotMessage[host].InfoPlugin == nil {
-                SCopilotMessage[host].InfoPlugin = make(map[string]int)
-            }
-            if SCopilotMessage[host].InfoPlugin[v.Plugin] > 0 {
-                SCopilotMessage[host].InfoPlugin[v.Plugin] = SCopilotMessage[host].InfoPlugin[v.Plugin] + 1
-            } else {
-                SCopilotMessage[host].InfoPlugin[v.Plugin] = 1
-            }
-        }
-        
-        for _, v := range data.PluginMsg {
-            if funk.Contains(SCopilotMessage[host].PluginMsg, v) {
-                continue
-            }
-            SCopilotMessage[host].PluginMsg = append(SCopilotMessage[host].PluginMsg, v)
-        }
-        
+    if merge(SCopilotMessage, host, data) {
         for _, v := range SCopilotLists {
             if v.Host == host {
                 v.InfoCount = len(SCopilotMessage[host].InfoMsg)
@@ -89,26 +37,7 @@ func SCopilot(host string, data SCopilotData) {
                 v.VulnCount = len(SCopilotMessage[host].VulMessage)
             }
         }
-        
-        SCopilotMessage[host].CollectionMsg.Subdomain = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.Subdomain, data.CollectionMsg.Subdomain...))
-        SCopilotMessage[host].CollectionMsg.OtherDomain = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.OtherDomain, data.CollectionMsg.OtherDomain...))
-        SCopilotMessage[host].CollectionMsg.PublicIp = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.PublicIp, data.CollectionMsg.PublicIp...))
-        SCopilotMessage[host].CollectionMsg.InnerIp = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.InnerIp, data.CollectionMsg.InnerIp...))
-        SCopilotMessage[host].CollectionMsg.Phone = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.Phone, data.CollectionMsg.Phone...))
-        SCopilotMessage[host].CollectionMsg.Email = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.Email, data.CollectionMsg.Email...))
-        SCopilotMessage[host].CollectionMsg.Others = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.Others, data.CollectionMsg.Others...))
-        SCopilotMessage[host].CollectionMsg.Urls = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.Urls, data.CollectionMsg.Urls...))
-        SCopilotMessage[host].CollectionMsg.Api = funk.UniqString(append(SCopilotMessage[host].CollectionMsg.Api, data.CollectionMsg.Api...))
-        
-        sort.SliceStable(SCopilotMessage[host].CollectionMsg.Api, func(i, j int) bool {
-            return compareApi(SCopilotMessage[host].CollectionMsg.Api[i], SCopilotMessage[host].CollectionMsg.Api[j])
-        })
-        
-        sort.SliceStable(SCopilotMessage[host].CollectionMsg.Urls, func(i, j int) bool {
-            return compareLinks(SCopilotMessage[host].CollectionMsg.Urls[i], SCopilotMessage[host].CollectionMsg.Urls[j])
-        })
     } else {
-        SCopilotMessage[host] = &data
         SCopilotLists = append(SCopilotLists, &SCopilotList{
             Host: host,
         })
@@ -121,6 +50,91 @@ func SCopilot(host string, data SCopilotData) {
     }
 }
 
+// scopilotHost 去掉 80 端口，example.com:80 和 example.com 认为是同一个网站
+func scopilotHost(host string) string {
+    _host := strings.Split(host, ":")
+    if len(_host) > 1 {
+        if _host[1] == "80" {
+            host = _host[0]
+        }
+    }
+    return host
+}
+
+// merge 将数据合并到 m 中，host 之前不存在时直接保存并返回 false
+func merge(m map[string]*SCopilotData, host string, data SCopilotData) bool {
+    if _, ok := m[host]; !ok {
+        m[host] = &data
+        return false
+    }
+    
+    // 合并去重
+    m[host].SiteMap = funk.UniqString(append(m[host].SiteMap, data.SiteMap...))
+    // 对 sitemap 链接进行排序
+    sort.SliceStable(m[host].SiteMap, func(i, j int) bool {
+        return compareLinks(m[host].SiteMap[i], m[host].SiteMap[j])
+    })
+    
+    m[host].Fingerprints = funk.UniqString(append(m[host].Fingerprints, data.Fingerprints...))
+    
+    for _, v := range data.VulMessage {
+        if funk.Contains(m[host].VulMessage, v) {
+            continue
+        }
+        m[host].VulMessage = append(m[host].VulMessage, v)
+        
+        if m[host].VulPlugin == nil {
+            m[host].VulPlugin = make(map[string]int)
+        }
+        if m[host].VulPlugin[v.Plugin] > 0 {
+            m[host].VulPlugin[v.Plugin] = m[host].VulPlugin[v.Plugin] + 1
+        } else {
+            m[host].VulPlugin[v.Plugin] = 1
+        }
+    }
+    
+    for _, v := range data.InfoMsg {
+        if funk.Contains(m[host].InfoMsg, v) {
+            continue
+        }
+        m[host].InfoMsg = append(m[host].InfoMsg, v)
+        if m[host].InfoPlugin == nil {
+            m[host].InfoPlugin = make(map[string]int)
+        }
+        if m[host].InfoPlugin[v.Plugin] > 0 {
+            m[host].InfoPlugin[v.Plugin] = m[host].InfoPlugin[v.Plugin] + 1
+        } else {
+            m[host].InfoPlugin[v.Plugin] = 1
+        }
+    }
+    
+    for _, v := range data.PluginMsg {
+        if funk.Contains(m[host].PluginMsg, v) {
+            continue
+        }
+        m[host].PluginMsg = append(m[host].PluginMsg, v)
+    }
+    
+    m[host].CollectionMsg.Subdomain = funk.UniqString(append(m[host].CollectionMsg.Subdomain, data.CollectionMsg.Subdomain...))
+    m[host].CollectionMsg.OtherDomain = funk.UniqString(append(m[host].CollectionMsg.OtherDomain, data.CollectionMsg.OtherDomain...))
+    m[host].CollectionMsg.PublicIp = funk.UniqString(append(m[host].CollectionMsg.PublicIp, data.CollectionMsg.PublicIp...))
+    m[host].CollectionMsg.InnerIp = funk.UniqString(append(m[host].CollectionMsg.InnerIp, data.CollectionMsg.InnerIp...))
+    m[host].CollectionMsg.Phone = funk.UniqString(append(m[host].CollectionMsg.Phone, data.CollectionMsg.Phone...))
+    m[host].CollectionMsg.Email = funk.UniqString(append(m[host].CollectionMsg.Email, data.CollectionMsg.Email...))
+    m[host].CollectionMsg.Others = funk.UniqString(append(m[host].CollectionMsg.Others, data.CollectionMsg.Others...))
+    m[host].CollectionMsg.Urls = funk.UniqString(append(m[host].CollectionMsg.Urls, data.CollectionMsg.Urls...))
+    m[host].CollectionMsg.Api = funk.UniqString(append(m[host].CollectionMsg.Api, data.CollectionMsg.Api...))
+    
+    sort.SliceStable(m[host].CollectionMsg.Api, func(i, j int) bool {
+        return compareApi(m[host].CollectionMsg.Api[i], m[host].CollectionMsg.Api[j])
+    })
+    
+    sort.SliceStable(m[host].CollectionMsg.Urls, func(i, j int) bool {
+        return compareLinks(m[host].CollectionMsg.Urls[i], m[host].CollectionMsg.Urls[j])
+    })
+    return true
+}
+
 // 按照目录结构对链接进行排序的比较函数
 func compareLinks(a, b string) bool {
     aURL, err := url.Parse(a)
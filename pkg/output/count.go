@@ -0,0 +1,40 @@
+package output
+
+import (
+    "net/url"
+    "strings"
+    "sync"
+    "sync/atomic"
+)
+
+/**
+   @author yhy
+   @since 2024/6/17
+   @desc 按照网站统计发现的漏洞数，批量扫描结束后汇总使用
+**/
+
+var findingCount sync.Map
+
+func countFinding(v VulMessage) {
+    c, _ := findingCount.LoadOrStore(findingHost(v.VulnData.Target), new(int64))
+    atomic.AddInt64(c.(*int64), 1)
+}
+
+// FindingCount 获取目标所在网站发现的漏洞数
+func FindingCount(target string) int64 {
+    if c, ok := findingCount.Load(findingHost(target)); ok {
+        return atomic.LoadInt64(c.(*int64))
+    }
+    return 0
+}
+
+func findingHost(target string) string {
+    if !strings.Contains(target, "://") {
+        target = "http://" + target
+    }
+    host := target
+    if u, err := url.Parse(target); err == nil {
+        host = u.Host
+    }
+    return strings.TrimSuffix(strings.TrimSuffix(host, ":80"), ":443")
+}
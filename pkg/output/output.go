@@ -25,6 +25,8 @@ func Write(progress bool) {
     }
     
     for v := range OutChannel {
+        countFinding(v)
+        
         // 漏洞保存到文件
         if conf.GlobalConfig.Options.Output != "" {
             ReportMessageChan <- v
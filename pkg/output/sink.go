@@ -0,0 +1,101 @@
+package output
+
+import (
+    "github.com/iancoleman/orderedmap"
+    "sync"
+)
+
+/**
+   @author yhy
+   @since 2024/6/16
+   @desc 扫描结果的接收者，嵌入使用时每个扫描器有自己的 Sink，漏洞和网站信息不会混到其他扫描器中
+        Sink 为空时使用全局的 OutChannel 和 SCopilotMessage，命令行模式下都是空的
+**/
+
+// Sink 扫描结果的接收者
+type Sink struct {
+    OnFinding func(msg VulMessage) // 发现漏洞时回调
+    
+    lock     sync.Mutex
+    messages map[string]*SCopilotData
+    seen     sync.Map // Once 记录的 key，随 Sink 一起释放
+}
+
+// seen 命令行模式下 Sink 为空，使用全局的记录
+var seen sync.Map
+
+// Once 同一个 key 只有第一次调用时返回 true，用于同一个扫描中的结果去重
+func (s *Sink) Once(key string) bool {
+    m := &seen
+    if s != nil {
+        m = &s.seen
+    }
+    _, loaded := m.LoadOrStore(key, true)
+    return !loaded
+}
+
+// Report 输出漏洞
+func (s *Sink) Report(msg VulMessage) {
+    if s == nil {
+        OutChannel <- msg
+        return
+    }
+    if s.OnFinding != nil {
+        s.OnFinding(msg)
+    }
+}
+
+// SCopilot 保存网站信息
+func (s *Sink) SCopilot(host string, data SCopilotData) {
+    if s == nil {
+        SCopilot(host, data)
+        return
+    }
+    s.lock.Lock()
+    defer s.lock.Unlock()
+    if s.messages == nil {
+        s.messages = make(map[string]*SCopilotData)
+    }
+    merge(s.messages, scopilotHost(host), data)
+}
+
+// CountParameters 统计网站请求、响应中出现的参数名，按照出现次数降序排列
+func (s *Sink) CountParameters(host string, names []string) {
+    var data *SCopilotData
+    if s == nil {
+        lock.Lock()
+        defer lock.Unlock()
+        data = SCopilotMessage[scopilotHost(host)]
+    } else {
+        s.lock.Lock()
+        defer s.lock.Unlock()
+        data = s.messages[scopilotHost(host)]
+    }
+    if data == nil {
+        return
+    }
+    if data.CollectionMsg.Parameters == nil {
+        data.CollectionMsg.Parameters = orderedmap.New()
+    }
+    for _, name := range names {
+        if v, ok := data.CollectionMsg.Parameters.Get(name); ok {
+            data.CollectionMsg.Parameters.Set(name, v.(int)+1)
+        } else {
+            data.CollectionMsg.Parameters.Set(name, 1)
+        }
+    }
+    data.CollectionMsg.Parameters.Sort(func(a *orderedmap.Pair, b *orderedmap.Pair) bool {
+        return a.Value().(int) > b.Value().(int)
+    })
+}
+
+// Messages 返回保存的网站信息，key 为 host
+func (s *Sink) Messages() map[string]*SCopilotData {
+    s.lock.Lock()
+    defer s.lock.Unlock()
+    res := make(map[string]*SCopilotData, len(s.messages))
+    for k, v := range s.messages {
+        res[k] = v
+    }
+    return res
+}
@@ -0,0 +1,38 @@
+package output
+
+import (
+    "fmt"
+    "github.com/yhy0/logging"
+    "sync"
+)
+
+/**
+   @author yhy
+   @since 2024/6/14
+   @desc 记录因为安全模式等原因没有运行的检测，方便使用者知道哪些检测被跳过了
+**/
+
+var skipped sync.Map
+
+// Skipped 记录一次被跳过的检测，同一个网站的同一个检测只记录一次
+func Skipped(host, target, plugin, reason string) {
+    (*Sink)(nil).Skipped(host, target, plugin, reason)
+}
+
+// Skipped 记录一次被跳过的检测，记录到 Sink 保存的网站信息中
+func (s *Sink) Skipped(host, target, plugin, reason string) {
+    if _, loaded := skipped.LoadOrStore(fmt.Sprintf("%p_%s_%s", s, host, plugin), true); loaded {
+        return
+    }
+    logging.Logger.Infoln(fmt.Sprintf("[skip] [%s] %s %s", plugin, target, reason))
+    s.SCopilot(host, SCopilotData{
+        Target: host,
+        InfoMsg: []PluginMsg{
+            {
+                Url:    target,
+                Plugin: "Skipped",
+                Result: []string{plugin + ": " + reason},
+            },
+        },
+    })
+}
@@ -0,0 +1,296 @@
+package httpx
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "golang.org/x/net/http2"
+    "golang.org/x/net/http2/hpack"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/20
+   @desc 原始 socket 发送 HTTP/1.1、HTTP/2 请求，请求内容完全由调用者控制，不会被修正 Content-Length 等头
+        用于请求走私这类需要畸形请求的检测，不支持代理
+**/
+
+// RawResponse 原始请求的响应
+type RawResponse struct {
+    StatusCode int
+    Raw        string        // 响应头和响应体
+    Duration   time.Duration // 从发送完请求到读取到响应的时间
+    Timeout    bool          // 在超时时间内没有读取到响应
+}
+
+// H2Request HTTP/2 请求, Headers 中需要包含 :method :path 等伪头，会原样编码发送，不做任何校验
+type H2Request struct {
+    Headers [][2]string
+    Body    []byte
+}
+
+// ErrRawClosed 连接在读取到响应之前被关闭
+var ErrRawClosed = errors.New("connection closed before response")
+
+const rawMaxBody = 64 * 1024
+
+// rawDial 建立到目标的 tcp 连接，https 时进行 tls 握手，alpn 指定协商的协议
+func (c *Client) rawDial(u *url.URL, timeout time.Duration, alpn []string) (net.Conn, error) {
+    host := u.Host
+    if u.Port() == "" {
+        if u.Scheme == "https" {
+            host = net.JoinHostPort(u.Hostname(), "443")
+        } else {
+            host = net.JoinHostPort(u.Hostname(), "80")
+        }
+    }
+    
+    dialer := &net.Dialer{Timeout: timeout}
+    if u.Scheme != "https" {
+        return dialer.Dial("tcp", host)
+    }
+    
+    conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{
+        ServerName:         u.Hostname(),
+        InsecureSkipVerify: !c.Options.VerifySSL,
+        NextProtos:         alpn,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return conn, nil
+}
+
+// Raw 在同一个连接上依次发送 requests，每发送一个读取一个响应
+// 某个请求读取响应超时或者连接被关闭后，后面的请求不再发送，返回的响应数量可能少于请求数量
+func (c *Client) Raw(target string, requests [][]byte, timeout time.Duration) ([]*RawResponse, error) {
+    if err := c.canceled(); err != nil {
+        return nil, err
+    }
+    u, err := url.Parse(target)
+    if err != nil {
+        return nil, err
+    }
+    
+    for _, r := range requests {
+        method, _, _ := strings.Cut(string(r), " ")
+        if err = safeCheck(target, method, c.Scope); err != nil {
+            return nil, err
+        }
+    }
+    
+    conn, err := c.rawDial(u, timeout, []string{"http/1.1"})
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+    
+    var responses []*RawResponse
+    reader := bufio.NewReader(conn)
+    for _, r := range requests {
+        if !c.takeBudget() {
+            return responses, ErrBudgetExhausted
+        }
+        c.RateLimiter.Take()
+        
+        conn.SetDeadline(time.Now().Add(timeout))
+        if _, err = conn.Write(r); err != nil {
+            return responses, err
+        }
+        
+        start := time.Now()
+        resp, err := http.ReadResponse(reader, nil)
+        if err != nil {
+            var netErr net.Error
+            if errors.As(err, &netErr) && netErr.Timeout() {
+                responses = append(responses, &RawResponse{Duration: time.Since(start), Timeout: true})
+                return responses, nil
+            }
+            if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+                return responses, ErrRawClosed
+            }
+            return responses, err
+        }
+        
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, rawMaxBody))
+        resp.Body.Close()
+        
+        var buf bytes.Buffer
+        fmt.Fprintf(&buf, "%s %s\r\n", resp.Proto, resp.Status)
+        resp.Header.Write(&buf)
+        buf.WriteString("\r\n")
+        buf.Write(body)
+        
+        responses = append(responses, &RawResponse{
+            StatusCode: resp.StatusCode,
+            Raw:        buf.String(),
+            Duration:   time.Since(start),
+        })
+        
+        if resp.Close {
+            break
+        }
+    }
+    return responses, nil
+}
+
+// RawH2 通过 HTTP/2 在同一个连接上依次发送 requests，每个请求使用单独的 stream，等待上一个 stream 结束后再发送下一个
+// 服务端不支持 h2 时返回错误
+func (c *Client) RawH2(target string, requests []*H2Request, timeout time.Duration) ([]*RawResponse, error) {
+    if err := c.canceled(); err != nil {
+        return nil, err
+    }
+    u, err := url.Parse(target)
+    if err != nil {
+        return nil, err
+    }
+    if u.Scheme != "https" {
+        return nil, errors.New("h2 requires https")
+    }
+    
+    for _, r := range requests {
+        for _, h := range r.Headers {
+            if h[0] == ":method" {
+                if err = safeCheck(target, strings.ToUpper(h[1]), c.Scope); err != nil {
+                    return nil, err
+                }
+            }
+        }
+    }
+    
+    conn, err := c.rawDial(u, timeout, []string{"h2"})
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+    
+    if conn.(*tls.Conn).ConnectionState().NegotiatedProtocol != "h2" {
+        return nil, errors.New("h2 not supported")
+    }
+    
+    conn.SetDeadline(time.Now().Add(timeout))
+    if _, err = conn.Write([]byte(http2.ClientPreface)); err != nil {
+        return nil, err
+    }
+    framer := http2.NewFramer(conn, conn)
+    framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+    if err = framer.WriteSettings(http2.Setting{ID: http2.SettingEnablePush, Val: 0}); err != nil {
+        return nil, err
+    }
+    
+    var (
+        hbuf      bytes.Buffer
+        encoder   = hpack.NewEncoder(&hbuf)
+        responses []*RawResponse
+    )
+    
+    for i, r := range requests {
+        if !c.takeBudget() {
+            return responses, ErrBudgetExhausted
+        }
+        c.RateLimiter.Take()
+        
+        streamID := uint32(2*i + 1)
+        hbuf.Reset()
+        for _, h := range r.Headers {
+            encoder.WriteField(hpack.HeaderField{Name: h[0], Value: h[1]})
+        }
+        
+        conn.SetDeadline(time.Now().Add(timeout))
+        err = framer.WriteHeaders(http2.HeadersFrameParam{
+            StreamID:      streamID,
+            BlockFragment: hbuf.Bytes(),
+            EndStream:     len(r.Body) == 0,
+            EndHeaders:    true,
+        })
+        if err != nil {
+            return responses, err
+        }
+        if len(r.Body) > 0 {
+            if err = framer.WriteData(streamID, true, r.Body); err != nil {
+                return responses, err
+            }
+        }
+        
+        start := time.Now()
+        resp, err := readH2Response(framer, streamID)
+        if err != nil {
+            var netErr net.Error
+            if errors.As(err, &netErr) && netErr.Timeout() {
+                responses = append(responses, &RawResponse{Duration: time.Since(start), Timeout: true})
+                return responses, nil
+            }
+            return responses, err
+        }
+        resp.Duration = time.Since(start)
+        responses = append(responses, resp)
+    }
+    return responses, nil
+}
+
+// readH2Response 读取指定 stream 的响应，直到 stream 结束
+func readH2Response(framer *http2.Framer, streamID uint32) (*RawResponse, error) {
+    var (
+        header bytes.Buffer
+        body   bytes.Buffer
+        status int
+    )
+    for {
+        frame, err := framer.ReadFrame()
+        if err != nil {
+            return nil, err
+        }
+        switch f := frame.(type) {
+        case *http2.SettingsFrame:
+            if !f.IsAck() {
+                framer.WriteSettingsAck()
+            }
+        case *http2.PingFrame:
+            if !f.IsAck() {
+                framer.WritePing(true, f.Data)
+            }
+        case *http2.GoAwayFrame:
+            return nil, fmt.Errorf("goaway: %s", f.ErrCode)
+        case *http2.RSTStreamFrame:
+            if f.StreamID == streamID {
+                return nil, fmt.Errorf("rst_stream: %s", f.ErrCode)
+            }
+        case *http2.MetaHeadersFrame:
+            if f.StreamID != streamID {
+                continue
+            }
+            for _, h := range f.Fields {
+                if h.Name == ":status" {
+                    fmt.Sscanf(h.Value, "%d", &status)
+                }
+                header.WriteString(h.Name + ": " + h.Value + "\r\n")
+            }
+            if f.StreamEnded() {
+                return &RawResponse{StatusCode: status, Raw: header.String() + "\r\n"}, nil
+            }
+        case *http2.DataFrame:
+            if f.StreamID != streamID {
+                continue
+            }
+            if body.Len() < rawMaxBody {
+                body.Write(f.Data())
+            }
+            if len(f.Data()) > 0 {
+                // 及时更新窗口，防止响应体较大时服务端停止发送
+                framer.WriteWindowUpdate(0, uint32(len(f.Data())))
+                framer.WriteWindowUpdate(streamID, uint32(len(f.Data())))
+            }
+            if f.StreamEnded() {
+                return &RawResponse{StatusCode: status, Raw: header.String() + "\r\n" + body.String()}, nil
+            }
+        }
+    }
+}
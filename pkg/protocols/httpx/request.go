@@ -3,12 +3,15 @@ package httpx
 import (
     "bufio"
     "bytes"
+    "context"
     "errors"
     "fmt"
     "github.com/imroc/req/v3"
     regexp "github.com/wasilibs/go-re2"
     "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/scan/gadget/sensitive"
+    "github.com/yhy0/Jie/scan/gadget/tracker"
     "github.com/yhy0/logging"
     "go.uber.org/ratelimit"
     "io/ioutil"
@@ -18,6 +21,7 @@ import (
     "net/url"
     "runtime"
     "strings"
+    "sync/atomic"
     "time"
 )
 
@@ -55,8 +59,24 @@ type Client struct {
     Client      *req.Client
     Options     *Options
     RateLimiter ratelimit.Limiter // 每秒请求速率限制
+    
+    // 下面这些是嵌入使用时每个扫描任务单独的设置，为空时使用全局的配置
+    Ctx   context.Context // 取消后不再发送请求，正在发送的请求也会被中断
+    Sink  *output.Sink    // 插件发现的漏洞、网站信息的接收者
+    Scope *conf.Scope     // 安全模式和扫描策略
+    
+    maxRequests int64   // 最多允许发送的请求数，0 为不限制，由扫描策略指定
+    requests    *int64  // 已经发送的请求数
+    parent      *Client // 从带有请求数限制的 client 派生时，同时受上级的限制
+    
+    rewrite   func(target, method, body string) (string, string, string)                                // 发送前修改请求，由 WithRewrite 指定
+    roundTrip func(c *Client, target, method, body string, header map[string]string) (*Response, error) // 代替发送请求，由 WithRoundTrip 指定
+    base      *Client                                                                                   // WithRoundTrip 派生前的 client
 }
 
+// ErrBudgetExhausted 扫描策略中限制的请求数已经用完
+var ErrBudgetExhausted = errors.New("request budget exhausted")
+
 func NewClient(o *Options) *Client {
     if o == nil {
         o = &Options{
@@ -122,6 +142,97 @@ func NewClient(o *Options) *Client {
     return client
 }
 
+// WithBudget 返回一个共享连接池和速率限制的 client，但最多只允许发送 max 个请求，max <= 0 时直接返回原 client
+func (c *Client) WithBudget(max int) *Client {
+    if max <= 0 {
+        return c
+    }
+    client := &Client{
+        Client:      c.Client,
+        Options:     c.Options,
+        RateLimiter: c.RateLimiter,
+        Ctx:         c.Ctx,
+        Sink:        c.Sink,
+        Scope:       c.Scope,
+        maxRequests: int64(max),
+        requests:    new(int64),
+    }
+    if c.maxRequests > 0 || c.parent != nil {
+        client.parent = c
+    }
+    return client
+}
+
+// WithRewrite 返回一个共享连接池、速率限制和请求数限制的 client，每个请求发送前先经过 f 修改
+func (c *Client) WithRewrite(f func(target, method, body string) (string, string, string)) *Client {
+    client := *c
+    client.rewrite = f
+    return &client
+}
+
+// WithRoundTrip 返回一个共享连接池、速率限制和请求数限制的 client，请求交给 f 发送，例如通过 WebSocket 发送
+// f 的参数 c 为原来的 client，不需要 f 处理的请求直接使用 c 发送
+func (c *Client) WithRoundTrip(f func(c *Client, target, method, body string, header map[string]string) (*Response, error)) *Client {
+    client := *c
+    client.roundTrip = f
+    client.base = c
+    return &client
+}
+
+// takeBudget 消耗一次请求数，超出限制时返回 false
+func (c *Client) takeBudget() bool {
+    if c.maxRequests > 0 && atomic.AddInt64(c.requests, 1) > c.maxRequests {
+        return false
+    }
+    return c.parent == nil || c.parent.takeBudget()
+}
+
+// Spend 记录一次不经过 client 发送的请求，例如爬虫、nuclei 自己发送的请求，超出限制时返回 ErrBudgetExhausted
+func (c *Client) Spend() error {
+    if c == nil || c.takeBudget() {
+        return nil
+    }
+    return ErrBudgetExhausted
+}
+
+// Budget 返回已经发送的请求数和最多允许发送的请求数，没有限制时都为 0
+func (c *Client) Budget() (int64, int64) {
+    if c.maxRequests <= 0 {
+        return 0, 0
+    }
+    used := atomic.LoadInt64(c.requests)
+    if used > c.maxRequests {
+        used = c.maxRequests
+    }
+    return used, c.maxRequests
+}
+
+// Report 输出插件发现的漏洞，c 为空时发送到 output.OutChannel
+func (c *Client) Report(msg output.VulMessage) {
+    if c == nil {
+        output.OutChannel <- msg
+        return
+    }
+    c.Sink.Report(msg)
+}
+
+// SCopilot 保存插件收集的网站信息，c 为空时保存到 output.SCopilotMessage
+func (c *Client) SCopilot(host string, data output.SCopilotData) {
+    if c == nil {
+        output.SCopilot(host, data)
+        return
+    }
+    c.Sink.SCopilot(host, data)
+}
+
+// canceled 扫描任务取消后返回错误
+func (c *Client) canceled() error {
+    if c.Ctx == nil {
+        return nil
+    }
+    return c.Ctx.Err()
+}
+
 func (c *Client) Basic(target string, method string, body string, header map[string]string, username, password string) (*Response, error) {
     c.Client.SetCommonBasicAuth(username, password)
     return c.Request(target, method, body, header)
@@ -129,6 +240,24 @@ func (c *Client) Basic(target string, method string, body string, header map[str
 
 func (c *Client) Request(target string, method string, body string, header map[string]string) (*Response, error) {
     method = strings.ToUpper(method)
+    if c.roundTrip != nil {
+        return c.roundTrip(c.base, target, method, body, header)
+    }
+    if c.rewrite != nil {
+        target, method, body = c.rewrite(target, method, body)
+    }
+    
+    if err := c.canceled(); err != nil {
+        return nil, err
+    }
+    
+    if err := safeCheck(target, method, c.Scope); err != nil {
+        return nil, err
+    }
+    
+    if !c.takeBudget() {
+        return nil, ErrBudgetExhausted
+    }
     
     // https://req.cool/docs/tutorial/debugging/
     var requestDumpBuf, responseDumpBuf bytes.Buffer
@@ -160,6 +289,9 @@ func (c *Client) Request(target string, method string, body string, header map[s
     c.Options.AllowRedirect = 0
     
     request := c.Client.R().SetDumpOptions(opt).EnableDump().EnableTrace() // 启用 trace，获取响应的时间
+    if c.Ctx != nil {
+        request.SetContext(c.Ctx)
+    }
     
     if c.Options.Headers != nil {
         if c.Options.Headers["Accept-Encoding"] == "gzip, deflate" {
@@ -223,7 +355,10 @@ func (c *Client) Request(target string, method string, body string, header map[s
     }
     
     // 检测所有的返回包，可能有某个插件导致报错，存在报错信息
-    sensitive.PageErrorMessageCheck(target, requestDumpBuf.String(), respBody)
+    sensitive.PageErrorMessageCheck(target, requestDumpBuf.String(), respBody, c.Sink)
+    
+    // 查找之前注入的标记，确认存储型漏洞
+    tracker.Check(target, requestDumpBuf.String(), responseDumpBuf.String())
     
     return &Response{
         Status:           resp.Status,
@@ -240,6 +375,15 @@ func (c *Client) Request(target string, method string, body string, header map[s
 }
 
 func (c *Client) Upload(target string, params map[string]string, name, fileName string) (*Response, error) {
+    // 文件上传会在目标上写入文件，安全模式下不允许
+    if c.Scope.Safe() {
+        return nil, ErrUnsafeRequest
+    }
+    
+    if !c.takeBudget() {
+        return nil, ErrBudgetExhausted
+    }
+    
     // https://req.cool/docs/tutorial/debugging/
     var requestDumpBuf, responseDumpBuf bytes.Buffer
     // Enable dump with fully customized settings at client level.
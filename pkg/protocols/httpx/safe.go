@@ -0,0 +1,91 @@
+package httpx
+
+import (
+    "errors"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/logging"
+    "net/url"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/14
+   @desc 安全模式下拒绝发送可能修改目标数据的请求
+        PUT、DELETE、PATCH 直接拒绝，POST 只拒绝路径看起来是增删改操作的接口，文件上传全部拒绝
+**/
+
+// ErrUnsafeRequest 安全模式下拒绝发送的请求
+var ErrUnsafeRequest = errors.New("request refused in safe mode")
+
+var unsafeMethods = []string{"PUT", "DELETE", "PATCH"}
+
+// 路径中包含这些关键字的接口认为是会修改数据的接口
+var stateChangingKeywords = []string{
+    "delete", "del", "remove", "drop", "destroy", "clear",
+    "update", "edit", "modify", "save", "add", "create", "insert",
+    "upload", "import", "reset", "logout", "signout",
+    "pay", "transfer", "order", "submit", "approve", "cancel",
+}
+
+// isUnsafeRequest 判断请求是否会修改目标的数据
+func isUnsafeRequest(target, method string) bool {
+    for _, m := range unsafeMethods {
+        if method == m {
+            return true
+        }
+    }
+    
+    if method != "POST" {
+        return false
+    }
+    
+    u, err := url.Parse(target)
+    if err != nil {
+        return false
+    }
+    
+    // 按照路径分隔符、驼峰拆分后匹配，防止 address 这种路径误匹配到 add
+    for _, w := range splitCamel(u.Path) {
+        w = strings.ToLower(w)
+        for _, k := range stateChangingKeywords {
+            if w == k || w == k+"s" {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// splitCamel 按照驼峰拆分路径，例如 /user/deleteById -> user delete By Id
+func splitCamel(path string) []string {
+    var words []string
+    var word []rune
+    for _, r := range path {
+        if r == '/' || r == '_' || r == '-' || r == '.' {
+            if len(word) > 0 {
+                words = append(words, string(word))
+            }
+            word = word[:0]
+            continue
+        }
+        if r >= 'A' && r <= 'Z' && len(word) > 0 {
+            words = append(words, string(word))
+            word = word[:0]
+        }
+        word = append(word, r)
+    }
+    if len(word) > 0 {
+        words = append(words, string(word))
+    }
+    return words
+}
+
+// safeCheck 安全模式下检查请求是否允许发送
+func safeCheck(target, method string, scope *conf.Scope) error {
+    if !scope.Safe() || !isUnsafeRequest(target, method) {
+        return nil
+    }
+    logging.Logger.Debugln("[safe mode] refuse", method, target)
+    return ErrUnsafeRequest
+}
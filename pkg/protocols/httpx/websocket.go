@@ -0,0 +1,209 @@
+package httpx
+
+import (
+    "context"
+    "crypto/tls"
+    "fmt"
+    "github.com/gorilla/websocket"
+    "net/http"
+    "net/http/httputil"
+    "net/url"
+    "sort"
+    "strings"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/27
+   @desc WebSocket 重放，建立一个新的连接发送消息，用于检测 WebSocket 消息中的参数和握手时的 Origin 校验
+**/
+
+// 握手时由 websocket 库生成的请求头，不能重复设置
+var handshakeHeaders = []string{"Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Host", "Content-Length"}
+
+// 单次连接最多接收的消息数
+const maxWebSocketReplies = 50
+
+// WebSocket 建立新的 WebSocket 连接，依次发送 messages，返回最后一条消息发送后收到的消息，多条消息以换行分隔
+// 之前的消息用于登录、订阅等前置操作，收到的回复会被丢弃。idle 时间内没有新消息时认为服务端回复结束
+// 握手失败时返回只有握手响应的 Response 和错误
+func (c *Client) WebSocket(target string, header map[string]string, messages []string, idle time.Duration) (*Response, error) {
+    if err := c.canceled(); err != nil {
+        return nil, err
+    }
+    // 只握手不会修改数据，发送消息时和 POST 一样按照路径判断是否允许
+    method := "GET"
+    if len(messages) > 0 {
+        method = "POST"
+    }
+    if err := safeCheck(target, method, c.Scope); err != nil {
+        return nil, err
+    }
+    if !c.takeBudget() {
+        return nil, ErrBudgetExhausted
+    }
+    
+    u, err := url.Parse(target)
+    if err != nil {
+        return nil, err
+    }
+    switch u.Scheme {
+    case "http":
+        u.Scheme = "ws"
+    case "https":
+        u.Scheme = "wss"
+    }
+    
+    timeout := time.Duration(c.Options.Timeout) * time.Second
+    dialer := &websocket.Dialer{
+        HandshakeTimeout: timeout,
+        TLSClientConfig:  &tls.Config{InsecureSkipVerify: !c.Options.VerifySSL},
+    }
+    if c.Options.Proxy != "" {
+        if proxyURL, err := url.Parse(c.Options.Proxy); err == nil {
+            dialer.Proxy = http.ProxyURL(proxyURL)
+        }
+    }
+    
+    h := http.Header{}
+    for k, v := range c.Options.Headers {
+        h.Set(k, v)
+    }
+    for k, v := range header {
+        skip := false
+        for _, hh := range handshakeHeaders {
+            if strings.EqualFold(k, hh) {
+                skip = true
+                break
+            }
+        }
+        if !skip {
+            h.Set(k, v)
+        }
+    }
+    
+    var requestDump, responseDump strings.Builder
+    requestDump.WriteString(fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n", u.RequestURI(), u.Host))
+    var keys []string
+    for k := range h {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    for _, k := range keys {
+        requestDump.WriteString(fmt.Sprintf("%s: %s\r\n", k, strings.Join(h[k], ", ")))
+    }
+    requestDump.WriteString("\r\n")
+    
+    c.RateLimiter.Take()
+    ctx := c.Ctx
+    if ctx == nil {
+        ctx = context.Background()
+    }
+    conn, resp, err := dialer.DialContext(ctx, u.String(), h)
+    if resp != nil {
+        if dump, e := httputil.DumpResponse(resp, false); e == nil {
+            responseDump.Write(dump)
+        }
+    }
+    if err != nil {
+        if resp == nil {
+            return nil, err
+        }
+        return &Response{
+            Status:       resp.Status,
+            StatusCode:   resp.StatusCode,
+            Header:       resp.Header,
+            RequestDump:  requestDump.String(),
+            ResponseDump: responseDump.String(),
+            RequestUrl:   target,
+        }, err
+    }
+    defer conn.Close()
+    
+    // 读取超时后 websocket 连接不能再读，所以单独读取，通过 channel 设置等待时间
+    done := make(chan struct{})
+    defer close(done)
+    ch := make(chan reply)
+    go func() {
+        defer close(ch)
+        for {
+            _, data, err := conn.ReadMessage()
+            if err != nil {
+                return
+            }
+            select {
+            case ch <- reply{data: string(data), at: time.Now()}:
+            case <-done:
+                return
+            }
+        }
+    }()
+    
+    var (
+        replies  []string
+        duration time.Duration
+    )
+    for i, m := range messages {
+        requestDump.WriteString("> " + m + "\n")
+        start := time.Now()
+        if err = conn.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+            return nil, err
+        }
+        
+        // 最后一条消息的第一个回复最多等待超时时间，时间盲注这类检测需要用到响应时间
+        if i < len(messages)-1 {
+            readReplies(ch, idle, idle)
+            continue
+        }
+        var first time.Time
+        replies, first = readReplies(ch, timeout, idle)
+        if !first.IsZero() {
+            duration = first.Sub(start)
+        }
+    }
+    for _, r := range replies {
+        responseDump.WriteString("< " + r + "\n")
+    }
+    
+    return &Response{
+        Status:           resp.Status,
+        StatusCode:       resp.StatusCode,
+        Body:             strings.Join(replies, "\n"),
+        RequestDump:      requestDump.String(),
+        ResponseDump:     responseDump.String(),
+        Header:           resp.Header,
+        RequestUrl:       target,
+        ServerDurationMs: float64(duration.Milliseconds()),
+    }, nil
+}
+
+type reply struct {
+    data string
+    at   time.Time
+}
+
+// readReplies 读取服务端的消息，first 为等待第一条消息的时间，之后 idle 时间内没有新消息时返回
+func readReplies(ch <-chan reply, first, idle time.Duration) ([]string, time.Time) {
+    var (
+        replies []string
+        at      time.Time
+    )
+    wait := first
+    for len(replies) < maxWebSocketReplies {
+        select {
+        case r, ok := <-ch:
+            if !ok {
+                return replies, at
+            }
+            if at.IsZero() {
+                at = r.at
+            }
+            replies = append(replies, r.data)
+            wait = idle
+        case <-time.After(wait):
+            return replies, at
+        }
+    }
+    return replies, at
+}
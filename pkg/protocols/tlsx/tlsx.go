@@ -0,0 +1,364 @@
+package tlsx
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "sort"
+    "strings"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/30
+   @desc TLS 配置和证书检测，枚举支持的协议版本、加密套件，检查证书链、有效期、密钥长度、签名算法、域名是否匹配
+        只依赖标准库，可以直接对本地 TLS 服务测试。Go 不支持 SSLv3，使用原始的 ClientHello 检测
+        每次连接前调用 spend 消耗一次请求数，ctx 取消或请求数用完时停止检测并返回错误
+**/
+
+// 问题等级，和 output 中的等级相同
+const (
+    Low    = "Low"
+    Medium = "Medium"
+)
+
+// 证书过期前多少天提示
+const expiryWarning = 30 * 24 * time.Hour
+
+// Issue 检测到的问题
+type Issue struct {
+    Name        string
+    Level       string
+    Description string
+}
+
+// Result 检测结果
+type Result struct {
+    Address  string
+    Versions []string            // 支持的协议版本
+    Ciphers  map[string][]string // key 为协议版本，value 为支持的加密套件，TLS 1.3 只有协商出的套件
+    Chain    []*x509.Certificate // 服务端返回的证书，第一个为叶子证书
+    SANs     []string
+    Issues   []Issue
+}
+
+var versions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// auditor 一次检测的上下文和请求数限制
+type auditor struct {
+    ctx     context.Context
+    timeout time.Duration
+    spend   func() error
+    err     error // ctx 取消或者请求数用完，不再继续连接
+}
+
+// Audit addr 为 host:port，serverName 为空时不检查域名是否匹配，spend 为空时不限制连接次数
+func Audit(ctx context.Context, addr, serverName string, timeout time.Duration, spend func() error) (*Result, error) {
+    if ctx == nil {
+        ctx = context.Background()
+    }
+    a := &auditor{ctx: ctx, timeout: timeout, spend: spend}
+    
+    // 先完成一次握手，确认是 TLS 服务并获取证书
+    state, err := a.handshake(addr, serverName, &tls.Config{MinVersion: tls.VersionTLS10})
+    if err != nil {
+        return nil, err
+    }
+    r := &Result{
+        Address: addr,
+        Ciphers: make(map[string][]string),
+        Chain:   state.PeerCertificates,
+    }
+    
+    if a.sslv3(addr) {
+        r.Versions = append(r.Versions, "SSLv3")
+    }
+    if a.err != nil {
+        return nil, a.err
+    }
+    for _, v := range versions {
+        // 默认的加密套件中没有 RSA 密钥交换、3DES 这些，需要全部指定，否则只支持这些套件的服务会被漏掉
+        var ids []uint16
+        for _, c := range suites(v) {
+            ids = append(ids, c.ID)
+        }
+        s, err := a.handshake(addr, serverName, &tls.Config{MinVersion: v, MaxVersion: v, CipherSuites: ids})
+        if a.err != nil {
+            return nil, a.err
+        }
+        if err != nil {
+            continue
+        }
+        name := tls.VersionName(v)
+        r.Versions = append(r.Versions, name)
+        // TLS 1.3 的加密套件无法指定
+        if v == tls.VersionTLS13 {
+            r.Ciphers[name] = []string{tls.CipherSuiteName(s.CipherSuite)}
+            continue
+        }
+        for _, c := range suites(v) {
+            _, err := a.handshake(addr, serverName, &tls.Config{MinVersion: v, MaxVersion: v, CipherSuites: []uint16{c.ID}})
+            if a.err != nil {
+                return nil, a.err
+            }
+            if err == nil {
+                r.Ciphers[name] = append(r.Ciphers[name], c.Name)
+            }
+        }
+    }
+    
+    r.protocols()
+    r.certificate(serverName)
+    return r, nil
+}
+
+// dial 每次连接前检查 ctx 并消耗一次请求数，失败后记录到 a.err，之后的连接都直接返回
+func (a *auditor) dial(addr string) (net.Conn, error) {
+    if a.err == nil {
+        a.err = a.ctx.Err()
+    }
+    if a.err == nil && a.spend != nil {
+        a.err = a.spend()
+    }
+    if a.err != nil {
+        return nil, a.err
+    }
+    return (&net.Dialer{Timeout: a.timeout}).DialContext(a.ctx, "tcp", addr)
+}
+
+func (a *auditor) handshake(addr, serverName string, config *tls.Config) (*tls.ConnectionState, error) {
+    config.InsecureSkipVerify = true
+    if net.ParseIP(serverName) == nil {
+        config.ServerName = serverName
+    }
+    raw, err := a.dial(addr)
+    if err != nil {
+        return nil, err
+    }
+    ctx, cancel := context.WithTimeout(a.ctx, a.timeout)
+    defer cancel()
+    conn := tls.Client(raw, config)
+    if err = conn.HandshakeContext(ctx); err != nil {
+        raw.Close()
+        return nil, err
+    }
+    defer conn.Close()
+    state := conn.ConnectionState()
+    return &state, nil
+}
+
+// suites 指定协议版本可以使用的加密套件，包括不安全的
+func suites(version uint16) []*tls.CipherSuite {
+    var res []*tls.CipherSuite
+    for _, c := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+        for _, v := range c.SupportedVersions {
+            if v == version {
+                res = append(res, c)
+                break
+            }
+        }
+    }
+    return res
+}
+
+// sslv3 发送 SSLv3 的 ClientHello，服务端返回 SSLv3 的 ServerHello 说明支持
+func (a *auditor) sslv3(addr string) bool {
+    conn, err := a.dial(addr)
+    if err != nil {
+        return false
+    }
+    defer conn.Close()
+    _ = conn.SetDeadline(time.Now().Add(a.timeout))
+    
+    // RSA 密钥交换的常见套件
+    ciphers := []uint16{0x002f, 0x0035, 0x000a, 0x0005, 0x0004, 0x0009, 0x0016, 0x0033, 0x0039}
+    body := []byte{0x03, 0x00}
+    random := make([]byte, 32)
+    _, _ = rand.Read(random)
+    body = append(body, random...)
+    body = append(body, 0x00) // session id
+    body = binary.BigEndian.AppendUint16(body, uint16(len(ciphers)*2))
+    for _, c := range ciphers {
+        body = binary.BigEndian.AppendUint16(body, c)
+    }
+    body = append(body, 0x01, 0x00) // 不压缩
+    
+    hello := []byte{0x01, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}
+    hello = append(hello, body...)
+    record := []byte{0x16, 0x03, 0x00}
+    record = binary.BigEndian.AppendUint16(record, uint16(len(hello)))
+    record = append(record, hello...)
+    if _, err = conn.Write(record); err != nil {
+        return false
+    }
+    
+    // 记录头 5 字节，握手消息类型 1 字节、长度 3 字节、版本 2 字节
+    buf := make([]byte, 11)
+    if _, err = io.ReadFull(conn, buf); err != nil {
+        return false
+    }
+    return buf[0] == 0x16 && buf[5] == 0x02 && buf[9] == 0x03 && buf[10] == 0x00
+}
+
+// protocols 协议版本和加密套件的问题
+func (r *Result) protocols() {
+    has := func(v string) bool {
+        for _, s := range r.Versions {
+            if s == v {
+                return true
+            }
+        }
+        return false
+    }
+    if has("SSLv3") {
+        r.add("sslv3", Medium, "SSLv3 is supported, it is vulnerable to POODLE")
+    }
+    var deprecated []string
+    for _, v := range []string{"TLS 1.0", "TLS 1.1"} {
+        if has(v) {
+            deprecated = append(deprecated, v)
+        }
+    }
+    if len(deprecated) > 0 {
+        r.add("deprecated protocol", Low, fmt.Sprintf("Deprecated protocols are supported: %s", strings.Join(deprecated, ", ")))
+    }
+    if !has("TLS 1.2") && !has("TLS 1.3") {
+        r.add("no modern protocol", Medium, "Neither TLS 1.2 nor TLS 1.3 is supported")
+    }
+    
+    var broken, weak, noPFS []string
+    for _, c := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+        if !r.supports(c.Name) {
+            continue
+        }
+        if strings.Contains(c.Name, "RC4") || strings.Contains(c.Name, "3DES") {
+            broken = append(broken, c.Name)
+        } else if strings.HasSuffix(c.Name, "CBC_SHA256") {
+            weak = append(weak, c.Name)
+        }
+        if strings.HasPrefix(c.Name, "TLS_RSA_") {
+            noPFS = append(noPFS, c.Name)
+        }
+    }
+    if len(broken) > 0 {
+        r.add("broken cipher", Medium, fmt.Sprintf("RC4/3DES cipher suites are supported (RC4 biases, SWEET32): %s", strings.Join(broken, ", ")))
+    }
+    if len(weak) > 0 {
+        r.add("weak cipher", Low, fmt.Sprintf("Insecure CBC-SHA256 cipher suites are supported (Lucky13): %s", strings.Join(weak, ", ")))
+    }
+    if len(noPFS) > 0 {
+        r.add("no forward secrecy", Low, fmt.Sprintf("Cipher suites with RSA key exchange are supported, they do not provide forward secrecy: %s", strings.Join(noPFS, ", ")))
+    }
+}
+
+func (r *Result) supports(cipher string) bool {
+    for _, cs := range r.Ciphers {
+        for _, c := range cs {
+            if c == cipher {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// certificate 证书的问题
+func (r *Result) certificate(serverName string) {
+    if len(r.Chain) == 0 {
+        return
+    }
+    leaf := r.Chain[0]
+    r.SANs = append(r.SANs, leaf.DNSNames...)
+    for _, ip := range leaf.IPAddresses {
+        r.SANs = append(r.SANs, ip.String())
+    }
+    sort.Strings(r.SANs)
+    
+    now := time.Now()
+    switch {
+    case now.After(leaf.NotAfter):
+        r.add("expired certificate", Medium, fmt.Sprintf("The certificate expired on %s", leaf.NotAfter.Format("2006-01-02")))
+    case now.Before(leaf.NotBefore):
+        r.add("certificate not yet valid", Medium, fmt.Sprintf("The certificate is not valid before %s", leaf.NotBefore.Format("2006-01-02")))
+    case leaf.NotAfter.Sub(now) < expiryWarning:
+        r.add("certificate expiring", Low, fmt.Sprintf("The certificate expires on %s", leaf.NotAfter.Format("2006-01-02")))
+    }
+    
+    intermediates := x509.NewCertPool()
+    for _, c := range r.Chain[1:] {
+        intermediates.AddCert(c)
+    }
+    // 有效期已经单独检查，这里使用有效期内的时间，只校验证书链
+    current := now
+    if now.After(leaf.NotAfter) || now.Before(leaf.NotBefore) {
+        current = leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) / 2)
+    }
+    if _, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: current}); err != nil {
+        var unknown x509.UnknownAuthorityError
+        switch {
+        case errors.As(err, &unknown) && leaf.CheckSignatureFrom(leaf) == nil:
+            r.add("self-signed certificate", Medium, "The certificate is self-signed")
+        case errors.As(err, &unknown):
+            r.add("untrusted certificate", Medium, fmt.Sprintf("The certificate chain is not trusted (issuer %s): %v", leaf.Issuer.String(), err))
+        default:
+            r.add("invalid certificate chain", Medium, fmt.Sprintf("The certificate chain is invalid: %v", err))
+        }
+    }
+    
+    if serverName != "" {
+        if err := leaf.VerifyHostname(serverName); err != nil {
+            r.add("hostname mismatch", Medium, fmt.Sprintf("The certificate is not valid for %s, SANs: %s", serverName, strings.Join(r.SANs, ", ")))
+        }
+    }
+    if len(leaf.DNSNames) == 0 && len(leaf.IPAddresses) == 0 {
+        r.add("missing san", Low, fmt.Sprintf("The certificate has no subjectAltName, browsers do not accept the common name %s", leaf.Subject.CommonName))
+    }
+    
+    switch key := leaf.PublicKey.(type) {
+    case *rsa.PublicKey:
+        if key.N.BitLen() < 2048 {
+            r.add("weak key", Medium, fmt.Sprintf("The certificate uses a %d-bit RSA key", key.N.BitLen()))
+        }
+    case *ecdsa.PublicKey:
+        if key.Curve.Params().BitSize < 256 {
+            r.add("weak key", Medium, fmt.Sprintf("The certificate uses a %d-bit ECDSA key", key.Curve.Params().BitSize))
+        }
+    }
+    
+    switch leaf.SignatureAlgorithm {
+    case x509.MD2WithRSA, x509.MD5WithRSA:
+        r.add("weak signature", Medium, fmt.Sprintf("The certificate is signed with %s", leaf.SignatureAlgorithm))
+    case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+        r.add("weak signature", Low, fmt.Sprintf("The certificate is signed with %s", leaf.SignatureAlgorithm))
+    }
+}
+
+func (r *Result) add(name, level, description string) {
+    r.Issues = append(r.Issues, Issue{Name: name, Level: level, Description: description})
+}
+
+// Summary 协议版本、加密套件和证书信息，用于记录
+func (r *Result) Summary() []string {
+    res := []string{"protocols: " + strings.Join(r.Versions, ", ")}
+    for _, v := range r.Versions {
+        if cs, ok := r.Ciphers[v]; ok {
+            res = append(res, v+" ciphers: "+strings.Join(cs, ", "))
+        }
+    }
+    if len(r.Chain) > 0 {
+        leaf := r.Chain[0]
+        res = append(res, fmt.Sprintf("certificate: subject %s, issuer %s, valid %s ~ %s, %s",
+            leaf.Subject.String(), leaf.Issuer.String(), leaf.NotBefore.Format("2006-01-02"), leaf.NotAfter.Format("2006-01-02"), leaf.SignatureAlgorithm))
+        res = append(res, "SANs: "+strings.Join(r.SANs, ", "))
+    }
+    return res
+}
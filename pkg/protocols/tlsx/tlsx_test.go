@@ -0,0 +1,182 @@
+package tlsx
+
+import (
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "errors"
+    "math/big"
+    "net"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// newCert 生成自签名证书
+func newCert(t *testing.T, key crypto.Signer, notBefore, notAfter time.Time, names []string) tls.Certificate {
+    template := &x509.Certificate{
+        SerialNumber:          big.NewInt(1),
+        Subject:               pkix.Name{CommonName: "jie test"},
+        NotBefore:             notBefore,
+        NotAfter:              notAfter,
+        DNSNames:              names,
+        KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+        ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+        IsCA:                  true,
+        BasicConstraintsValid: true,
+    }
+    der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// serve 启动本地 TLS 服务，返回地址
+func serve(t *testing.T, config *tls.Config) string {
+    l, err := tls.Listen("tcp", "127.0.0.1:0", config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { l.Close() })
+    go func() {
+        for {
+            conn, err := l.Accept()
+            if err != nil {
+                return
+            }
+            go func() {
+                defer conn.Close()
+                _ = conn.(*tls.Conn).Handshake()
+            }()
+        }
+    }()
+    return l.Addr().String()
+}
+
+func names(issues []Issue) map[string]bool {
+    res := make(map[string]bool)
+    for _, i := range issues {
+        res[i.Name] = true
+    }
+    return res
+}
+
+func TestAuditWeak(t *testing.T) {
+    key, err := rsa.GenerateKey(rand.Reader, 1024)
+    if err != nil {
+        t.Fatal(err)
+    }
+    now := time.Now()
+    addr := serve(t, &tls.Config{
+        Certificates: []tls.Certificate{newCert(t, key, now.Add(-48*time.Hour), now.Add(-24*time.Hour), []string{"example.com", "dev.example.com"})},
+        MinVersion:   tls.VersionTLS10,
+        MaxVersion:   tls.VersionTLS12,
+        CipherSuites: []uint16{tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA, tls.TLS_RSA_WITH_AES_128_CBC_SHA, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+    })
+    
+    r, err := Audit(context.Background(), addr, "localhost", 3*time.Second, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(r.Versions, []string{"TLS 1.0", "TLS 1.1", "TLS 1.2"}) {
+        t.Errorf("versions %v", r.Versions)
+    }
+    if !reflect.DeepEqual(r.SANs, []string{"dev.example.com", "example.com"}) {
+        t.Errorf("SANs %v", r.SANs)
+    }
+    got := names(r.Issues)
+    for _, name := range []string{"deprecated protocol", "broken cipher", "no forward secrecy", "expired certificate", "self-signed certificate", "hostname mismatch", "weak key"} {
+        if !got[name] {
+            t.Errorf("missing issue %q in %v", name, r.Issues)
+        }
+    }
+    if got["sslv3"] || got["no modern protocol"] {
+        t.Errorf("unexpected issues %v", r.Issues)
+    }
+}
+
+func TestAuditStrong(t *testing.T) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    now := time.Now()
+    addr := serve(t, &tls.Config{
+        Certificates: []tls.Certificate{newCert(t, key, now.Add(-time.Hour), now.Add(365*24*time.Hour), []string{"localhost"})},
+        MinVersion:   tls.VersionTLS12,
+        CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+    })
+    
+    r, err := Audit(context.Background(), addr, "localhost", 3*time.Second, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(r.Versions, []string{"TLS 1.2", "TLS 1.3"}) {
+        t.Errorf("versions %v", r.Versions)
+    }
+    if len(r.Ciphers["TLS 1.2"]) != 2 {
+        t.Errorf("TLS 1.2 ciphers %v", r.Ciphers["TLS 1.2"])
+    }
+    // 本地证书不受信任，其他都没有问题
+    if !reflect.DeepEqual(names(r.Issues), map[string]bool{"self-signed certificate": true}) {
+        t.Errorf("issues %v", r.Issues)
+    }
+}
+
+func TestAuditNotTLS(t *testing.T) {
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer l.Close()
+    go func() {
+        conn, err := l.Accept()
+        if err == nil {
+            _, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+            conn.Close()
+        }
+    }()
+    if _, err := Audit(context.Background(), l.Addr().String(), "", time.Second, nil); err == nil {
+        t.Error("expected error for a plain tcp service")
+    }
+}
+
+// 请求数用完或者 ctx 取消后停止检测
+func TestAuditAbort(t *testing.T) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    now := time.Now()
+    addr := serve(t, &tls.Config{
+        Certificates: []tls.Certificate{newCert(t, key, now.Add(-time.Hour), now.Add(365*24*time.Hour), []string{"localhost"})},
+    })
+    
+    errBudget := errors.New("budget exhausted")
+    dials := 0
+    spend := func() error {
+        if dials++; dials > 3 {
+            return errBudget
+        }
+        return nil
+    }
+    if _, err := Audit(context.Background(), addr, "localhost", 3*time.Second, spend); !errors.Is(err, errBudget) {
+        t.Errorf("budget: err = %v", err)
+    }
+    if dials != 4 {
+        t.Errorf("budget: %d dials, want 4", dials)
+    }
+    
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := Audit(ctx, addr, "localhost", 3*time.Second, nil); !errors.Is(err, context.Canceled) {
+        t.Errorf("canceled: err = %v", err)
+    }
+}
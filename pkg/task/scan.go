@@ -1,7 +1,6 @@
 package task
 
 import (
-    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/scan"
     "path"
@@ -35,8 +34,8 @@ func (t *Task) PerServer(in *input.CrawlResult) {
     // 不管第一次传入的是不是 http://examples.com 这种主域名格式，都只会取域名转换为这种 http://examples.com，进行一次扫描
     target := in.ParseUrl.Scheme + "://" + strings.TrimRight(strings.TrimRight(in.ParseUrl.Host, ":443"), ":80")
     
-    for _, plugin := range scan.PerServerPlugins {
-        if conf.Plugin[plugin.Name()] {
+    for _, plugin := range t.PluginSet().PerServer {
+        if t.Enable(plugin.Name()) && t.riskAllowed(plugin, in.Host, target) {
             if t.ScanTask[in.Host].PerServer[plugin.Name()] {
                 continue
             }
@@ -46,7 +45,7 @@ func (t *Task) PerServer(in *input.CrawlResult) {
             t.AddWg(in.Host)
             go func(p scan.Addon) {
                 defer t.DoneWg(in.Host)
-                p.Scan(target, "/", in, t.ScanTask[in.Host].Client)
+                p.Scan(target, "/", in, t.ScanTask[in.Host].PluginClient(p.Name()))
             }(plugin)
         }
     }
@@ -78,8 +77,8 @@ func (t *Task) PerFolder(in *input.CrawlResult) {
         // 重新构建 URL 字符串
         target := in.ParseUrl.Scheme + "://" + in.ParseUrl.Host + parentDir
         
-        for _, plugin := range scan.PerFolderPlugins {
-            if conf.Plugin[plugin.Name()] {
+        for _, plugin := range t.PluginSet().PerFolder {
+            if t.Enable(plugin.Name()) && t.riskAllowed(plugin, in.Host, target) {
                 // 说明这个目录整体都扫描过了，跳过
                 if t.ScanTask[in.Host].PerFolder[plugin.Name()+"_"+parentDir] {
                     continue
@@ -98,7 +97,7 @@ func (t *Task) PerFolder(in *input.CrawlResult) {
                 t.AddWg(in.Host)
                 go func(a scan.Addon, targetUrl, path string) {
                     defer t.DoneWg(in.Host)
-                    a.Scan(targetUrl, p, in, t.ScanTask[in.Host].Client)
+                    a.Scan(targetUrl, p, in, t.ScanTask[in.Host].PluginClient(a.Name()))
                 }(plugin, target, p)
             }
         }
@@ -109,18 +108,51 @@ func (t *Task) PerFolder(in *input.CrawlResult) {
 func (t *Task) PerFile(in *input.CrawlResult) {
     defer t.DoneWg(in.Host)
     // 这里就不用单独抽离 url 了，插件内部并不会改变这个值,所有的插件内部都最好不要更改任何 in 中的值
-    for _, plugin := range scan.PerFilePlugins {
-        if conf.Plugin[plugin.Name()] {
+    for _, plugin := range t.PluginSet().PerFile {
+        if t.Enable(plugin.Name()) && t.riskAllowed(plugin, in.Host, in.Url) {
             // 防止创建过多的协程
             t.AddWg(in.Host)
             go func(p scan.Addon) {
                 defer t.DoneWg(in.Host)
-                p.Scan(in.Url, "", in, t.ScanTask[in.Host].Client)
+                client := t.ScanTask[in.Host].PluginClient(p.Name())
+                p.Scan(in.Url, "", in, client)
+                if e, ok := p.(scan.Expander); ok {
+                    t.expand(e, e.Expand(in, client))
+                }
             }(plugin)
         }
     }
 }
 
+// expand 插件展开的请求使用指定的插件继续检测，请求发送前由 Rewrite 还原为原始格式，或者由 RoundTrip 发送
+func (t *Task) expand(e scan.Expander, derived []*input.CrawlResult) {
+    for _, d := range derived {
+        for _, name := range e.Plugins() {
+            plugin, ok := t.PluginSet().PerFile[name]
+            if !ok || !t.Enable(plugin.Name()) || !t.riskAllowed(plugin, d.Host, d.Url) {
+                continue
+            }
+            client := t.ScanTask[d.Host].PluginClient(plugin.Name())
+            if d.Rewrite != nil {
+                client = client.WithRewrite(d.Rewrite)
+            }
+            if d.RoundTrip != nil {
+                client = client.WithRoundTrip(d.RoundTrip)
+            }
+            plugin.Scan(d.Url, "", d, client)
+        }
+    }
+}
+
+// riskAllowed 安全模式下跳过 destructive、lockout-risk 等级的插件，并记录下来
+func (t *Task) riskAllowed(plugin scan.Addon, host, target string) bool {
+    if t.Scope.RiskAllowed(plugin.Risk()) {
+        return true
+    }
+    t.Sink.Skipped(host, target, plugin.Name(), "safe mode, risk: "+plugin.Risk())
+    return false
+}
+
 func (t *Task) AddWg(host string) {
     t.WgAddLock.Lock() // 保护对ScanTask映射的访问
     defer t.WgAddLock.Unlock()
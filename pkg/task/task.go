@@ -1,8 +1,8 @@
 package task
 
 import (
+    "context"
     "fmt"
-    "github.com/iancoleman/orderedmap"
     "github.com/panjf2000/ants/v2"
     regexp "github.com/wasilibs/go-re2"
     "github.com/yhy0/Jie/conf"
@@ -14,8 +14,10 @@ import (
     "github.com/yhy0/Jie/scan"
     "github.com/yhy0/Jie/scan/Pocs/pocs_go"
     "github.com/yhy0/Jie/scan/gadget/collection"
+    "github.com/yhy0/Jie/scan/gadget/headers"
     "github.com/yhy0/Jie/scan/gadget/jwt"
     "github.com/yhy0/Jie/scan/gadget/sensitive"
+    "github.com/yhy0/Jie/scan/gadget/tracker"
     scan_util "github.com/yhy0/Jie/scan/util"
     "github.com/yhy0/logging"
     "github.com/yhy0/sizedwaitgroup"
@@ -44,6 +46,24 @@ type Task struct {
     Lock         sync.Mutex           // 对 Distribution函数中的一些 map 并发操作进行保护
     WgLock       sync.Mutex           // ScanTask 是一个 map，运行插件时会并发操作，加锁保护
     WgAddLock    sync.Mutex           // ScanTask 是一个 map，运行插件时会并发操作，加锁保护
+    
+    // 下面这些是嵌入使用、批量扫描时，每个任务单独的配置，为空时使用全局的配置
+    Ctx         context.Context             // 取消扫描，取消后不再分发新的扫描任务
+    Plugins     *scan.PluginSet             // 插件实例，为空时使用 scan.DefaultPlugins
+    Enabled     map[string]bool             // 开启的插件，为空时使用 conf.Plugin
+    HttpOptions *httpx.Options              // 创建 client 使用的配置，为空时使用配置文件中的配置
+    OnCrawl     func(in *input.CrawlResult) // 每个要扫描的请求分发前回调
+    MaxRequests int                         // 整个任务最多发送的请求数，0 为不限制，批量主动扫描时每个目标单独限制
+    Sink        *output.Sink                // 扫描结果的接收者，为空时输出到 output.OutChannel
+    Scope       *conf.Scope                 // 安全模式和扫描策略，为空时使用全局的配置
+    
+    Total int64 // 已分发的扫描任务数
+    Done  int64 // 已完成的扫描任务数
+    
+    client     *httpx.Client // 设置了 MaxRequests 时，所有 client 共享这个限制
+    clientOnce sync.Once
+    seen       sync.Map        // 这里主要是为了一些返回包检测类的判断是否识别过，减小开销，扫描类内部会判断是否扫描过
+    audit      headers.Auditor // 安全响应头检测的去重记录
 }
 
 type ScanTask struct {
@@ -53,11 +73,97 @@ type ScanTask struct {
     Client    *httpx.Client                  // 用来进行请求的 client
     Archive   bool                           // 用来判断是否扫描过
     Wg        *sizedwaitgroup.SizedWaitGroup // 限制对每个url扫描时同时运行的插件数
+    
+    clients    map[string]*httpx.Client // 扫描策略中限制了请求数的插件单独使用一个 client，key 为插件名字
+    clientLock sync.Mutex
 }
 
-var rex = regexp.MustCompile(`//#\s+sourceMappingURL=(.*\.map)`)
+// PluginClient 获取插件使用的 client, 扫描策略中限制了请求数的插件会得到一个带有请求数限制的 client，对同一个网站共享这个限制
+func (s *ScanTask) PluginClient(name string) *httpx.Client {
+    max := s.Client.Scope.MaxRequests(name)
+    if max <= 0 {
+        return s.Client
+    }
+    
+    s.clientLock.Lock()
+    defer s.clientLock.Unlock()
+    if s.clients == nil {
+        s.clients = make(map[string]*httpx.Client)
+    }
+    if _, ok := s.clients[name]; !ok {
+        s.clients[name] = s.Client.WithBudget(max)
+    }
+    return s.clients[name]
+}
+
+// PluginSet 当前任务使用的插件实例
+func (t *Task) PluginSet() *scan.PluginSet {
+    if t.Plugins != nil {
+        return t.Plugins
+    }
+    return scan.DefaultPlugins
+}
+
+// Enable 插件是否开启
+func (t *Task) Enable(name string) bool {
+    if t.Enabled != nil {
+        return t.Enabled[name]
+    }
+    return conf.Plugin[name]
+}
+
+// Canceled 任务是否已经被取消
+func (t *Task) Canceled() bool {
+    if t.Ctx == nil {
+        return false
+    }
+    return t.Ctx.Err() != nil
+}
+
+// Context 任务的 ctx，没有设置时返回 context.Background()
+func (t *Task) Context() context.Context {
+    if t.Ctx == nil {
+        return context.Background()
+    }
+    return t.Ctx
+}
+
+// Spend 记录一次爬虫自己发送的请求，设置了 MaxRequests 时计入整个任务的请求数限制，用完后返回 httpx.ErrBudgetExhausted
+func (t *Task) Spend() error {
+    if t.MaxRequests <= 0 {
+        return nil
+    }
+    return t.NewClient().Spend()
+}
+
+// NewClient 按照任务的配置创建 client，设置了 MaxRequests 时返回的都是同一个带有请求数限制的 client
+// client 带有任务的 Ctx、Sink 和 Scope，插件通过 client 输出结果，任务取消后不再发送请求
+func (t *Task) NewClient() *httpx.Client {
+    if t.MaxRequests <= 0 {
+        return t.scoped(httpx.NewClient(t.HttpOptions))
+    }
+    t.clientOnce.Do(func() {
+        t.client = t.scoped(httpx.NewClient(t.HttpOptions)).WithBudget(t.MaxRequests)
+    })
+    return t.client
+}
+
+func (t *Task) scoped(client *httpx.Client) *httpx.Client {
+    client.Ctx = t.Ctx
+    client.Sink = t.Sink
+    client.Scope = t.Scope
+    return client
+}
 
-var seenRequests sync.Map // 这里主要是为了一些返回包检测类的判断是否识别过，减小开销，扫描类内部会判断是否扫描过
+// Budget 返回任务已经发送的请求数和最多允许发送的请求数，没有限制时都为 0
+func (t *Task) Budget() (int64, int64) {
+    if t.client == nil {
+        return 0, 0
+    }
+    return t.client.Budget()
+}
+
+var rex = regexp.MustCompile(`//#\s+sourceMappingURL=(.*\.map)`)
 
 // DistributionTaskFunc ants 提交任务需要一个无参数的函数
 type DistributionTaskFunc func()
@@ -69,14 +175,25 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
             atomic.AddInt64(&output.TaskCounter, 1)
         }
         
+        atomic.AddInt64(&t.Total, 1)
+        
         defer func() {
             t.WG.Done()
             logging.Logger.Debugln("扫描任务结束:", in.Url)
             if !conf.NoProgressBar {
                 atomic.AddInt64(&output.TaskCompletionCounter, 1)
             }
+            atomic.AddInt64(&t.Done, 1)
         }()
         
+        if t.Canceled() {
+            return
+        }
+        
+        if t.OnCrawl != nil {
+            t.OnCrawl(in)
+        }
+        
         logging.Logger.Debugln(fmt.Sprintf("[%s] [%s] %s 扫描任务开始", in.UniqueId, in.Method, in.Url))
         // 这些返回包内容检测、指纹识别等因为没有使用检测是否扫描的逻辑，所以会重复检测，造成一定程度的资源消耗，问题应该不大
         // TODO 还没有想好怎么写逻辑，因为一些扫描插件会用到这些结果，搞成插件化的话，就需要控制插件的执行顺序，后续看看吧，目前影响不大
@@ -107,7 +224,7 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
                 PerServer: make(map[string]bool),
                 PerFolder: make(map[string]bool),
                 PocPlugin: make(map[string]bool),
-                Client:    httpx.NewClient(nil),
+                Client:    t.NewClient(),
                 // 3: 同时运行 3 个插件，3 供 PreServer、 PreFolder、PerFile这三个函数使用，防止马上退出 所以这里同时运行的插件个数为3-5 个
                 // TODO 更优雅的实现方式
                 Wg: sizedwaitgroup.New(3 + 3),
@@ -160,6 +277,9 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
         
         msg.CollectionMsg = collection.Info(in.Url, hostNoPort, in.Resp.Body, in.ContentType)
         
+        // 安全响应头、cookie 属性检测，同一个网站的问题只记录一次
+        msg.InfoMsg = append(msg.InfoMsg, t.audit.Audit(in)...)
+        
         // 请求头中存在 Authorization 时，看看是不是 JWT ，如果是，自动对 JWT 进行爆破
         if v, ok := in.Headers["Authorization"]; ok {
             value := strings.Split(v, " ") // 有的 JWT ，是 Xxx Jwt 这种格式
@@ -179,7 +299,7 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
                     jwt.Jwts[jwtString] = true
                     secret := jwt.GenerateSignature()
                     if secret != "" {
-                        output.OutChannel <- output.VulMessage{
+                        t.Sink.Report(output.VulMessage{
                             DataType: "web_vul",
                             Plugin:   "JWT",
                             VulnData: output.VulnData{
@@ -190,7 +310,7 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
                                 Payload:    secret,
                             },
                             Level: output.Critical,
-                        }
+                        })
                     }
                 }
             }
@@ -208,11 +328,18 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
         t.Lock.Unlock()
         
         // 更新数据
-        output.SCopilot(in.Host, msg)
+        t.Sink.SCopilot(in.Host, msg)
+        
+        sensitive.KeyDetection(in.Url, in.Resp.Body, t.Sink)
         
-        sensitive.KeyDetection(in.Url, in.Resp.Body)
+        // 爬虫、被动代理的响应中查找之前注入的标记，确认存储型漏洞
+        response := in.RawResponse
+        if response == "" {
+            response = in.Resp.Body
+        }
+        tracker.Check(in.Url, in.RawRequest, response)
         
-        errorMsg := sensitive.PageErrorMessageCheck(in.Url, in.RawRequest, in.Resp.Body)
+        errorMsg := sensitive.PageErrorMessageCheck(in.Url, in.RawRequest, in.Resp.Body, t.Sink)
         if len(errorMsg) > 0 {
             var res []string
             for _, v := range errorMsg {
@@ -242,35 +369,21 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
             } else {
                 in.ParamNames = paramNames
                 // 看请求、返回包中的参数是否包含敏感参数
-                scan.PerFilePlugins["SensitiveParameters"].Scan("", "", in, nil)
+                t.PluginSet().PerFile["SensitiveParameters"].Scan("", "", in, t.ScanTask[in.Host].Client)
                 
-                if output.SCopilotMessage[in.Host].CollectionMsg.Parameters == nil {
-                    output.SCopilotMessage[in.Host].CollectionMsg.Parameters = orderedmap.New()
-                }
                 resParamNames, _ := util.GetResParameters(strings.ToLower(in.Resp.Header.Get("Content-Type")), []byte(in.Resp.Body))
-                paramNames = append(paramNames, resParamNames...)
-                for _, _para := range paramNames {
-                    v, ok := output.SCopilotMessage[in.Host].CollectionMsg.Parameters.Get(_para)
-                    if ok {
-                        output.SCopilotMessage[in.Host].CollectionMsg.Parameters.Set(_para, v.(int)+1)
-                    } else {
-                        output.SCopilotMessage[in.Host].CollectionMsg.Parameters.Set(_para, 1)
-                    }
-                }
-                // 按照value的字典序升序排序
-                output.SCopilotMessage[in.Host].CollectionMsg.Parameters.Sort(func(a *orderedmap.Pair, b *orderedmap.Pair) bool {
-                    return a.Value().(int) > b.Value().(int)
-                })
+                // 按照出现次数降序排序
+                t.Sink.CountParameters(in.Host, append(paramNames, resParamNames...))
             }
             
             // poc 模块依托于指纹识别，只有识别到对应的指纹才会扫描，所以这里就不插件化了
-            if conf.Plugin["poc"] {
+            if t.Enable("poc") {
                 t.ScanTask[in.Host].PocPlugin = pocs_go.PocCheck(in.Fingerprints, in.Target, in.Url, in.Ip, t.ScanTask[in.Host].PocPlugin, t.ScanTask[in.Host].Client)
             }
             t.Lock.Unlock()
         } else {
             // 下面这些使用去重逻辑，因为扫描结果不会被别的插件用到
-            if isScanned(in.UniqueId) {
+            if t.isScanned(in.UniqueId) {
                 return
             }
             
@@ -290,7 +403,7 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
                     msg.Fingerprints = util.RemoveDuplicateElement(append(msg.Fingerprints, "SourceMap"))
                     in.Fingerprints = util.RemoveDuplicateElement(append(in.Fingerprints, msg.Fingerprints...))
                     t.Lock.Unlock()
-                    output.OutChannel <- output.VulMessage{
+                    t.Sink.Report(output.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "SourceMap",
                         VulnData: output.VulnData{
@@ -298,13 +411,13 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
                             Target:     in.Url,
                         },
                         Level: output.Low,
-                    }
+                    })
                 }
             }
         }
         
         // 更新数据
-        output.SCopilot(in.Host, msg)
+        t.Sink.SCopilot(in.Host, msg)
         
         go func() {
             // 判断 Archive 是否有数据，如果有的话分发至扫描
@@ -348,13 +461,10 @@ func (t *Task) Distribution(in *input.CrawlResult) DistributionTaskFunc {
     }
 }
 
-func isScanned(key string) bool {
+func (t *Task) isScanned(key string) bool {
     if key == "" {
         return false
     }
-    if _, ok := seenRequests.Load(key); ok {
-        return true
-    }
-    seenRequests.Store(key, true)
-    return false
+    _, ok := t.seen.LoadOrStore(key, true)
+    return ok
 }
@@ -18,14 +18,7 @@ import (
 
 // UniqueId 生成唯一id(md5), 用来判断是否扫描过 空代表目前逻辑还不支持判断，可以看成没有扫描过
 func UniqueId(req *proxy.Request) string {
-    // 目前暂时不处理文件上传请求， 还没想好怎么测试 自动修改文件名后缀，然后判断返回包里是否包含修改后的后缀？
-    // TODO
-    contentType := req.Header.Get("Content-Type")
-    isFileUpload := strings.Contains(contentType, "multipart/form-data")
-    if isFileUpload {
-        return ""
-    }
-    // 为请求生成唯一标识符
+    // 为请求生成唯一标识符，文件上传请求使用表单字段名，由 upload 插件检测
     key, err := getRequestKey(req)
     if err != nil {
         logging.Logger.Errorln(err)
@@ -1,9 +1,13 @@
 package util
 
 import (
+    "bytes"
     "encoding/json"
     "encoding/xml"
     "errors"
+    "io"
+    "mime"
+    "mime/multipart"
     "net/url"
     "strings"
 )
@@ -50,6 +54,23 @@ func GetReqParameters(method, contentType string, target *url.URL, body []byte)
             for paramName := range xmlData {
                 paramNames = append(paramNames, paramName)
             }
+        } else if strings.Contains(contentType, "multipart/form-data") {
+            _, params, err := mime.ParseMediaType(contentType)
+            if err != nil {
+                return nil, err
+            }
+            reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+            for {
+                part, err := reader.NextPart()
+                if err == io.EOF {
+                    break
+                }
+                if err != nil {
+                    return nil, err
+                }
+                paramNames = append(paramNames, part.FormName())
+                part.Close()
+            }
         }
     }
     
@@ -0,0 +1,284 @@
+package authz
+
+import (
+    "fmt"
+    "github.com/antlabs/strsim"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/PerFile/sql"
+    "github.com/yhy0/logging"
+    "net/http"
+    "path"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 越权检测，类似 Burp 的 Autorize 插件
+        1. 带有认证信息的请求，使用配置的其他身份和未登录状态重放，根据状态码、和原始响应的相似度判断为已校验、越权、不确定
+        2. 请求中数字、UUID 类型的对象 id 替换为其他响应中收集到的 id，使用原始身份重放，判断能否访问别人的数据
+        每次重放的结果和差异都记录到 SCopilot 中，越权的同时作为漏洞输出
+        POST、PUT、DELETE 等请求重放时会以别人的身份、对别人的对象重复操作，默认只重放 GET、HEAD，开启 unsafeMethods 后作为 destructive 检测重放
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+    pools        sync.Map // key 为 host，value 为 *pool，从响应中收集到的对象 id
+}
+
+// 重放结果
+const (
+    enforced = "enforced"
+    bypassed = "bypassed"
+    unclear  = "unclear"
+)
+
+// 未登录状态的身份名
+const anonymous = "unauthenticated"
+
+// 认证信息常用的请求头，重放时去掉
+var authHeaders = []string{"Cookie", "Authorization", "X-Auth-Token", "X-Access-Token", "X-Api-Key", "Api-Key", "Token", "X-Token"}
+
+// 不检测的静态资源
+var staticExts = []string{".js", ".css", ".png", ".jpg", ".jpeg", ".gif", ".ico", ".svg", ".woff", ".woff2", ".ttf", ".map", ".mp4", ".webp"}
+
+// 未授权时常见的跳转、提示
+var loginKeywords = []string{"login", "signin", "sign in", "log in", "unauthorized", "not authorized", "permission denied", "access denied", "forbidden", "未登录", "请登录", "登录超时", "无权限", "没有权限", "权限不足"}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.Resp == nil || in.Resp.StatusCode < 200 || in.Resp.StatusCode >= 300 || in.ParseUrl == nil {
+        return
+    }
+    if static(in.ParseUrl.Path) {
+        return
+    }
+    
+    pool := p.pool(in.Host)
+    // 先收集 id，之后的请求可以使用
+    defer pool.harvest(in.Resp.Body)
+    
+    if !authenticated(in.Headers) || !replayable(in.Method, client) || p.IsScanned(in.UniqueId) {
+        return
+    }
+    
+    tpl := sql.NewTemplate(in, client)
+    if tpl == nil {
+        return
+    }
+    
+    results := make(map[string]string)
+    for _, id := range identities() {
+        headers := replace(in.Headers, id.Headers)
+        res, err := client.Request(in.Url, in.Method, in.RequestBody, headers)
+        if err != nil {
+            logging.Logger.Debugln("[authz]", in.Url, err)
+            continue
+        }
+        result, sim := classify(tpl, in.Resp, res)
+        results[id.Name] = result
+        record(in, id.Name, fmt.Sprintf("%s as %s (similarity %.2f)", result, id.Name, sim), res, client)
+        
+        if result != bypassed {
+            continue
+        }
+        if id.Name == anonymous {
+            // 公开的页面未登录也能访问，只对接口类的响应输出漏洞
+            if api(in, res) {
+                report(in, id.Name, "unauthenticated access", res, output.Medium,
+                    fmt.Sprintf("The request returns the same response without any credentials (%s removed), the endpoint does not enforce authentication.", strings.Join(authHeaders, ", ")), client)
+            }
+        } else if results[anonymous] == enforced {
+            report(in, id.Name, "broken access control", res, output.High,
+                fmt.Sprintf("The request returns the same response when replayed as identity %q, while the unauthenticated replay is rejected. Another user can access this resource (horizontal/vertical privilege escalation).", id.Name), client)
+        }
+    }
+    
+    p.swap(in, tpl, pool, client)
+}
+
+// replayable GET、HEAD 请求重放不会修改数据，其他方法需要开启 unsafeMethods 并且允许 destructive 检测
+func replayable(method string, client *httpx.Client) bool {
+    if method == "GET" || method == "HEAD" {
+        return true
+    }
+    return conf.GlobalConfig.Plugins.Authz.UnsafeMethods && client.Scope.RiskAllowed(conf.RiskDestructive)
+}
+
+// identities 未登录状态和配置的其他身份，未登录放在最前面，其他身份的结果需要和未登录的比较
+func identities() []conf.Identity {
+    ids := []conf.Identity{{Name: anonymous}}
+    for i, id := range conf.GlobalConfig.Plugins.Authz.Identities {
+        if len(id.Headers) == 0 {
+            continue
+        }
+        if id.Name == "" {
+            id.Name = fmt.Sprintf("identity-%d", i+1)
+        }
+        ids = append(ids, id)
+    }
+    return ids
+}
+
+// authenticated 请求中是否带有认证信息
+func authenticated(headers map[string]string) bool {
+    for k, v := range headers {
+        if v != "" && util.InCaseFoldSlice(authNames(), k) {
+            return true
+        }
+    }
+    return false
+}
+
+// authNames 需要替换的请求头，包括配置的身份中使用的请求头
+func authNames() []string {
+    names := append([]string{}, authHeaders...)
+    for _, id := range conf.GlobalConfig.Plugins.Authz.Identities {
+        for k := range id.Headers {
+            names = append(names, k)
+        }
+    }
+    return names
+}
+
+// replace 去掉原始请求中的认证信息，换成 identity 的请求头
+func replace(headers map[string]string, identity map[string]string) map[string]string {
+    names := authNames()
+    h := make(map[string]string)
+    for k, v := range headers {
+        if !util.InCaseFoldSlice(names, k) {
+            h[k] = v
+        }
+    }
+    // 配置文件读取后 key 会变为小写
+    for k, v := range identity {
+        h[http.CanonicalHeaderKey(k)] = v
+    }
+    return h
+}
+
+// classify 比较重放的响应和原始响应
+func classify(tpl *sql.Sqlmap, original, res *httpx.Response) (string, float64) {
+    sim := similarity(original.Body, res.Body)
+    switch {
+    case res.StatusCode == 401 || res.StatusCode == 403:
+        return enforced, sim
+    case res.StatusCode >= 300 && res.StatusCode < 400:
+        if strings.Contains(strings.ToLower(res.Location), "login") || original.StatusCode < 300 {
+            return enforced, sim
+        }
+    case res.StatusCode != original.StatusCode:
+        if res.StatusCode >= 400 {
+            return enforced, sim
+        }
+        return unclear, sim
+    }
+    
+    if tpl.Similar(res) || sim >= sql.SimilarityRatio {
+        return bypassed, sim
+    }
+    lower := strings.ToLower(res.Body)
+    for _, k := range loginKeywords {
+        if strings.Contains(lower, k) && !strings.Contains(strings.ToLower(original.Body), k) {
+            return enforced, sim
+        }
+    }
+    if sim < 0.5 {
+        return enforced, sim
+    }
+    return unclear, sim
+}
+
+func similarity(a, b string) float64 {
+    if a == b {
+        return 1
+    }
+    if len(a) < sql.MaxDifflibSequenceLength && len(b) < sql.MaxDifflibSequenceLength {
+        return strsim.Compare(a, b)
+    }
+    return 0
+}
+
+// api 响应是否为接口返回的数据
+func api(in *input.CrawlResult, res *httpx.Response) bool {
+    ct := strings.ToLower(res.Header.Get("Content-Type"))
+    if strings.Contains(ct, "json") || strings.Contains(ct, "xml") {
+        return true
+    }
+    for k := range in.Headers {
+        if strings.EqualFold(k, "Authorization") {
+            return true
+        }
+    }
+    return false
+}
+
+// static 是否为静态资源，没有后缀的接口不算
+func static(p string) bool {
+    ext := path.Ext(p)
+    return ext != "" && util.InCaseFoldSlice(staticExts, ext)
+}
+
+func (p *Plugin) pool(host string) *pool {
+    v, _ := p.pools.LoadOrStore(host, &pool{})
+    return v.(*pool)
+}
+
+// record 重放结果记录到 SCopilot，原始响应和重放的响应放在一起，并附上差异
+func record(in *input.CrawlResult, identity, result string, res *httpx.Response, client *httpx.Client) {
+    client.SCopilot(in.Host, output.SCopilotData{
+        Target: in.Host,
+        PluginMsg: []output.PluginMsg{
+            {
+                Url:      in.Url,
+                Plugin:   "Authz",
+                Result:   []string{result, diff(in.Resp.Body, res.Body)},
+                Request:  in.RawRequest + "\n\n========== " + identity + " ==========\n\n" + res.RequestDump,
+                Response: in.RawResponse + "\n\n========== " + identity + " ==========\n\n" + res.ResponseDump,
+            },
+        },
+    })
+}
+
+func report(in *input.CrawlResult, identity, kind string, res *httpx.Response, level, description string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "Authz",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    kind,
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Payload:     identity,
+            Request:     in.RawRequest + "\n\n========== " + identity + " ==========\n\n" + res.RequestDump,
+            Response:    in.RawResponse + "\n\n========== " + identity + " ==========\n\n" + res.ResponseDump,
+            Description: description + "\n" + diff(in.Resp.Body, res.Body),
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "authz"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
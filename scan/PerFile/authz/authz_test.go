@@ -0,0 +1,124 @@
+package authz
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "strings"
+    "testing"
+)
+
+func TestStatic(t *testing.T) {
+    tests := map[string]bool{
+        "/static/app.js":     true,
+        "/static/APP.CSS":    true,
+        "/img/logo.png":      true,
+        "/api/users/1":       false,
+        "/api/users":         false,
+        "/":                  false,
+        "/api/users/1.json":  false,
+        "/download/file.jsp": false,
+    }
+    for p, want := range tests {
+        if got := static(p); got != want {
+            t.Errorf("static(%q) = %v, want %v", p, got, want)
+        }
+    }
+}
+
+func TestAuthenticated(t *testing.T) {
+    defer func(ids []conf.Identity) { conf.GlobalConfig.Plugins.Authz.Identities = ids }(conf.GlobalConfig.Plugins.Authz.Identities)
+    conf.GlobalConfig.Plugins.Authz.Identities = []conf.Identity{{Name: "user", Headers: map[string]string{"x-session": "abc"}}}
+    
+    tests := []struct {
+        headers map[string]string
+        want    bool
+    }{
+        {map[string]string{"Cookie": "session=1"}, true},
+        {map[string]string{"authorization": "Bearer x"}, true},
+        {map[string]string{"X-Session": "abc"}, true},
+        {map[string]string{"Cookie": ""}, false},
+        {map[string]string{"Accept": "*/*"}, false},
+        // 只是包含认证头名称的请求头不算
+        {map[string]string{"Auth": "x"}, false},
+        {map[string]string{"Token-Type": "jwt"}, false},
+        {map[string]string{"X-Cookie-Consent": "yes"}, false},
+        {nil, false},
+    }
+    for _, tt := range tests {
+        if got := authenticated(tt.headers); got != tt.want {
+            t.Errorf("authenticated(%v) = %v, want %v", tt.headers, got, tt.want)
+        }
+    }
+}
+
+func TestReplace(t *testing.T) {
+    defer func(ids []conf.Identity) { conf.GlobalConfig.Plugins.Authz.Identities = ids }(conf.GlobalConfig.Plugins.Authz.Identities)
+    conf.GlobalConfig.Plugins.Authz.Identities = []conf.Identity{{Name: "user", Headers: map[string]string{"x-session": "abc"}}}
+    
+    headers := map[string]string{
+        "Cookie":           "session=admin",
+        "authorization":    "Bearer admin",
+        "X-Session":        "admin",
+        "Accept":           "*/*",
+        "Token-Type":       "jwt",
+        "X-Cookie-Consent": "yes",
+    }
+    
+    got := replace(headers, map[string]string{"x-session": "abc", "cookie": "session=user"})
+    want := map[string]string{
+        "Accept":           "*/*",
+        "Token-Type":       "jwt",
+        "X-Cookie-Consent": "yes",
+        "X-Session":        "abc",
+        "Cookie":           "session=user",
+    }
+    if len(got) != len(want) {
+        t.Fatalf("replace() = %v, want %v", got, want)
+    }
+    for k, v := range want {
+        if got[k] != v {
+            t.Errorf("replace()[%q] = %q, want %q", k, got[k], v)
+        }
+    }
+    
+    // 未登录身份去掉所有认证信息
+    got = replace(headers, nil)
+    for _, k := range []string{"Cookie", "authorization", "X-Session"} {
+        if _, ok := got[k]; ok {
+            t.Errorf("replace(nil) keeps %q", k)
+        }
+    }
+    if got["Accept"] != "*/*" {
+        t.Errorf("replace(nil) drops Accept: %v", got)
+    }
+    if headers["Cookie"] != "session=admin" {
+        t.Errorf("replace() modifies the original headers: %v", headers)
+    }
+}
+
+func TestDiff(t *testing.T) {
+    if got := diff("same", "same"); got != "responses are identical" {
+        t.Errorf("diff(identical) = %q", got)
+    }
+    if got := diff(`{"id":1}`, "{\"id\":1}\n\n"); got != "responses differ only in whitespace" {
+        t.Errorf("diff(whitespace) = %q", got)
+    }
+    large := strings.Repeat("a", maxDiffInput+1)
+    if got := diff(large, large+"b"); got != "responses differ (too large to diff)" {
+        t.Errorf("diff(large) = %q", got)
+    }
+    
+    got := diff(`{"id":1,"name":"alice","email":"alice@example.com"}`, `{"id":1,"name":"bob","email":"bob@example.com"}`)
+    for _, s := range []string{"- alice", "+ bob"} {
+        if !strings.Contains(got, s) {
+            t.Errorf("diff() = %q, want %q", got, s)
+        }
+    }
+    if strings.Contains(got, `"id":1`) {
+        t.Errorf("diff() = %q, contains unchanged text", got)
+    }
+    
+    got = diff(strings.Repeat("x", maxDiff*3), strings.Repeat("y", maxDiff*3))
+    if !strings.HasSuffix(got, "...") {
+        t.Errorf("diff() is not truncated: %d bytes", len(got))
+    }
+}
@@ -0,0 +1,51 @@
+package authz
+
+import (
+    "github.com/sergi/go-diff/diffmatchpatch"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 原始响应和重放响应的差异，- 为原始响应中有的，+ 为重放响应中有的
+**/
+
+// 差异最多输出的长度
+const maxDiff = 2000
+
+// 响应太大时不计算差异
+const maxDiffInput = 100 * 1024
+
+func diff(original, replay string) string {
+    if original == replay {
+        return "responses are identical"
+    }
+    if len(original) > maxDiffInput || len(replay) > maxDiffInput {
+        return "responses differ (too large to diff)"
+    }
+    dmp := diffmatchpatch.New()
+    diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(original, replay, false))
+    
+    var b strings.Builder
+    for _, d := range diffs {
+        if b.Len() > maxDiff {
+            b.WriteString("...")
+            break
+        }
+        text := strings.TrimSpace(d.Text)
+        if text == "" {
+            continue
+        }
+        switch d.Type {
+        case diffmatchpatch.DiffDelete:
+            b.WriteString("- " + text + "\n")
+        case diffmatchpatch.DiffInsert:
+            b.WriteString("+ " + text + "\n")
+        }
+    }
+    if b.Len() == 0 {
+        return "responses differ only in whitespace"
+    }
+    return b.String()
+}
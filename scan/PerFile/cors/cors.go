@@ -0,0 +1,245 @@
+package cors
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/gadget/collection"
+    "github.com/yhy0/logging"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/21
+   @desc CORS 配置错误检测
+        使用构造的 Origin 重放请求，根据 Access-Control-Allow-Origin、Access-Control-Allow-Credentials 判断是否信任了攻击者可控的源
+        危害等级根据是否允许携带凭证、响应中是否包含敏感数据来评估
+        使用观察到的方法和请求体重放，安全模式下只重放 GET、HEAD 请求，预检使用 OPTIONS
+        参考 https://portswigger.net/research/exploiting-cors-misconfigurations-for-bitcoins-and-bounties
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+// origin 构造的 Origin，trusted 为 true 表示这个源本身攻击者不能直接控制，需要配合子域名 XSS、中间人等利用
+type origin struct {
+    name    string
+    value   string
+    trusted bool
+}
+
+// result 被信任的 Origin 及其响应
+type result struct {
+    origin      origin
+    credentials bool
+    response    *httpx.Response
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    
+    if in.ParseUrl == nil || in.Resp == nil {
+        return
+    }
+    
+    // 安全模式下不重放 POST 等请求，重放可能会修改数据，CORS 一般按路径配置，同一路径的 GET 请求同样能检测出来
+    if !replayable(in.Method, client) {
+        return
+    }
+    
+    var results []result
+    for _, o := range origins(in.ParseUrl.Scheme, in.ParseUrl.Hostname()) {
+        header := make(map[string]string)
+        for k, v := range in.Headers {
+            header[k] = v
+        }
+        header["Origin"] = o.value
+        
+        res, err := client.Request(in.Url, in.Method, in.RequestBody, header)
+        if err != nil {
+            logging.Logger.Debugln("[cors]", in.Url, err)
+            continue
+        }
+        
+        acao := res.Header.Get("Access-Control-Allow-Origin")
+        if acao != o.value {
+            continue
+        }
+        results = append(results, result{
+            origin:      o,
+            credentials: strings.EqualFold(res.Header.Get("Access-Control-Allow-Credentials"), "true"),
+            response:    res,
+        })
+    }
+    
+    if len(results) == 0 {
+        return
+    }
+    
+    // 选出危害最大的一个作为证据
+    worst := results[0]
+    var accepted []string
+    for _, r := range results {
+        accepted = append(accepted, fmt.Sprintf("%s(%s)", r.origin.value, r.origin.name))
+        if rank(r) > rank(worst) {
+            worst = r
+        }
+    }
+    
+    sensitive := collection.SensitiveResponse(worst.response.Header.Get("Content-Type"), worst.response.Body)
+    
+    level := grade(worst, len(sensitive) > 0)
+    if level == "" {
+        return
+    }
+    
+    description := fmt.Sprintf("The server reflects the Origin %s in Access-Control-Allow-Origin", worst.origin.value)
+    if worst.credentials {
+        description += " with Access-Control-Allow-Credentials: true, so a page on that origin can read authenticated responses."
+    } else {
+        description += " without Access-Control-Allow-Credentials, so only responses that do not depend on cookies can be read."
+    }
+    if len(sensitive) > 0 {
+        description += " The response contains sensitive data: " + strings.Join(util.RemoveDuplicateElement(sensitive), ", ") + "."
+    }
+    if preflight := preflight(in, worst.origin.value, client); preflight != "" {
+        description += " Preflight: " + preflight
+    }
+    
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "CORS",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Param:       "Origin",
+            Payload:     strings.Join(accepted, ", "),
+            Request:     worst.response.RequestDump,
+            Response:    worst.response.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+// origins 根据目标构造要测试的 Origin
+func origins(scheme, host string) []origin {
+    random := util.RandomLowLetterNumber(8)
+    res := []origin{
+        {"arbitrary origin", "https://" + random + ".com", false},
+        {"null origin", "null", false},
+        {"target as prefix", scheme + "://" + host + "." + random + ".com", false},
+        {"target as suffix", scheme + "://" + random + host, false},
+        {"arbitrary subdomain", scheme + "://" + random + "." + host, true},
+    }
+    
+    // www.example.com -> wwwxexample.com，正则中的 . 没有转义时会被信任
+    if i := strings.Index(host, "."); i > 0 && strings.Count(host, ".") > 1 {
+        res = append(res, origin{"unescaped dot", scheme + "://" + host[:i] + "x" + host[i+1:], false})
+    }
+    
+    if scheme == "https" {
+        res = append(res, origin{"http downgrade", "http://" + host, true})
+    }
+    return res
+}
+
+// rank 危害排序: 攻击者可控 > 需要配合其他漏洞，允许凭证 > 不允许凭证
+func rank(r result) int {
+    n := 0
+    if !r.origin.trusted {
+        n += 2
+    }
+    if r.credentials {
+        n += 1
+    }
+    return n
+}
+
+// grade 根据是否允许凭证、是否包含敏感数据评估危害等级，返回空表示不需要报告
+func grade(r result, sensitive bool) string {
+    if r.origin.trusted {
+        if r.credentials && sensitive {
+            return output.Medium
+        }
+        if r.credentials || sensitive {
+            return output.Low
+        }
+        return ""
+    }
+    
+    if r.credentials && sensitive {
+        return output.High
+    }
+    if r.credentials {
+        return output.Medium
+    }
+    if sensitive {
+        return output.Low
+    }
+    return ""
+}
+
+// replayable GET、HEAD 请求总是重放，其他方法只在允许 destructive 检测(非安全模式)时重放
+func replayable(method string, client *httpx.Client) bool {
+    if method == "" || strings.EqualFold(method, "GET") || strings.EqualFold(method, "HEAD") {
+        return true
+    }
+    return client.Scope.RiskAllowed(conf.RiskDestructive)
+}
+
+// preflight 发送预检请求，返回允许的方法和请求头
+func preflight(in *input.CrawlResult, origin string, client *httpx.Client) string {
+    header := map[string]string{
+        "Origin":                         origin,
+        "Access-Control-Request-Method":  "PUT",
+        "Access-Control-Request-Headers": "authorization, content-type, x-requested-with",
+    }
+    res, err := client.Request(in.Url, "OPTIONS", "", header)
+    if err != nil || res.Header.Get("Access-Control-Allow-Origin") != origin {
+        return ""
+    }
+    
+    var info []string
+    if acam := res.Header.Get("Access-Control-Allow-Methods"); acam != "" {
+        info = append(info, "Access-Control-Allow-Methods: "+acam)
+    }
+    if acah := res.Header.Get("Access-Control-Allow-Headers"); acah != "" {
+        info = append(info, "Access-Control-Allow-Headers: "+acah)
+    }
+    if acma := res.Header.Get("Access-Control-Max-Age"); acma != "" {
+        info = append(info, "Access-Control-Max-Age: "+acma)
+    }
+    return strings.Join(info, "; ")
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "cors"
+}
+
+// Risk 非安全模式下会重放 POST 等请求
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
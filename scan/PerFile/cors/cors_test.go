@@ -0,0 +1,50 @@
+package cors
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "testing"
+)
+
+func TestGrade(t *testing.T) {
+    tests := []struct {
+        trusted     bool
+        credentials bool
+        sensitive   bool
+        want        string
+    }{
+        {false, true, true, output.High},
+        {false, true, false, output.Medium},
+        {false, false, true, output.Low},
+        {false, false, false, ""},
+        {true, true, true, output.Medium},
+        {true, true, false, output.Low},
+        {true, false, false, ""},
+    }
+    for _, tt := range tests {
+        r := result{origin: origin{trusted: tt.trusted}, credentials: tt.credentials}
+        if got := grade(r, tt.sensitive); got != tt.want {
+            t.Errorf("grade(trusted=%v, credentials=%v, sensitive=%v) = %q, want %q", tt.trusted, tt.credentials, tt.sensitive, got, tt.want)
+        }
+    }
+}
+
+// 只读插件不能重放可能修改数据的请求
+func TestReplayable(t *testing.T) {
+    safe := &httpx.Client{Scope: &conf.Scope{SafeMode: true}}
+    unsafe := &httpx.Client{Scope: &conf.Scope{}}
+    for _, m := range []string{"GET", "head", ""} {
+        if !replayable(m, safe) || !replayable(m, unsafe) {
+            t.Errorf("%q should be replayed", m)
+        }
+    }
+    for _, m := range []string{"POST", "PUT", "DELETE", "PATCH"} {
+        if replayable(m, safe) {
+            t.Errorf("%q should not be replayed in safe mode", m)
+        }
+        if !replayable(m, unsafe) {
+            t.Errorf("%q should be replayed outside safe mode", m)
+        }
+    }
+}
@@ -0,0 +1,161 @@
+package csrf
+
+import (
+    "encoding/json"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "net/url"
+    "sort"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 请求体中的参数，去掉、替换其中的 token 后重新生成请求体
+**/
+
+const (
+    kindForm      = "form"
+    kindJson      = "json"
+    kindMultipart = "multipart"
+)
+
+type body struct {
+    kind       string
+    names      []string          // 参数名，按照请求中的顺序
+    values     map[string]string // 参数值，已经解码
+    raw        map[string]interface{}
+    variations *httpx.Variations
+}
+
+// parseBody 解析 POST 请求体，不支持的类型返回 nil
+func parseBody(in *input.CrawlResult) *body {
+    ct := strings.ToLower(in.ContentType)
+    b := &body{values: make(map[string]string)}
+    switch {
+    case strings.Contains(ct, "application/x-www-form-urlencoded") || (ct == "" && !strings.HasPrefix(strings.TrimSpace(in.RequestBody), "{")):
+        b.kind = kindForm
+        for _, kv := range strings.Split(in.RequestBody, "&") {
+            if kv == "" {
+                continue
+            }
+            k, v, _ := strings.Cut(kv, "=")
+            name, err := url.QueryUnescape(k)
+            if err != nil {
+                name = k
+            }
+            value, err := url.QueryUnescape(v)
+            if err != nil {
+                value = v
+            }
+            b.names = append(b.names, name)
+            b.values[name] = value
+        }
+    case strings.Contains(ct, "json") || strings.HasPrefix(strings.TrimSpace(in.RequestBody), "{"):
+        b.kind = kindJson
+        if err := json.Unmarshal([]byte(in.RequestBody), &b.raw); err != nil {
+            return nil
+        }
+        for k, v := range b.raw {
+            b.names = append(b.names, k)
+            if s, ok := v.(string); ok {
+                b.values[k] = s
+            } else {
+                value, _ := json.Marshal(v)
+                b.values[k] = string(value)
+            }
+        }
+        sort.Strings(b.names)
+    case strings.Contains(ct, "multipart/form-data"):
+        b.kind = kindMultipart
+        variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), in.Method, in.ContentType, in.Headers)
+        if err != nil {
+            return nil
+        }
+        b.variations = variations
+        for _, p := range variations.Params {
+            b.names = append(b.names, p.Name)
+            if !p.IsFile {
+                b.values[p.Name] = p.Value
+            }
+        }
+    default:
+        return nil
+    }
+    return b
+}
+
+// build 生成请求体，exclude 中的参数去掉，override 中的参数替换值
+func (b *body) build(exclude map[string]bool, override map[string]string) string {
+    switch b.kind {
+    case kindForm:
+        var kvs []string
+        for _, name := range b.names {
+            if exclude[name] {
+                continue
+            }
+            value := b.values[name]
+            if v, ok := override[name]; ok {
+                value = v
+            }
+            kvs = append(kvs, url.QueryEscape(name)+"="+url.QueryEscape(value))
+        }
+        return strings.Join(kvs, "&")
+    case kindJson:
+        obj := make(map[string]interface{})
+        for k, v := range b.raw {
+            if exclude[k] {
+                continue
+            }
+            obj[k] = v
+            if o, ok := override[k]; ok {
+                obj[k] = o
+            }
+        }
+        data, _ := json.Marshal(obj)
+        return string(data)
+    case kindMultipart:
+        v := &httpx.Variations{MimeType: b.variations.MimeType}
+        for _, p := range b.variations.Params {
+            if exclude[p.Name] {
+                continue
+            }
+            if o, ok := override[p.Name]; ok {
+                p.Value = o
+            }
+            v.Params = append(v.Params, p)
+        }
+        if len(v.Params) == 0 {
+            return ""
+        }
+        return v.Release()
+    }
+    return ""
+}
+
+// form 转换为表单请求体，json 中非字符串的值使用 json 格式
+func (b *body) form(exclude map[string]bool) string {
+    var kvs []string
+    for _, name := range b.names {
+        if !exclude[name] {
+            kvs = append(kvs, url.QueryEscape(name)+"="+url.QueryEscape(b.values[name]))
+        }
+    }
+    return strings.Join(kvs, "&")
+}
+
+// textPlain text/plain 表单提交 json 时的请求体，表单会在最后加上一个多余的字段 {"a":"b","jie":"="}
+func (b *body) textPlain(exclude map[string]bool) (string, string, string) {
+    data := b.build(exclude, nil)
+    if !strings.HasSuffix(data, "}") {
+        return "", "", ""
+    }
+    name := strings.TrimSuffix(data, "}")
+    if name != "{" {
+        name += ","
+    }
+    name += `"jie":"`
+    value := `"}`
+    return name + "=" + value, name, value
+}
@@ -0,0 +1,313 @@
+package csrf
+
+import (
+    "fmt"
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/PerFile/sql"
+    "github.com/yhy0/logging"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc CSRF 检测，使用 cookie 认证的 POST 请求
+        1. 查找请求中的 anti-CSRF token、自定义请求头(跨站需要 CORS 预检)，以及 cookie 的 SameSite 属性
+        2. 分别去掉 token、替换为其他 token、去掉 Referer/Origin、json 改为表单/text/plain 重放，
+           和原始响应相同说明没有校验，最后去掉所有保护重放一次，成功则确认存在 CSRF，并生成 PoC
+        PUT、DELETE 这类请求跨站时需要 CORS 预检，由 cors 插件检测
+        重放会重新提交修改数据的请求，风险等级为 destructive，安全模式下任务不会运行该插件；直接调用 Scan 时安全模式下只分析不重放
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+    cookies      sync.Map // key 为 host|cookie 名，value 为 http.SameSite，响应中 Set-Cookie 设置的属性
+}
+
+var (
+    // token 参数名
+    tokenRegex = regexp.MustCompile(`(?i)(csrf|xsrf|^_?token$|_token$|authenticity_token|requestverificationtoken|^nonce$|_nonce$|formhash|^__requestdigest$)`)
+    // 登录、搜索这类的接口 CSRF 危害小
+    lowImpactRegex = regexp.MustCompile(`(?i)(login|logon|signin|search|query|find|filter|list|captcha|track|log$|analytics|collect|beacon)`)
+    // 修改密码、邮箱、转账这类危害大
+    highImpactRegex = regexp.MustCompile(`(?i)(password|passwd|pwd|email|mail|phone|mobile|transfer|withdraw|pay|admin|role|permission|privilege|delete|remove|user/add|adduser|token|apikey|api_key|secret|bind|oauth)`)
+)
+
+// 跨站请求无法设置的请求头，带有这些请求头说明接口可能要求自定义请求头
+var customHeaders = []string{"X-Csrf-Token", "X-Xsrf-Token", "X-Csrftoken", "X-Requested-With", "Requestverificationtoken", "X-Request-Verification-Token", "Csrf-Token"}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    p.collect(in)
+    
+    if in.Method != "POST" || in.Resp == nil || in.Resp.StatusCode >= 400 || in.ParseUrl == nil {
+        return
+    }
+    // 其他插件展开的请求不是浏览器发出的
+    if in.Source == "graphql" || in.Source == "websocket-message" {
+        return
+    }
+    cookie := header(in.Headers, "Cookie")
+    if cookie == "" || p.IsScanned(in.UniqueId) {
+        return
+    }
+    b := parseBody(in)
+    if b == nil {
+        return
+    }
+    
+    // token 参数
+    tokens := make(map[string]bool)
+    for _, name := range b.names {
+        if tokenRegex.MatchString(name) {
+            tokens[name] = true
+        }
+    }
+    // 自定义请求头
+    var custom []string
+    for k := range in.Headers {
+        if util.InSliceCaseFold(k, customHeaders) || tokenRegex.MatchString(k) {
+            custom = append(custom, k)
+        }
+    }
+    sameSite, protected := p.sameSite(in.Host, cookie)
+    
+    var notes []string
+    var names []string
+    for name := range tokens {
+        names = append(names, name)
+    }
+    notes = append(notes, fmt.Sprintf("anti-CSRF token parameters: %s", orNone(names)))
+    notes = append(notes, fmt.Sprintf("custom headers: %s", orNone(custom)))
+    notes = append(notes, "cookies: "+sameSite)
+    
+    // 安全模式下不重放，只根据请求判断
+    if client.Scope.Safe() {
+        if len(tokens) == 0 && len(custom) == 0 && !protected {
+            p.report(in, "missing csrf protection", "", &httpx.Response{RequestDump: in.RawRequest, ResponseDump: in.RawResponse}, output.Low, fmt.Sprintf("The state-changing request has no anti-CSRF token and no custom header, and the cookies are not protected by SameSite (not verified by replay in safe mode). %s", strings.Join(notes, "; ")), "", client)
+        }
+        return
+    }
+    
+    tpl := sql.NewTemplate(in, client)
+    if tpl == nil {
+        return
+    }
+    send := func(name string, headers map[string]string, target, data string) *httpx.Response {
+        res, err := client.Request(target, "POST", data, headers)
+        if err != nil {
+            logging.Logger.Debugln("[csrf]", name, in.Url, err)
+            return nil
+        }
+        accepted := res.StatusCode == in.Resp.StatusCode && tpl.Similar(res)
+        result := "rejected"
+        if accepted {
+            result = "accepted"
+        }
+        notes = append(notes, name+": "+result)
+        if !accepted {
+            return nil
+        }
+        return res
+    }
+    
+    originless := strip(in.Headers, append([]string{"Referer", "Origin"}, custom...))
+    // 分别测试每一种保护
+    if len(tokens) > 0 {
+        send("token removed", in.Headers, in.Url, b.build(tokens, nil))
+        foreign := make(map[string]string)
+        for name := range tokens {
+            foreign[name] = util.RandomLetterNumbers(max(len(b.values[name]), 8))
+        }
+        send("foreign token", in.Headers, in.Url, b.build(nil, foreign))
+    }
+    send("Referer/Origin stripped", strip(in.Headers, []string{"Referer", "Origin"}), in.Url, in.RequestBody)
+    if len(custom) > 0 {
+        send("custom headers removed", strip(in.Headers, custom), in.Url, in.RequestBody)
+    }
+    
+    // 去掉所有保护，使用跨站表单能发送的请求
+    var (
+        res     *httpx.Response
+        html    string
+        payload string
+    )
+    switch b.kind {
+    case kindForm, kindMultipart:
+        data := b.build(tokens, nil)
+        if res = send("forged request", originless, in.Url, data); res != nil {
+            enctype := ""
+            if b.kind == kindMultipart {
+                enctype = "multipart/form-data"
+            }
+            html = poc(in.Url, enctype, fields(b, tokens))
+            payload = data
+        }
+    case kindJson:
+        // 先尝试改为表单，不行再使用 text/plain 提交 json
+        headers := strip(originless, []string{"Content-Type"})
+        headers["Content-Type"] = "application/x-www-form-urlencoded"
+        data := b.form(tokens)
+        if res = send("forged request (json as form)", headers, in.Url, data); res != nil {
+            html = poc(in.Url, "", fields(b, tokens))
+            payload = data
+            break
+        }
+        data, name, value := b.textPlain(tokens)
+        if data == "" {
+            break
+        }
+        headers["Content-Type"] = "text/plain"
+        if res = send("forged request (json as text/plain)", headers, in.Url, data); res != nil {
+            html = poc(in.Url, "text/plain", []field{{name, value}})
+            payload = data
+        }
+    }
+    if res == nil {
+        return
+    }
+    
+    level := output.Medium
+    if lowImpactRegex.MatchString(in.ParseUrl.Path) {
+        level = output.Low
+    } else if highImpactRegex.MatchString(in.ParseUrl.Path) {
+        level = output.High
+    }
+    description := "The state-changing request is accepted without anti-CSRF token, custom headers, Referer and Origin, using a content type a cross-site form can send. A malicious page can submit it with the victim's cookies (CSRF)."
+    if protected {
+        level = output.Low
+        description += " The session cookies are SameSite Lax/Strict, so modern browsers do not send them on cross-site POST requests, which mitigates the issue."
+    }
+    p.report(in, "csrf", payload, res, level, description+"\n"+strings.Join(notes, "\n"), html, client)
+}
+
+// collect 记录响应中 Set-Cookie 的 SameSite 属性
+func (p *Plugin) collect(in *input.CrawlResult) {
+    if in.Resp == nil || in.Resp.Header == nil {
+        return
+    }
+    for _, c := range (&http.Response{Header: in.Resp.Header}).Cookies() {
+        p.cookies.Store(in.Host+"|"+c.Name, c.SameSite)
+    }
+}
+
+// sameSite 请求中 cookie 的 SameSite 属性，所有 cookie 都为 Lax、Strict 时认为受到保护
+func (p *Plugin) sameSite(host, cookie string) (string, bool) {
+    req := &http.Request{Header: http.Header{"Cookie": {cookie}}}
+    var attrs []string
+    protected := true
+    for _, c := range req.Cookies() {
+        attr := "unknown"
+        if v, ok := p.cookies.Load(host + "|" + c.Name); ok {
+            switch v.(http.SameSite) {
+            case http.SameSiteLaxMode:
+                attr = "Lax"
+            case http.SameSiteStrictMode:
+                attr = "Strict"
+            case http.SameSiteNoneMode:
+                attr = "None"
+            default:
+                // 没有设置时 Chrome 默认为 Lax，但是设置后两分钟内跨站 POST 仍然会带上
+                attr = "not set"
+            }
+        }
+        if attr != "Lax" && attr != "Strict" {
+            protected = false
+        }
+        attrs = append(attrs, c.Name+"="+attr)
+    }
+    if len(attrs) == 0 {
+        return "none", false
+    }
+    return "SameSite " + strings.Join(attrs, ", "), protected
+}
+
+// fields 表单 PoC 中的字段，文件字段无法通过表单自动提交，去掉
+func fields(b *body, exclude map[string]bool) []field {
+    var fs []field
+    for _, name := range b.names {
+        if exclude[name] {
+            continue
+        }
+        if _, ok := b.values[name]; !ok {
+            continue
+        }
+        fs = append(fs, field{name, b.values[name]})
+    }
+    return fs
+}
+
+func header(headers map[string]string, name string) string {
+    for k, v := range headers {
+        if strings.EqualFold(k, name) {
+            return v
+        }
+    }
+    return ""
+}
+
+// strip 去掉请求头
+func strip(headers map[string]string, names []string) map[string]string {
+    h := make(map[string]string)
+    for k, v := range headers {
+        if !util.InSliceCaseFold(k, names) {
+            h[k] = v
+        }
+    }
+    return h
+}
+
+func orNone(s []string) string {
+    if len(s) == 0 {
+        return "none"
+    }
+    return strings.Join(s, ", ")
+}
+
+func (p *Plugin) report(in *input.CrawlResult, kind, payload string, res *httpx.Response, level, description, html string, client *httpx.Client) {
+    if html != "" {
+        description += "\n\nPoC:\n" + html
+    }
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "CSRF",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    kind,
+            Target:      in.Url,
+            Method:      "POST",
+            Ip:          in.Ip,
+            Payload:     payload,
+            Request:     res.RequestDump,
+            Response:    res.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "csrf"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskDestructive
+}
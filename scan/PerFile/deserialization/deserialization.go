@@ -0,0 +1,346 @@
+package deserialization
+
+import (
+    "fmt"
+    "github.com/yaklang/yaklang/common/yso"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/reverse"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/30
+   @desc Java 反序列化检测
+        1. 被动: 查找参数、cookie、请求体、响应体中的 Java 序列化数据(rO0AB、aced0005、gzip+base64、二进制)
+        2. 主动: 在同样的位置使用相同的编码发送不存在的类，响应中出现类名或 ClassNotFoundException 说明服务端会反序列化
+           配置了 dnslog 时发送 URLDNS，收到 dns 请求后再使用 FindClassByDNS 逐个探测 gadget 依赖的类，和 shiro 一样使用 yso 生成
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+    reported     sync.Map // 被动发现的位置只输出一次
+}
+
+// 等待 dnslog 收到请求的时间
+const dnsWait = 3 * time.Second
+
+// 反序列化失败时常见的异常
+var exceptions = []string{"ClassNotFoundException", "java.io.InvalidClassException", "java.io.StreamCorruptedException", "java.io.ObjectInputStream", "invalid stream header", "java.io.OptionalDataException"}
+
+// point 序列化数据所在的位置
+type point struct {
+    location string // parameter、cookie、body
+    name     string
+    enc      string
+    class    string // 原始数据中的类名
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.ParseUrl == nil {
+        return
+    }
+    points := find(in)
+    
+    // 响应中的序列化数据只记录
+    if in.Resp != nil {
+        if enc, data := detect(in.Resp.Body); enc != "" {
+            p.passive(in, point{location: "response", enc: enc, class: className(data)}, client)
+        }
+    }
+    for _, pt := range points {
+        p.passive(in, pt, client)
+    }
+    if len(points) == 0 || p.IsScanned(in.UniqueId) {
+        return
+    }
+    
+    for _, pt := range points {
+        p.confirm(in, pt, client)
+    }
+}
+
+// find 查找请求中的序列化数据
+func find(in *input.CrawlResult) []point {
+    var points []point
+    for name, values := range in.ParseUrl.Query() {
+        for _, v := range values {
+            if enc, data := detect(v); enc != "" {
+                points = append(points, point{location: "parameter", name: name, enc: enc, class: className(data)})
+                break
+            }
+        }
+    }
+    if cookie := header(in.Headers, "Cookie"); cookie != "" {
+        req := &http.Request{Header: http.Header{"Cookie": {cookie}}}
+        for _, c := range req.Cookies() {
+            if enc, data := detect(c.Value); enc != "" {
+                points = append(points, point{location: "cookie", name: c.Name, enc: enc, class: className(data)})
+            }
+        }
+    }
+    if in.RequestBody == "" {
+        return points
+    }
+    // 整个请求体就是序列化数据
+    if enc, data := detect(in.RequestBody); enc != "" {
+        return append(points, point{location: "body", enc: enc, class: className(data)})
+    }
+    variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), "POST", in.ContentType, in.Headers)
+    if err != nil {
+        return points
+    }
+    for _, v := range variations.Params {
+        if v.IsFile {
+            continue
+        }
+        if enc, data := detect(v.Value); enc != "" {
+            points = append(points, point{location: "body parameter", name: v.Name, enc: enc, class: className(data)})
+        }
+    }
+    return points
+}
+
+// send 在原来的位置使用相同的编码发送 payload
+func (pt point) send(in *input.CrawlResult, client *httpx.Client, payload []byte) (*httpx.Response, error) {
+    value := encode(pt.enc, payload)
+    target, body, headers := in.Url, in.RequestBody, in.Headers
+    switch pt.location {
+    case "parameter":
+        u := *in.ParseUrl
+        q := u.Query()
+        q.Set(pt.name, value)
+        u.RawQuery = q.Encode()
+        target = u.String()
+    case "cookie":
+        headers = make(map[string]string)
+        for k, v := range in.Headers {
+            headers[k] = v
+        }
+        req := &http.Request{Header: http.Header{"Cookie": {header(in.Headers, "Cookie")}}}
+        var cookies []string
+        for _, c := range req.Cookies() {
+            if c.Name == pt.name {
+                c.Value = value
+            }
+            cookies = append(cookies, c.Name+"="+c.Value)
+        }
+        for k := range headers {
+            if strings.EqualFold(k, "Cookie") {
+                delete(headers, k)
+            }
+        }
+        headers["Cookie"] = strings.Join(cookies, "; ")
+    case "body":
+        body = value
+    case "body parameter":
+        variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), "POST", in.ContentType, in.Headers)
+        if err != nil {
+            return nil, err
+        }
+        // 表单 Release 时不会编码
+        if !strings.Contains(strings.ToLower(in.ContentType), "json") && !strings.Contains(strings.ToLower(in.ContentType), "multipart") {
+            value = url.QueryEscape(value)
+        }
+        if err = variations.Set(pt.name, value); err != nil {
+            return nil, err
+        }
+        body = variations.Release()
+    }
+    return client.Request(target, in.Method, body, headers)
+}
+
+// confirm 主动确认服务端是否会反序列化
+func (p *Plugin) confirm(in *input.CrawlResult, pt point, client *httpx.Client) {
+    var (
+        evidence []string
+        res      *httpx.Response
+    )
+    
+    // 不存在的类，出现类名或者反序列化的异常说明进行了反序列化
+    name := "jie." + util.RandomLetterNumbers(10)
+    if r, err := pt.send(in, client, unknownClass(name)); err == nil {
+        if strings.Contains(r.Body, name) {
+            evidence = append(evidence, fmt.Sprintf("the response contains the unknown class name %s", name))
+            res = r
+        } else {
+            for _, e := range exceptions {
+                if strings.Contains(r.Body, e) && (in.Resp == nil || !strings.Contains(in.Resp.Body, e)) {
+                    evidence = append(evidence, fmt.Sprintf("an object of unknown class %s causes %s in the response", name, e))
+                    res = r
+                    break
+                }
+            }
+        }
+    }
+    
+    var families []string
+    if conf.GlobalConfig.Reverse.Host != "" {
+        if dig := reverse.GetSubDomain(); dig != nil {
+            if r := p.urldns(in, pt, client, dig); r != nil {
+                evidence = append(evidence, fmt.Sprintf("URLDNS gadget triggered a dns lookup of %s", dig.Domain))
+                res = r
+                families = p.classes(in, pt, client, dig)
+            }
+        }
+    }
+    if res == nil {
+        return
+    }
+    
+    level := output.High
+    description := fmt.Sprintf("The %s %s contains a serialized Java object (%s, %s) that the server deserializes: %s.", pt.location, pt.name, pt.enc, orUnknown(pt.class), strings.Join(evidence, "; "))
+    if len(families) > 0 {
+        description += "\nClasses of gadget chains found on the classpath: " + strings.Join(families, ", ")
+        for _, f := range families {
+            if f != "Linux_OS" && f != "Windows_OS" {
+                level = output.Critical
+                break
+            }
+        }
+    }
+    p.report(in, pt, "java deserialization", res, level, description, client)
+}
+
+// urldns 发送 URLDNS，收到 dns 请求时返回响应
+func (p *Plugin) urldns(in *input.CrawlResult, pt point, client *httpx.Client, dig *reverse.Dig) *httpx.Response {
+    obj, err := yso.GetURLDNSJavaObject(dig.Domain)
+    if err != nil {
+        logging.Logger.Errorln("[deserialization]", err)
+        return nil
+    }
+    payload, err := yso.ToBytes(obj)
+    if err != nil {
+        logging.Logger.Errorln("[deserialization]", err)
+        return nil
+    }
+    res, err := pt.send(in, client, payload)
+    if err != nil {
+        return nil
+    }
+    time.Sleep(dnsWait)
+    if !reverse.PullLogs(dig) {
+        return nil
+    }
+    return res
+}
+
+// classes 使用 FindClassByDNS 探测 gadget 依赖的类，存在时会请求 别名.dnslog 域名
+func (p *Plugin) classes(in *input.CrawlResult, pt point, client *httpx.Client, dig *reverse.Dig) []string {
+    checklist := yso.GetGadgetChecklist()
+    var aliases []string
+    for alias := range checklist {
+        aliases = append(aliases, alias)
+    }
+    sort.Strings(aliases)
+    
+    for _, alias := range aliases {
+        obj, err := yso.GenerateGadget(string(yso.GadgetFindClassByDNS), "class-dnslog", map[string]string{
+            "domain": label(alias) + "." + dig.Domain,
+            "class":  checklist[alias],
+        })
+        if err != nil {
+            logging.Logger.Debugln("[deserialization]", alias, err)
+            continue
+        }
+        payload, err := yso.ToBytes(obj)
+        if err != nil {
+            continue
+        }
+        _, _ = pt.send(in, client, payload)
+    }
+    time.Sleep(dnsWait)
+    if !reverse.PullLogs(dig) {
+        return nil
+    }
+    
+    var families []string
+    logs := strings.ToLower(dig.Msg)
+    for _, alias := range aliases {
+        if strings.Contains(logs, label(alias)+"."+strings.ToLower(dig.Domain)) {
+            families = append(families, alias)
+        }
+    }
+    return families
+}
+
+// label 别名作为 dns 的子域名
+func label(alias string) string {
+    return strings.ToLower(strings.ReplaceAll(alias, "_", "-"))
+}
+
+// passive 被动发现的序列化数据，同一个位置只记录一次
+func (p *Plugin) passive(in *input.CrawlResult, pt point, client *httpx.Client) {
+    key := in.Host + in.ParseUrl.Path + "|" + pt.location + "|" + pt.name
+    if _, ok := p.reported.LoadOrStore(key, true); ok {
+        return
+    }
+    res := &httpx.Response{RequestDump: in.RawRequest, ResponseDump: in.RawResponse}
+    description := fmt.Sprintf("A serialized Java object (%s, %s) is found in the %s %s. If the server deserializes it, it may be vulnerable to deserialization attacks.", pt.enc, orUnknown(pt.class), pt.location, pt.name)
+    p.report(in, pt, "java serialized object", res, output.Low, description, client)
+}
+
+func (p *Plugin) report(in *input.CrawlResult, pt point, kind string, res *httpx.Response, level, description string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "Deserialization",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    kind,
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Param:       pt.name,
+            Payload:     pt.location + " " + pt.name + " (" + pt.enc + ")",
+            Request:     res.RequestDump,
+            Response:    res.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func header(headers map[string]string, name string) string {
+    for k, v := range headers {
+        if strings.EqualFold(k, name) {
+            return v
+        }
+    }
+    return ""
+}
+
+func orUnknown(class string) string {
+    if class == "" {
+        return "unknown class"
+    }
+    return "class " + class
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "deserialization"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
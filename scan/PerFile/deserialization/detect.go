@@ -0,0 +1,115 @@
+package deserialization
+
+import (
+    "bytes"
+    "compress/gzip"
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/hex"
+    "io"
+    "net/url"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/30
+   @desc 识别 Java 序列化数据，支持原始二进制、base64、gzip+base64、hex，生成 payload 时使用相同的编码
+**/
+
+// 序列化数据的编码方式
+const (
+    encRaw        = "raw"
+    encBase64     = "base64"
+    encBase64Url  = "base64url"
+    encGzipBase64 = "gzip+base64"
+    encHex        = "hex"
+)
+
+// 序列化流开头的魔数和版本 ACED0005
+var magic = []byte{0xac, 0xed, 0x00, 0x05}
+
+// detect 判断值是否为 Java 序列化数据，返回编码方式和解码后的数据
+func detect(value string) (string, []byte) {
+    if bytes.HasPrefix([]byte(value), magic) {
+        return encRaw, []byte(value)
+    }
+    v := strings.TrimSpace(value)
+    if strings.Contains(v, "%") {
+        if u, err := url.QueryUnescape(v); err == nil {
+            v = u
+        }
+    }
+    // 查询参数解码后 base64 中的 + 会变成空格
+    v = strings.ReplaceAll(v, " ", "+")
+    switch {
+    case strings.HasPrefix(v, "rO0AB"):
+        if data, err := base64.StdEncoding.DecodeString(v); err == nil && bytes.HasPrefix(data, magic) {
+            return encBase64, data
+        }
+        if data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "=")); err == nil && bytes.HasPrefix(data, magic) {
+            return encBase64Url, data
+        }
+    case strings.HasPrefix(v, "H4sI"):
+        data, err := base64.StdEncoding.DecodeString(v)
+        if err != nil {
+            return "", nil
+        }
+        r, err := gzip.NewReader(bytes.NewReader(data))
+        if err != nil {
+            return "", nil
+        }
+        data, err = io.ReadAll(io.LimitReader(r, 1<<20))
+        if err == nil && bytes.HasPrefix(data, magic) {
+            return encGzipBase64, data
+        }
+    case len(v) >= 8 && strings.EqualFold(v[:8], "aced0005"):
+        if data, err := hex.DecodeString(v); err == nil {
+            return encHex, data
+        }
+    }
+    return "", nil
+}
+
+// encode 使用和原始数据相同的编码
+func encode(enc string, data []byte) string {
+    switch enc {
+    case encBase64:
+        return base64.StdEncoding.EncodeToString(data)
+    case encBase64Url:
+        return base64.RawURLEncoding.EncodeToString(data)
+    case encGzipBase64:
+        var buf bytes.Buffer
+        w := gzip.NewWriter(&buf)
+        _, _ = w.Write(data)
+        _ = w.Close()
+        return base64.StdEncoding.EncodeToString(buf.Bytes())
+    case encHex:
+        return hex.EncodeToString(data)
+    }
+    return string(data)
+}
+
+// className 序列化流中第一个对象的类名 TC_OBJECT TC_CLASSDESC
+func className(data []byte) string {
+    if len(data) < 8 || data[4] != 0x73 || data[5] != 0x72 {
+        return ""
+    }
+    n := int(binary.BigEndian.Uint16(data[6:8]))
+    if len(data) < 8+n {
+        return ""
+    }
+    return string(data[8 : 8+n])
+}
+
+// unknownClass 一个类名不存在的对象，服务端反序列化时会抛出 ClassNotFoundException
+func unknownClass(name string) []byte {
+    data := append([]byte{}, magic...)
+    data = append(data, 0x73, 0x72) // TC_OBJECT TC_CLASSDESC
+    data = binary.BigEndian.AppendUint16(data, uint16(len(name)))
+    data = append(data, name...)
+    data = append(data, 0, 0, 0, 0, 0, 0, 0, 1) // serialVersionUID
+    data = append(data, 0x02, 0x00, 0x00)       // SC_SERIALIZABLE，没有字段
+    data = append(data, 0x78, 0x70)             // TC_ENDBLOCKDATA，父类 TC_NULL
+    return data
+}
@@ -0,0 +1,139 @@
+package deserialization
+
+import (
+    "bytes"
+    "encoding/base64"
+    "encoding/hex"
+    "strings"
+    "testing"
+)
+
+// java.lang.Integer(1) 和 shiro 的 SimplePrincipalCollection，ObjectOutputStream 序列化后的数据
+const (
+    javaInteger = "rO0ABXNyABFqYXZhLmxhbmcuSW50ZWdlchLioKT3gYc4AgABSQAFdmFsdWV4cgAQamF2YS5sYW5nLk51bWJlcoaslR0LlOCLAgAAeHAAAAAB"
+    javaShiro   = "rO0ABXNyADJvcmcuYXBhY2hlLnNoaXJvLnN1YmplY3QuU2ltcGxlUHJpbmNpcGFsQ29sbGVjdGlvbqh/WCXGowhKAwABTAAPcmVhbG1QcmluY2lwYWxzdAAPTGphdmEvdXRpbC9NYXA7eHBwdwEAeA=="
+    shiroClass  = "org.apache.shiro.subject.SimplePrincipalCollection"
+)
+
+func decode(t *testing.T, s string) []byte {
+    data, err := base64.StdEncoding.DecodeString(s)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return data
+}
+
+func TestDetect(t *testing.T) {
+    integer := decode(t, javaInteger)
+    shiro := decode(t, javaShiro)
+    tests := []struct {
+        name  string
+        value string
+        enc   string
+        class string
+    }{
+        {"raw", string(integer), encRaw, "java.lang.Integer"},
+        {"base64", javaInteger, encBase64, "java.lang.Integer"},
+        {"base64 padded", javaShiro, encBase64, shiroClass},
+        {"base64 spaces", "  " + javaShiro + "\n", encBase64, shiroClass},
+        {"url encoded", "rO0ABXNyADJvcmcuYXBhY2hlLnNoaXJvLnN1YmplY3QuU2ltcGxlUHJpbmNpcGFsQ29sbGVjdGlvbqh%2FWCXGowhKAwABTAAPcmVhbG1QcmluY2lwYWxzdAAPTGphdmEvdXRpbC9NYXA7eHBwdwEAeA%3D%3D", encBase64, shiroClass},
+        {"base64url", base64.RawURLEncoding.EncodeToString(shiro), encBase64Url, shiroClass},
+        {"hex", hex.EncodeToString(integer), encHex, "java.lang.Integer"},
+        {"hex upper", strings.ToUpper(hex.EncodeToString(shiro)), encHex, shiroClass},
+        {"gzip+base64", encode(encGzipBase64, shiro), encGzipBase64, shiroClass},
+        // 截断的数据: 魔数完整时仍然识别，类名不完整时为空
+        {"truncated base64", javaInteger[:16], encBase64, ""},
+        {"truncated magic", "rO0AB", "", ""},
+        {"truncated hex", hex.EncodeToString(integer)[:41], "", ""},
+        {"short hex", "aced00", "", ""},
+        {"truncated gzip", encode(encGzipBase64, shiro)[:20], "", ""},
+        {"gzip not java", base64.StdEncoding.EncodeToString(gzipped(t, []byte(`{"id":1}`))), "", ""},
+        // .NET BinaryFormatter、ViewState(LosFormatter)、PHP serialize 不是 Java 序列化数据
+        {".net binaryformatter", "AAEAAAD/////AQAAAAAAAAAMAgAAAElTeXN0ZW0sIFZlcnNpb249NC4wLjAuMA==", "", ""},
+        {".net viewstate", "/wEPDwULLTE2MTY2ODcyMjlkZA==", "", ""},
+        {"php", `O:8:"stdClass":1:{s:3:"foo";s:3:"bar";}`, "", ""},
+        {"php base64", "Tzo4OiJzdGRDbGFzcyI6MTp7czozOiJmb28iO3M6MzoiYmFyIjt9", "", ""},
+        {"php array", `a:1:{i:0;s:4:"test";}`, "", ""},
+        {"json", `{"id":1}`, "", ""},
+        {"empty", "", "", ""},
+    }
+    for _, tt := range tests {
+        enc, data := detect(tt.value)
+        if enc != tt.enc {
+            t.Errorf("%s: detect() enc = %q, want %q", tt.name, enc, tt.enc)
+            continue
+        }
+        if enc == "" {
+            if data != nil {
+                t.Errorf("%s: detect() data = %x, want nil", tt.name, data)
+            }
+            continue
+        }
+        if !bytes.HasPrefix(data, magic) {
+            t.Errorf("%s: detect() data = %x", tt.name, data)
+        }
+        if class := className(data); class != tt.class {
+            t.Errorf("%s: className() = %q, want %q", tt.name, class, tt.class)
+        }
+    }
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+    b, err := base64.StdEncoding.DecodeString(encode(encGzipBase64, data))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return b
+}
+
+// payload 使用原始数据的编码后，detect 能识别出同样的编码和数据
+func TestEncode(t *testing.T) {
+    data := unknownClass("com.jie.Probe")
+    for _, enc := range []string{encRaw, encBase64, encBase64Url, encGzipBase64, encHex} {
+        got, decoded := detect(encode(enc, data))
+        if got != enc || !bytes.Equal(decoded, data) {
+            t.Errorf("detect(encode(%s)) = %s, %x", enc, got, decoded)
+        }
+    }
+    if got := encode(encHex, magic); got != "aced0005" {
+        t.Errorf("encode(hex) = %s", got)
+    }
+    if got := encode(encBase64Url, decode(t, javaShiro)); strings.ContainsAny(got, "+/=") {
+        t.Errorf("encode(base64url) = %s", got)
+    }
+}
+
+func TestClassName(t *testing.T) {
+    tests := []struct {
+        name string
+        data []byte
+        want string
+    }{
+        {"integer", decode(t, javaInteger), "java.lang.Integer"},
+        {"shiro", decode(t, javaShiro), shiroClass},
+        // TC_STRING 不是对象
+        {"string", append(append([]byte{}, magic...), 0x74, 0x00, 0x03, 'a', 'b', 'c'), ""},
+        {"magic only", magic, ""},
+        {"truncated name", decode(t, javaInteger)[:20], ""},
+        {"nil", nil, ""},
+    }
+    for _, tt := range tests {
+        if got := className(tt.data); got != tt.want {
+            t.Errorf("%s: className() = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestUnknownClass(t *testing.T) {
+    data := unknownClass("com.jie.Probe")
+    want := "aced0005" + "7372" + "000d" + hex.EncodeToString([]byte("com.jie.Probe")) + "0000000000000001" + "020000" + "7870"
+    if got := hex.EncodeToString(data); got != want {
+        t.Errorf("unknownClass() = %s, want %s", got, want)
+    }
+    if got := className(data); got != "com.jie.Probe" {
+        t.Errorf("className(unknownClass()) = %q", got)
+    }
+    if got := className(unknownClass("")); got != "" {
+        t.Errorf("className(unknownClass(\"\")) = %q", got)
+    }
+}
@@ -0,0 +1,241 @@
+package domxss
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "github.com/chromedp/cdproto/fetch"
+    "github.com/chromedp/cdproto/network"
+    "github.com/chromedp/cdproto/page"
+    "github.com/chromedp/cdproto/runtime"
+    "github.com/chromedp/chromedp"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/crawler/crawlergo/xss"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/logging"
+    "html"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/6
+   @desc dom 型 xss 污点传播分析，爬虫(crawlergo、katana)和被动代理的 html/js 响应都会检测
+        1. 拦截页面的文档请求，使用扫描时得到的响应(内联 js 经过 HookParse 转换)返回，外部 js 在响应阶段转换
+        2. 注入 preload.js hook source、sink，sink 收到被污染的数据时回传 source、sink 的调用栈
+        3. 根据 source、sink 生成最小的 payload，在浏览器中打开确认
+        js 响应放到一个只引用它的空白页面中渲染，同一个路径只渲染一次
+**/
+
+var errNoBrowser = errors.New("browser not available")
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.Resp == nil || in.Resp.Header == nil || in.ParseUrl == nil || in.Resp.Body == "" {
+        return
+    }
+    contentType := strings.ToLower(in.Resp.Header.Get("Content-Type"))
+    script := strings.Contains(contentType, "javascript") || strings.HasSuffix(strings.ToLower(in.ParseUrl.Path), ".js")
+    if !script && !(strings.Contains(contentType, "html") && strings.Contains(strings.ToLower(in.Resp.Body), "<script")) {
+        return
+    }
+    if p.IsScanned(in.Host + in.ParseUrl.Path) {
+        return
+    }
+    
+    points, err := render(in, script)
+    if err != nil {
+        logging.Logger.Debugln("[domxss]", in.Url, err)
+    }
+    if len(points) == 0 {
+        return
+    }
+    logging.Logger.Infoln("[domxss]", in.Url, len(points), "source to sink flows")
+    xss.Report(points, func(poc xss.Poc) bool {
+        return confirm(poc, in.Headers)
+    }, client.Sink)
+}
+
+// render 插桩后渲染页面，返回 sink 回传的污点传播
+func render(in *input.CrawlResult, script bool) ([]xss.VulPoint, error) {
+    inUse.RLock()
+    defer inUse.RUnlock()
+    bro := browser()
+    if bro == nil {
+        return nil, errNoBrowser
+    }
+    acquire()
+    defer release()
+    ctx, cancel := bro.NewTab(30 * time.Second)
+    defer cancel()
+    
+    body := []byte(in.Resp.Body)
+    if script {
+        body = []byte(fmt.Sprintf(`<html><head><script src="%s"></script></head><body></body></html>`, html.EscapeString(in.Url)))
+    }
+    document := base64.StdEncoding.EncodeToString(xss.HookDocument(body, in.Url))
+    
+    var (
+        lock      sync.Mutex
+        points    []xss.VulPoint
+        fulfilled bool
+    )
+    chromedp.ListenTarget(ctx, func(ev interface{}) {
+        switch e := ev.(type) {
+        case *fetch.EventRequestPaused:
+            go func() {
+                // 响应阶段，转换外部 js
+                if e.ResponseStatusCode != 0 {
+                    err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+                        return xss.ParseDomHookResponseJs(ctx, e)
+                    }))
+                    if err != nil {
+                        _ = chromedp.Run(ctx, fetch.ContinueRequest(e.RequestID))
+                    }
+                    return
+                }
+                // 请求阶段，第一个文档请求使用扫描时的响应
+                lock.Lock()
+                first := !fulfilled
+                fulfilled = true
+                lock.Unlock()
+                if !first {
+                    _ = chromedp.Run(ctx, fetch.ContinueRequest(e.RequestID))
+                    return
+                }
+                _ = chromedp.Run(ctx, fetch.FulfillRequest(e.RequestID, 200).
+                    WithResponseHeaders([]*fetch.HeaderEntry{{Name: "Content-Type", Value: "text/html; charset=utf-8"}}).
+                    WithBody(document))
+            }()
+        case *runtime.EventBindingCalled:
+            if e.Name != xss.EventPushVul {
+                return
+            }
+            var vuls []xss.VulPoint
+            if err := json.Unmarshal([]byte(e.Payload), &vuls); err != nil {
+                logging.Logger.Debugln("[domxss] json.Unmarshal error:", err)
+                return
+            }
+            lock.Lock()
+            points = append(points, vuls...)
+            lock.Unlock()
+        case *page.EventJavascriptDialogOpening:
+            go func() {
+                _ = chromedp.Run(ctx, page.HandleJavaScriptDialog(false))
+            }()
+        }
+    })
+    
+    err := chromedp.Run(ctx,
+        runtime.Enable(),
+        network.Enable(),
+        network.SetExtraHTTPHeaders(extraHeaders(in.Headers)),
+        fetch.Enable().WithPatterns([]*fetch.RequestPattern{
+            {URLPattern: "*", ResourceType: network.ResourceTypeDocument, RequestStage: fetch.RequestStageRequest},
+            {URLPattern: "*", ResourceType: network.ResourceTypeScript, RequestStage: fetch.RequestStageResponse},
+        }),
+        runtime.AddBinding(xss.EventPushVul),
+        chromedp.ActionFunc(func(ctx context.Context) error {
+            _, err := page.AddScriptToEvaluateOnNewDocument(xss.PreloadJS).Do(ctx)
+            return err
+        }),
+        chromedp.Navigate(in.Url),
+        // 等待定时器、异步加载的 js 执行
+        chromedp.Sleep(3*time.Second),
+    )
+    
+    lock.Lock()
+    defer lock.Unlock()
+    return points, err
+}
+
+// trigger 点击属性中带有标记的元素，javascript: 伪协议需要点击才会执行
+const trigger = `(function(m){document.querySelectorAll("*").forEach(function(e){
+if(Array.prototype.some.call(e.attributes,function(a){return a.value.indexOf(m)>=0})){try{e.click()}catch(x){}}
+})})("%s")`
+
+// confirm 打开 poc 地址(不插桩)，弹窗中出现标记说明 payload 执行了，带上原始请求的 cookie 等请求头，需要登录的页面才能确认
+func confirm(poc xss.Poc, headers map[string]string) bool {
+    inUse.RLock()
+    defer inUse.RUnlock()
+    bro := browser()
+    if bro == nil {
+        return false
+    }
+    acquire()
+    defer release()
+    ctx, cancel := bro.NewTab(20 * time.Second)
+    defer cancel()
+    
+    var (
+        lock     sync.Mutex
+        executed bool
+    )
+    chromedp.ListenTarget(ctx, func(ev interface{}) {
+        if e, ok := ev.(*page.EventJavascriptDialogOpening); ok {
+            if strings.Contains(e.Message, poc.Marker) {
+                lock.Lock()
+                executed = true
+                lock.Unlock()
+            }
+            go func() {
+                _ = chromedp.Run(ctx, page.HandleJavaScriptDialog(true))
+            }()
+        }
+    })
+    
+    err := chromedp.Run(ctx,
+        network.Enable(),
+        network.SetExtraHTTPHeaders(extraHeaders(headers)),
+        chromedp.Navigate(poc.Url),
+        chromedp.Sleep(2*time.Second),
+        chromedp.Evaluate(fmt.Sprintf(trigger, poc.Marker), nil),
+        chromedp.Sleep(time.Second),
+    )
+    if err != nil {
+        logging.Logger.Debugln("[domxss] confirm", poc.Url, err)
+    }
+    lock.Lock()
+    defer lock.Unlock()
+    return executed
+}
+
+// extraHeaders 带上原始请求中的 cookie 等请求头
+func extraHeaders(headers map[string]string) network.Headers {
+    extra := make(network.Headers)
+    for k, v := range headers {
+        switch strings.ToLower(k) {
+        case "host", "content-length", "content-type", "connection", "accept-encoding":
+            continue
+        }
+        extra[k] = v
+    }
+    return extra
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "domxss"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
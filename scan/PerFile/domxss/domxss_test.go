@@ -0,0 +1,46 @@
+package domxss
+
+import (
+    "testing"
+    "time"
+)
+
+// 确认时带上原始请求的 cookie，去掉浏览器自己处理的请求头
+func TestExtraHeaders(t *testing.T) {
+    extra := extraHeaders(map[string]string{
+        "Cookie":          "session=1",
+        "Authorization":   "Bearer x",
+        "Host":            "example.com",
+        "Content-Length":  "10",
+        "Accept-Encoding": "gzip",
+    })
+    if len(extra) != 2 || extra["Cookie"] != "session=1" || extra["Authorization"] != "Bearer x" {
+        t.Errorf("extraHeaders = %v", extra)
+    }
+}
+
+func TestCloseWithoutBrowser(t *testing.T) {
+    Close()
+    Close()
+}
+
+// 正在渲染时 Close 等待渲染结束
+func TestCloseWaitsForRender(t *testing.T) {
+    inUse.RLock()
+    closed := make(chan struct{})
+    go func() {
+        Close()
+        close(closed)
+    }()
+    select {
+    case <-closed:
+        t.Fatal("Close returned while a render is in flight")
+    case <-time.After(100 * time.Millisecond):
+    }
+    inUse.RUnlock()
+    select {
+    case <-closed:
+    case <-time.After(time.Second):
+        t.Fatal("Close blocked after the render finished")
+    }
+}
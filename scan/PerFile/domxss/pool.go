@@ -0,0 +1,75 @@
+package domxss
+
+import (
+    "github.com/go-rod/rod/lib/launcher"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/crawler"
+    "github.com/yhy0/Jie/crawler/crawlergo/engine"
+    "github.com/yhy0/logging"
+    "sync"
+)
+
+/**
+   @author yhy
+   @since 2024/7/6
+   @desc 渲染使用的浏览器，优先使用爬虫(crawlergo)启动的浏览器，katana 爬虫、被动代理等没有浏览器时单独启动一个
+        使用 tabs 限制同时打开的标签页数量，单独启动的浏览器在扫描结束时通过 Close 关闭
+**/
+
+var (
+    // 使用浏览器期间持有读锁，Close 等待正在进行的渲染结束后再关闭浏览器，读锁不能嵌套获取
+    inUse sync.RWMutex
+    
+    browserLock sync.Mutex
+    ownBrowser  *engine.Browser
+    noChromium  bool // 没有安装 chromium，只提示一次
+    
+    tabsOnce sync.Once
+    tabs     chan struct{}
+)
+
+// browser 返回可以使用的浏览器，没有安装 chromium 时返回 nil
+func browser() *engine.Browser {
+    if crawler.Browser != nil {
+        return crawler.Browser
+    }
+    browserLock.Lock()
+    defer browserLock.Unlock()
+    if ownBrowser == nil && !noChromium {
+        if _, exists := launcher.LookPath(); !exists {
+            logging.Logger.Warnln("[domxss] chromium not found, dom xss taint tracking is disabled")
+            noChromium = true
+            return nil
+        }
+        ownBrowser = engine.InitBrowser("", false)
+    }
+    return ownBrowser
+}
+
+// Close 等待正在进行的渲染结束后关闭单独启动的浏览器，之后再检测时会重新启动
+func Close() {
+    inUse.Lock()
+    defer inUse.Unlock()
+    browserLock.Lock()
+    defer browserLock.Unlock()
+    if ownBrowser != nil {
+        ownBrowser.Close()
+        ownBrowser = nil
+    }
+}
+
+// acquire 占用一个标签页，超过 tabs 时等待
+func acquire() {
+    tabsOnce.Do(func() {
+        n := conf.GlobalConfig.Plugins.DomXss.Tabs
+        if n <= 0 {
+            n = 4
+        }
+        tabs = make(chan struct{}, n)
+    })
+    tabs <- struct{}{}
+}
+
+func release() {
+    <-tabs
+}
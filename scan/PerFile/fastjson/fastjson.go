@@ -3,6 +3,7 @@ package fastjson
 import (
     "fmt"
     "github.com/thoas/go-funk"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -40,7 +41,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
     results := Scan(in.Url, client)
     
     if results.Type != "" {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "fastjson",
             VulnData: output.VulnData{
@@ -53,7 +54,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
                 Description: fmt.Sprintf("Type: %s,Version: %s, AutoType: %v, Netout: %v, Dependency: %v", results.Type, results.Version, results.AutoType, results.Netout, results.Dependency),
             },
             Level: output.Medium,
-        }
+        })
     }
 }
 
@@ -71,3 +72,7 @@ func (p *Plugin) IsScanned(key string) bool {
 func (p *Plugin) Name() string {
     return "fastjson"
 }
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
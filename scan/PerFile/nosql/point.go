@@ -0,0 +1,127 @@
+package nosql
+
+import (
+    "encoding/json"
+    "github.com/yhy0/Jie/pkg/input"
+    "net/url"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/25
+   @desc 注入点，查询参数、表单参数使用 param[$ne]=x，JSON 使用 {"param": {"$ne": "x"}}
+**/
+
+const (
+    locationQuery = "query"
+    locationForm  = "form"
+    locationJson  = "json"
+)
+
+type point struct {
+    in       *input.CrawlResult
+    name     string
+    original string
+    location string
+}
+
+// injectionPoints 查询参数、表单参数、JSON 顶层的参数
+func injectionPoints(in *input.CrawlResult) []*point {
+    var points []*point
+    if in.ParseUrl != nil {
+        for name, values := range in.ParseUrl.Query() {
+            points = append(points, &point{in: in, name: name, original: values[0], location: locationQuery})
+        }
+    }
+    
+    if in.Method != "POST" || in.RequestBody == "" {
+        return points
+    }
+    
+    body := strings.TrimSpace(in.RequestBody)
+    if strings.Contains(strings.ToLower(in.ContentType), "json") || strings.HasPrefix(body, "{") {
+        m := make(map[string]interface{})
+        if err := json.Unmarshal([]byte(body), &m); err != nil {
+            return points
+        }
+        for name, v := range m {
+            // 只替换基本类型的值，对象、数组本身可能就是查询条件
+            switch v.(type) {
+            case map[string]interface{}, []interface{}:
+                continue
+            }
+            s, _ := json.Marshal(v)
+            points = append(points, &point{in: in, name: name, original: strings.Trim(string(s), `"`), location: locationJson})
+        }
+    } else if strings.Contains(strings.ToLower(in.ContentType), "x-www-form-urlencoded") || in.ContentType == "" {
+        form, err := url.ParseQuery(body)
+        if err != nil {
+            return points
+        }
+        for name, values := range form {
+            points = append(points, &point{in: in, name: name, original: values[0], location: locationForm})
+        }
+    }
+    return points
+}
+
+// build 修改参数后返回请求的 url 和 body，set 修改查询参数或者表单参数，setJson 修改 JSON
+func (p *point) build(set func(v url.Values), setJson func(m map[string]interface{})) (string, string) {
+    target, body := p.in.Url, p.in.RequestBody
+    switch p.location {
+    case locationQuery:
+        u := *p.in.ParseUrl
+        q := u.Query()
+        set(q)
+        u.RawQuery = q.Encode()
+        target = u.String()
+    case locationForm:
+        form, _ := url.ParseQuery(body)
+        set(form)
+        body = form.Encode()
+    case locationJson:
+        m := make(map[string]interface{})
+        json.Unmarshal([]byte(body), &m)
+        setJson(m)
+        b, _ := json.Marshal(m)
+        body = string(b)
+    }
+    return target, body
+}
+
+// value 替换为普通字符串
+func (p *point) value(v string) (string, string) {
+    return p.build(func(q url.Values) {
+        q.Set(p.name, v)
+    }, func(m map[string]interface{}) {
+        m[p.name] = v
+    })
+}
+
+// operator 替换为操作符对象
+func (p *point) operator(o operator) (string, string) {
+    return p.build(func(q url.Values) {
+        q.Del(p.name)
+        q.Set(p.name+"["+o.name+"]", o.value)
+    }, func(m map[string]interface{}) {
+        m[p.name] = map[string]interface{}{o.name: o.value}
+    })
+}
+
+// where 保留原始参数，添加顶层的 $where
+func (p *point) where(expr string) (string, string) {
+    return p.build(func(q url.Values) {
+        q.Set("$where", expr)
+    }, func(m map[string]interface{}) {
+        m["$where"] = expr
+    })
+}
+
+// describe 展示注入的形式
+func (p *point) describe(o operator) string {
+    if p.location == locationJson {
+        return `"` + p.name + `": ` + o.String()
+    }
+    return p.name + "[" + o.name + "]=" + o.value
+}
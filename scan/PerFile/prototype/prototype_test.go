@@ -0,0 +1,192 @@
+package prototype
+
+import (
+    "encoding/json"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/logging"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "reflect"
+    "strings"
+    "sync"
+    "testing"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "prototype", false)
+    conf.InitDefault()
+    os.Exit(m.Run())
+}
+
+func TestInject(t *testing.T) {
+    props := map[string]interface{}{"json spaces": jsonSpaces}
+    tests := []struct {
+        body   string
+        vector int
+        want   string
+    }{
+        {`{"name":"a"}`, 0, `{"name":"a","__proto__":{"json spaces":7}}`},
+        {` { "name" : "a" } `, 0, `{"name" : "a","__proto__":{"json spaces":7}}`},
+        {`{}`, 0, `{"__proto__":{"json spaces":7}}`},
+        {`{"name":"a"}`, 1, `{"name":"a","constructor":{"prototype":{"json spaces":7}}}`},
+    }
+    for _, tt := range tests {
+        got := inject(tt.body, vectors[tt.vector], props)
+        if got != tt.want {
+            t.Errorf("inject(%s, %s) = %s, want %s", tt.body, vectors[tt.vector].name, got, tt.want)
+        }
+        if !json.Valid([]byte(got)) {
+            t.Errorf("inject(%s) is not valid JSON: %s", tt.body, got)
+        }
+    }
+}
+
+func TestUtf7(t *testing.T) {
+    tests := map[string]string{
+        "a":        "+AGE-",
+        "abc":      "+AGEAYgBj-",
+        "jie12345": "+AGoAaQBlADEAMgAzADQANQ-",
+        "中":        "+Ti0-",
+    }
+    for s, want := range tests {
+        if got := utf7(s); got != want {
+            t.Errorf("utf7(%q) = %q, want %q", s, got, want)
+        }
+    }
+}
+
+func TestWithString(t *testing.T) {
+    tests := []struct {
+        body string
+        want string
+    }{
+        {`{"name":"a","id":1}`, `{"id":1,"name":"x"}`},
+        // 按 key 排序后替换第一个字符串
+        {`{"b":"1","a":"2"}`, `{"a":"x","b":"1"}`},
+        {`{"id":1,"ok":true}`, ""},
+        {`[1,2]`, ""},
+    }
+    for _, tt := range tests {
+        if got := withString(tt.body, "x"); got != tt.want {
+            t.Errorf("withString(%s) = %q, want %q", tt.body, got, tt.want)
+        }
+    }
+}
+
+func gadgetByName(t *testing.T, name string) gadget {
+    for _, g := range gadgets {
+        if g.name == name {
+            return g
+        }
+    }
+    t.Fatalf("gadget %q not found", name)
+    return gadget{}
+}
+
+func TestGadgets(t *testing.T) {
+    const marker = "jie12345"
+    header := func(k, v string) http.Header {
+        h := make(http.Header)
+        h.Set(k, v)
+        return h
+    }
+    tests := []struct {
+        gadget   string
+        res      *httpx.Response
+        ready    bool
+        polluted bool
+    }{
+        {"json spaces", &httpx.Response{Body: `{"id":1,"name":"a"}`}, true, false},
+        {"json spaces", &httpx.Response{Body: "{\n  \"id\": 1\n}"}, true, false},
+        {"json spaces", &httpx.Response{Body: "{\n       \"id\": 1\n}"}, false, true},
+        {"json spaces", &httpx.Response{Body: "<pre>\n       x</pre>"}, false, false},
+        {"exposed headers", &httpx.Response{Header: http.Header{}}, true, false},
+        {"exposed headers", &httpx.Response{Header: header("Access-Control-Expose-Headers", "X-Request-Id")}, true, false},
+        {"exposed headers", &httpx.Response{Header: header("Access-Control-Expose-Headers", "JIE12345")}, false, true},
+        {"status code override", &httpx.Response{StatusCode: 400}, true, false},
+        {"status code override", &httpx.Response{StatusCode: 200}, false, false},
+        {"status code override", &httpx.Response{StatusCode: pollutedStatus}, false, true},
+        {"charset override", &httpx.Response{Body: `{"name":"` + utf7(marker) + `"}`}, true, false},
+        {"charset override", &httpx.Response{Body: `{"name":"` + marker + `"}`}, false, true},
+        {"charset override", &httpx.Response{Body: `{"name":"a"}`}, false, false},
+    }
+    for _, tt := range tests {
+        g := gadgetByName(t, tt.gadget)
+        if got := g.ready(tt.res, marker); got != tt.ready {
+            t.Errorf("%s ready(%+v) = %v, want %v", tt.gadget, tt.res, got, tt.ready)
+        }
+        if got := g.polluted(tt.res, marker); got != tt.polluted {
+            t.Errorf("%s polluted(%+v) = %v, want %v", tt.gadget, tt.res, got, tt.polluted)
+        }
+    }
+}
+
+// 污染和恢复的属性名相同，恢复时才能覆盖污染的值
+func TestGadgetReset(t *testing.T) {
+    for _, g := range gadgets {
+        props := g.props("jie12345")
+        keys, reset := make([]string, 0), make([]string, 0)
+        for k := range props {
+            keys = append(keys, k)
+        }
+        for k := range g.reset {
+            reset = append(reset, k)
+        }
+        if !reflect.DeepEqual(keys, reset) {
+            t.Errorf("%s props %v, reset %v", g.name, keys, reset)
+        }
+    }
+}
+
+// 模拟 express: __proto__ 中的 json spaces 会影响之后所有的 JSON 响应
+func TestScan(t *testing.T) {
+    var (
+        lock   sync.Mutex
+        spaces int
+    )
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        data, _ := io.ReadAll(r.Body)
+        var obj map[string]map[string]interface{}
+        _ = json.Unmarshal(data, &obj)
+        lock.Lock()
+        defer lock.Unlock()
+        if v, ok := obj["__proto__"]["json spaces"].(float64); ok {
+            spaces = int(v)
+        }
+        w.Header().Set("Content-Type", "application/json")
+        res, _ := json.MarshalIndent(map[string]bool{"ok": true}, "", strings.Repeat(" ", spaces))
+        w.Write(res)
+    }))
+    defer server.Close()
+    
+    var found []output.VulMessage
+    client := httpx.NewClient(&httpx.Options{QPS: 10, Timeout: 5})
+    client.Sink = &output.Sink{OnFinding: func(msg output.VulMessage) { found = append(found, msg) }}
+    
+    u, _ := url.Parse(server.URL + "/api/user")
+    (&Plugin{}).Scan(server.URL, u.Path, &input.CrawlResult{
+        Url:         u.String(),
+        ParseUrl:    u,
+        Host:        u.Host,
+        Method:      "POST",
+        ContentType: "application/json",
+        RequestBody: `{"name":"a"}`,
+        Headers:     map[string]string{"Content-Type": "application/json"},
+        UniqueId:    "prototype",
+    }, client)
+    
+    if len(found) != 1 || found[0].VulnData.Param != "__proto__" || !strings.Contains(found[0].VulnData.Description, "json spaces") {
+        t.Fatalf("found = %+v", found)
+    }
+    lock.Lock()
+    defer lock.Unlock()
+    if spaces != 0 {
+        t.Errorf("json spaces is not reset: %d", spaces)
+    }
+}
@@ -0,0 +1,53 @@
+package redirect
+
+import (
+    "context"
+    "errors"
+    "github.com/chromedp/cdproto/network"
+    "github.com/chromedp/chromedp"
+    "github.com/yhy0/Jie/crawler"
+    "sync/atomic"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/22
+   @desc 使用爬虫的浏览器打开页面，监听是否向 attacker 发起了导航请求，确认 DOM 跳转
+**/
+
+var errNoBrowser = errors.New("browser not started")
+
+// headless 在浏览器中打开 u，页面跳转到 attacker 时返回 true，没有启动浏览器时返回 errNoBrowser
+func headless(u, attacker string) (bool, error) {
+    if crawler.Browser == nil {
+        return false, errNoBrowser
+    }
+    
+    ctx, cancel := crawler.Browser.NewTab(15 * time.Second)
+    defer cancel()
+    
+    var navigated atomic.Bool
+    chromedp.ListenTarget(ctx, func(ev interface{}) {
+        if e, ok := ev.(*network.EventRequestWillBeSent); ok && e.Type == network.ResourceTypeDocument {
+            // 和 HTTP 跳转使用同样的判断，攻击者域名的子域名同样算
+            if redirectsTo(u, e.Request.URL, attacker) {
+                navigated.Store(true)
+            }
+        }
+    })
+    
+    err := chromedp.Run(ctx,
+        network.Enable(),
+        chromedp.ActionFunc(func(ctx context.Context) error {
+            // 跳转的目标无法访问时导航会报错，这里忽略错误，只关心是否发起了请求
+            chromedp.Navigate(u).Do(ctx)
+            return nil
+        }),
+        chromedp.Sleep(3*time.Second),
+    )
+    if err != nil && !navigated.Load() {
+        return false, err
+    }
+    return navigated.Load(), nil
+}
@@ -0,0 +1,334 @@
+package redirect
+
+import (
+    "fmt"
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/22
+   @desc 开放重定向检测，参数、路径、请求头
+        1. 参数名像跳转参数，或者参数值出现在 Location、meta refresh、js location 中的参数作为候选
+        2. 注入一系列绕过 payload，解析 Location 确认为服务端跳转；跳转在页面中(meta refresh、js)时，有浏览器的情况下使用无头浏览器确认为 DOM 跳转
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+    SeenHosts    sync.Map
+}
+
+// 常见的跳转参数名
+var redirectParams = []string{
+    "redirect", "redirect_uri", "redirect_url", "redirecturl", "redirecturi", "redirect_to", "redirectto", "redir", "rurl",
+    "url", "uri", "u", "r", "next", "nexturl", "return", "return_to", "returnto", "returnurl", "return_url", "returnuri",
+    "goto", "go", "target", "dest", "destination", "continue", "forward", "jump", "jumpto", "jump_url", "link", "location",
+    "out", "to", "view", "service", "callback", "callbackurl", "success", "successurl", "back", "backurl", "from", "fromurl",
+    "ref", "referer", "referrer", "login_url", "logout", "checkout_url", "image_url",
+}
+
+var (
+    metaRefreshRegex = regexp.MustCompile(`(?i)<meta[^>]+http-equiv\s*=\s*["']?refresh["']?[^>]*content\s*=\s*["']?\s*\d*\s*;?\s*url\s*=\s*([^"'>\s]+)`)
+    jsLocationRegex  = regexp.MustCompile(`(?i)(?:window\.|document\.|top\.|self\.)?location(?:\.href)?\s*=\s*["']([^"']+)["']|location\.(?:replace|assign)\(\s*["']([^"']+)["']`)
+)
+
+// finding 确认的跳转
+type finding struct {
+    kind     string // header、DOM
+    param    string
+    payload  string
+    location string
+    verified bool // 无头浏览器中确认发生了跳转
+    response *httpx.Response
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    if in.ParseUrl == nil {
+        return
+    }
+    
+    attacker := util.RandomLowLetterNumber(8) + ".com"
+    host := in.ParseUrl.Hostname()
+    
+    if f := p.params(in, attacker, host, client); f != nil {
+        report(in, f, client)
+    }
+    
+    // 路径、请求头每个网站只检测一次
+    if _, ok := p.SeenHosts.LoadOrStore(in.Host, true); ok {
+        return
+    }
+    base := in.ParseUrl.Scheme + "://" + in.ParseUrl.Host
+    if f := paths(base, attacker, client); f != nil {
+        report(in, f, client)
+    }
+    if f := headers(in, attacker, client); f != nil {
+        report(in, f, client)
+    }
+}
+
+// params 检测参数中的跳转
+func (p *Plugin) params(in *input.CrawlResult, attacker, host string, client *httpx.Client) *finding {
+    variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), in.Method, in.ContentType, in.Headers)
+    if err != nil || variations == nil {
+        return nil
+    }
+    
+    var original []string
+    if in.Resp != nil {
+        original = destinations(in.Resp)
+    }
+    
+    for i, param := range variations.Params {
+        if param.IsFile || !candidate(param.Name, param.Value, original) {
+            continue
+        }
+        for _, payload := range payloads(attacker, host) {
+            request := variations.SetPayloadByIndex(i, in.Url, payload, in.Method)
+            if request == "" {
+                continue
+            }
+            
+            var res *httpx.Response
+            if in.Method == "GET" {
+                res, err = client.Request(request, in.Method, "", in.Headers)
+            } else {
+                res, err = client.Request(in.Url, in.Method, request, in.Headers)
+            }
+            if err != nil {
+                logging.Logger.Debugln("[redirect]", in.Url, err)
+                continue
+            }
+            
+            if f := confirm(res, in.Url, attacker); f != nil {
+                // 页面中的跳转，使用浏览器确认，GET 请求才能直接在浏览器中打开，没有浏览器时只根据页面内容判断
+                if f.kind == "DOM" && in.Method == "GET" {
+                    navigated, err := headless(request, attacker)
+                    if err == nil && !navigated {
+                        continue
+                    }
+                    f.verified = navigated
+                }
+                f.param = param.Name
+                f.payload = payload
+                return f
+            }
+        }
+    }
+    return nil
+}
+
+// candidate 参数名像跳转参数，或者参数值出现在原始响应的跳转地址中
+func candidate(name, value string, original []string) bool {
+    if util.InCaseFoldSlice(redirectParams, name) {
+        return true
+    }
+    value, _ = url.QueryUnescape(value)
+    if strings.HasPrefix(value, "http") || strings.HasPrefix(value, "//") || strings.HasPrefix(value, "/") {
+        return true
+    }
+    if len(value) < 3 {
+        return false
+    }
+    for _, d := range original {
+        if strings.Contains(d, value) {
+            return true
+        }
+    }
+    return false
+}
+
+// payloads 跳转绕过 payload，host 为目标域名，用于白名单的前缀、后缀混淆
+func payloads(attacker, host string) []string {
+    return []string{
+        "https://" + attacker,
+        "//" + attacker,
+        "///" + attacker,
+        "/\\" + attacker,
+        "\\\\" + attacker,
+        "/%09/" + attacker,
+        "https:/\\" + attacker,
+        "HtTpS://" + attacker,
+        "https:%2F%2F" + attacker,
+        "%2F%2F" + attacker,
+        "https://" + host + "@" + attacker,
+        "https://" + attacker + "#@" + host,
+        "https://" + attacker + "?" + host,
+        "https://" + attacker + "/" + host,
+        "https://" + host + "." + attacker,
+        "https://" + attacker + "%23." + host,
+        "https://" + attacker + "\\." + host,
+    }
+}
+
+// paths 路径中的跳转，常见于对路径做规范化后跳转的服务
+func paths(base, attacker string, client *httpx.Client) *finding {
+    for _, p := range []string{"//" + attacker + "/%2e%2e", "/%2f%2f" + attacker, "/%5c" + attacker, "//" + attacker + "/", "/\\" + attacker} {
+        res, err := client.Request(base+p, "GET", "", nil)
+        if err != nil {
+            continue
+        }
+        if f := confirm(res, base+p, attacker); f != nil && f.kind == "header" {
+            f.param = "path"
+            f.payload = p
+            return f
+        }
+    }
+    return nil
+}
+
+// headers 请求头中的跳转，X-Forwarded-Host 等被用于生成跳转地址
+func headers(in *input.CrawlResult, attacker string, client *httpx.Client) *finding {
+    for _, name := range []string{"X-Forwarded-Host", "X-Host", "X-Forwarded-Server", "X-Original-Host"} {
+        header := make(map[string]string)
+        for k, v := range in.Headers {
+            header[k] = v
+        }
+        header[name] = attacker
+        
+        res, err := client.Request(in.Url, in.Method, in.RequestBody, header)
+        if err != nil {
+            continue
+        }
+        if f := confirm(res, in.Url, attacker); f != nil && f.kind == "header" {
+            f.param = name
+            f.payload = name + ": " + attacker
+            return f
+        }
+    }
+    return nil
+}
+
+// confirm 解析响应中的跳转地址，跳转到 attacker 时返回结果
+func confirm(res *httpx.Response, base, attacker string) *finding {
+    if res.StatusCode >= 300 && res.StatusCode < 400 {
+        if loc := res.Header.Get("Location"); redirectsTo(base, loc, attacker) {
+            return &finding{kind: "header", location: loc, response: res}
+        }
+    }
+    if refresh := res.Header.Get("Refresh"); refresh != "" {
+        if _, loc, ok := strings.Cut(strings.ToLower(refresh), "url="); ok && redirectsTo(base, loc, attacker) {
+            return &finding{kind: "header", location: refresh, response: res}
+        }
+    }
+    
+    for _, d := range bodyDestinations(res.Body) {
+        if redirectsTo(base, d, attacker) {
+            return &finding{kind: "DOM", location: d, response: res}
+        }
+    }
+    return nil
+}
+
+// destinations 响应中所有的跳转地址
+func destinations(res *httpx.Response) []string {
+    var result []string
+    if loc := res.Header.Get("Location"); loc != "" {
+        result = append(result, loc)
+    }
+    return append(result, bodyDestinations(res.Body)...)
+}
+
+// bodyDestinations 页面中 meta refresh、js location 的跳转地址
+func bodyDestinations(body string) []string {
+    var result []string
+    for _, m := range metaRefreshRegex.FindAllStringSubmatch(body, -1) {
+        result = append(result, m[1])
+    }
+    for _, m := range jsLocationRegex.FindAllStringSubmatch(body, -1) {
+        if m[1] != "" {
+            result = append(result, m[1])
+        } else {
+            result = append(result, m[2])
+        }
+    }
+    return result
+}
+
+// redirectsTo 按照浏览器的方式解析跳转地址，判断最终的域名是否为 attacker
+func redirectsTo(base, location, attacker string) bool {
+    if location == "" {
+        return false
+    }
+    // 浏览器会把 \ 当作 /，并且忽略 tab、换行
+    location = strings.NewReplacer("\\", "/", "\t", "", "\r", "", "\n", "").Replace(strings.TrimSpace(location))
+    if unescaped, err := url.PathUnescape(location); err == nil && strings.HasPrefix(strings.ToLower(unescaped), "http") {
+        location = unescaped
+    }
+    
+    b, err := url.Parse(base)
+    if err != nil {
+        return false
+    }
+    l, err := b.Parse(location)
+    if err != nil {
+        return false
+    }
+    // 跳转到攻击者域名的子域名同样可以利用
+    host := strings.ToLower(l.Hostname())
+    attacker = strings.ToLower(attacker)
+    return host == attacker || strings.HasSuffix(host, "."+attacker)
+}
+
+func report(in *input.CrawlResult, f *finding, client *httpx.Client) {
+    description := fmt.Sprintf("Server-side redirect: the Location header points to the injected domain (%s).", f.location)
+    level := output.Low
+    if f.kind == "DOM" {
+        description = fmt.Sprintf("DOM-based redirect: the page navigates to the injected domain via meta refresh or JavaScript (%s).", f.location)
+        if f.verified {
+            description += " Confirmed by navigation in a headless browser."
+        }
+        // 页面中的跳转通常可以使用 javascript: 伪协议进一步利用为 xss
+        level = output.Medium
+    }
+    
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "Open Redirect",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    f.kind,
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Param:       f.param,
+            Payload:     f.payload,
+            Request:     f.response.RequestDump,
+            Response:    f.response.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "redirect"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
@@ -0,0 +1,27 @@
+package redirect
+
+import (
+    "testing"
+)
+
+func TestRedirectsTo(t *testing.T) {
+    tests := []struct {
+        location string
+        want     bool
+    }{
+        {"https://evil.com/", true},
+        {"//EVIL.com", true},
+        {"https://www.example.com.evil.com/", true},
+        {"https:%2f%2fevil.com", true},
+        {"/\\evil.com", true},
+        {"https://notevil.com/", false},
+        {"https://evil.com.example.com/", false},
+        {"/login?next=evil.com", false},
+        {"", false},
+    }
+    for _, tt := range tests {
+        if got := redirectsTo("https://www.example.com/a", tt.location, "evil.com"); got != tt.want {
+            t.Errorf("redirectsTo(%q) = %v, want %v", tt.location, got, tt.want)
+        }
+    }
+}
@@ -42,7 +42,7 @@ func (sql *Sqlmap) checkBoolBased(pos int, closeType string) bool {
             }
 
             // 每种检测方式都加上这个报错检测
-            sql.DBMS = checkDBMSError(sql.Url, param.Name, payload, res)
+            sql.DBMS = checkDBMSError(sql.Url, param.Name, payload, res, sql.Client)
             if sql.DBMS != "" {
                 return true
             }
@@ -240,7 +240,7 @@ func (sql *Sqlmap) checkBoolBased(pos int, closeType string) bool {
             //    continue
             // }
 
-            JieOutput.OutChannel <- JieOutput.VulMessage{
+            sql.Client.Report(JieOutput.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "SQL Injection",
                 VulnData: JieOutput.VulnData{
@@ -254,7 +254,7 @@ func (sql *Sqlmap) checkBoolBased(pos int, closeType string) bool {
                     Description: fmt.Sprintf("Bool-Based SQL Injection: [%v:%v]", param.Name, param.Value),
                 },
                 Level: JieOutput.Critical,
-            }
+            })
             // 至此，误报检测完成，确定存在注入
             logging.Logger.Infof("%s 存在基于布尔的 SQL 注入: [参数名:%v]", sql.Url, param.Name)
             return true
@@ -6,6 +6,7 @@ import (
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/pkg/util"
     "github.com/yhy0/logging"
+    "strings"
     "time"
 )
 
@@ -15,10 +16,10 @@ import (
   @desc: 基于报错注入, 只是简单验证了是否存在报错信息
 **/
 
-func checkDBMSError(url, param, payload string, res *httpx.Response) string {
+func checkDBMSError(url, param, payload string, res *httpx.Response, client *httpx.Client) string {
     for DBMS, regexps := range DbmsErrors {
         if math, err := util.MatchAnyOfRegexp(regexps, res.ResponseDump); math {
-            JieOutput.OutChannel <- JieOutput.VulMessage{
+            client.Report(JieOutput.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "SQL Injection",
                 VulnData: JieOutput.VulnData{
@@ -32,7 +33,7 @@ func checkDBMSError(url, param, payload string, res *httpx.Response) string {
                     Description: fmt.Sprintf("ERROR-Based SQL Injection: [%v] Guess DBMS: %v", param, DBMS),
                 },
                 Level: JieOutput.Critical,
-            }
+            })
 
             logging.Logger.Infof("%s %s 检测到数据库报错信息[%s:%s]", url, param, DBMS, err)
 
@@ -41,3 +42,30 @@ func checkDBMSError(url, param, payload string, res *httpx.Response) string {
     }
     return ""
 }
+
+// 标记前后多少字节内的数据库报错认为是标记引起的
+const errorWindow = 300
+
+// dbmsError 输出存储的标记的页面中，标记附近出现数据库报错，说明存储的值被拼接到了 sql 语句中
+// 数据库报错一般会带上出错位置附近的语句(near '...')，页面中其他位置本来就有的报错不算
+func dbmsError(response, token string) bool {
+    for i := 0; ; {
+        j := strings.Index(response[i:], token)
+        if j < 0 {
+            return false
+        }
+        start, end := i+j-errorWindow, i+j+len(token)+errorWindow
+        if start < 0 {
+            start = 0
+        }
+        if end > len(response) {
+            end = len(response)
+        }
+        for _, regexps := range DbmsErrors {
+            if match, _ := util.MatchAnyOfRegexp(regexps, response[start:end]); match {
+                return true
+            }
+        }
+        i += j + len(token)
+    }
+}
@@ -4,9 +4,11 @@ import (
     "fmt"
     "github.com/thoas/go-funk"
     regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
     JieOutput "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/gadget/tracker"
     "github.com/yhy0/logging"
     "strings"
     "time"
@@ -45,7 +47,9 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
         }
         
         if flag {
-            payload := sql.Variations.SetPayloadByIndex(p.Index, sql.Url, p.Value+randomTestString, sql.Method)
+            // 带上唯一标记，存储后在其他页面引起报错时由 tracker 确认二阶注入
+            token := tracker.Token()
+            payload := sql.Variations.SetPayloadByIndex(p.Index, sql.Url, p.Value+token+randomTestString, sql.Method)
             if payload == "" {
                 continue
             }
@@ -65,6 +69,19 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
                 continue
             }
             
+            tracker.Register(token, tracker.Origin{
+                Plugin:     "SQL Injection",
+                Kind:       "second-order sql injection",
+                Level:      JieOutput.High,
+                Url:        sql.Url,
+                Method:     sql.Method,
+                Param:      p.Name,
+                Payload:    p.Value + token + randomTestString,
+                Request:    res.RequestDump,
+                Sink:       sql.Client.Sink,
+                Executable: dbmsError,
+            })
+            
             for _, value := range FormatExceptionStrings {
                 if funk.Contains(res.Body, value) {
                     cast = true
@@ -79,7 +96,7 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
             }
             
             // 这里出现 sql 报错信息则直接认为存在注入点，直接返回，后续不进行，减少流量。 验证交给人工/sql, 误报应该不多吧？
-            sql.DBMS = checkDBMSError(sql.Url, p.Name, payload, res)
+            sql.DBMS = checkDBMSError(sql.Url, p.Name, payload, res, sql.Client)
             if sql.DBMS != "" {
                 errInject = true
                 return
@@ -101,7 +118,7 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
                 }
                 
                 time.Sleep(time.Millisecond * 500)
-                sql.DBMS = checkDBMSError(sql.Url, p.Name, payload, res)
+                sql.DBMS = checkDBMSError(sql.Url, p.Name, payload, res, sql.Client)
                 if sql.DBMS != "" {
                     errInject = true
                     return
@@ -130,7 +147,7 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
             }
             
             if funk.Contains(res.Body, value) {
-                JieOutput.OutChannel <- JieOutput.VulMessage{
+                sql.Client.Report(JieOutput.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "XSS",
                     VulnData: JieOutput.VulnData{
@@ -144,7 +161,7 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
                         Response:   res.ResponseDump,
                     },
                     Level: JieOutput.Medium,
-                }
+                })
             }
             
             // 检测文件包含
@@ -153,7 +170,7 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
             
             for _, match := range matches {
                 if strings.Contains(strings.ToLower(match[0]), strings.ToLower(randStr1)) {
-                    JieOutput.OutChannel <- JieOutput.VulMessage{
+                    sql.Client.Report(JieOutput.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "FileInclude",
                         VulnData: JieOutput.VulnData{
@@ -167,7 +184,7 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
                             Response:   res.ResponseDump,
                         },
                         Level: JieOutput.Critical,
-                    }
+                    })
                     break
                 }
             }
@@ -186,19 +203,20 @@ func (sql *Sqlmap) HeuristicCheckSqlInjection() {
 }
 
 func (sql *Sqlmap) checkSqlInjection(pos int) {
-    for _, closeType := range CloseType {
+    for _, closeType := range sql.getCloseType() {
         if sql.checkUnionBased(pos, closeType) {
             return
         }
     }
     
-    for _, closeType := range CloseType {
+    for _, closeType := range sql.getCloseType() {
         if sql.checkBoolBased(pos, closeType) {
             return
         }
     }
     
-    if sql.checkTimeBasedBlind(pos) {
+    // quick 策略下不进行耗时的时间盲注检测
+    if sql.Client.Scope.PayloadLevel() > conf.PayloadQuick && sql.checkTimeBasedBlind(pos) {
         return
     }
 }
@@ -6,6 +6,7 @@ import (
     "fmt"
     "github.com/antlabs/strsim"
     "github.com/beevik/etree"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/pkg/util"
@@ -39,6 +40,9 @@ var (
     
     CloseType = map[int]string{0: `'`, 1: `"`, 2: ``, 3: `')`, 4: `")`}
     
+    // DeepCloseType deep 策略下额外尝试的闭合方式
+    DeepCloseType = map[int]string{5: `'))`, 6: `"))`, 7: "`", 8: "`)", 9: `')))`}
+    
     // CloseType = map[int]string{0: `'`}
     
     // FormatExceptionStrings 用于检测格式错误的字符串
@@ -207,28 +211,20 @@ func (p *Plugin) Name() string {
     return "sql"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
 // check 检测动态页面，参数
 func check(sql *Sqlmap) bool {
-    res, err := sql.Client.Request(sql.Url, sql.Method, sql.RequestBody, sql.Headers)
-    
-    if err != nil {
+    if !sql.template() {
         return false
     }
     
-    if len(res.Body) < MaxDifflibSequenceLength && len(sql.OriginalBody) < MaxDifflibSequenceLength {
-        // todo 没有经过大量测试，有待优化
-        sim := strsim.Compare(res.Body, sql.OriginalBody)
-        if sim < SimilarityRatio {
-            logging.Logger.Debugln(sql.Url, " 检测到动态页面, 相似度为：", sim)
-            prefix, suffix := findDynamicContent(sql.OriginalBody, res.Body)
-            sql.DynamicMarkings["prefix"] = prefix
-            sql.DynamicMarkings["suffix"] = suffix
-            
-            // 去除请求页面的动态内容，设置模板页面
-            sql.TemplateBody = sql.removeDynamicContent(sql.OriginalBody)
-        }
-    }
-    
+    var (
+        res *httpx.Response
+        err error
+    )
     // 动态参数检测
     for _, p := range sql.Variations.Params {
         payload := sql.Variations.SetPayloadByIndex(p.Index, sql.Url, strconv.Itoa(util.RandomNumber(0, 9999)), sql.Method)
@@ -258,3 +254,21 @@ func check(sql *Sqlmap) bool {
     
     return true
 }
+
+// getCloseType 根据扫描策略中的 payload 深度获取要尝试的闭合方式
+func (sql *Sqlmap) getCloseType() map[int]string {
+    switch sql.Client.Scope.PayloadLevel() {
+    case conf.PayloadQuick:
+        return map[int]string{0: CloseType[0], 1: CloseType[1]}
+    case conf.PayloadDeep:
+        closeType := make(map[int]string)
+        for k, v := range CloseType {
+            closeType[k] = v
+        }
+        for k, v := range DeepCloseType {
+            closeType[k] = v
+        }
+        return closeType
+    }
+    return CloseType
+}
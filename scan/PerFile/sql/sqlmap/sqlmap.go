@@ -54,7 +54,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
     logging.Logger.Debugln("Sqlmap Scan started for target:", in.Url, taskID)
     
     // 监控任务状态
-    go getTaskStatus(taskID, in.Url)
+    go getTaskStatus(taskID, in.Url, client.Sink)
 }
 
 func (p *Plugin) IsScanned(key string) bool {
@@ -72,6 +72,10 @@ func (p *Plugin) Name() string {
     return "sqlmapApi"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
 var client = &http.Client{}
 
 func createTask() string {
@@ -129,7 +133,7 @@ func startScan(taskID string, in *input.CrawlResult) bool {
     return result.Success
 }
 
-func getTaskStatus(taskID string, target string) {
+func getTaskStatus(taskID string, target string, sink *JieOutput.Sink) {
     statusURL := fmt.Sprintf("/scan/%s/status", taskID)
     resultURL := fmt.Sprintf("/scan/%s/data", taskID)
     for {
@@ -174,7 +178,7 @@ func getTaskStatus(taskID string, target string) {
                         for _, iValue := range injectTypes {
                             injectTitle := iValue.(map[string]interface{})["title"].(string)
                             injectPayload := iValue.(map[string]interface{})["payload"].(string)
-                            JieOutput.OutChannel <- JieOutput.VulMessage{
+                            sink.Report(JieOutput.VulMessage{
                                 DataType: "web_vul",
                                 Plugin:   "SQL Injection",
                                 VulnData: JieOutput.VulnData{
@@ -185,7 +189,7 @@ func getTaskStatus(taskID string, target string) {
                                     Description: injectTitle,
                                 },
                                 Level: JieOutput.Critical,
-                            }
+                            })
                             logging.Logger.Infof("Sqlmap 检测到%s %s 参数存在 sql 注入[%s:%s]", target, param, injectTitle, injectPayload)
                             return
                         }
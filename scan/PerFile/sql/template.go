@@ -0,0 +1,76 @@
+package sql
+
+import (
+    "github.com/antlabs/strsim"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/logging"
+)
+
+/**
+   @author yhy
+   @since 2024/6/25
+   @desc 页面模板、相似度比较，nosql 等其他注入插件也使用这里的方法判断页面是否相同
+**/
+
+// template 重新请求一次原始链接，页面不同时找出动态内容，设置去除动态内容后的模板页面
+func (sql *Sqlmap) template() bool {
+    res, err := sql.Client.Request(sql.Url, sql.Method, sql.RequestBody, sql.Headers)
+    
+    if err != nil {
+        return false
+    }
+    
+    if len(res.Body) < MaxDifflibSequenceLength && len(sql.OriginalBody) < MaxDifflibSequenceLength {
+        // todo 没有经过大量测试，有待优化
+        sim := strsim.Compare(res.Body, sql.OriginalBody)
+        if sim < SimilarityRatio {
+            logging.Logger.Debugln(sql.Url, " 检测到动态页面, 相似度为：", sim)
+            prefix, suffix := findDynamicContent(sql.OriginalBody, res.Body)
+            sql.DynamicMarkings["prefix"] = prefix
+            sql.DynamicMarkings["suffix"] = suffix
+            
+            // 去除请求页面的动态内容，设置模板页面
+            sql.TemplateBody = sql.removeDynamicContent(sql.OriginalBody)
+        }
+    }
+    return true
+}
+
+// NewTemplate 根据原始请求创建页面模板，用于后续比较注入 payload 的响应和原始页面是否相同
+func NewTemplate(in *input.CrawlResult, client *httpx.Client) *Sqlmap {
+    if in.Resp == nil {
+        return nil
+    }
+    sql := &Sqlmap{
+        Url:          in.Url,
+        OriginalBody: in.Resp.Body,
+        Method:       in.Method,
+        Client:       client,
+        Headers:      in.Headers,
+        ContentType:  in.ContentType,
+        RequestBody:  in.RequestBody,
+        TemplateCode: in.Resp.StatusCode,
+        TemplateBody: in.Resp.Body,
+        DynamicMarkings: map[string]string{
+            "prefix": "",
+            "suffix": "",
+        },
+    }
+    if !sql.template() {
+        return nil
+    }
+    return sql
+}
+
+// Similar 响应和模板页面是否相同，状态码不同时认为不同
+func (sql *Sqlmap) Similar(res *httpx.Response) bool {
+    similar, _ := sql.comparison(res.Body, res.StatusCode, -1)
+    return similar
+}
+
+// NormalRespondTime 正常请求的响应时间上限，单位毫秒
+func (sql *Sqlmap) NormalRespondTime() (float64, error) {
+    err, t := getNormalRespondTime(sql)
+    return t, err
+}
@@ -23,7 +23,7 @@ func (sql *Sqlmap) checkTimeBasedBlind(pos int) bool {
     }
     logging.Logger.Debugf("%s 网站的正常响应时间应小于: %v ms", sql.Url, standardRespTime)
 
-    for _, closeType := range CloseType {
+    for _, closeType := range sql.getCloseType() {
         payload := fmt.Sprintf(`%v/**/And/**/SleeP(%v)#`, closeType, standardRespTime*2/1000+3)
 
         for index, param := range sql.Variations.Params {
@@ -44,7 +44,7 @@ func (sql *Sqlmap) checkTimeBasedBlind(pos int) bool {
                 }
 
                 if res.ServerDurationMs > standardRespTime+2000 {
-                    JieOutput.OutChannel <- JieOutput.VulMessage{
+                    sql.Client.Report(JieOutput.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "SQL Injection",
                         VulnData: JieOutput.VulnData{
@@ -58,7 +58,7 @@ func (sql *Sqlmap) checkTimeBasedBlind(pos int) bool {
                             Description: fmt.Sprintf("Time-Based Blind SQL Injection: [%v:%v]", param.Name, param.Value),
                         },
                         Level: JieOutput.Critical,
-                    }
+                    })
                     logging.Logger.Debugf("存在基于时间的 SQL 注入: [参数名:%v 原值:%v]", param.Name, param.Value)
 
                     return true
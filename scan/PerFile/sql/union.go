@@ -56,7 +56,7 @@ func (sql *Sqlmap) guessColumnNum(pos int, closeType string) int {
         for index, param := range sql.Variations.Params {
             if index == pos {
                 payload = param.Value + closeType + `/**/ORDeR/**/bY/**/` + strconv.Itoa(columnNum) + "#"
-                JieOutput.OutChannel <- JieOutput.VulMessage{
+                sql.Client.Report(JieOutput.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "SQL Injection",
                     VulnData: JieOutput.VulnData{
@@ -70,68 +70,13 @@ func (sql *Sqlmap) guessColumnNum(pos int, closeType string) int {
                         Description: fmt.Sprintf("Union-Based SQL Injection: [%v:%v]", param.Name, param.Value),
                     },
                     Level: JieOutput.Critical,
-                }
+                })
                 logging.Logger.Errorln("request", resp.RequestDump)
                 logging.Logger.Errorln("response", resp.ResponseDump)
                 logging.Logger.Debugln("UNION 列数经过ORDER BY 探测为 ", columnNum)
                 return columnNum
             }
         }
-        // for !found {
-        //    condition_volatile, defaultRatio_tmp, _ := sql.orderByTest(highCols, pos, closeType, defaultRatio)
-        //    defaultRatio = defaultRatio_tmp
-        //    if condition_volatile {
-        //        lowCols = highCols
-        //        highCols += OrderByStep
-        //        if highCols > OrderByMax {
-        //            break
-        //        }
-        //    } else {
-        //        var res *httpx.Response
-        //        for !found {
-        //            var condition_volatile_sec bool
-        //            mid := highCols - int(math.Round(float64((highCols-lowCols)/2)))
-        //
-        //            condition_volatile_sec, defaultRatio_tmp, res = sql.orderByTest(mid, pos, closeType, defaultRatio)
-        //
-        //            defaultRatio = defaultRatio_tmp
-        //            if condition_volatile_sec {
-        //                lowCols = mid
-        //            } else {
-        //                highCols = mid
-        //            }
-        //
-        //            if (highCols - lowCols) < 2 {
-        //                columnNum = lowCols
-        //                found = true
-        //            }
-        //        }
-        //
-        //        for index, param := range sql.Variations.Params {
-        //            if index == pos {
-        //                payload = param.Value + closeType + `/**/ORDeR/**/bY/**/` + strconv.Itoa(columnNum) + "#"
-        //                JieOutput.OutChannel <- JieOutput.VulMessage{
-        //                    DataType: "web_vul",
-        //                    Plugin:   "SQL Injection",
-        //                    VulnData: JieOutput.VulnData{
-        //                        CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
-        //                        Target:      sql.Url,
-        //                        Ip:          "",
-        //                        Param:       param.Name,
-        //                        Request:     res.RequestDump,
-        //                        Response:    res.ResponseDump,
-        //                        Payload:     payload,
-        //                        Description: fmt.Sprintf("Union-Based SQL Injection: [%v:%v]", param.Name, param.Value),
-        //                    },
-        //                    Level: JieOutput.Critical,
-        //                }
-        //                logging.Logger.Debugln("UNION 列数经过ORDER BY 探测为 ", columnNum)
-        //                return columnNum
-        //            }
-        //        }
-        //
-        //    }
-        // }
     }
     return -1
 }
@@ -253,7 +198,7 @@ func (sql *Sqlmap) bruteColumnNum(pos int, closeType string) int {
 
                 md5CheckVal := util.MD5(md5Randstr)
                 if funk.Contains(res.ResponseDump, md5CheckVal) {
-                    JieOutput.OutChannel <- JieOutput.VulMessage{
+                    sql.Client.Report(JieOutput.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "SQL Injection",
                         VulnData: JieOutput.VulnData{
@@ -267,7 +212,7 @@ func (sql *Sqlmap) bruteColumnNum(pos int, closeType string) int {
                             Description: fmt.Sprintf("UNION SQL Injection: [%v:%v]", param.Name, param.Value),
                         },
                         Level: JieOutput.Critical,
-                    }
+                    })
 
                     logging.Logger.Debugln("UNION 列数经过ORDER BY 探测为 ", columnNum)
                     return columnNum
@@ -296,7 +241,7 @@ func (sql *Sqlmap) bruteColumnNum(pos int, closeType string) int {
                 }
 
                 if res.ServerDurationMs > standardRespTime*2+1000 {
-                    JieOutput.OutChannel <- JieOutput.VulMessage{
+                    sql.Client.Report(JieOutput.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "SQL Injection",
                         VulnData: JieOutput.VulnData{
@@ -310,7 +255,7 @@ func (sql *Sqlmap) bruteColumnNum(pos int, closeType string) int {
                             Description: fmt.Sprintf("UNION SQL Injection: [%v:%v]", param.Name, param.Value),
                         },
                         Level: JieOutput.Critical,
-                    }
+                    })
                     logging.Logger.Debugln(sql.Url, "UNION 列数经过UNION BruteForce sleep探测为 ", i)
                     return i
                 } else {
@@ -347,7 +292,7 @@ func (sql *Sqlmap) orderByTest(number, pos int, closeType string, defaultRatio f
             }
 
             // 每种检测方式都加上这个报错检测
-            sql.DBMS = checkDBMSError(sql.Url, param.Name, payload, res)
+            sql.DBMS = checkDBMSError(sql.Url, param.Name, payload, res, sql.Client)
             if sql.DBMS != "" {
                 return false, 0, nil
             }
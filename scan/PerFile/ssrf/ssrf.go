@@ -101,6 +101,10 @@ func (p *Plugin) Name() string {
     return "ssrf"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
 // ssrf
 func ssrf(in *input.CrawlResult, variations *httpx.Variations, payload string, dnslog *reverse.Dig, client *httpx.Client) bool {
     for _, p := range variations.Params {
@@ -137,7 +141,7 @@ func ssrf(in *input.CrawlResult, variations *httpx.Variations, payload string, d
         }
 
         if isVul {
-            output.OutChannel <- output.VulMessage{
+            client.Report(output.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "SSRF",
                 VulnData: output.VulnData{
@@ -152,7 +156,7 @@ func ssrf(in *input.CrawlResult, variations *httpx.Variations, payload string, d
                     Description: desc,
                 },
                 Level: output.Critical,
-            }
+            })
             return true
         }
     }
@@ -186,7 +190,7 @@ func readFile(in *input.CrawlResult, variations *httpx.Variations, payloads []st
             }
 
             if funk.Contains(res.Body, "root:x:0:0:root:/root:") || funk.Contains(res.Body, "root:[x*]:0:0:") || funk.Contains(res.Body, "; for 16-bit app support") {
-                output.OutChannel <- output.VulMessage{
+                client.Report(output.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "READ-FILE",
                     VulnData: output.VulnData{
@@ -200,7 +204,7 @@ func readFile(in *input.CrawlResult, variations *httpx.Variations, payloads []st
                         Payload:    payload,
                     },
                     Level: output.Critical,
-                }
+                })
                 return true
             }
         }
@@ -249,7 +253,7 @@ func header(in *input.CrawlResult, dnslog *reverse.Dig, client *httpx.Client) bo
     isVul := reverse.PullLogs(dnslog)
 
     if isVul {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "SSRF",
             VulnData: output.VulnData{
@@ -263,7 +267,7 @@ func header(in *input.CrawlResult, dnslog *reverse.Dig, client *httpx.Client) bo
                 Payload:    "token: " + dnslog.Token + " msg: " + dnslog.Msg,
             },
             Level: output.Critical,
-        }
+        })
         return true
     }
     return false
@@ -0,0 +1,148 @@
+package ssti
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/23
+   @desc 模板引擎的注入语法和识别规则，参考 https://github.com/epinna/tplmap
+        先用各个语法的算术表达式确认注入点，再按照对应语法的决策树执行引擎特有的表达式识别具体的引擎
+**/
+
+// syntax 一种模板语法，wrap 把表达式包装成这种语法
+type syntax struct {
+    name        string
+    wrap        func(expr string) string
+    engines     []engine // 按顺序检测，越靠前越特殊
+    fallback    string   // 都没有命中时的名称
+    fingerprint []string // 匹配到这些指纹时优先检测
+}
+
+// engine 引擎特有的表达式，expr 中的 %s 替换为随机字符串，期望输出 expect
+type engine struct {
+    name   string
+    expr   string
+    expect func(rnd string) string
+}
+
+func upper(rnd string) string {
+    return strings.ToUpper(rnd)
+}
+
+func twice(rnd string) string {
+    return rnd + rnd
+}
+
+func format(f string) func(expr string) string {
+    return func(expr string) string {
+        return fmt.Sprintf(f, expr)
+    }
+}
+
+var syntaxes = []*syntax{
+    {
+        name: "{{ }}",
+        wrap: format("{{%s}}"),
+        engines: []engine{
+            // jinja2 中过滤器优先级高于 *，'x'|upper*2 => XX
+            {"Jinja2", "'%s'|upper*2", func(rnd string) string { return upper(rnd) + upper(rnd) }},
+            {"Tornado", "'%s'*2", twice},
+            // max 是 twig 的函数，nunjucks 中没有
+            {"Twig", "'%s'|upper~max(1,2)", func(rnd string) string { return upper(rnd) + "2" }},
+            {"Nunjucks", "'%s'|upper", upper},
+        },
+        fallback:    "Unknown ({{ }})",
+        fingerprint: []string{"flask", "python", "django", "php", "symfony", "laravel", "node", "express"},
+    },
+    {
+        name: "${ }",
+        wrap: format("${%s}"),
+        engines: []engine{
+            {"Freemarker", `"%s"?upper_case`, upper},
+            {"Mako", "'%s'.upper()", upper},
+            {"Spring EL", "T(java.lang.String).valueOf('%s').toUpperCase()", upper},
+            {"Groovy", "'%s'*2", twice},
+            {"Java EL / JavaScript", "'%s'.toUpperCase()", upper},
+        },
+        fallback:    "Unknown (${ })",
+        fingerprint: []string{"java", "spring", "tomcat", "jsp", "struts", "weblogic", "jboss", "python"},
+    },
+    {
+        name: "#{ }",
+        wrap: format("#{%s}"),
+        engines: []engine{
+            {"Ruby (Slim/Haml)", "'%s'.upcase", upper},
+            {"Pug / Java EL", "'%s'.toUpperCase()", upper},
+        },
+        fallback:    "Unknown (#{ })",
+        fingerprint: []string{"ruby", "rails", "node", "express", "jsf", "java"},
+    },
+    {
+        name: "<%= %>",
+        wrap: format("<%%=%s%%>"),
+        engines: []engine{
+            {"ERB", "'%s'.upcase", upper},
+            {"EJS", "'%s'.toUpperCase()", upper},
+        },
+        fallback:    "Unknown (<%= %>)",
+        fingerprint: []string{"ruby", "rails", "node", "express"},
+    },
+    {
+        name: "[[${ }]]",
+        wrap: format("[[${%s}]]"),
+        engines: []engine{
+            {"Thymeleaf", "'%s'.toUpperCase()", upper},
+        },
+        fallback:    "Thymeleaf",
+        fingerprint: []string{"spring", "java", "thymeleaf"},
+    },
+    {
+        name: "{ }",
+        wrap: format("{%s}"),
+        engines: []engine{
+            {"Smarty", "'%s'|upper", upper},
+        },
+        fallback:    "Unknown ({ })",
+        fingerprint: []string{"php", "smarty"},
+    },
+    {
+        name: "@( )",
+        wrap: format("@(%s)"),
+        engines: []engine{
+            {"Razor", `("%s").ToUpper()`, upper},
+        },
+        fallback:    "Razor",
+        fingerprint: []string{"asp", ".net", "iis"},
+    },
+}
+
+// arithmetic 算术探测表达式和期望结果
+func arithmetic(a, b int) (string, string) {
+    return fmt.Sprintf("%d*%d", a, b), strconv.Itoa(a * b)
+}
+
+// velocity 和 go 模板没有算术表达式，单独使用特有的语法探测
+func velocity(a, b int) (string, string) {
+    return fmt.Sprintf("#set($j=%d*%d)${j}", a, b), strconv.Itoa(a * b)
+}
+
+func goTemplate(n int) (string, string) {
+    return fmt.Sprintf(`{{printf "%%x" %d}}`, n), strconv.FormatInt(int64(n), 16)
+}
+
+// polyglot 多种语法的算术表达式组合在一起，任意一种被执行都能在响应中看到结果
+func polyglot(expr string) string {
+    var sb strings.Builder
+    for _, s := range []string{"{{ }}", "${ }", "#{ }", "<%= %>"} {
+        for _, sy := range syntaxes {
+            if sy.name == s {
+                sb.WriteString(sy.wrap(expr))
+            }
+        }
+    }
+    return sb.String()
+}
@@ -0,0 +1,101 @@
+package ssti
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+    "text/template"
+)
+
+// 每个引擎的探测表达式和期望的输出，rnd 为 jie
+func TestEngines(t *testing.T) {
+    tests := map[string]struct {
+        probe  string
+        expect string
+    }{
+        "Jinja2":               {"{{'jie'|upper*2}}", "JIEJIE"},
+        "Tornado":              {"{{'jie'*2}}", "jiejie"},
+        "Twig":                 {"{{'jie'|upper~max(1,2)}}", "JIE2"},
+        "Nunjucks":             {"{{'jie'|upper}}", "JIE"},
+        "Freemarker":           {`${"jie"?upper_case}`, "JIE"},
+        "Mako":                 {"${'jie'.upper()}", "JIE"},
+        "Spring EL":            {"${T(java.lang.String).valueOf('jie').toUpperCase()}", "JIE"},
+        "Groovy":               {"${'jie'*2}", "jiejie"},
+        "Java EL / JavaScript": {"${'jie'.toUpperCase()}", "JIE"},
+        "Ruby (Slim/Haml)":     {"#{'jie'.upcase}", "JIE"},
+        "Pug / Java EL":        {"#{'jie'.toUpperCase()}", "JIE"},
+        "ERB":                  {"<%='jie'.upcase%>", "JIE"},
+        "EJS":                  {"<%='jie'.toUpperCase()%>", "JIE"},
+        "Thymeleaf":            {"[[${'jie'.toUpperCase()}]]", "JIE"},
+        "Smarty":               {"{'jie'|upper}", "JIE"},
+        "Razor":                {`@(("jie").ToUpper())`, "JIE"},
+    }
+    seen := 0
+    for _, sy := range syntaxes {
+        for _, e := range sy.engines {
+            tt, ok := tests[e.name]
+            if !ok {
+                t.Errorf("%s: no test case", e.name)
+                continue
+            }
+            seen++
+            if probe := sy.wrap(fmt.Sprintf(e.expr, "jie")); probe != tt.probe {
+                t.Errorf("%s: probe = %s, want %s", e.name, probe, tt.probe)
+            }
+            if expect := e.expect("jie"); expect != tt.expect {
+                t.Errorf("%s: expect = %s, want %s", e.name, expect, tt.expect)
+            }
+        }
+    }
+    if seen != len(tests) {
+        t.Errorf("%d engines tested, %d test cases", seen, len(tests))
+    }
+}
+
+func TestArithmetic(t *testing.T) {
+    tests := []struct {
+        probe  func(a, b int) (string, string)
+        expr   string
+        expect string
+    }{
+        {arithmetic, "1234*5678", "7006652"},
+        {velocity, "#set($j=1234*5678)${j}", "7006652"},
+    }
+    for _, tt := range tests {
+        expr, expect := tt.probe(1234, 5678)
+        if expr != tt.expr || expect != tt.expect {
+            t.Errorf("probe(1234, 5678) = %s, %s, want %s, %s", expr, expect, tt.expr, tt.expect)
+        }
+    }
+    if got := polyglot("1*2"); got != "{{1*2}}${1*2}#{1*2}<%=1*2%>" {
+        t.Errorf("polyglot() = %s", got)
+    }
+}
+
+// go 模板的探测表达式使用 text/template 实际执行
+func TestGoTemplate(t *testing.T) {
+    expr, expect := goTemplate(7006652)
+    tpl, err := template.New("").Parse("hello " + expr)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var sb strings.Builder
+    if err = tpl.Execute(&sb, nil); err != nil {
+        t.Fatal(err)
+    }
+    if expect != "6ae9bc" || sb.String() != "hello "+expect {
+        t.Errorf("goTemplate() = %s => %s, rendered %s", expr, expect, sb.String())
+    }
+}
+
+func TestPrioritize(t *testing.T) {
+    if got := prioritize([]string{"Spring Boot"}); got[0].name != "${ }" {
+        t.Errorf("prioritize(spring) = %s", got[0].name)
+    }
+    if got := prioritize([]string{"ASP.NET"}); got[0].name != "@( )" {
+        t.Errorf("prioritize(asp.net) = %s", got[0].name)
+    }
+    if got := prioritize(nil); got[0].name != syntaxes[0].name || len(got) != len(syntaxes) {
+        t.Errorf("prioritize(nil) changes the order")
+    }
+}
@@ -0,0 +1,211 @@
+package ssti
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/23
+   @desc 服务端模板注入检测
+        1. 对每个参数先发送多种语法组合的算术表达式，再逐个语法发送，响应中出现计算结果说明存在注入
+        2. 根据注入的语法执行对应的决策树，识别具体的模板引擎，只使用计算、字符串处理作为证明，不执行命令
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+// result 确认的注入
+type result struct {
+    param    string
+    engine   string
+    payloads []string // 确认注入和识别引擎使用的 payload 及结果
+    response *httpx.Response
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    
+    variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), in.Method, in.ContentType, in.Headers)
+    if err != nil || variations == nil {
+        return
+    }
+    
+    var baseline string
+    if in.Resp != nil {
+        baseline = in.Resp.Body
+    }
+    
+    ordered := prioritize(in.Fingerprints)
+    for i, param := range variations.Params {
+        if param.IsFile || util.SliceInCaseFold(param.Name, util.ParamFilter) {
+            continue
+        }
+        s := &sender{in: in, variations: variations, index: i, value: param.Value, baseline: baseline, client: client}
+        if r := s.detect(ordered); r != nil {
+            r.param = param.Name
+            report(in, r, client)
+            return
+        }
+    }
+}
+
+// prioritize 根据指纹调整语法的检测顺序，匹配到指纹的语法优先检测
+func prioritize(fingerprints []string) []*syntax {
+    ordered := make([]*syntax, len(syntaxes))
+    copy(ordered, syntaxes)
+    
+    score := func(s *syntax) int {
+        for _, f := range s.fingerprint {
+            if util.InSliceCaseFold(f, fingerprints) {
+                return 1
+            }
+        }
+        return 0
+    }
+    sort.SliceStable(ordered, func(i, j int) bool {
+        return score(ordered[i]) > score(ordered[j])
+    })
+    return ordered
+}
+
+// sender 向一个参数发送 payload
+type sender struct {
+    in         *input.CrawlResult
+    variations *httpx.Variations
+    index      int
+    value      string
+    baseline   string
+    client     *httpx.Client
+}
+
+// send 发送 payload，响应中出现 expect 并且原始响应中没有时返回响应
+func (s *sender) send(payload, expect string) *httpx.Response {
+    if strings.Contains(s.baseline, expect) {
+        return nil
+    }
+    request := s.variations.SetPayloadByIndex(s.index, s.in.Url, s.value+payload, s.in.Method)
+    if request == "" {
+        return nil
+    }
+    
+    var res *httpx.Response
+    var err error
+    if s.in.Method == "GET" {
+        res, err = s.client.Request(request, s.in.Method, "", s.in.Headers)
+    } else {
+        res, err = s.client.Request(s.in.Url, s.in.Method, request, s.in.Headers)
+    }
+    if err != nil {
+        logging.Logger.Debugln("[ssti]", s.in.Url, err)
+        return nil
+    }
+    // 结果和 payload 一起原样回显时不算，例如 {{1*2}} 中包含 2
+    if !strings.Contains(strings.ReplaceAll(res.Body, payload, ""), expect) {
+        return nil
+    }
+    return res
+}
+
+// detect 先发送组合的 payload，再逐个语法确认，确认后识别引擎
+func (s *sender) detect(ordered []*syntax) *result {
+    a, b := util.RandomNumber(1000, 9999), util.RandomNumber(1000, 9999)
+    expr, expect := arithmetic(a, b)
+    
+    // 组合 payload 中某个语法出错可能导致整个模板报错，所以没有命中时仍然逐个语法检测
+    mixed := polyglot(expr)
+    hit := s.send(mixed, expect)
+    
+    for _, sy := range ordered {
+        payload := sy.wrap(expr)
+        res := s.send(payload, expect)
+        if res == nil {
+            continue
+        }
+        r := &result{
+            engine:   sy.fallback,
+            payloads: []string{fmt.Sprintf("%s => %s", payload, expect)},
+            response: res,
+        }
+        
+        rnd := strings.ToLower(util.RandomString(6))
+        for _, e := range sy.engines {
+            payload = sy.wrap(fmt.Sprintf(e.expr, rnd))
+            if res = s.send(payload, e.expect(rnd)); res != nil {
+                r.engine = e.name
+                r.payloads = append(r.payloads, fmt.Sprintf("%s => %s", payload, e.expect(rnd)))
+                r.response = res
+                break
+            }
+        }
+        return r
+    }
+    
+    expr, expect = velocity(a, b)
+    if res := s.send(expr, expect); res != nil {
+        return &result{engine: "Velocity", payloads: []string{fmt.Sprintf("%s => %s", expr, expect)}, response: res}
+    }
+    
+    expr, expect = goTemplate(a * b)
+    if res := s.send(expr, expect); res != nil {
+        return &result{engine: "Go text/template", payloads: []string{fmt.Sprintf("%s => %s", expr, expect)}, response: res}
+    }
+    
+    // 只有组合 payload 被执行，无法确定语法
+    if hit != nil {
+        return &result{engine: "Unknown", payloads: []string{fmt.Sprintf("%s => %s", mixed, expect)}, response: hit}
+    }
+    return nil
+}
+
+func report(in *input.CrawlResult, r *result, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "SSTI",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    r.engine,
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Param:       r.param,
+            Payload:     strings.Join(r.payloads, "\n"),
+            Request:     r.response.RequestDump,
+            Response:    r.response.ResponseDump,
+            Description: fmt.Sprintf("Server-side template injection, engine: %s. The injected expressions were evaluated by the template engine.", r.engine),
+        },
+        Level: output.Critical,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "ssti"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
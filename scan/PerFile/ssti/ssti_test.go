@@ -0,0 +1,110 @@
+package ssti
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/logging"
+    "html"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+    "testing"
+    "text/template"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "ssti", false)
+    conf.InitDefault()
+    os.Exit(m.Run())
+}
+
+var (
+    mulRegex   = regexp.MustCompile(`\{\{(\d+)\*(\d+)\}\}`)
+    upperRegex = regexp.MustCompile(`\{\{'(\w+)'\|upper\*2\}\}`)
+)
+
+// renderers 模拟的模板引擎，参数 q 经过渲染后输出
+var renderers = map[string]func(q string) string{
+    // 只实现了乘法和 jinja2 的探测表达式
+    "jinja2": func(q string) string {
+        q = mulRegex.ReplaceAllStringFunc(q, func(s string) string {
+            m := mulRegex.FindStringSubmatch(s)
+            a, _ := strconv.Atoi(m[1])
+            b, _ := strconv.Atoi(m[2])
+            return strconv.Itoa(a * b)
+        })
+        return upperRegex.ReplaceAllStringFunc(q, func(s string) string {
+            w := strings.ToUpper(upperRegex.FindStringSubmatch(s)[1])
+            return w + w
+        })
+    },
+    // 使用 text/template 渲染，解析出错时原样输出
+    "go": func(q string) string {
+        tpl, err := template.New("").Parse(q)
+        if err != nil {
+            return q
+        }
+        var sb strings.Builder
+        if err = tpl.Execute(&sb, nil); err != nil {
+            return q
+        }
+        return sb.String()
+    },
+    "reflected": func(q string) string { return q },
+    "escaped":   html.EscapeString,
+}
+
+func scan(t *testing.T, engine string) []output.VulMessage {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("<html><body>hello " + renderers[engine](r.URL.Query().Get("q")) + "</body></html>"))
+    }))
+    defer server.Close()
+    
+    var found []output.VulMessage
+    client := httpx.NewClient(&httpx.Options{QPS: 50, Timeout: 5})
+    client.Sink = &output.Sink{OnFinding: func(msg output.VulMessage) { found = append(found, msg) }}
+    
+    u, _ := url.Parse(server.URL + "/?q=jie")
+    res, err := client.Request(u.String(), "GET", "", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    (&Plugin{}).Scan(server.URL, "/", &input.CrawlResult{
+        Url:      u.String(),
+        ParseUrl: u,
+        Host:     u.Host,
+        Method:   "GET",
+        Headers:  map[string]string{},
+        Resp:     res,
+        UniqueId: engine,
+    }, client)
+    return found
+}
+
+func TestScan(t *testing.T) {
+    tests := map[string]string{
+        "jinja2": "Jinja2",
+        "go":     "Go text/template",
+        // 表达式只是原样或者转义后回显，没有被执行
+        "reflected": "",
+        "escaped":   "",
+    }
+    for engine, want := range tests {
+        found := scan(t, engine)
+        if want == "" {
+            if len(found) != 0 {
+                t.Errorf("%s: reported %s %s", engine, found[0].VulnData.VulnType, found[0].VulnData.Payload)
+            }
+            continue
+        }
+        if len(found) != 1 || found[0].VulnData.VulnType != want || found[0].VulnData.Param != "q" {
+            t.Errorf("%s: found %+v, want %s", engine, found, want)
+        }
+    }
+}
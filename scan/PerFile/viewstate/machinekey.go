@@ -0,0 +1,215 @@
+package viewstate
+
+import (
+    "bufio"
+    "crypto/hmac"
+    "crypto/md5"
+    "crypto/sha1"
+    "crypto/sha256"
+    "crypto/sha512"
+    _ "embed"
+    "encoding/binary"
+    "encoding/hex"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/logging"
+    "hash"
+    "os"
+    "strings"
+    "sync"
+)
+
+/**
+   @author yhy
+   @since 2024/7/1
+   @desc 使用公开的 machineKey 离线计算 ViewState 的 MAC，不发送请求
+        1. 旧版本(<4.5): HMAC(validationKey, 数据 + __VIEWSTATEGENERATOR)，加密时 HMAC(validationKey, 密文)，MD5 不是 HMAC，见 md5Legacy
+        2. 4.5 及以上: validationKey 使用 SP800-108 派生，purpose 为页面目录和类名，HMAC(派生的 key, 密文)
+        内置的 key 之外，配置 machineKeys 指定 Blacklist3r 的 MachineKeys.txt 等同样格式的文件加载更多的 key
+        参考 https://github.com/NotSoSecure/Blacklist3r
+**/
+
+//go:embed keys/machinekeys.txt
+var machineKeysFile string
+
+// machineKey validationKey、decryptionKey
+type machineKey struct {
+    validation    []byte
+    validationHex string
+    decryptionHex string
+}
+
+var (
+    machineKeys []machineKey
+    keysOnce    sync.Once
+)
+
+// loadKeys 内置的 key 和配置的 key 文件，第一次使用时加载
+func loadKeys() []machineKey {
+    keysOnce.Do(func() {
+        machineKeys = parseKeys(machineKeysFile)
+        if file := conf.GlobalConfig.Plugins.Viewstate.MachineKeys; file != "" {
+            data, err := os.ReadFile(file)
+            if err != nil {
+                logging.Logger.Errorln("[viewstate] read machineKeys", err)
+                return
+            }
+            machineKeys = append(machineKeys, parseKeys(string(data))...)
+        }
+    })
+    return machineKeys
+}
+
+// parseKeys 每行一个 validationKey,decryptionKey
+func parseKeys(data string) []machineKey {
+    var keys []machineKey
+    seen := make(map[string]bool)
+    scanner := bufio.NewScanner(strings.NewReader(data))
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        v, d, _ := strings.Cut(line, ",")
+        v, d = strings.TrimSpace(v), strings.TrimSpace(d)
+        key, err := hex.DecodeString(v)
+        if err != nil || len(key) == 0 || seen[strings.ToUpper(line)] {
+            continue
+        }
+        seen[strings.ToUpper(line)] = true
+        keys = append(keys, machineKey{validation: key, validationHex: v, decryptionHex: d})
+    }
+    return keys
+}
+
+type algorithm struct {
+    name string
+    new  func() hash.Hash
+}
+
+// MAC 长度对应的算法
+var algorithms = map[int][]algorithm{
+    16: {{"MD5", md5.New}},
+    20: {{"SHA1", sha1.New}},
+    32: {{"HMACSHA256", sha256.New}},
+    48: {{"HMACSHA384", sha512.New384}},
+    64: {{"HMACSHA512", sha512.New}},
+}
+
+// match 匹配成功的 machineKey
+type match struct {
+    key       machineKey
+    algorithm string
+    mode      string
+}
+
+// page 计算 MAC 需要的页面信息
+type page struct {
+    generator string // __VIEWSTATEGENERATOR
+    directory string // TemplateSourceDirectory
+    typeName  string // 页面的类名，如 default_aspx
+}
+
+// bruteforce data 为 base64 解码后的 ViewState，macLen 为 0 时尝试所有长度
+func bruteforce(data []byte, encrypted bool, macLen int, p page) *match {
+    lengths := []int{macLen}
+    if macLen == 0 {
+        lengths = []int{32, 20, 48, 64, 16}
+    }
+    for _, l := range lengths {
+        if len(data) <= l || algorithms[l] == nil {
+            continue
+        }
+        body, mac := data[:len(data)-l], data[len(data)-l:]
+        for _, alg := range algorithms[l] {
+            for _, key := range loadKeys() {
+                if m := legacy(body, mac, encrypted, key, alg, p); m != nil {
+                    return m
+                }
+                if dotnet45(body, mac, key, alg, p) {
+                    return &match{key: key, algorithm: alg.name, mode: ".NET 4.5+"}
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// legacy 未加密时 MAC 计算加上 __VIEWSTATEGENERATOR 对应的 4 个字节
+func legacy(body, mac []byte, encrypted bool, key machineKey, alg algorithm, p page) *match {
+    var modifier []byte
+    if !encrypted {
+        gen, err := hex.DecodeString(p.generator)
+        if err != nil || len(gen) != 4 {
+            return nil
+        }
+        modifier = binary.LittleEndian.AppendUint32(nil, binary.BigEndian.Uint32(gen))
+    }
+    var sum []byte
+    if alg.name == "MD5" {
+        sum = md5Legacy(body, modifier, key.validation)
+    } else {
+        h := hmac.New(alg.new, key.validation)
+        h.Write(body)
+        h.Write(modifier)
+        sum = h.Sum(nil)
+    }
+    if hmac.Equal(sum, mac) {
+        return &match{key: key, algorithm: alg.name, mode: "legacy (< .NET 4.5)"}
+    }
+    return nil
+}
+
+// md5Legacy 旧版本 MD5 不使用 HMAC，MachineKeySection.HashDataUsingNonKeyedAlgorithm 计算 MD5(数据 + modifier + validationKey)
+// 但 validationKey 和 modifier 复制到了同一个偏移，结果为 MD5(数据 + validationKey + len(modifier) 个 0)
+func md5Legacy(body, modifier, key []byte) []byte {
+    buf := make([]byte, len(body)+len(key)+len(modifier))
+    copy(buf, body)
+    copy(buf[len(body):], key)
+    sum := md5.Sum(buf)
+    return sum[:]
+}
+
+func dotnet45(body, mac []byte, key machineKey, alg algorithm, p page) bool {
+    derived := deriveKey(key.validation, "WebForms.HiddenFieldPageStatePersister.ClientState", []string{
+        "TemplateSourceDirectory: " + strings.ToUpper(p.directory),
+        "Type: " + strings.ToUpper(p.typeName),
+    })
+    h := hmac.New(alg.new, derived)
+    h.Write(body)
+    return hmac.Equal(h.Sum(nil), mac)
+}
+
+// deriveKey SP800-108 CTR HMAC-SHA512，派生的 key 和原始 key 长度相同
+func deriveKey(key []byte, label string, purposes []string) []byte {
+    var context []byte
+    for _, s := range purposes {
+        context = append(context, write7BitEncodedInt(len(s))...)
+        context = append(context, s...)
+    }
+    bits := uint32(len(key) * 8)
+    
+    buf := make([]byte, 4, 4+len(label)+1+len(context)+4)
+    buf = append(buf, label...)
+    buf = append(buf, 0)
+    buf = append(buf, context...)
+    buf = binary.BigEndian.AppendUint32(buf, bits)
+    
+    var out []byte
+    for i := uint32(1); len(out) < len(key); i++ {
+        binary.BigEndian.PutUint32(buf, i)
+        h := hmac.New(sha512.New, key)
+        h.Write(buf)
+        out = append(out, h.Sum(nil)...)
+    }
+    return out[:len(key)]
+}
+
+// write7BitEncodedInt BinaryWriter 写入字符串时的长度前缀
+func write7BitEncodedInt(v int) []byte {
+    var b []byte
+    for v >= 0x80 {
+        b = append(b, byte(v|0x80))
+        v >>= 7
+    }
+    return append(b, byte(v))
+}
@@ -0,0 +1,116 @@
+package viewstate
+
+import (
+    "encoding/hex"
+    "strings"
+    "testing"
+)
+
+// 测试向量使用 .NET 的 HMACSHA1、HMACSHA256、SP800108HmacCounterKdf、BinaryWriter 生成
+// validationKey 为内置的第一个 key，__VIEWSTATEGENERATOR 为 CA0B0334，页面为 /admin/user.aspx
+const (
+    testKey  = "C50B3C89CB21F4F1422FF158A5B42D0E8DB8CB5CDA1742572A487D9401E3400267682B202B746511891C1BAF47F8D25C07F6C39A104696DB51F17C529AD3CABE"
+    testBody = "ff010f0f0502016105026a6964640100"
+)
+
+func decode(t *testing.T, s string) []byte {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return b
+}
+
+func testPage() page {
+    return pageOf("/admin/user.aspx", "CA0B0334")
+}
+
+func TestLegacy(t *testing.T) {
+    key := machineKey{validation: decode(t, testKey)}
+    body := decode(t, testBody)
+    tests := []struct {
+        name      string
+        alg       algorithm
+        encrypted bool
+        mac       string
+    }{
+        {"SHA1", algorithms[20][0], false, "32cd21ea8cfb0db36a2e2172cbe15952db456f07"},
+        {"HMACSHA256", algorithms[32][0], false, "2a8fe0ae49d6a6a32aa2ccccbdfdd9ce77901350153511ac75366587fde7f67a"},
+        {"HMACSHA256 encrypted", algorithms[32][0], true, "e918c2256d216cc2b13bca2cf464fe05acc86d8f3f3f72eeff0011b78b099f33"},
+    }
+    for _, tt := range tests {
+        if m := legacy(body, decode(t, tt.mac), tt.encrypted, key, tt.alg, testPage()); m == nil || m.algorithm != tt.alg.name {
+            t.Errorf("%s: MAC not matched", tt.name)
+        }
+        // 其他页面的 __VIEWSTATEGENERATOR 不匹配
+        other := testPage()
+        other.generator = "CA0B0335"
+        if m := legacy(body, decode(t, tt.mac), tt.encrypted, key, tt.alg, other); m != nil && !tt.encrypted {
+            t.Errorf("%s: matched with another generator", tt.name)
+        }
+    }
+}
+
+func TestDeriveKey(t *testing.T) {
+    p := testPage()
+    got := deriveKey(decode(t, testKey), "WebForms.HiddenFieldPageStatePersister.ClientState", []string{
+        "TemplateSourceDirectory: /ADMIN",
+        "Type: ADMIN_USER_ASPX",
+    })
+    want := "330a1f4d0518264c9ab9bf7c83ec6208655bcce873376e03cc0075e1bd714ca1c751a89db193f38fd4537879b8b7fb69d069cdea47dc9de4c631767b2e1c674d"
+    if hex.EncodeToString(got) != want {
+        t.Errorf("deriveKey = %x, want %s", got, want)
+    }
+    if p.directory != "/admin" || p.typeName != "admin_user_aspx" {
+        t.Errorf("pageOf = %+v", p)
+    }
+}
+
+func TestDotnet45(t *testing.T) {
+    key := machineKey{validation: decode(t, testKey)}
+    body := decode(t, testBody)
+    if !dotnet45(body, decode(t, "b24a69e53819b8d3ed849c263c0a5fb6cef2043aeb246802c591a1367a41d2f0"), key, algorithms[32][0], testPage()) {
+        t.Error("HMACSHA256 MAC not matched")
+    }
+    if !dotnet45(body, decode(t, "790f9ef132ed9a69edc22a01345ad12ea29ce347"), key, algorithms[20][0], testPage()) {
+        t.Error("HMACSHA1 MAC not matched")
+    }
+    if dotnet45(body, decode(t, "b24a69e53819b8d3ed849c263c0a5fb6cef2043aeb246802c591a1367a41d2f0"), key, algorithms[32][0], pageOf("/default.aspx", "")) {
+        t.Error("matched with another page")
+    }
+}
+
+func TestBruteforce(t *testing.T) {
+    data := append(decode(t, testBody), decode(t, "b24a69e53819b8d3ed849c263c0a5fb6cef2043aeb246802c591a1367a41d2f0")...)
+    m := bruteforce(data, true, 0, testPage())
+    if m == nil || m.algorithm != "HMACSHA256" || m.mode != ".NET 4.5+" || m.key.validationHex != testKey {
+        t.Fatalf("bruteforce = %+v", m)
+    }
+    
+    // MD5 的 MAC 为 16 字节
+    md5Data := append(decode(t, testBody), md5Legacy(decode(t, testBody), []byte{0x34, 0x03, 0x0b, 0xca}, decode(t, testKey))...)
+    if m = bruteforce(md5Data, false, 16, testPage()); m == nil || m.algorithm != "MD5" {
+        t.Errorf("MD5 bruteforce = %+v", m)
+    }
+}
+
+func TestParseKeys(t *testing.T) {
+    keys := parseKeys("# comment\n\nAABB,CCDD\nnot-hex,CCDD\naabb,ccdd\nAABB,CCDD\n")
+    if len(keys) != 1 || keys[0].validationHex != "AABB" || keys[0].decryptionHex != "CCDD" {
+        t.Errorf("parseKeys = %+v", keys)
+    }
+}
+
+// 内置的列表中每个 key 都能解析，避免合并 Blacklist3r 的列表时格式出错
+func TestEmbeddedKeys(t *testing.T) {
+    lines := 0
+    for _, line := range strings.Split(machineKeysFile, "\n") {
+        line = strings.TrimSpace(line)
+        if line != "" && !strings.HasPrefix(line, "#") {
+            lines++
+        }
+    }
+    if keys := parseKeys(machineKeysFile); lines == 0 || len(keys) != lines {
+        t.Errorf("parseKeys(machinekeys.txt) = %d keys, want %d", len(keys), lines)
+    }
+}
@@ -0,0 +1,283 @@
+package viewstate
+
+import (
+    "encoding/base64"
+    "fmt"
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/gadget/sensitive"
+    "html"
+    "net/url"
+    "path"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/1
+   @desc ASP.NET ViewState 检测，只分析请求、响应中的 __VIEWSTATE，不发送请求
+        1. 解码 ViewState，判断是否加密、是否开启 MAC 校验，MAC 关闭时可以直接构造反序列化 payload
+        2. 使用公开的 machineKey 离线计算 MAC，匹配时可以伪造任意 ViewState
+        3. 未加密的 ViewState 中的字符串检测敏感信息
+        ViewState 的基本信息(加密、MAC、类型等)记录到 SCopilot 中，不作为漏洞输出
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+var (
+    inputRegex = regexp.MustCompile(`(?i)<input[^>]+>`)
+    nameRegex  = regexp.MustCompile(`(?i)\bname\s*=\s*["']?([^"'\s>]+)`)
+    valueRegex = regexp.MustCompile(`(?i)\bvalue\s*=\s*["']([^"']*)["']`)
+)
+
+// ViewState 中的敏感信息
+var sensitiveRegexes = map[string]*regexp.Regexp{
+    "email":             regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`),
+    "connection string": regexp.MustCompile(`(?i)(data source|initial catalog|user id|uid|password|pwd)\s*=\s*[^;\s]+`),
+    "internal ip":       regexp.MustCompile(`\b(10\.\d{1,3}|172\.(1[6-9]|2\d|3[01])|192\.168)\.\d{1,3}\.\d{1,3}\b`),
+    "file path":         regexp.MustCompile(`(?i)\b[a-z]:\\[^\s"'<>]+|/(home|var|etc|usr|opt)/[^\s"'<>]+`),
+    "credential":        regexp.MustCompile(`(?i)(password|passwd|secret|token|apikey|api_key)\s*[:=]\s*\S+`),
+}
+
+// state 页面中的 ViewState 相关字段
+type state struct {
+    viewState       string
+    generator       string
+    encrypted       bool // __VIEWSTATEENCRYPTED
+    eventValidation bool
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.ParseUrl == nil {
+        return
+    }
+    var s *state
+    if in.Resp != nil {
+        s = fromHtml(in.Resp.Body)
+    }
+    if s == nil && in.Method == "POST" {
+        s = fromForm(in.RequestBody)
+    }
+    if s == nil || p.IsScanned(in.Host+in.ParseUrl.Path) {
+        return
+    }
+    
+    data, err := base64.StdEncoding.DecodeString(s.viewState)
+    if err != nil || len(data) == 0 {
+        return
+    }
+    pg := pageOf(in.ParseUrl.Path, s.generator)
+    
+    var (
+        notes   []string
+        macLen  int
+        parsed  bool
+        strs    []string
+        typeRef []string
+    )
+    encrypted := s.encrypted || len(data) < 2 || data[0] != 0xff || data[1] != 0x01
+    if encrypted {
+        notes = append(notes, "encrypted: yes")
+    } else {
+        notes = append(notes, "encrypted: no")
+        r, n, err := parse(data)
+        if r != nil {
+            strs, typeRef = r.strings, r.types
+        }
+        if err == nil {
+            parsed = true
+            macLen = len(data) - n
+            notes = append(notes, "MAC: "+macName(macLen))
+            if r.binary {
+                notes = append(notes, "contains BinaryFormatter data")
+            }
+        } else {
+            notes = append(notes, "failed to parse, the MAC state is unknown")
+        }
+    }
+    notes = append(notes, fmt.Sprintf("size: %d bytes", len(data)))
+    if s.generator != "" {
+        notes = append(notes, "__VIEWSTATEGENERATOR: "+s.generator)
+    }
+    if s.eventValidation {
+        notes = append(notes, "__EVENTVALIDATION present")
+    }
+    if len(typeRef) > 0 {
+        notes = append(notes, "types: "+strings.Join(util.RemoveDuplicateElement(typeRef), ", "))
+    }
+    summary := strings.Join(notes, "\n")
+    
+    res := &httpx.Response{RequestDump: in.RawRequest, ResponseDump: in.RawResponse}
+    if parsed && macLen == 0 {
+        p.report(in, "viewstate mac disabled", s.viewState, res, output.High,
+            "ViewState MAC validation is disabled (enableViewStateMac=false), a forged ViewState with a LosFormatter/ObjectStateFormatter gadget (e.g. ysoserial.net TypeConfuseDelegate) leads to remote code execution.\n"+summary, client)
+    } else if m := bruteforce(data, encrypted, macLen, pg); m != nil {
+        p.report(in, "known machinekey", s.viewState, res, output.Critical,
+            fmt.Sprintf("The ViewState MAC is computed with a publicly known machineKey, ViewState can be forged for remote code execution.\nvalidationKey: %s\ndecryptionKey: %s\nvalidation: %s\nmode: %s\n%s",
+                m.key.validationHex, m.key.decryptionHex, m.algorithm, m.mode, summary), client)
+    }
+    
+    // 未加密时检测其中的敏感信息
+    if len(strs) > 0 {
+        text := strings.Join(strs, "\n")
+        var found []string
+        for name, re := range sensitiveRegexes {
+            for _, m := range util.RemoveDuplicateElement(re.FindAllString(text, 5)) {
+                found = append(found, name+": "+m)
+            }
+        }
+        if len(found) > 0 {
+            p.report(in, "sensitive data in viewstate", strings.Join(found, ", "), res, output.Medium,
+                "The unencrypted ViewState contains sensitive data:\n"+strings.Join(found, "\n")+"\n"+summary, client)
+        }
+        sensitive.KeyDetection(in.Url, text, client.Sink)
+    }
+    
+    client.SCopilot(in.Host, output.SCopilotData{
+        Target: in.Host,
+        InfoMsg: []output.PluginMsg{
+            {
+                Url:      in.Url,
+                Plugin:   "ViewState",
+                Result:   notes,
+                Request:  in.RawRequest,
+                Response: in.RawResponse,
+            },
+        },
+    })
+}
+
+// fromHtml 页面中的隐藏字段
+func fromHtml(body string) *state {
+    if !strings.Contains(body, "__VIEWSTATE") {
+        return nil
+    }
+    s := &state{}
+    for _, tag := range inputRegex.FindAllString(body, -1) {
+        name := nameRegex.FindStringSubmatch(tag)
+        if name == nil {
+            continue
+        }
+        var value string
+        if v := valueRegex.FindStringSubmatch(tag); v != nil {
+            value = html.UnescapeString(v[1])
+        }
+        s.set(name[1], value)
+    }
+    if s.viewState == "" {
+        return nil
+    }
+    return s
+}
+
+// fromForm 提交的表单中的字段
+func fromForm(body string) *state {
+    if !strings.Contains(body, "__VIEWSTATE") {
+        return nil
+    }
+    values, err := url.ParseQuery(body)
+    if err != nil {
+        return nil
+    }
+    s := &state{}
+    for k, v := range values {
+        if len(v) > 0 {
+            s.set(k, v[0])
+        }
+    }
+    if s.viewState == "" {
+        return nil
+    }
+    return s
+}
+
+func (s *state) set(name, value string) {
+    switch name {
+    case "__VIEWSTATE":
+        s.viewState = value
+    case "__VIEWSTATEGENERATOR":
+        s.generator = value
+    case "__VIEWSTATEENCRYPTED":
+        s.encrypted = true
+    case "__EVENTVALIDATION":
+        s.eventValidation = value != ""
+    }
+}
+
+// pageOf 根据路径得到页面目录和类名，/admin/user.aspx 的类名为 admin_user_aspx
+func pageOf(p, generator string) page {
+    if p == "" || strings.HasSuffix(p, "/") {
+        p += "default.aspx"
+    }
+    dir := path.Dir(p)
+    return page{
+        generator: generator,
+        directory: dir,
+        typeName:  strings.NewReplacer("/", "_", ".", "_").Replace(strings.ToLower(strings.TrimPrefix(p, "/"))),
+    }
+}
+
+func macName(n int) string {
+    switch n {
+    case 0:
+        return "disabled"
+    case 16:
+        return "enabled (MD5)"
+    case 20:
+        return "enabled (SHA1)"
+    case 32:
+        return "enabled (HMACSHA256)"
+    case 48:
+        return "enabled (HMACSHA384)"
+    case 64:
+        return "enabled (HMACSHA512)"
+    }
+    return fmt.Sprintf("enabled (%d bytes)", n)
+}
+
+func (p *Plugin) report(in *input.CrawlResult, kind, payload string, res *httpx.Response, level, description string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "ViewState",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    kind,
+            Target:      in.Url,
+            Method:      in.Method,
+            Ip:          in.Ip,
+            Param:       "__VIEWSTATE",
+            Payload:     payload,
+            Request:     res.RequestDump,
+            Response:    res.ResponseDump,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "viewstate"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
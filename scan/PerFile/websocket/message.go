@@ -0,0 +1,177 @@
+package websocket
+
+import (
+    "encoding/json"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/27
+   @desc WebSocket 消息，JSON 对象中的字符串字段(嵌套对象使用 a.b 形式的键)作为注入点，纯文本消息整体作为 message 参数
+**/
+
+// 嵌套对象最多展开的层数
+const maxDepth = 2
+
+type message struct {
+    raw    map[string]interface{} // 为 nil 时是纯文本消息
+    fields map[string]string
+}
+
+func parse(m string) *message {
+    if strings.TrimSpace(m) == "" {
+        return nil
+    }
+    var obj map[string]interface{}
+    if err := json.Unmarshal([]byte(m), &obj); err != nil {
+        return &message{fields: map[string]string{"message": m}}
+    }
+    msg := &message{raw: obj, fields: make(map[string]string)}
+    flatten("", obj, msg.fields, 0)
+    if len(msg.fields) == 0 {
+        return nil
+    }
+    return msg
+}
+
+func flatten(prefix string, obj map[string]interface{}, fields map[string]string, depth int) {
+    for k, v := range obj {
+        switch v := v.(type) {
+        case string:
+            fields[prefix+k] = v
+        case map[string]interface{}:
+            if depth < maxDepth {
+                flatten(prefix+k+".", v, fields, depth+1)
+            }
+        }
+    }
+}
+
+// shape 消息的结构，用于去重，只是字段值不同的消息只检测一次
+func (m *message) shape() string {
+    if m.raw == nil {
+        return "text"
+    }
+    var keys []string
+    for k := range m.fields {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return strings.Join(keys, ",")
+}
+
+// build 用修改后的字段值还原消息
+func (m *message) build(values map[string]interface{}) string {
+    if m.raw == nil {
+        s, _ := values["message"].(string)
+        return s
+    }
+    var obj map[string]interface{}
+    b, _ := json.Marshal(m.raw)
+    json.Unmarshal(b, &obj)
+    for k, v := range values {
+        if _, ok := m.fields[k]; ok {
+            set(obj, strings.Split(k, "."), v)
+        }
+    }
+    b, _ = json.Marshal(obj)
+    return string(b)
+}
+
+func set(obj map[string]interface{}, path []string, v interface{}) {
+    if len(path) == 1 {
+        obj[path[0]] = v
+        return
+    }
+    if next, ok := obj[path[0]].(map[string]interface{}); ok {
+        set(next, path[1:], v)
+    }
+}
+
+// decode payload 会被 url 编码，这里解码还原，使用 PathUnescape 不会把消息中的 + 当作空格，没有修改的字段原样发送
+func (m *message) decode(values map[string]interface{}) {
+    for k, v := range values {
+        s, ok := v.(string)
+        if !ok || s == m.fields[k] {
+            continue
+        }
+        if decoded, err := url.PathUnescape(s); err == nil {
+            values[k] = decoded
+        }
+    }
+}
+
+// roundTrip 其他插件发送到 url 的 POST 请求改为重新建立 WebSocket 连接发送消息，
+// 先发送之前的消息(登录、订阅等)，再发送根据 JSON 参数还原的消息
+func (m *message) roundTrip(endpoint string, previous []string) func(c *httpx.Client, target, method, body string, header map[string]string) (*httpx.Response, error) {
+    return func(c *httpx.Client, target, method, body string, header map[string]string) (*httpx.Response, error) {
+        values := make(map[string]interface{})
+        if method != "POST" || target != endpoint || json.Unmarshal([]byte(body), &values) != nil {
+            return c.Request(target, method, body, header)
+        }
+        m.decode(values)
+        
+        messages := append(append([]string{}, previous...), m.build(values))
+        res, err := c.WebSocket(endpoint, header, messages, replyIdle)
+        if err != nil {
+            return nil, err
+        }
+        // 其他插件根据状态码、Content-Type 判断响应，回复作为正常的响应
+        res.Status = "200 OK"
+        res.StatusCode = 200
+        res.Header = res.Header.Clone()
+        if res.Header == nil {
+            res.Header = http.Header{}
+        }
+        if json.Valid([]byte(res.Body)) {
+            res.Header.Set("Content-Type", "application/json")
+        } else {
+            res.Header.Set("Content-Type", "text/plain")
+        }
+        return res, nil
+    }
+}
+
+// derive 把消息展开为一个新的扫描请求，先发送一次原始消息作为其他插件比较的原始响应
+func derive(in *input.CrawlResult, previous []string, msg *message, client *httpx.Client) *input.CrawlResult {
+    headers := make(map[string]string)
+    for k, v := range in.Headers {
+        headers[k] = v
+    }
+    headers["Content-Type"] = "application/json"
+    
+    body, _ := json.Marshal(msg.fields)
+    roundTrip := msg.roundTrip(in.Url, previous)
+    res, err := client.WithRoundTrip(roundTrip).Request(in.Url, "POST", string(body), headers)
+    if err != nil {
+        return nil
+    }
+    
+    return &input.CrawlResult{
+        Target:       in.Target,
+        Host:         in.Host,
+        Url:          in.Url,
+        ParseUrl:     in.ParseUrl,
+        Ip:           in.Ip,
+        Cdn:          in.Cdn,
+        Port:         in.Port,
+        UniqueId:     util.MD5(in.Url + msg.shape()),
+        Method:       "POST",
+        Headers:      headers,
+        RequestBody:  string(body),
+        ContentType:  "application/json",
+        Resp:         res,
+        RawRequest:   res.RequestDump,
+        RawResponse:  res.ResponseDump,
+        Fingerprints: in.Fingerprints,
+        Source:       "websocket-message",
+        RoundTrip:    roundTrip,
+    }
+}
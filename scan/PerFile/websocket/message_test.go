@@ -0,0 +1,44 @@
+package websocket
+
+import (
+    "encoding/json"
+    "testing"
+)
+
+// payload 中的 + 不能被解码为空格，没有修改的字段原样发送
+func TestDecode(t *testing.T) {
+    m := parse(`{"action": "search", "token": "a+b%2Fc", "data": {"q": "x"}}`)
+    if m == nil {
+        t.Fatal("message not parsed")
+    }
+    values := map[string]interface{}{
+        "action": "search",
+        "token":  "a+b%2Fc",
+        "data.q": "1+1%27%22",
+    }
+    m.decode(values)
+    
+    var got map[string]interface{}
+    if err := json.Unmarshal([]byte(m.build(values)), &got); err != nil {
+        t.Fatal(err)
+    }
+    if got["token"] != "a+b%2Fc" {
+        t.Errorf("unchanged field decoded: %v", got["token"])
+    }
+    if q := got["data"].(map[string]interface{})["q"]; q != `1+1'"` {
+        t.Errorf("payload = %v, want 1+1'\"", q)
+    }
+}
+
+func TestParseText(t *testing.T) {
+    m := parse("hello")
+    if m == nil || m.fields["message"] != "hello" || m.shape() != "text" {
+        t.Fatalf("text message = %+v", m)
+    }
+    if got := m.build(map[string]interface{}{"message": "bye"}); got != "bye" {
+        t.Errorf("build = %q", got)
+    }
+    if parse("  ") != nil || parse(`{"id": 1}`) != nil {
+        t.Error("messages without string fields should be skipped")
+    }
+}
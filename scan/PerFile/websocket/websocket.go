@@ -0,0 +1,147 @@
+package websocket
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/27
+   @desc WebSocket 安全检测，被动代理记录的 WebSocket 连接
+        1. 跨站 WebSocket 劫持(CSWSH): 使用其他网站的 Origin 重新握手，握手成功说明没有校验 Origin
+        2. 客户端发送的 JSON、文本消息中的字段展开为 JSON 参数交给 xss、sql、cmd 插件检测，
+           发送时重新建立连接，依次发送之前的消息和修改后的消息(安全模式下只发送修改后的消息)，服务端的回复作为响应
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+// 展开的请求交给这些插件继续检测
+var injectionPlugins = []string{"xss", "sql", "cmd"}
+
+// 服务端这么长时间没有新消息时认为回复结束
+const replyIdle = time.Second
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if in.Source != "websocket" || p.IsScanned(in.UniqueId) {
+        return
+    }
+    if in.ParseUrl == nil {
+        return
+    }
+    
+    rnd := strings.ToLower(util.RandomLetterNumbers(6))
+    // 第二个用于绕过只校验 Origin 是否以目标域名开头的情况
+    origins := []string{
+        "https://" + rnd + ".com",
+        "https://" + in.ParseUrl.Hostname() + "." + rnd + ".com",
+    }
+    for _, origin := range origins {
+        headers := make(map[string]string)
+        for k, v := range in.Headers {
+            if !strings.EqualFold(k, "Origin") {
+                headers[k] = v
+            }
+        }
+        headers["Origin"] = origin
+        
+        res, err := client.WebSocket(in.Url, headers, nil, replyIdle)
+        if err != nil {
+            logging.Logger.Debugln("[websocket]", in.Url, err)
+            continue
+        }
+        if res.StatusCode != 101 {
+            continue
+        }
+        
+        level := output.Low
+        description := fmt.Sprintf("The WebSocket handshake accepts the cross-site Origin %s. The connection is not authenticated by cookies, so the impact is limited to what an anonymous connection can do.", origin)
+        if cookie(in.Headers) {
+            level = output.High
+            description = fmt.Sprintf("The WebSocket handshake accepts the cross-site Origin %s and the connection is authenticated by cookies, any website can open the connection as the victim and read or send messages (Cross-Site WebSocket Hijacking).", origin)
+        }
+        client.Report(output.VulMessage{
+            DataType: "web_vul",
+            Plugin:   "WebSocket",
+            VulnData: output.VulnData{
+                CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+                VulnType:    "cswsh",
+                Target:      in.Url,
+                Method:      "GET",
+                Ip:          in.Ip,
+                Param:       "Origin",
+                Payload:     origin,
+                Request:     res.RequestDump,
+                Response:    res.ResponseDump,
+                Description: description,
+            },
+            Level: level,
+        })
+        return
+    }
+}
+
+func cookie(headers map[string]string) bool {
+    for k, v := range headers {
+        if strings.EqualFold(k, "Cookie") && v != "" {
+            return true
+        }
+    }
+    return false
+}
+
+// Expand 把客户端发送的消息展开为扫描请求，结构相同的消息只展开一次
+func (p *Plugin) Expand(in *input.CrawlResult, client *httpx.Client) []*input.CrawlResult {
+    if in.Source != "websocket" {
+        return nil
+    }
+    var derived []*input.CrawlResult
+    for i, m := range in.WebSocket {
+        msg := parse(m)
+        if msg == nil || p.IsScanned(util.MD5(in.Url+msg.shape())) {
+            continue
+        }
+        // 安全模式下不重放之前的消息，之前的消息可能是下单、修改配置这类操作
+        var previous []string
+        if !client.Scope.Safe() {
+            previous = in.WebSocket[:i]
+        }
+        if d := derive(in, previous, msg, client); d != nil {
+            derived = append(derived, d)
+        }
+    }
+    return derived
+}
+
+func (p *Plugin) Plugins() []string {
+    return injectionPlugins
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "websocket"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
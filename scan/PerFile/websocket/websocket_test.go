@@ -0,0 +1,102 @@
+package websocket
+
+import (
+    "errors"
+    gws "github.com/gorilla/websocket"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/logging"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "websocket", false)
+    conf.InitDefault()
+    os.Exit(m.Run())
+}
+
+// server 回显收到的消息，记录单个连接最多收到的消息数
+func server(max *int, mu *sync.Mutex) *httptest.Server {
+    upgrader := gws.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        conn, err := upgrader.Upgrade(w, r, nil)
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        n := 0
+        for {
+            _, data, err := conn.ReadMessage()
+            if err != nil {
+                return
+            }
+            n++
+            mu.Lock()
+            if n > *max {
+                *max = n
+            }
+            mu.Unlock()
+            _ = conn.WriteMessage(gws.TextMessage, data)
+        }
+    }))
+}
+
+// 安全模式下展开第二条消息时不重放第一条消息
+func TestExpandSafeMode(t *testing.T) {
+    for _, safe := range []bool{false, true} {
+        var (
+            max int
+            mu  sync.Mutex
+        )
+        s := server(&max, &mu)
+        u, _ := url.Parse(s.URL + "/ws")
+        client := httpx.NewClient(&httpx.Options{QPS: 50, Timeout: 5})
+        client.Scope = &conf.Scope{SafeMode: safe}
+        
+        derived := (&Plugin{}).Expand(&input.CrawlResult{
+            Url:       u.String(),
+            ParseUrl:  u,
+            Source:    "websocket",
+            WebSocket: []string{`{"action":"login","token":"x"}`, `{"action":"search","q":"a"}`},
+        }, client)
+        s.Close()
+        if len(derived) != 2 {
+            t.Fatalf("safe=%v: %d messages expanded", safe, len(derived))
+        }
+        
+        mu.Lock()
+        if want := map[bool]int{false: 2, true: 1}[safe]; max != want {
+            t.Errorf("safe=%v: %d messages sent on one connection, want %d", safe, max, want)
+        }
+        mu.Unlock()
+    }
+}
+
+// 安全模式下只允许握手，不向看起来会修改数据的接口发送消息
+func TestWebSocketSafeMode(t *testing.T) {
+    var (
+        max int
+        mu  sync.Mutex
+    )
+    s := server(&max, &mu)
+    defer s.Close()
+    client := httpx.NewClient(&httpx.Options{QPS: 50, Timeout: 5})
+    client.Scope = &conf.Scope{SafeMode: true}
+    
+    if _, err := client.WebSocket(s.URL+"/ws/deleteOrder", nil, []string{"1"}, 100*time.Millisecond); !errors.Is(err, httpx.ErrUnsafeRequest) {
+        t.Errorf("message to deleteOrder = %v, want ErrUnsafeRequest", err)
+    }
+    if _, err := client.WebSocket(s.URL+"/ws/deleteOrder", nil, nil, 100*time.Millisecond); err != nil {
+        t.Errorf("handshake refused: %v", err)
+    }
+    if res, err := client.WebSocket(s.URL+"/ws", nil, []string{"hello"}, 100*time.Millisecond); err != nil || res.Body != "hello" {
+        t.Errorf("message to /ws = %v, %v", res, err)
+    }
+}
@@ -4,6 +4,7 @@ import (
     "fmt"
     regexp "github.com/wasilibs/go-re2"
     "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/logging"
     "strings"
     "time"
@@ -16,7 +17,7 @@ import (
     // 这种不行，先弃用 没有链路追踪，误报太多
 **/
 
-func Dom(u, response string) {
+func Dom(u, response string, client *httpx.Client) {
     var highlighted []string
     sources := regexp.MustCompile(`\b(?:document\.(URL|documentURI|URLUnencoded|baseURI|cookie|referrer)|location\.(href|search|hash|pathname)|window\.name|history\.(pushState|replaceState)(local|session)Storage)\b`)
     sinks := regexp.MustCompile(`\b(?:eval|evaluate|execCommand|assign|navigate|getResponseHeaderopen|showModalDialog|Function|set(Timeout|Interval|Immediate)|execScript|crypto.generateCRMFRequest|ScriptElement\.(src|text|textContent|innerText)|.*?\.onEventName|document\.(write|writeln)|.*?\.innerHTML|Range\.createContextualFragment|(document|window)\.location)\b`)
@@ -92,7 +93,7 @@ func Dom(u, response string) {
     }
     
     if sinkFound || sourceFound {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "XSS",
             VulnData: output.VulnData{
@@ -102,7 +103,7 @@ func Dom(u, response string) {
                 Payload:    strings.Join(highlighted, "\t"),
             },
             Level: output.Medium,
-        }
+        })
         logging.Logger.Infoln(u, highlighted)
     }
 }
@@ -34,7 +34,7 @@ func HookParse(code string) (string, error) {
     }
     convWalk(ast)
 
-    return ast.String(), nil
+    return ast.JSString(), nil
 }
 
 func convExpr(node js.IExpr) js.IExpr {
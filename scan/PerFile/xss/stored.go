@@ -0,0 +1,95 @@
+package xss
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/Jie/scan/gadget/tracker"
+    "github.com/yhy0/logging"
+    "net/url"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/7/3
+   @desc 存储型 xss，请求中的每个参数都替换为带有唯一标记的标签 payload，一次发送
+        之后在其他页面中输出时由 tracker 确认，标签原样输出说明可以利用
+**/
+
+// storedPayload 闭合引号、标签后插入一个以标记为名字的标签
+func storedPayload(token string) string {
+    return fmt.Sprintf(`'"><%s>`, token)
+}
+
+func stored(in *input.CrawlResult, client *httpx.Client) {
+    if in.ParseUrl == nil {
+        return
+    }
+    tokens := make(map[string]string) // 参数名: 标记
+    target, body := in.Url, in.RequestBody
+    
+    if in.Method == "GET" {
+        u := *in.ParseUrl
+        query := u.Query()
+        for name := range query {
+            if util.SliceInCaseFold(name, util.ParamFilter) {
+                continue
+            }
+            tokens[name] = tracker.Token()
+            query.Set(name, storedPayload(tokens[name]))
+        }
+        u.RawQuery = query.Encode()
+        target = u.String()
+    } else if in.RequestBody != "" {
+        variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), "POST", in.ContentType, in.Headers)
+        if err != nil {
+            logging.Logger.Debugln("[stored xss]", err)
+            return
+        }
+        contentType := strings.ToLower(in.ContentType)
+        for _, param := range variations.Params {
+            if param.IsFile || util.SliceInCaseFold(param.Name, util.ParamFilter) {
+                continue
+            }
+            tokens[param.Name] = tracker.Token()
+            value := storedPayload(tokens[param.Name])
+            // 表单 Release 时不会编码
+            if !strings.Contains(contentType, "json") && !strings.Contains(contentType, "multipart") {
+                value = url.QueryEscape(value)
+            }
+            _ = variations.Set(param.Name, value)
+        }
+        body = variations.Release()
+    }
+    if len(tokens) == 0 {
+        return
+    }
+    
+    res, err := client.Request(target, in.Method, body, in.Headers)
+    if err != nil {
+        logging.Logger.Debugln("[stored xss]", err)
+        return
+    }
+    for name, token := range tokens {
+        tracker.Register(token, tracker.Origin{
+            Plugin:     "XSS",
+            Kind:       "stored xss",
+            Level:      output.High,
+            Url:        in.Url,
+            Method:     in.Method,
+            Param:      name,
+            Payload:    storedPayload(token),
+            Request:    res.RequestDump,
+            Sink:       client.Sink,
+            Executable: tagInjected,
+        })
+    }
+}
+
+// tagInjected 以标记为名字的标签原样输出，说明可以插入任意标签
+func tagInjected(response, token string) bool {
+    return strings.Contains(strings.ToLower(response), "<"+token+">")
+}
@@ -79,6 +79,9 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
         return
     }
 
+    // 开启 browserVerify 时在浏览器中确认
+    checker := newVerifier(in, xssUrl, variations, client)
+
     for index, param := range variations.Params {
         // 判断是否为不可更改的参数名，TODO 有没有更好的实现方式，不然每次都要手动写个判断 ，目前不能再 ParseUri 函数中写，不然发包时，参数会少
         if util.SliceInCaseFold(param.Name, util.ParamFilter) {
@@ -114,7 +117,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                         _locations := ast.SearchInputInResponse(payload, resp.Body)
                         for _, _item := range _locations {
                             if funk.Contains(_item.Details.Value.Content, payload) && _item.Details.Value.TagName == "style" {
-                                output.OutChannel <- output.VulMessage{
+                                checker.report(index, output.VulMessage{
                                     DataType: "web_vul",
                                     Plugin:   "XSS",
                                     VulnData: output.VulnData{
@@ -127,7 +130,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                         Description: "IE下可执行的表达式 expression(alert(1))",
                                     },
                                     Level: output.Medium,
-                                }
+                                })
                                 break
                             }
                         }
@@ -148,7 +151,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                     _locations := ast.SearchInputInResponse(flag, resp.Body)
                     for _, _item := range _locations {
                         if _item.Details.Value.TagName == flag {
-                            output.OutChannel <- output.VulMessage{
+                            checker.report(index, output.VulMessage{
                                 DataType: "web_vul",
                                 Plugin:   "XSS",
                                 VulnData: output.VulnData{
@@ -161,7 +164,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                     Description: fmt.Sprintf("html标签可被闭合, <%s>可被闭合,可使用%s进行攻击测试", item.Details.Value.TagName, truepayload),
                                 },
                                 Level: output.Medium,
-                            }
+                            }, htmlVariants("</"+item.Details.Value.TagName+">")...)
                             break
                         }
                     }
@@ -180,7 +183,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                         _locations := ast.SearchInputInResponse(flag, resp.Body)
                         for _, _item := range _locations {
                             if _item.Details.Value.TagName == flag {
-                                output.OutChannel <- output.VulMessage{
+                                checker.report(index, output.VulMessage{
                                     DataType: "web_vul",
                                     Plugin:   "XSS",
                                     VulnData: output.VulnData{
@@ -193,7 +196,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                         Description: fmt.Sprintf("html标签可被闭合, <%s>可被闭合,可使用%s进行攻击测试", item.Details.Value.TagName, truepayload),
                                     },
                                     Level: output.Medium,
-                                }
+                                }, htmlVariants(">")...)
                                 break
                             }
                         }
@@ -209,7 +212,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                         for _, _item := range _locations {
                             for _, v := range _item.Details.Value.Attributes {
                                 if v.Key == flag {
-                                    output.OutChannel <- output.VulMessage{
+                                    checker.report(index, output.VulMessage{
                                         DataType: "web_vul",
                                         Plugin:   "XSS",
                                         VulnData: output.VulnData{
@@ -222,7 +225,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                             Description: "可以自定义类似 'onmouseover=prompt(1)'的标签事件",
                                         },
                                         Level: output.Medium,
-                                    }
+                                    }, " autofocus onfocus={js} x", " onmouseover={js} x")
                                     break
                                 }
                             }
@@ -244,7 +247,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                             for _, _item := range _locations {
                                 for _, v := range _item.Details.Value.Attributes {
                                     if v.Key == flag {
-                                        output.OutChannel <- output.VulMessage{
+                                        checker.report(index, output.VulMessage{
                                             DataType: "web_vul",
                                             Plugin:   "XSS",
                                             VulnData: output.VulnData{
@@ -257,7 +260,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                                 Description: fmt.Sprintf("引号可被闭合,可使用其他事件造成xss, 可使用 %s 进行攻击测试", truepayload),
                                             },
                                             Level: output.Medium,
-                                        }
+                                        }, attrVariants(_payload)...)
                                         break
                                     }
                                 }
@@ -275,7 +278,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                             _locations := ast.SearchInputInResponse(flag, resp.Body)
                             for _, _item := range _locations {
                                 if _item.Details.Value.TagName == flag {
-                                    output.OutChannel <- output.VulMessage{
+                                    checker.report(index, output.VulMessage{
                                         DataType: "web_vul",
                                         Plugin:   "XSS",
                                         VulnData: output.VulnData{
@@ -288,7 +291,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                             Description: fmt.Sprintf("html标签可被闭合,可使用 %s 进行攻击测试", fmt.Sprintf(_payload, "svg onload=alert`1`")),
                                         },
                                         Level: output.Medium,
-                                    }
+                                    }, htmlVariants(strings.TrimSuffix(_payload, "<%s>"))...)
                                     break
                                 }
 
@@ -316,7 +319,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                         truepayload = "javascript:alert(1)"
                                     }
 
-                                    output.OutChannel <- output.VulMessage{
+                                    checker.report(index, output.VulMessage{
                                         DataType: "web_vul",
                                         Plugin:   "XSS",
                                         VulnData: output.VulnData{
@@ -329,7 +332,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                             Description: fmt.Sprintf("值可控,%s的值可控，可能被恶意攻击,payload:%s", keyname, truepayload),
                                         },
                                         Level: output.Medium,
-                                    }
+                                    }, "javascript:{js}", "<img src=x onerror={js}>")
                                     break
                                 }
 
@@ -344,7 +347,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                             _locations := ast.SearchInputInResponse(payload, resp.Body)
                             for _, _item := range _locations {
                                 if funk.Contains(util.StructToJsonString(_item.Details), payload) && len(_item.Details.Value.Attributes) > 0 && _item.Details.Value.Attributes[0].Key == keyname {
-                                    output.OutChannel <- output.VulMessage{
+                                    checker.report(index, output.VulMessage{
                                         DataType: "web_vul",
                                         Plugin:   "XSS",
                                         VulnData: output.VulnData{
@@ -357,7 +360,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                             Description: "IE下可执行的表达式 payload:expression(alert(1))",
                                         },
                                         Level: output.Medium,
-                                    }
+                                    })
                                     break
                                 }
                             }
@@ -370,7 +373,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                             _locations := ast.SearchInputInResponse(payload, resp.Body)
                             for _, _item := range _locations {
                                 if len(_item.Details.Value.Attributes) > 0 && _item.Details.Value.Attributes[0].Val == payload && strings.ToLower(_item.Details.Value.Attributes[0].Key) == strings.ToLower(keyname) {
-                                    output.OutChannel <- output.VulMessage{
+                                    checker.report(index, output.VulMessage{
                                         DataType: "web_vul",
                                         Plugin:   "XSS",
                                         VulnData: output.VulnData{
@@ -383,7 +386,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                             Description: fmt.Sprintf("事件的值可控, %s的值可控，可能被恶意攻击", keyname),
                                         },
                                         Level: output.Medium,
-                                    }
+                                    }, "{js}", "{js}//")
                                     break
                                 }
                             }
@@ -403,7 +406,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                         _locations := ast.SearchInputInResponse(flag, resp.Body)
                         for _, _item := range _locations {
                             if _item.Details.Value.TagName == flag {
-                                output.OutChannel <- output.VulMessage{
+                                checker.report(index, output.VulMessage{
                                     DataType: "web_vul",
                                     Plugin:   "XSS",
                                     VulnData: output.VulnData{
@@ -416,7 +419,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                         Description: fmt.Sprintf("html注释可被闭合 测试payload: %s", truepayload),
                                     },
                                     Level: output.Medium,
-                                }
+                                }, htmlVariants(_payload)...)
                                 break
                             }
                         }
@@ -436,7 +439,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                     _locations := ast.SearchInputInResponse(flag, resp.Body)
                     for _, _item := range _locations {
                         if _item.Details.Value.Content == flag && strings.ToLower(_item.Details.Value.TagName) == strings.ToLower(script_tag) {
-                            output.OutChannel <- output.VulMessage{
+                            checker.report(index, output.VulMessage{
                                 DataType: "web_vul",
                                 Plugin:   "XSS",
                                 VulnData: output.VulnData{
@@ -449,7 +452,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                     Description: fmt.Sprintf("可以新建script标签执行任意代码 测试payload: %s", truepayload),
                                 },
                                 Level: output.Medium,
-                            }
+                            }, htmlVariants("</"+script_tag+">")...)
                             break
                         }
                     }
@@ -474,7 +477,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                 occurence := ast.SearchInputInScript(flag, __item.Details.Value.Content)
                                 for _, _output := range occurence {
                                     if funk.Contains(_output.Details.Value.Content, flag) && _output.Type == "ScriptIdentifier" {
-                                        output.OutChannel <- output.VulMessage{
+                                        checker.report(index, output.VulMessage{
                                             DataType: "web_vul",
                                             Plugin:   "XSS",
                                             VulnData: output.VulnData{
@@ -487,7 +490,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                                 Description: fmt.Sprintf("js单行注释可被\\n bypass, 测试payload: %s", truepayload),
                                             },
                                             Level: output.Medium,
-                                        }
+                                        }, "\n;{js};//")
                                         break
                                     }
                                 }
@@ -508,7 +511,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                 occurence := ast.SearchInputInScript(flag, __item.Details.Value.Content)
                                 for _, _output := range occurence {
                                     if funk.Contains(_output.Details.Value.Content, flag) && _output.Type == "ScriptIdentifier" {
-                                        output.OutChannel <- output.VulMessage{
+                                        checker.report(index, output.VulMessage{
                                             DataType: "web_vul",
                                             Plugin:   "XSS",
                                             VulnData: output.VulnData{
@@ -521,14 +524,14 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                                 Description: fmt.Sprintf("js单行注释可被*/ bypass, 测试payload: %s", truepayload),
                                             },
                                             Level: output.Medium,
-                                        }
+                                        }, "*/{js};/*")
                                         break
                                     }
                                 }
                             }
                         }
                     } else if _item.Type == "ScriptIdentifier" {
-                        output.OutChannel <- output.VulMessage{
+                        checker.report(index, output.VulMessage{
                             DataType: "web_vul",
                             Plugin:   "XSS",
                             VulnData: output.VulnData{
@@ -541,13 +544,15 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                 Description: "可直接执行任意js命令, ScriptIdentifier类型 测试payloadL: prompt(1);//",
                             },
                             Level: output.Medium,
-                        }
+                        }, "{js};//", "{js}")
                     } else if _item.Type == "ScriptLiteral" {
                         quote := string(_item.Details.Value.Content[0])
                         flag = util.RandomLetters(6)
+                        literal := []string{"{js}"}
                         if quote == "'" || quote == "\"" {
                             payload = fmt.Sprintf("%s-%s-%s", quote, flag, quote)
                             truepayload = fmt.Sprintf("%s-%s-%s", quote, "prompt(1)", quote)
+                            literal = []string{quote + "-{js}-" + quote, quote + ";{js};//"}
                         } else {
                             flag = util.RandomFromChoices(4, "abcdef123456")
                             payload = flag
@@ -572,7 +577,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                         occurence := ast.SearchInputInResponse(flag, resp2)
                         for _, _output := range occurence {
                             if funk.Contains(_output.Details.Value.Content, flag) && _output.Type == "ScriptIdentifier" {
-                                output.OutChannel <- output.VulMessage{
+                                checker.report(index, output.VulMessage{
                                     DataType: "web_vul",
                                     Plugin:   "XSS",
                                     VulnData: output.VulnData{
@@ -585,7 +590,7 @@ func Audit(in *input.CrawlResult, client *httpx.Client) {
                                         Description: fmt.Sprintf("script脚本内容可被任意设置, 测试payload: %s", truepayload),
                                     },
                                     Level: output.Medium,
-                                }
+                                }, literal...)
                                 break
                             }
                         }
@@ -0,0 +1,271 @@
+package xss
+
+import (
+    "context"
+    "encoding/base64"
+    "fmt"
+    "github.com/chromedp/cdproto/fetch"
+    "github.com/chromedp/cdproto/network"
+    "github.com/chromedp/cdproto/page"
+    "github.com/chromedp/cdproto/runtime"
+    "github.com/chromedp/chromedp"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/crawler"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/5
+   @desc 反射型 xss 的浏览器验证
+        语法分析只能判断输出位置，过滤、CSP 会导致误报。开启 browserVerify 后，在爬虫的浏览器中打开根据上下文生成的 payload
+        通过弹窗、console 的 hook 确认 js 真正执行，页面的 CSP 正常生效，确认的漏洞为 High，没有确认的降为 Low
+        没有启动浏览器时不验证，保持原来的等级；safe 模式下不在浏览器中重放 GET、HEAD 以外的请求，同样保持原来的等级
+        每次打开页面消耗一次请求数，扫描任务取消时关闭标签页
+**/
+
+// 在模板中替换为 probe 的表达式，alert 被页面重写时 console 也能收到
+const jsPlaceholder = "{js}"
+
+// cspHook 记录 CSP 拦截的内容，没有确认时在描述中说明
+const cspHook = `document.addEventListener("securitypolicyviolation", function(e){console.log("jie-csp " + e.violatedDirective)});`
+
+// trigger 对属性中包含标记的元素触发事件，事件属性、javascript: 伪协议需要交互才会执行
+const trigger = `(function(m){document.querySelectorAll("*").forEach(function(e){
+if(!Array.prototype.some.call(e.attributes,function(a){return a.value.indexOf(m)>=0}))return;
+["mouseover","focus"].forEach(function(t){try{e.dispatchEvent(new Event(t))}catch(x){}});
+try{e.click()}catch(x){}
+})})("%s")`
+
+// htmlVariants 闭合 prefix 后插入可以执行的标签
+func htmlVariants(prefix string) []string {
+    return []string{
+        prefix + "<img src=x onerror={js}>",
+        prefix + "<svg onload={js}>",
+        prefix + "<details open ontoggle={js}>",
+        prefix + "<script>{js}</script>",
+    }
+}
+
+// attrVariants 闭合引号后插入事件属性或标签
+func attrVariants(quote string) []string {
+    return []string{
+        quote + " autofocus onfocus={js} x=" + quote,
+        quote + " onmouseover={js} x=" + quote,
+        quote + "><img src=x onerror={js}>",
+    }
+}
+
+func probe(marker string) string {
+    return fmt.Sprintf("alert(%s)||console.log(%s)", marker, marker)
+}
+
+// verifier 一次 Audit 中需要验证的请求
+type verifier struct {
+    in         *input.CrawlResult
+    target     string
+    variations *httpx.Variations
+    client     *httpx.Client
+    tried      map[string]*execution // 参数位置 + payload: 结果，同一个参数的同一个 payload 只在浏览器中打开一次
+}
+
+// execution 浏览器中打开的结果
+type execution struct {
+    confirmed bool
+    payload   string
+    hook      string   // 确认执行的方式，dialog alert、console.log
+    csp       []string // CSP 拦截的指令
+}
+
+func newVerifier(in *input.CrawlResult, target string, variations *httpx.Variations, client *httpx.Client) *verifier {
+    return &verifier{in: in, target: target, variations: variations, client: client, tried: make(map[string]*execution)}
+}
+
+// report 输出语法分析发现的漏洞，开启验证时使用 variants 在浏览器中确认
+// variants 为空时(如只在 IE 下执行的 expression)无法在浏览器中确认，和没有确认的一样降为 Low
+func (v *verifier) report(index int, msg output.VulMessage, variants ...string) {
+    if !conf.GlobalConfig.Plugins.XSS.BrowserVerify || crawler.Browser == nil || !v.replayable() {
+        v.client.Report(msg)
+        return
+    }
+    if len(variants) == 0 {
+        msg.Level = output.Low
+        msg.VulnData.Description += " [not confirmed in browser: the payload only executes in legacy browsers and can not be verified]"
+        v.client.Report(msg)
+        return
+    }
+    
+    var csp []string
+    for _, variant := range variants {
+        res := v.verify(index, variant)
+        if res == nil {
+            continue
+        }
+        if res.confirmed {
+            msg.Level = output.High
+            msg.VulnData.Payload = res.payload
+            msg.VulnData.Description += fmt.Sprintf(" [confirmed in browser: the payload %s executed, caught by the %s hook]", res.payload, res.hook)
+            v.client.Report(msg)
+            return
+        }
+        csp = append(csp, res.csp...)
+    }
+    
+    msg.Level = output.Low
+    if csp = util.RemoveDuplicateElement(csp); len(csp) > 0 {
+        msg.VulnData.Description += fmt.Sprintf(" [not confirmed in browser: the payloads were blocked by the Content-Security-Policy directive %s]", strings.Join(csp, ", "))
+    } else {
+        msg.VulnData.Description += " [not confirmed in browser: none of the context payloads executed, the input may be sanitized]"
+    }
+    v.client.Report(msg)
+}
+
+// replayable safe 模式下只在浏览器中打开 GET、HEAD 请求，其他方法会重新提交请求体
+func (v *verifier) replayable() bool {
+    return v.in.Method == "GET" || v.in.Method == "HEAD" || !v.client.Scope.Safe()
+}
+
+// verify 生成 payload 对应的请求，在浏览器中打开
+func (v *verifier) verify(index int, variant string) *execution {
+    key := fmt.Sprintf("%d|%s", index, variant)
+    if res, ok := v.tried[key]; ok {
+        return res
+    }
+    marker := util.RandomFromChoices(9, "123456789")
+    payload := strings.ReplaceAll(variant, jsPlaceholder, probe(marker))
+    
+    target, body := v.target, ""
+    if v.in.Method == "GET" {
+        target = v.variations.SetPayloadByIndex(index, v.target, payload, v.in.Method)
+    } else {
+        body = v.variations.SetPayloadByIndex(index, v.target, payload, v.in.Method)
+    }
+    if target == "" || (v.in.Method != "GET" && body == "") {
+        v.tried[key] = nil
+        return nil
+    }
+    if err := v.client.Spend(); err != nil {
+        v.tried[key] = nil
+        return nil
+    }
+    
+    res, err := execute(v.client.Ctx, target, v.in.Method, body, v.in.ContentType, v.in.Headers, marker)
+    if err != nil {
+        logging.Logger.Debugln("[xss verify]", target, err)
+    }
+    if res != nil {
+        res.payload = payload
+    }
+    v.tried[key] = res
+    return res
+}
+
+// execute 打开页面并触发事件，弹窗或 console 中出现 marker 说明 js 执行了
+func execute(parent context.Context, target, method, body, contentType string, headers map[string]string, marker string) (*execution, error) {
+    if contentType == "" {
+        contentType = "application/x-www-form-urlencoded"
+    }
+    ctx, cancel := crawler.Browser.NewTabContext(parent, 20*time.Second)
+    defer cancel()
+    
+    var (
+        lock sync.Mutex
+        res  = &execution{}
+        sent bool
+    )
+    chromedp.ListenTarget(ctx, func(ev interface{}) {
+        switch e := ev.(type) {
+        case *page.EventJavascriptDialogOpening:
+            if strings.Contains(e.Message, marker) {
+                lock.Lock()
+                res.confirmed, res.hook = true, "dialog "+e.Type.String()
+                lock.Unlock()
+            }
+            go func() {
+                _ = chromedp.Run(ctx, page.HandleJavaScriptDialog(true))
+            }()
+        case *runtime.EventConsoleAPICalled:
+            for _, arg := range e.Args {
+                value := strings.Trim(string(arg.Value), `"`)
+                lock.Lock()
+                if strings.Contains(value, marker) && !res.confirmed {
+                    res.confirmed, res.hook = true, "console."+e.Type.String()
+                } else if strings.HasPrefix(value, "jie-csp ") {
+                    res.csp = append(res.csp, strings.TrimPrefix(value, "jie-csp "))
+                }
+                lock.Unlock()
+            }
+        case *fetch.EventRequestPaused:
+            // 只改写第一个文档请求的方法和请求体，其他的请求正常发送
+            go func() {
+                lock.Lock()
+                first := !sent && e.ResourceType == network.ResourceTypeDocument
+                sent = sent || first
+                lock.Unlock()
+                if !first {
+                    _ = chromedp.Run(ctx, fetch.ContinueRequest(e.RequestID))
+                    return
+                }
+                var entries []*fetch.HeaderEntry
+                for k, v := range e.Request.Headers {
+                    if !strings.EqualFold(k, "Content-Type") {
+                        entries = append(entries, &fetch.HeaderEntry{Name: k, Value: fmt.Sprint(v)})
+                    }
+                }
+                entries = append(entries, &fetch.HeaderEntry{Name: "Content-Type", Value: contentType})
+                _ = chromedp.Run(ctx, fetch.ContinueRequest(e.RequestID).
+                    WithMethod(method).
+                    WithPostData(base64.StdEncoding.EncodeToString([]byte(body))).
+                    WithHeaders(entries))
+            }()
+        }
+    })
+    
+    // 带上原始请求中的 cookie 等请求头
+    extra := make(network.Headers)
+    for k, v := range headers {
+        switch strings.ToLower(k) {
+        case "host", "content-length", "content-type", "connection", "accept-encoding":
+            continue
+        }
+        extra[k] = v
+    }
+    
+    tasks := chromedp.Tasks{
+        runtime.Enable(),
+        network.Enable(),
+        network.SetExtraHTTPHeaders(extra),
+        chromedp.ActionFunc(func(ctx context.Context) error {
+            _, err := page.AddScriptToEvaluateOnNewDocument(cspHook).Do(ctx)
+            return err
+        }),
+    }
+    if method != "GET" {
+        tasks = append(tasks, fetch.Enable().WithPatterns([]*fetch.RequestPattern{
+            {URLPattern: "*", ResourceType: network.ResourceTypeDocument, RequestStage: fetch.RequestStageRequest},
+        }))
+    }
+    tasks = append(tasks,
+        chromedp.Navigate(target),
+        chromedp.Sleep(2*time.Second),
+        chromedp.Evaluate(fmt.Sprintf(trigger, marker), nil),
+        chromedp.Sleep(time.Second),
+    )
+    err := chromedp.Run(ctx, tasks)
+    
+    lock.Lock()
+    defer lock.Unlock()
+    // 执行过程中报错(如超时)时，已经确认的结果仍然有效
+    if err != nil && !res.confirmed && len(res.csp) == 0 {
+        return nil, err
+    }
+    result := *res
+    return &result, nil
+}
@@ -1,8 +1,10 @@
 package xss
 
 import (
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "strings"
     "sync"
 )
 
@@ -21,9 +23,13 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
         return
     }
     Audit(in, client)
+    // 存储型 xss，由 tracker 在之后的响应中确认
+    stored(in, client)
     // dom 随主动爬虫检测了，默认就会检测
-    // 原型链污染查找 xss
-    // Prototype(in.Url)
+    // 原型链污染查找 xss，需要启动浏览器，同一个页面只检测一次
+    if in.ParseUrl != nil && in.Resp != nil && strings.Contains(strings.ToLower(in.Resp.Body), "<script") && !p.IsScanned("prototype|"+in.Host+in.ParseUrl.Path) {
+        Prototype(in, client)
+    }
 }
 
 func (p *Plugin) IsScanned(key string) bool {
@@ -40,3 +46,7 @@ func (p *Plugin) IsScanned(key string) bool {
 func (p *Plugin) Name() string {
     return "xss"
 }
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
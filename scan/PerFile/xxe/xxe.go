@@ -2,6 +2,7 @@ package xxe
 
 import (
     "github.com/thoas/go-funk"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -46,7 +47,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
     }
     res, payload, isVul := startTesting(in, client)
     if isVul {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "XXE",
             VulnData: output.VulnData{
@@ -60,7 +61,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
                 Payload:    payload,
             },
             Level: output.Critical,
-        }
+        })
         return
     }
 
@@ -82,6 +83,10 @@ func (p *Plugin) Name() string {
     return "xxe"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
 func startTesting(in *input.CrawlResult, client *httpx.Client) (*httpx.Response, string, bool) {
     variations, err := httpx.ParseUri(in.Url, []byte(in.RequestBody), in.Method, in.ContentType, in.Headers)
     if err != nil {
@@ -2,6 +2,7 @@ package crlf
 
 import (
     regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -46,6 +47,10 @@ func (p *Plugin) Name() string {
     return "crlf"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
 func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
     if p.IsScanned(in.UniqueId) {
         return
@@ -69,7 +74,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
             
             C := r.FindAllStringSubmatch(httpx.Header(res.Header), -1)
             if len(C) != 0 {
-                output.OutChannel <- output.VulMessage{
+                client.Report(output.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "CRLF",
                     VulnData: output.VulnData{
@@ -83,7 +88,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
                         Payload:    npl,
                     },
                     Level: output.Medium,
-                }
+                })
                 return
             }
             
@@ -94,7 +99,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
                 return
             }
             if str := r.FindString(httpx.Header(res.Header)); str != "" {
-                output.OutChannel <- output.VulMessage{
+                client.Report(output.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "CRLF",
                     VulnData: output.VulnData{
@@ -108,7 +113,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
                         Payload:    in.Resp.Body + pl,
                     },
                     Level: output.Medium,
-                }
+                })
                 return
             }
         }
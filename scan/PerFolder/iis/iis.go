@@ -1,6 +1,7 @@
 package iis
 
 import (
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -78,7 +79,7 @@ func (s *Scanner) Run() {
 
 func (s *Scanner) Report() {
     if len(s.files) > 0 {
-        output.OutChannel <- output.VulMessage{
+        s.client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "IIS",
             VulnData: output.VulnData{
@@ -90,7 +91,7 @@ func (s *Scanner) Report() {
                 Payload:    strings.Join(s.files, ", "),
             },
             Level: output.Medium,
-        }
+        })
         logging.Logger.Printf("%d Directories, %d Files found in total\nDirs: %v \nFile: %v\n", len(s.dirs), len(s.files), s.dirs, s.files)
     }
 
@@ -130,6 +131,10 @@ func (p *Plugin) Name() string {
     return "iis"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
+
 func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
     if p.IsScanned(in.UniqueId) {
         return
@@ -1,6 +1,7 @@
 package log4j
 
 import (
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/scan/Pocs/pocs_go/log4j"
@@ -21,6 +22,10 @@ func (p *Plugin) Name() string {
     return "log4j"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
 func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
     if p.IsScanned(in.UniqueId) {
         return
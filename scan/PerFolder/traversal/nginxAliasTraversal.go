@@ -2,6 +2,7 @@ package traversal
 
 import (
     "github.com/PuerkitoBio/goquery"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -41,7 +42,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
     if p.IsScanned(in.UniqueId) {
         return
     }
-    NginxAlias(target, in.Resp.Body, path)
+    NginxAlias(target, in.Resp.Body, path, client)
 }
 
 func (p *Plugin) IsScanned(key string) bool {
@@ -59,17 +60,21 @@ func (p *Plugin) Name() string {
     return "nginx-alias-traversal"
 }
 
-func NginxAlias(url string, body string, path string) {
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
+func NginxAlias(url string, body string, path string, client *httpx.Client) {
     if url[len(url)-1:] != "/" {
         url = url + "/"
     }
     path = strings.TrimPrefix(path, "/")
     // 检查默认字典加上 传来的路径
-    CheckFoldersForTraversal(url, util.RemoveDuplicateElement(append(dictionary, path)))
+    CheckFoldersForTraversal(url, util.RemoveDuplicateElement(append(dictionary, path)), client)
 
     // Check for alias traversal vulnerability (endpoint finding)
     if body == "" {
-        resp, err := httpx.Get(url)
+        resp, err := client.Request(url, "GET", "", nil)
         if err != nil {
             logging.Logger.Errorln(err)
             return
@@ -78,14 +83,14 @@ func NginxAlias(url string, body string, path string) {
     }
 
     // 使用 findEndpoints 获取当前页面的所有路径，然后再跑一遍。 TODO 这种不太好，会导致重复扫描
-    CheckFoldersForTraversal(url, findEndpoints(body))
+    CheckFoldersForTraversal(url, findEndpoints(body), client)
     // Check for directory listing
     // Check for file existence
     // Check for file contents
 }
 
-func CheckFolderForTraversal(url string, folder string) bool {
-    resp, err := httpx.Get(url + folder + ".")
+func CheckFolderForTraversal(url string, folder string, client *httpx.Client) bool {
+    resp, err := client.Request(url+folder+".", "GET", "", nil)
 
     if err != nil {
         return false
@@ -97,30 +102,30 @@ func CheckFolderForTraversal(url string, folder string) bool {
 
     if resp.StatusCode == 301 || resp.StatusCode == 302 {
         if strings.HasSuffix(resp.Location, folder+"./") {
-            resp, err := httpx.Get(url + folder + "..")
+            resp, err := client.Request(url+folder+"..", "GET", "", nil)
             if err != nil {
                 return false
             }
             if resp.StatusCode == 301 || resp.StatusCode == 302 {
                 if strings.HasSuffix(resp.Location, folder+"../") {
-                    respNotFound, err := httpx.Get(url + folder + "." + util.RandomString(4))
+                    respNotFound, err := client.Request(url+folder+"."+util.RandomString(4), "GET", "", nil)
                     if err != nil {
                         return false
                     }
                     if respNotFound.StatusCode == 404 || strings.Contains(strings.ToLower(respNotFound.Body), "not found") {
-                        respNotFound2, err := httpx.Get(url + folder + "z")
+                        respNotFound2, err := client.Request(url+folder+"z", "GET", "", nil)
                         if err != nil {
                             return false
                         }
                         if respNotFound2.StatusCode == 404 || strings.Contains(strings.ToLower(respNotFound2.Body), "not found") {
                             // vulnerable
-                            statusNotFound3, err := httpx.Get(url + folder + "z..")
+                            statusNotFound3, err := client.Request(url+folder+"z..", "GET", "", nil)
                             if err != nil {
                                 return false
                             }
                             if statusNotFound3.StatusCode != 302 && statusNotFound3.StatusCode != 301 {
                                 // vulnerable
-                                output.OutChannel <- output.VulMessage{
+                                client.Report(output.VulMessage{
                                     DataType: "web_vul",
                                     Plugin:   "Nginx Alias Traversal",
                                     VulnData: output.VulnData{
@@ -134,7 +139,7 @@ func CheckFolderForTraversal(url string, folder string) bool {
                                         Payload:    url + folder + "../",
                                     },
                                     Level: output.Medium,
-                                }
+                                })
                                 logging.Logger.Infof("Vulnerable: %s", url+folder+"../")
                                 return true
                             }
@@ -147,7 +152,7 @@ func CheckFolderForTraversal(url string, folder string) bool {
     return false
 }
 
-func CheckFoldersForTraversal(url string, folders []string) {
+func CheckFoldersForTraversal(url string, folders []string, client *httpx.Client) {
     var wg sync.WaitGroup
     semaphore := make(chan struct{}, 10)
 
@@ -164,7 +169,7 @@ func CheckFoldersForTraversal(url string, folders []string) {
         // Acquire a token from the semaphore channel
         <-semaphore
         go func(word string) {
-            CheckFolderForTraversal(url, word)
+            CheckFolderForTraversal(url, word, client)
             // Release the token back to the semaphore channel
             semaphore <- struct{}{}
             wg.Done()
@@ -4,6 +4,7 @@ import (
     "github.com/logrusorgru/aurora"
     "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/logging"
     "testing"
 )
@@ -26,5 +27,5 @@ func TestTraversal(t *testing.T) {
         }
     }()
 
-    NginxAlias("https://md.huodong.baidu.com/", "", "")
+    NginxAlias("https://md.huodong.baidu.com/", "", "", httpx.NewClient(&httpx.Options{QPS: 10}))
 }
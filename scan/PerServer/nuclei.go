@@ -1,6 +1,7 @@
 package PerServer
 
 import (
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/scan/Pocs/nuclei"
@@ -22,7 +23,7 @@ func (p *NucleiPlugin) Scan(target string, path string, in *input.CrawlResult, c
         return
     }
     
-    NucleiScan(target, in.Fingerprints)
+    NucleiScan(target, in.Fingerprints, client)
 }
 
 func (p *NucleiPlugin) IsScanned(key string) bool {
@@ -40,7 +41,11 @@ func (p *NucleiPlugin) Name() string {
     return "nuclei"
 }
 
-func NucleiScan(target string, fingerprints []string) {
+func (p *NucleiPlugin) Risk() string {
+    return conf.RiskIntrusive
+}
+
+func NucleiScan(target string, fingerprints []string, client *httpx.Client) {
     // 这里根据指纹进行对应的检测,TODO 还没搞好怎么和指纹匹配后再扫描
-    nuclei.Scan(target, fingerprints)
+    nuclei.Scan(target, fingerprints, client)
 }
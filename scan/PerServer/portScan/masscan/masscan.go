@@ -28,26 +28,36 @@ type State struct {
     ReasonTTL string `xml:"reason_ttl,attr"`
 }
 
+// Nmaprun nmap -oX 和 masscan -oX 的结果格式一样，都可以用这个解析
 type Nmaprun struct {
     XMLName    xml.Name `xml:"nmaprun"`
     StartTime  string   `xml:"start,attr"`
     Scanner    string   `xml:"scanner,attr"`
     Version    string   `xml:"version,attr"`
     XmlVersion string   `xml:"xmloutputversion,attr"`
+    Hosts      []Host   `xml:"host"`
 }
 
+// Host nmap 的结果中一个 host 会有多个 address(ipv4、mac)
 type Host struct {
     StartTime       string
-    Endtime         string  `xml:"endtime,attr"`
-    Address         Address `xml:"address"`
-    Ports           Ports   `xml:"ports>port"`
+    Endtime         string     `xml:"endtime,attr"`
+    Addresses       []Address  `xml:"address"`
+    Hostnames       []Hostname `xml:"hostnames>hostname"`
+    Ports           Ports      `xml:"ports>port"`
     LastScanTime    int
     LastScanEndTime int
 }
 
+type Hostname struct {
+    Name string `xml:"name,attr"`
+    Type string `xml:"type,attr"`
+}
+
 type Service struct {
     Name   string `xml:"name,attr"`
     Banner string `xml:"banner,attr"`
+    Tunnel string `xml:"tunnel,attr"`
 }
 
 type Masscan struct {
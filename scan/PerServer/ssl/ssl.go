@@ -0,0 +1,124 @@
+package ssl
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "github.com/yhy0/Jie/pkg/protocols/tlsx"
+    "github.com/yhy0/logging"
+    "golang.org/x/net/publicsuffix"
+    "net"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/6/30
+   @desc https 服务的 TLS 配置和证书检测，检测逻辑在 tlsx 中
+        证书 SAN 中的域名记录到 CollectionMsg 中，作为新发现的子域名
+        直接和目标建立 TLS 连接，不经过配置的代理
+**/
+
+type Plugin struct {
+    SeenRequests sync.Map
+}
+
+func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) {
+    u, err := url.Parse(target)
+    if err != nil || u.Scheme != "https" {
+        return
+    }
+    addr := u.Host
+    if u.Port() == "" {
+        addr = net.JoinHostPort(u.Hostname(), "443")
+    }
+    // 同一个 host:port 只检测一次
+    if p.IsScanned(addr) {
+        return
+    }
+    
+    timeout := time.Duration(conf.GlobalConfig.Http.Timeout) * time.Second
+    if timeout <= 0 {
+        timeout = 10 * time.Second
+    }
+    res, err := tlsx.Audit(client.Ctx, addr, u.Hostname(), timeout, client.Spend)
+    if err != nil {
+        logging.Logger.Debugln("[ssl]", addr, err)
+        return
+    }
+    
+    subdomains, others := domains(u.Hostname(), res.SANs)
+    client.SCopilot(in.Host, output.SCopilotData{
+        Target: in.Host,
+        PluginMsg: []output.PluginMsg{
+            {
+                Url:    target,
+                Plugin: "SSL",
+                Result: res.Summary(),
+            },
+        },
+        CollectionMsg: output.Collection{
+            Subdomain:   subdomains,
+            OtherDomain: others,
+        },
+    })
+    
+    summary := strings.Join(res.Summary(), "\n")
+    for _, issue := range res.Issues {
+        client.Report(output.VulMessage{
+            DataType: "web_vul",
+            Plugin:   "SSL",
+            VulnData: output.VulnData{
+                CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+                VulnType:    issue.Name,
+                Target:      target,
+                Ip:          in.Ip,
+                Description: issue.Description + "\n\n" + summary,
+            },
+            Level: issue.Level,
+        })
+    }
+}
+
+// domains SAN 中和目标同一个主域名的作为子域名，通配符去掉 *.，ip 不记录
+func domains(host string, sans []string) (subdomains, others []string) {
+    root, _ := publicsuffix.EffectiveTLDPlusOne(host)
+    for _, san := range sans {
+        if net.ParseIP(san) != nil {
+            continue
+        }
+        san = strings.TrimPrefix(strings.ToLower(san), "*.")
+        if san == host {
+            continue
+        }
+        if root != "" && (san == root || strings.HasSuffix(san, "."+root)) {
+            subdomains = append(subdomains, san)
+        } else {
+            others = append(others, san)
+        }
+    }
+    return
+}
+
+func (p *Plugin) IsScanned(key string) bool {
+    if key == "" {
+        return false
+    }
+    if _, ok := p.SeenRequests.Load(key); ok {
+        return true
+    }
+    p.SeenRequests.Store(key, true)
+    return false
+}
+
+func (p *Plugin) Name() string {
+    return "ssl"
+}
+
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
@@ -1,6 +1,7 @@
 package PerServer
 
 import (
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/scan/gadget/waybackarchive"
@@ -39,3 +40,7 @@ func (p *ArchivePlugin) IsScanned(key string) bool {
 func (p *ArchivePlugin) Name() string {
     return "archive"
 }
+
+func (p *ArchivePlugin) Risk() string {
+    return conf.RiskReadOnly
+}
@@ -30,6 +30,7 @@ import (
     "github.com/projectdiscovery/ratelimit"
     "github.com/yhy0/Jie/conf"
     JieOutput "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/pkg/util"
     "github.com/yhy0/logging"
     "os"
@@ -40,7 +41,8 @@ import (
 
 var updateLock sync.Mutex
 
-func Scan(target string, fingerprints []string) {
+// Scan 根据指纹选择模板扫描，client 提供扫描任务的安全模式、扫描策略和结果的接收者
+func Scan(target string, fingerprints []string, client *httpx.Client) {
     // todo payload 可以考虑和基础的信息扫描、 fuzz分开，防止被 waf 检测到发大量 payload 被封
     ft, tags := generateTemplates(fingerprints)
     
@@ -48,7 +50,7 @@ func Scan(target string, fingerprints []string) {
     outputWriter := testutils.NewMockOutputWriter(false)
     
     outputWriter.WriteCallback = func(event *output.ResultEvent) {
-        JieOutput.OutChannel <- JieOutput.VulMessage{
+        client.Report(JieOutput.VulMessage{
             DataType: "web_vul",
             Plugin:   "POC",
             VulnData: JieOutput.VulnData{
@@ -63,18 +65,39 @@ func Scan(target string, fingerprints []string) {
                 Description: event.Info.Description,
             },
             Level: util.FirstToUpper(event.Info.SeverityHolder.Severity.String()),
-        }
+        })
         return
     }
     
-    nuclei(target, ft, tags, outputWriter)
+    nuclei(target, ft, tags, outputWriter, client)
+}
+
+// budgetProgress nuclei 每发送一个请求消耗一次 client 的请求数，用完后取消扫描
+type budgetProgress struct {
+    testutils.MockProgressClient
+    client *httpx.Client
+    cancel context.CancelFunc
+}
+
+func (p *budgetProgress) IncrementRequests() {
+    if p.client.Spend() != nil {
+        p.cancel()
+    }
 }
 
-func nuclei(target string, ft []string, tags []string, outputWriter *testutils.MockOutputWriter) {
+func nuclei(target string, ft []string, tags []string, outputWriter *testutils.MockOutputWriter, client *httpx.Client) {
     cache := hosterrorscache.New(30, hosterrorscache.DefaultMaxHostsCount, nil)
     defer cache.Close()
     
-    mockProgress := &testutils.MockProgressClient{}
+    // 扫描任务取消或者请求数用完后停止
+    ctx := context.Background()
+    if client.Ctx != nil {
+        ctx = client.Ctx
+    }
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    
+    mockProgress := &budgetProgress{client: client, cancel: cancel}
     
     reportingClient, err := reporting.New(&reporting.Options{}, "", false)
     if err != nil {
@@ -129,8 +152,13 @@ func nuclei(target string, ft []string, tags []string, outputWriter *testutils.M
             "fuzzing/wordpress-plugins-detect.yaml",
             "fuzzing/wordpress-themes-detect.yaml",
         }
-        defaultOpts.Tags = tags
+        // 扫描策略中指定的模板标签
+        defaultOpts.Tags = templateTags(tags, client.Scope.ActivePolicy().TemplateTags)
         defaultOpts.ExcludeTags = append(config.ReadIgnoreFile().Tags, []string{"dos", "tech"}...)
+        // 安全模式下排除会对目标造成破坏、爆破类的模板
+        if client.Scope.Safe() {
+            defaultOpts.ExcludeTags = append(defaultOpts.ExcludeTags, []string{"intrusive", "fuzz", "bruteforce", "default-login", "fileupload"}...)
+        }
         update(defaultOpts)
     }
     
@@ -177,10 +205,18 @@ func nuclei(target string, ft []string, tags []string, outputWriter *testutils.M
     }
     store.Load()
     
-    _ = engine.Execute(context.Background(), store.Templates(), provider.NewSimpleInputProviderWithUrls(target))
+    _ = engine.Execute(ctx, store.Templates(), provider.NewSimpleInputProviderWithUrls(target))
     engine.WorkPool().Wait() // Wait for the scan to finish
 }
 
+// templateTags 合并指纹对应的标签和策略指定的标签，复制一份，避免 append 修改 tags 的底层数组
+func templateTags(tags, policyTags []string) []string {
+    res := make([]string, 0, len(tags)+len(policyTags))
+    res = append(res, tags...)
+    res = append(res, policyTags...)
+    return util.RemoveDuplicateElement(res)
+}
+
 // update 模板更新、下载
 func update(defaultOpts *types.Options) {
     updateLock.Lock()
@@ -5,6 +5,7 @@ import (
     "github.com/logrusorgru/aurora"
     "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/logging"
     "testing"
     "time"
@@ -29,8 +30,21 @@ func TestNuclei(t *testing.T) {
         }
     }()
     
-    Scan("https://yarx.koalr.me/", nil)
+    Scan("https://yarx.koalr.me/", nil, httpx.NewClient(&httpx.Options{QPS: 10}))
     
     fmt.Println("wait ...")
     time.Sleep(5 * time.Second)
 }
+
+// 合并策略标签不能修改指纹标签的底层数组
+func TestTemplateTags(t *testing.T) {
+    backing := []string{"apache", "tomcat", ""}
+    tags := backing[:2]
+    res := templateTags(tags, []string{"cve", "tomcat"})
+    if backing[2] != "" {
+        t.Errorf("templateTags wrote into the caller's slice: %v", backing)
+    }
+    if len(res) != 3 {
+        t.Errorf("templateTags = %v", res)
+    }
+}
@@ -38,11 +38,13 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
             }
         } else if strings.Contains(wt, "tomcat") && check["tomcat"] == false {
             check["tomcat"] = true
-            username, password := brute.TomcatBrute(target, client)
-            if username != "" {
-                vulnerability = true
-                plugin = "Apache Tomcat"
-                payload = fmt.Sprintf("brute-Tomcat|%s:%s", username, password)
+            if allowed("brute", target, client) {
+                username, password := brute.TomcatBrute(target, client)
+                if username != "" {
+                    vulnerability = true
+                    plugin = "Apache Tomcat"
+                    payload = fmt.Sprintf("brute-Tomcat|%s:%s", username, password)
+                }
             }
             var HOST string
             if host, err := url.Parse(target); err == nil {
@@ -54,26 +56,30 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
                 plugin = "Apache Tomcat"
                 payload += "exp-Tomcat|CVE_2020_1938 \n"
             }
-            if tomcat.CVE_2017_12615(target, client) {
+            if allowed("CVE_2017_12615", target, client) && tomcat.CVE_2017_12615(target, client) {
                 vulnerability = true
                 plugin = "Apache Tomcat"
                 payload += "exp-Tomcat|CVE_2017_12615 \n"
             }
         } else if strings.Contains(wt, "basic") && check["basic"] == false { // todo 这里还没有匹配到
             check["basic"] = true
-            username, password, _ := brute.BasicBrute(target, client)
-            if username != "" {
-                vulnerability = true
-                plugin = "Basic"
-                payload = fmt.Sprintf("brute-basic|%s:%s", username, password)
+            if allowed("brute", target, client) {
+                username, password, _ := brute.BasicBrute(target, client)
+                if username != "" {
+                    vulnerability = true
+                    plugin = "Basic"
+                    payload = fmt.Sprintf("brute-basic|%s:%s", username, password)
+                }
             }
         } else if strings.Contains(wt, "weblogic") && check["WebLogic"] == false {
             check["WebLogic"] = true
-            username, password := brute.WeblogicBrute(target, client)
-            if username != "" {
-                vulnerability = true
-                plugin = "WebLogic"
-                payload = fmt.Sprintf("brute-Weblogic|%s:%s \n", username, password)
+            if allowed("brute", target, client) {
+                username, password := brute.WeblogicBrute(target, client)
+                if username != "" {
+                    vulnerability = true
+                    plugin = "WebLogic"
+                    payload = fmt.Sprintf("brute-Weblogic|%s:%s \n", username, password)
+                }
             }
             if weblogic.CVE_2014_4210(target, client) {
                 vulnerability = true
@@ -115,7 +121,7 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
                 plugin = "WebLogic"
                 payload += "exp-WebLogic|CVE_2020_14882 \n"
             }
-            if weblogic.CVE_2020_14883(target, client) {
+            if allowed("CVE_2020_14883", target, client) && weblogic.CVE_2020_14883(target, client) {
                 vulnerability = true
                 plugin = "WebLogic"
                 payload += "exp-WebLogic|CVE_2020_14883 \n"
@@ -132,11 +138,13 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
                 plugin = "Jboss"
                 payload += "exp-Jboss|CVE_2017_12149| \n"
             }
-            username, password := brute.JbossBrute(target, client)
-            if username != "" {
-                vulnerability = true
-                plugin = "Jboss"
-                payload += fmt.Sprintf("brute-Jboss|%s:%s", username, password)
+            if allowed("brute", target, client) {
+                username, password := brute.JbossBrute(target, client)
+                if username != "" {
+                    vulnerability = true
+                    plugin = "Jboss"
+                    payload += fmt.Sprintf("brute-Jboss|%s:%s", username, password)
+                }
             }
         } else if strings.Contains(wt, "jenkins") && check["Jenkins"] == false {
             check["Jenkins"] = true
@@ -181,12 +189,12 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
                 plugin = "seeyon"
                 payload += "exp-seeyon|SeeyonFastjson \n"
             }
-            if seeyon.SessionUpload(target, client) {
+            if allowed("SessionUpload", target, client) && seeyon.SessionUpload(target, client) {
                 vulnerability = true
                 plugin = "seeyon"
                 payload += "exp-seeyon|SessionUpload \n"
             }
-            if seeyon.CNVD_2019_19299(target, client) {
+            if allowed("CNVD_2019_19299", target, client) && seeyon.CNVD_2019_19299(target, client) {
                 vulnerability = true
                 plugin = "seeyon"
                 payload += "exp-seeyon|CNVD_2019_19299 \n"
@@ -196,7 +204,7 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
                 plugin = "seeyon"
                 payload += "exp-seeyon|CNVD_2020_62422 \n"
             }
-            if seeyon.CNVD_2021_01627(target, client) {
+            if allowed("CNVD_2021_01627", target, client) && seeyon.CNVD_2021_01627(target, client) {
                 vulnerability = true
                 plugin = "seeyon"
                 payload += "exp-seeyon|CNVD_2021_01627 \n"
@@ -221,7 +229,7 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
                 plugin = "seeyon"
                 payload += "exp-seeyon|InitDataAssess \n"
             }
-            if seeyon.ManagementStatus(target, client) {
+            if allowed("ManagementStatus", target, client) && seeyon.ManagementStatus(target, client) {
                 vulnerability = true
                 plugin = "seeyon"
                 payload += "exp-seeyon|ManagementStatus \n"
@@ -233,11 +241,13 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
             }
         } else if (strings.Contains(wt, "loginPage") || strings.Contains(wt, "登录")) && check["loginPage"] == false {
             check["loginPage"] = true
-            username, password, loginurl := brute.Admin_brute(finalURL, client)
-            if loginurl != "" {
-                vulnerability = true
-                plugin = "LoginPage"
-                payload += fmt.Sprintf("brute-admin|%s:%s", username, password)
+            if allowed("brute", target, client) {
+                username, password, loginurl := brute.Admin_brute(finalURL, client)
+                if loginurl != "" {
+                    vulnerability = true
+                    plugin = "LoginPage"
+                    payload += fmt.Sprintf("brute-admin|%s:%s", username, password)
+                }
             }
         } else if strings.Contains(wt, "用友NC") && check["YongYouNc"] == false {
             check["YongYouNc"] = true
@@ -271,7 +281,7 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
     }
     
     if vulnerability {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   plugin,
             VulnData: output.VulnData{
@@ -281,7 +291,7 @@ func PocCheck(technologies []string, target string, finalURL string, ip string,
                 Payload:    payload,
             },
             Level: output.Critical,
-        }
+        })
     }
     
     return check
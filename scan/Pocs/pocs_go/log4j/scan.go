@@ -36,7 +36,7 @@ func Scan(target, method, body string, client *httpx.Client) {
     }
 
     if reverse.PullLogs(dig) {
-        JieOutput.OutChannel <- JieOutput.VulMessage{
+        client.Report(JieOutput.VulMessage{
             DataType: "web_vul",
             Plugin:   "Log4j",
             VulnData: JieOutput.VulnData{
@@ -47,7 +47,7 @@ func Scan(target, method, body string, client *httpx.Client) {
                 Payload:    dig.Key + "  " + dig.Token,
             },
             Level: JieOutput.Critical,
-        }
+        })
     }
 }
 
@@ -0,0 +1,45 @@
+package pocs_go
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "net/url"
+)
+
+/**
+   @author yhy
+   @since 2024/6/14
+   @desc poc 的风险等级，没有列出的默认为 intrusive
+**/
+
+var pocRisk = map[string]string{
+    "CVE_2017_12615":   conf.RiskDestructive, // PUT 写入文件
+    "CVE_2020_14883":   conf.RiskDestructive, // 在 console 目录下写入文件
+    "SessionUpload":    conf.RiskDestructive, // 上传文件
+    "CNVD_2019_19299":  conf.RiskDestructive, // 写入文件
+    "CNVD_2021_01627":  conf.RiskDestructive, // 写入文件
+    "ManagementStatus": conf.RiskLockout,     // 尝试默认密码登录
+    "brute":            conf.RiskLockout,     // 各种爆破
+}
+
+// PocRisk 获取 poc 的风险等级
+func PocRisk(name string) string {
+    if risk, ok := pocRisk[name]; ok {
+        return risk
+    }
+    return conf.RiskIntrusive
+}
+
+// allowed 判断当前模式下 poc 是否允许运行，不允许时记录下来
+func allowed(name, target string, client *httpx.Client) bool {
+    risk := PocRisk(name)
+    if client.Scope.RiskAllowed(risk) {
+        return true
+    }
+    host := target
+    if u, err := url.Parse(target); err == nil && u.Host != "" {
+        host = u.Host
+    }
+    client.Sink.Skipped(host, target, "poc-"+name, "safe mode, risk: "+risk)
+    return false
+}
@@ -4,6 +4,7 @@ import (
     "github.com/antlabs/strsim"
     "github.com/panjf2000/ants/v2"
     regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -12,6 +13,7 @@ import (
     scan_util "github.com/yhy0/Jie/scan/util"
     "github.com/yhy0/logging"
     "net/url"
+    "path"
     "strconv"
     "strings"
     "sync"
@@ -43,6 +45,7 @@ type Rule struct {
     TypeNo     string   // 不可能返回的 ContentType
     Fingprints []string // 指纹，只有匹配到该指纹，才会进行目录扫描
     Root       bool     // 是否为一级目录
+    RuleSet    string   // 所属的规则集，即 rules 目录下的文件名(不带后缀)，用于扫描策略筛选规则
 }
 
 var Rules map[string]*Rule
@@ -116,6 +119,7 @@ func init() {
                     continue
                 }
                 var rule Rule
+                rule.RuleSet = strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
                 
                 tag := RegTag.FindStringSubmatch(str)
                 status := RegStatus.FindStringSubmatch(str)
@@ -248,6 +252,11 @@ func BBscan(u string, root bool, fingprints []string, header map[string]string,
             continue
         }
         
+        // 扫描策略中指定了规则集时，只使用指定的规则集
+        if !ruleSetEnabled(rule.RuleSet, client.Scope.ActivePolicy().BBscanRules) {
+            continue
+        }
+        
         if util.Contains(path, "{sub}") {
             t, err := url.Parse(u)
             if err != nil {
@@ -363,7 +372,7 @@ func BBscan(u string, root bool, fingprints []string, header map[string]string,
                     
                     l.Unlock()
                     
-                    output.OutChannel <- output.VulMessage{
+                    client.Report(output.VulMessage{
                         DataType: "web_vul",
                         Plugin:   "BBscan",
                         VulnData: output.VulnData{
@@ -375,7 +384,7 @@ func BBscan(u string, root bool, fingprints []string, header map[string]string,
                             Response:   res.ResponseDump,
                         },
                         Level: output.Low,
-                    }
+                    })
                 }
             }
         })
@@ -491,3 +500,12 @@ func (p *Plugin) IsScanned(key string) bool {
 func (p *Plugin) Name() string {
     return "bbscan"
 }
+
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
+
+// ruleSetEnabled 规则集是否在策略指定的规则集中，需要完全匹配，防止 git 匹配到 git_and_svn 这种
+func ruleSetEnabled(ruleSet string, rules []string) bool {
+    return len(rules) == 0 || util.InCaseFoldSlice(rules, ruleSet)
+}
@@ -14,3 +14,15 @@ import (
 func TestBBscan(t *testing.T) {
     fmt.Println(Rules["/util/exec_sh?filePath=1"])
 }
+
+func TestRuleSetEnabled(t *testing.T) {
+    if !ruleSetEnabled("git_and_svn", nil) {
+        t.Error("all rule sets should be enabled without a policy")
+    }
+    if !ruleSetEnabled("Git_And_Svn", []string{"git_and_svn"}) {
+        t.Error("rule set names are case insensitive")
+    }
+    if ruleSetEnabled("git", []string{"git_and_svn"}) || ruleSetEnabled("git_and_svn", []string{"git"}) {
+        t.Error("rule set names must match exactly")
+    }
+}
@@ -13,7 +13,8 @@ import (
   @desc: //TODO
 **/
 
-func Hydra(target, host, service string, port int) {
+// Hydra 服务爆破，sink 为空时结果发送到 output.OutChannel
+func Hydra(target, host, service string, port int, sink *output.Sink) {
     var (
         msg string
         err error
@@ -49,7 +50,7 @@ func Hydra(target, host, service string, port int) {
         return
     }
 
-    output.OutChannel <- output.VulMessage{
+    sink.Report(output.VulMessage{
         DataType: "web_vul",
         Plugin:   "Hydra",
         VulnData: output.VulnData{
@@ -59,5 +60,5 @@ func Hydra(target, host, service string, port int) {
             Payload:    msg,
         },
         Level: output.Critical,
-    }
+    })
 }
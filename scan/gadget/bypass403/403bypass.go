@@ -3,6 +3,7 @@ package bypass403
 import (
     "embed"
     "fmt"
+    "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
@@ -79,6 +80,10 @@ func (p *Plugin) Name() string {
     return "bypass403"
 }
 
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
+
 func Bypass403(uri, m string, client *httpx.Client) {
     if !strings.HasSuffix(uri, "/") {
         uri += "/"
@@ -92,7 +97,7 @@ func Bypass403(uri, m string, client *httpx.Client) {
     
     result := method(uri, m, client)
     if result != nil {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "403 bypass",
             VulnData: output.VulnData{
@@ -103,13 +108,13 @@ func Bypass403(uri, m string, client *httpx.Client) {
                 Response:   result.Response,
             },
             Level: output.Medium,
-        }
+        })
         return
     }
     
     result = headers(uri, m, client)
     if result != nil {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "403 bypass",
             VulnData: output.VulnData{
@@ -120,13 +125,13 @@ func Bypass403(uri, m string, client *httpx.Client) {
                 Response:   result.Response,
             },
             Level: output.Medium,
-        }
+        })
         return
     }
     
     result = endPaths(uri, m, client)
     if result != nil {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "403 bypass",
             VulnData: output.VulnData{
@@ -137,13 +142,13 @@ func Bypass403(uri, m string, client *httpx.Client) {
                 Response:   result.Response,
             },
             Level: output.Medium,
-        }
+        })
         return
     }
     
     result = midPaths(uri, m, client)
     if result != nil {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "403 bypass",
             VulnData: output.VulnData{
@@ -154,13 +159,13 @@ func Bypass403(uri, m string, client *httpx.Client) {
                 Response:   result.Response,
             },
             Level: output.Medium,
-        }
+        })
         return
     }
     
     result = capital(uri, m, client)
     if result != nil {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "403 bypass",
             VulnData: output.VulnData{
@@ -171,13 +176,13 @@ func Bypass403(uri, m string, client *httpx.Client) {
                 Response:   result.Response,
             },
             Level: output.Medium,
-        }
+        })
         return
     }
     
     result = http10(uri, m)
     if result != nil {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "403 bypass",
             VulnData: output.VulnData{
@@ -188,7 +193,7 @@ func Bypass403(uri, m string, client *httpx.Client) {
                 Response:   result.Response,
             },
             Level: output.Medium,
-        }
+        })
         return
     }
     
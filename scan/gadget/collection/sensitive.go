@@ -1,11 +1,13 @@
 package collection
 
 import (
+    regexp "github.com/wasilibs/go-re2"
     "github.com/yhy0/Jie/conf"
     "github.com/yhy0/Jie/pkg/input"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/Jie/pkg/protocols/httpx"
     "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
     "strings"
     "sync"
     "time"
@@ -26,7 +28,7 @@ func (p *Plugin) Scan(target string, path string, in *input.CrawlResult, client
         return
     }
     
-    SensitiveParameters(in)
+    SensitiveParameters(in, client)
 }
 
 func (p *Plugin) IsScanned(key string) bool {
@@ -44,7 +46,12 @@ func (p *Plugin) Name() string {
     return "SensitiveParameters"
 }
 
-func SensitiveParameters(in *input.CrawlResult) {
+func (p *Plugin) Risk() string {
+    return conf.RiskReadOnly
+}
+
+// SensitiveParameters 请求、响应中出现敏感参数时输出，client 为空时结果发送到 output.OutChannel
+func SensitiveParameters(in *input.CrawlResult, client *httpx.Client) {
     var sensitiveParameters, rawRequest, rawResponse string
     resParameters, _ := util.GetResParameters(strings.ToLower(in.Resp.Header.Get("Content-Type")), []byte(in.Resp.Body))
     
@@ -71,7 +78,7 @@ func SensitiveParameters(in *input.CrawlResult) {
         }
     }
     if sensitiveParameters != "" {
-        output.OutChannel <- output.VulMessage{
+        client.Report(output.VulMessage{
             DataType: "web_vul",
             Plugin:   "SensitiveParameters",
             VulnData: output.VulnData{
@@ -83,6 +90,48 @@ func SensitiveParameters(in *input.CrawlResult) {
                 Response:   rawResponse,
             },
             Level: output.Low,
+        })
+    }
+}
+
+// compiled 编译好的配置文件中的正则，SensitiveResponse 每个 CORS 结果都会调用，避免重复编译
+var compiled sync.Map
+
+// compile 返回缓存的正则，规则写错时返回 nil
+func compile(pattern string) *regexp.Regexp {
+    if re, ok := compiled.Load(pattern); ok {
+        return re.(*regexp.Regexp)
+    }
+    re, err := regexp.Compile(pattern)
+    if err != nil {
+        logging.Logger.Errorln("invalid collection rule", pattern, err)
+        compiled.Store(pattern, (*regexp.Regexp)(nil))
+        return nil
+    }
+    compiled.Store(pattern, re)
+    return re
+}
+
+// SensitiveResponse 返回响应中的敏感参数名以及手机号、邮箱、身份证号，只做判断不输出结果，供其他插件评估响应是否包含敏感数据
+func SensitiveResponse(contentType, body string) []string {
+    var result []string
+    resParameters, _ := util.GetResParameters(strings.ToLower(contentType), []byte(body))
+    for _, para := range conf.GlobalConfig.Collection.SensitiveParameters {
+        if strings.HasPrefix(para, "_") {
+            if util.InSliceCaseFold(para, resParameters) {
+                result = append(result, para)
+            }
+        } else if util.InCaseFoldSlice(resParameters, para) {
+            result = append(result, para)
+        }
+    }
+    
+    for _, rules := range [][]string{conf.GlobalConfig.Collection.Phone, conf.GlobalConfig.Collection.Email, conf.GlobalConfig.Collection.IDCard} {
+        for _, v := range rules {
+            if re := compile(v); re != nil {
+                result = append(result, util.RemoveQuotation(re.FindAllString(body, -1))...)
+            }
         }
     }
+    return result
 }
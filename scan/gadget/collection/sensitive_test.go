@@ -0,0 +1,29 @@
+package collection
+
+import (
+    "github.com/yhy0/Jie/conf"
+    "github.com/yhy0/logging"
+    "os"
+    "testing"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "collection", false)
+    os.Exit(m.Run())
+}
+
+func TestSensitiveResponse(t *testing.T) {
+    conf.GlobalConfig = &conf.Config{}
+    conf.GlobalConfig.Collection.Email = []string{`[a-z0-9]+@[a-z0-9]+\.com`, `(invalid`}
+    
+    body := `{"user": "admin", "contact": "admin@example.com"}`
+    for i := 0; i < 2; i++ {
+        res := SensitiveResponse("application/json", body)
+        if len(res) != 1 || res[0] != "admin@example.com" {
+            t.Errorf("SensitiveResponse = %v", res)
+        }
+    }
+    if compile(`[a-z0-9]+@[a-z0-9]+\.com`) != compile(`[a-z0-9]+@[a-z0-9]+\.com`) {
+        t.Error("rule compiled twice")
+    }
+}
@@ -0,0 +1,148 @@
+package headers
+
+import (
+    "fmt"
+    "strings"
+)
+
+/**
+   @author yhy
+   @since 2024/6/29
+   @desc CSP 策略评估，参考 Google CSP Evaluator
+**/
+
+// 允许这些域名时可以通过 JSONP、AngularJS 等绕过 CSP
+var bypassHosts = []string{
+    "www.google.com", "accounts.google.com", "www.googleapis.com", "ajax.googleapis.com", "apis.google.com",
+    "www.gstatic.com", "www.googletagmanager.com", "www.google-analytics.com", "translate.googleapis.com",
+    "cdnjs.cloudflare.com", "cdn.jsdelivr.net", "unpkg.com", "code.jquery.com", "raw.githubusercontent.com",
+    "connect.facebook.net", "graph.facebook.com", "api.twitter.com", "syndication.twitter.com",
+    "api.vk.com", "suggest.yandex.ru", "mc.yandex.ru", "api-maps.yandex.ru", "api.map.baidu.com",
+    "s3.amazonaws.com", "d1.awsstatic.com", "appspot.com", "herokuapp.com", "firebaseapp.com", "github.io",
+    "googleusercontent.com", "storage.googleapis.com", "azurewebsites.net", "cloudfront.net",
+}
+
+type policy map[string][]string
+
+// parsePolicy 解析 CSP，指令名小写，重复的指令只使用第一个
+func parsePolicy(s string) policy {
+    p := make(policy)
+    for _, d := range strings.Split(s, ";") {
+        fields := strings.Fields(strings.TrimSpace(d))
+        if len(fields) == 0 {
+            continue
+        }
+        name := strings.ToLower(fields[0])
+        if _, ok := p[name]; ok {
+            continue
+        }
+        var sources []string
+        for _, f := range fields[1:] {
+            sources = append(sources, strings.ToLower(f))
+        }
+        p[name] = sources
+    }
+    return p
+}
+
+// directive 指令没有设置时使用 default-src
+func (p policy) directive(name string) ([]string, bool) {
+    if v, ok := p[name]; ok {
+        return v, true
+    }
+    v, ok := p["default-src"]
+    return v, ok
+}
+
+func contains(sources []string, s string) bool {
+    for _, v := range sources {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
+
+// evaluate 返回策略中的问题
+func evaluate(s string) []string {
+    p := parsePolicy(s)
+    var issues []string
+    
+    scripts, ok := p.directive("script-src")
+    if !ok {
+        issues = append(issues, "script-src and default-src are missing, scripts from any source are allowed")
+    } else {
+        nonce := false
+        for _, src := range scripts {
+            if strings.HasPrefix(src, "'nonce-") || strings.HasPrefix(src, "'sha256-") || strings.HasPrefix(src, "'sha384-") || strings.HasPrefix(src, "'sha512-") {
+                nonce = true
+            }
+        }
+        strictDynamic := contains(scripts, "'strict-dynamic'")
+        
+        // 有 nonce、hash 时 unsafe-inline 会被忽略
+        if contains(scripts, "'unsafe-inline'") && !nonce {
+            issues = append(issues, "script-src allows 'unsafe-inline', inline scripts and event handlers can run (XSS is not mitigated)")
+        }
+        if contains(scripts, "'unsafe-eval'") {
+            issues = append(issues, "script-src allows 'unsafe-eval', eval() and similar functions can run")
+        }
+        // strict-dynamic 时白名单会被忽略
+        if !strictDynamic {
+            for _, src := range scripts {
+                switch {
+                case src == "*" || src == "http:" || src == "https:":
+                    issues = append(issues, fmt.Sprintf("script-src allows the wildcard source %s, scripts can be loaded from any host", src))
+                case src == "data:" || src == "blob:":
+                    issues = append(issues, fmt.Sprintf("script-src allows %s, scripts can be injected as %s URIs", src, src))
+                case strings.HasPrefix(src, "'"):
+                default:
+                    if h := bypassHost(src); h != "" {
+                        issues = append(issues, fmt.Sprintf("script-src allows %s which hosts JSONP endpoints or script gadgets (%s) that can bypass the policy", src, h))
+                    } else if h := hostOf(src); strings.HasPrefix(h, "*.") && !strings.Contains(h[2:], ".") {
+                        issues = append(issues, fmt.Sprintf("script-src allows the wildcard source %s, scripts can be loaded from any domain under it", src))
+                    }
+                }
+            }
+        }
+        if nonce && !contains(p["base-uri"], "'none'") && !contains(p["base-uri"], "'self'") {
+            issues = append(issues, "base-uri is missing, an injected <base> tag can load nonce-allowed scripts from an attacker host")
+        }
+    }
+    
+    if objects, ok := p.directive("object-src"); !ok {
+        issues = append(issues, "object-src is missing, plugins (<object>, <embed>) can be used to execute scripts")
+    } else if !contains(objects, "'none'") {
+        for _, src := range objects {
+            if src == "*" || src == "http:" || src == "https:" || src == "data:" {
+                issues = append(issues, fmt.Sprintf("object-src allows %s, plugins can be loaded from any source", src))
+            }
+        }
+    }
+    return issues
+}
+
+// bypassHost 来源是否匹配已知可以绕过 CSP 的域名
+func bypassHost(src string) string {
+    host := hostOf(src)
+    wildcard := strings.HasPrefix(host, "*.")
+    host = strings.TrimPrefix(host, "*.")
+    for _, h := range bypassHosts {
+        if host == h || strings.HasSuffix(host, "."+h) || (wildcard && strings.HasSuffix(h, "."+host)) {
+            return h
+        }
+    }
+    return ""
+}
+
+// hostOf 来源中的域名，去掉协议、端口和路径
+func hostOf(src string) string {
+    host := src
+    if i := strings.Index(host, "://"); i >= 0 {
+        host = host[i+3:]
+    }
+    if i := strings.IndexAny(host, "/:"); i >= 0 {
+        host = host[:i]
+    }
+    return host
+}
@@ -0,0 +1,55 @@
+package headers
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestEvaluate(t *testing.T) {
+    tests := []struct {
+        policy string
+        want   []string // 每个问题中包含的关键字，顺序一致
+    }{
+        {"default-src 'self'; object-src 'none'", nil},
+        {"script-src 'nonce-abc' 'strict-dynamic' https:; object-src 'none'; base-uri 'none'", nil},
+        {"object-src 'none'", []string{"script-src and default-src are missing"}},
+        {"script-src 'self'", []string{"object-src is missing"}},
+        // object-src 没有设置时使用 default-src
+        {"default-src 'self'", nil},
+        {"script-src 'self' 'unsafe-inline' 'unsafe-eval'; object-src 'none'", []string{"'unsafe-inline'", "'unsafe-eval'"}},
+        // 有 nonce 时 unsafe-inline 被忽略，但缺少 base-uri
+        {"script-src 'nonce-abc' 'unsafe-inline'; object-src 'none'", []string{"base-uri is missing"}},
+        {"script-src * data:; object-src *", []string{"wildcard source *", "allows data:", "object-src allows *"}},
+        {"script-src https://ajax.googleapis.com *.org; object-src 'none'", []string{"(ajax.googleapis.com)", "wildcard source *.org"}},
+        {"script-src *.example.com cdn.example.com; object-src 'none'", nil},
+        // 重复的指令只使用第一个
+        {"script-src 'self'; script-src *; object-src 'none'", nil},
+    }
+    for _, tt := range tests {
+        got := evaluate(tt.policy)
+        if len(got) != len(tt.want) {
+            t.Errorf("evaluate(%q) = %q, want %d issues", tt.policy, got, len(tt.want))
+            continue
+        }
+        for i, w := range tt.want {
+            if !strings.Contains(got[i], w) {
+                t.Errorf("evaluate(%q)[%d] = %q, want it to contain %q", tt.policy, i, got[i], w)
+            }
+        }
+    }
+}
+
+func TestBypassHost(t *testing.T) {
+    tests := map[string]string{
+        "https://www.google.com/recaptcha/": "www.google.com",
+        "*.googleapis.com":                  "www.googleapis.com",
+        "foo.github.io":                     "github.io",
+        "https://example.com":               "",
+        "notgithub.io":                      "",
+    }
+    for src, want := range tests {
+        if got := bypassHost(src); got != want {
+            t.Errorf("bypassHost(%q) = %q, want %q", src, got, want)
+        }
+    }
+}
@@ -0,0 +1,202 @@
+package headers
+
+import (
+    "fmt"
+    regexp "github.com/wasilibs/go-re2"
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/output"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+/**
+   @author yhy
+   @since 2024/6/29
+   @desc 被动检测安全响应头和 cookie 属性，CSP、HSTS、X-Frame-Options、Referrer-Policy、X-Content-Type-Options
+        以及 cookie 的 Secure/HttpOnly/SameSite，同一个网站的同一个问题只记录一次(第一次出现的链接)，不对每个链接重复输出
+        去重记录保存在 Auditor 中，每个扫描任务一个，不同任务之间互不影响
+**/
+
+const plugin = "SecurityHeaders"
+
+// 问题等级，缺失和配置不当
+const (
+    missing = "missing"
+    weak    = "weak"
+)
+
+// HSTS 最小有效期，180 天
+const hstsMinAge = 15552000
+
+var (
+    // 会话类的 cookie 名，需要 HttpOnly
+    sessionRegex = regexp.MustCompile(`(?i)(sess|sid$|^sid|token|auth|jwt|login|user|remember|jsessionid|phpsessid|asp\.net)`)
+    maxAgeRegex  = regexp.MustCompile(`(?i)max-age\s*=\s*"?(\d+)`)
+)
+
+// maxHosts 单个任务最多记录的网站数，被动扫描长时间运行时超过后清空重新记录，最多导致同一个问题再输出一次
+const maxHosts = 10000
+
+// Auditor 记录已经输出过的问题，零值可用
+type Auditor struct {
+    lock sync.Mutex
+    seen map[string]map[string]bool // key 为 host，value 为已经记录过的问题
+}
+
+type issue struct {
+    key    string // 去重使用
+    grade  string
+    result string
+}
+
+// Audit 检测响应中的安全头，返回这个网站之前没有出现过的问题
+func (a *Auditor) Audit(in *input.CrawlResult) []output.PluginMsg {
+    if in.Resp == nil || in.Resp.Header == nil || in.Resp.StatusCode >= 400 {
+        return nil
+    }
+    header := in.Resp.Header
+    https := strings.HasPrefix(strings.ToLower(in.Url), "https://")
+    html := strings.Contains(strings.ToLower(header.Get("Content-Type")), "text/html")
+    
+    var issues []issue
+    if html {
+        issues = append(issues, csp(header)...)
+        issues = append(issues, frame(header)...)
+        issues = append(issues, referrer(header)...)
+    }
+    if https {
+        issues = append(issues, hsts(header)...)
+    }
+    if v := header.Get("X-Content-Type-Options"); !strings.EqualFold(strings.TrimSpace(v), "nosniff") {
+        issues = append(issues, issue{"nosniff", missing, "X-Content-Type-Options: nosniff is missing, browsers may MIME-sniff responses"})
+    }
+    issues = append(issues, cookies(header, https)...)
+    
+    a.lock.Lock()
+    defer a.lock.Unlock()
+    if a.seen == nil || len(a.seen) >= maxHosts && a.seen[in.Host] == nil {
+        a.seen = make(map[string]map[string]bool)
+    }
+    seen := a.seen[in.Host]
+    if seen == nil {
+        seen = make(map[string]bool)
+        a.seen[in.Host] = seen
+    }
+    var msgs []output.PluginMsg
+    for _, i := range issues {
+        if seen[i.key] {
+            continue
+        }
+        seen[i.key] = true
+        msgs = append(msgs, output.PluginMsg{
+            Url:    in.Url,
+            Plugin: plugin,
+            Result: []string{fmt.Sprintf("[%s] %s", i.grade, i.result)},
+        })
+    }
+    return msgs
+}
+
+// csp 只检查 HTML 页面，只有 Report-Only 时策略不会生效
+func csp(header http.Header) []issue {
+    policies := header.Values("Content-Security-Policy")
+    if len(policies) == 0 {
+        if ro := header.Get("Content-Security-Policy-Report-Only"); ro != "" {
+            return []issue{{"csp", weak, "Only Content-Security-Policy-Report-Only is set, the policy is not enforced: " + ro}}
+        }
+        return []issue{{"csp", missing, "Content-Security-Policy is missing"}}
+    }
+    var issues []issue
+    for _, p := range policies {
+        for _, s := range evaluate(p) {
+            issues = append(issues, issue{"csp|" + s, weak, "Content-Security-Policy " + s + ": " + p})
+        }
+    }
+    return issues
+}
+
+// frame X-Frame-Options 或者 CSP frame-ancestors 防止点击劫持
+func frame(header http.Header) []issue {
+    for _, v := range header.Values("Content-Security-Policy") {
+        if ancestors, ok := parsePolicy(v)["frame-ancestors"]; ok {
+            if contains(ancestors, "*") || contains(ancestors, "http:") || contains(ancestors, "https:") {
+                return []issue{{"frame", weak, "CSP frame-ancestors allows any origin to frame the page (clickjacking): " + strings.Join(ancestors, " ")}}
+            }
+            return nil
+        }
+    }
+    xfo := strings.ToUpper(strings.TrimSpace(header.Get("X-Frame-Options")))
+    switch {
+    case xfo == "":
+        return []issue{{"frame", missing, "X-Frame-Options and CSP frame-ancestors are missing, the page can be framed (clickjacking)"}}
+    case strings.HasPrefix(xfo, "ALLOW-FROM"):
+        return []issue{{"frame", weak, "X-Frame-Options ALLOW-FROM is not supported by modern browsers, use CSP frame-ancestors: " + xfo}}
+    case xfo != "DENY" && xfo != "SAMEORIGIN":
+        return []issue{{"frame", weak, "X-Frame-Options has an invalid value and is ignored: " + xfo}}
+    }
+    return nil
+}
+
+// hsts 只检查 https
+func hsts(header http.Header) []issue {
+    v := header.Get("Strict-Transport-Security")
+    if v == "" {
+        return []issue{{"hsts", missing, "Strict-Transport-Security is missing, the first request can be downgraded to http"}}
+    }
+    m := maxAgeRegex.FindStringSubmatch(v)
+    if m == nil {
+        return []issue{{"hsts", weak, "Strict-Transport-Security has no max-age and is ignored: " + v}}
+    }
+    age, _ := strconv.Atoi(m[1])
+    if age == 0 {
+        return []issue{{"hsts", weak, "Strict-Transport-Security max-age=0 disables HSTS: " + v}}
+    }
+    if age < hstsMinAge {
+        return []issue{{"hsts", weak, fmt.Sprintf("Strict-Transport-Security max-age is less than %d seconds (180 days): %s", hstsMinAge, v)}}
+    }
+    return nil
+}
+
+func referrer(header http.Header) []issue {
+    v := strings.ToLower(strings.TrimSpace(header.Get("Referrer-Policy")))
+    if v == "" {
+        return []issue{{"referrer", missing, "Referrer-Policy is missing"}}
+    }
+    // 可以有多个值，最后一个支持的生效
+    values := strings.Split(v, ",")
+    last := strings.TrimSpace(values[len(values)-1])
+    if last == "unsafe-url" || last == "no-referrer-when-downgrade" {
+        return []issue{{"referrer", weak, "Referrer-Policy " + last + " leaks the full URL to other origins"}}
+    }
+    return nil
+}
+
+// cookies 每个 cookie 名只记录一次
+func cookies(header http.Header, https bool) []issue {
+    var issues []issue
+    for _, c := range (&http.Response{Header: header}).Cookies() {
+        // 删除 cookie
+        if c.MaxAge < 0 || c.Value == "" {
+            continue
+        }
+        key := "cookie|" + c.Name
+        if https && !c.Secure {
+            issues = append(issues, issue{key + "|secure", missing, fmt.Sprintf("Cookie %s is set over https without the Secure flag", c.Name)})
+        }
+        if !c.HttpOnly && sessionRegex.MatchString(c.Name) {
+            issues = append(issues, issue{key + "|httponly", missing, fmt.Sprintf("Session cookie %s is set without the HttpOnly flag, it can be read by scripts", c.Name)})
+        }
+        switch c.SameSite {
+        case http.SameSiteLaxMode, http.SameSiteStrictMode:
+        case http.SameSiteNoneMode:
+            if !c.Secure {
+                issues = append(issues, issue{key + "|samesite", weak, fmt.Sprintf("Cookie %s is set with SameSite=None but without Secure, browsers reject it", c.Name)})
+            }
+        default:
+            issues = append(issues, issue{key + "|samesite", missing, fmt.Sprintf("Cookie %s is set without the SameSite attribute", c.Name)})
+        }
+    }
+    return issues
+}
@@ -0,0 +1,47 @@
+package headers
+
+import (
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/Jie/pkg/protocols/httpx"
+    "net/http"
+    "strconv"
+    "testing"
+)
+
+func crawlResult(host string) *input.CrawlResult {
+    header := http.Header{}
+    header.Set("Content-Type", "text/html")
+    return &input.CrawlResult{
+        Url:  "http://" + host + "/",
+        Host: host,
+        Resp: &httpx.Response{StatusCode: 200, Header: header},
+    }
+}
+
+// 同一个任务中同一个网站的问题只输出一次，不同任务互不影响
+func TestAuditorDedupe(t *testing.T) {
+    var a, b Auditor
+    first := a.Audit(crawlResult("example.com"))
+    if len(first) == 0 {
+        t.Fatal("no issues for a page without security headers")
+    }
+    if again := a.Audit(crawlResult("example.com")); len(again) != 0 {
+        t.Errorf("issues reported twice: %v", again)
+    }
+    if other := a.Audit(crawlResult("example.org")); len(other) != len(first) {
+        t.Errorf("another host got %d issues, want %d", len(other), len(first))
+    }
+    if fresh := b.Audit(crawlResult("example.com")); len(fresh) != len(first) {
+        t.Errorf("another auditor got %d issues, want %d", len(fresh), len(first))
+    }
+}
+
+func TestAuditorBounded(t *testing.T) {
+    var a Auditor
+    for i := 0; i < maxHosts+10; i++ {
+        a.Audit(crawlResult("host" + strconv.Itoa(i) + ".com"))
+    }
+    if len(a.seen) > maxHosts {
+        t.Errorf("auditor keeps %d hosts, at most %d", len(a.seen), maxHosts)
+    }
+}
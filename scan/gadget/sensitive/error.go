@@ -1,6 +1,7 @@
 package sensitive
 
 import (
+    "fmt"
     regexp "github.com/wasilibs/go-re2"
     "github.com/yhy0/Jie/pkg/output"
     "github.com/yhy0/logging"
@@ -83,12 +84,12 @@ func init() {
     }
 }
 
-func PageErrorMessageCheck(url, req, body string) []ErrorMessage {
-    // 因为放到了 httpx.Request 中，所以会有很多重复，这里检验一下 url 是否已经检测过了
-    if _, ok := seenRequests.Load(url); ok {
+// PageErrorMessageCheck 检测页面中的报错信息，sink 为空时结果发送到 output.OutChannel
+func PageErrorMessageCheck(url, req, body string, sink *output.Sink) []ErrorMessage {
+    // 因为放到了 httpx.Request 中，所以会有很多重复，这里检验一下 url 是否已经检测过了，不同的扫描任务分开记录
+    if _, ok := seenRequests.LoadOrStore(fmt.Sprintf("%p|%s", sink, url), true); ok {
         return nil
     }
-    seenRequests.Store(url, true)
     
     var results []ErrorMessage
     for _, errorMsg := range errorCompiled {
@@ -105,7 +106,7 @@ func PageErrorMessageCheck(url, req, body string) []ErrorMessage {
                 Type: errorMsg.Msg.Type,
             })
             
-            output.OutChannel <- output.VulMessage{
+            sink.Report(output.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "Sensitive error",
                 VulnData: output.VulnData{
@@ -117,7 +118,7 @@ func PageErrorMessageCheck(url, req, body string) []ErrorMessage {
                     // Response:   body,
                 },
                 Level: output.Low,
-            }
+            })
             logging.Logger.Infoln("[Sensitive]", url, errorMsg.Msg.Type, result)
         }
     }
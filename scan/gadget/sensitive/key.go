@@ -84,8 +84,8 @@ func init() {
     
 }
 
-// KeyDetection 页面敏感信息检测
-func KeyDetection(url, body string) {
+// KeyDetection 页面敏感信息检测，sink 为空时结果发送到 output.OutChannel
+func KeyDetection(url, body string, sink *output.Sink) {
     for id, regexs := range regexCompiled {
         var matchedRegexes []string
         for _, regex := range regexs {
@@ -109,7 +109,7 @@ func KeyDetection(url, body string) {
         }
         
         if len(matchedRegexes) > 0 {
-            output.OutChannel <- output.VulMessage{
+            sink.Report(output.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "Sensitive Key",
                 VulnData: output.VulnData{
@@ -120,7 +120,7 @@ func KeyDetection(url, body string) {
                     // Response:   body, // todo js 这种文本过大，不显示了
                 },
                 Level: output.Medium,
-            }
+            })
         }
     }
     
@@ -1,14 +1,16 @@
 package sensitive
 
+import "github.com/yhy0/Jie/pkg/output"
+
 /**
   @author: yhy
   @since: 2023/10/18
   @desc: //TODO
 **/
 
-// Detection 页面敏感信息检测
-func Detection(url, req, body string) {
-    go KeyDetection(url, body)
-    go PageErrorMessageCheck(url, req, body)
-    go Wih(url, req, body)
+// Detection 页面敏感信息检测，sink 为空时结果发送到 output.OutChannel
+func Detection(url, req, body string, sink *output.Sink) {
+    go KeyDetection(url, body, sink)
+    go PageErrorMessageCheck(url, req, body, sink)
+    go Wih(url, req, body, sink)
 }
@@ -47,7 +47,8 @@ func init() {
     }
 }
 
-func Wih(url, req, body string) {
+// Wih 使用 wih 规则检测页面中的敏感信息，sink 为空时结果发送到 output.OutChannel
+func Wih(url, req, body string, sink *output.Sink) {
     for name, regex := range wihRegexCompiled {
         var matchedRegexes []string
         
@@ -68,7 +69,7 @@ func Wih(url, req, body string) {
         }
         
         if len(matchedRegexes) > 0 {
-            output.OutChannel <- output.VulMessage{
+            sink.Report(output.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "Sensitive Key",
                 VulnData: output.VulnData{
@@ -78,7 +79,7 @@ func Wih(url, req, body string) {
                     Payload:    strings.Join(matchedRegexes, ","),
                 },
                 Level: output.Medium,
-            }
+            })
         }
     }
 }
@@ -390,7 +390,7 @@ func scanApi(method, baseUrl, path, queryParams, bodyParams string, header map[s
         }
         
         if resp.StatusCode == 200 {
-            output.OutChannel <- output.VulMessage{
+            client.Report(output.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "Swagger 文件上传",
                 VulnData: output.VulnData{
@@ -403,7 +403,7 @@ func scanApi(method, baseUrl, path, queryParams, bodyParams string, header map[s
                     Response:   resp.Body,
                 },
                 Level: output.Medium,
-            }
+            })
         }
         
     } else { // ssrf、 文件读取测试
@@ -465,7 +465,7 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
             sqlPlugin := &sql.Plugin{}
             sqlPlugin.Scan(target, "", in, client)
             
-            output.OutChannel <- output.VulMessage{
+            client.Report(output.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "Swagger unauthorized",
                 VulnData: output.VulnData{
@@ -478,12 +478,12 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
                     Response:   res.Body,
                 },
                 Level: output.Low,
-            }
+            })
             
             if util.Contains(res.Body, "<title>百度一下，你就知道</title>") {
                 logging.Logger.Infof("存在 SSRF漏洞: GET %s", target)
                 
-                output.OutChannel <- output.VulMessage{
+                client.Report(output.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "Swagger SSRF",
                     VulnData: output.VulnData{
@@ -496,13 +496,13 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
                         Response:   res.Body,
                     },
                     Level: output.Critical,
-                }
+                })
             }
             
             if util.Contains(res.Body, "root:x:0:0:root:/root:") {
                 logging.Logger.Infof("存在任意文件读取漏洞: GET %s", target)
                 
-                output.OutChannel <- output.VulMessage{
+                client.Report(output.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "Swagger File Reading",
                     VulnData: output.VulnData{
@@ -515,7 +515,7 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
                         Response:   res.Body,
                     },
                     Level: output.Critical,
-                }
+                })
             }
         }
         
@@ -539,7 +539,7 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
             sqlPlugin := &sql.Plugin{}
             sqlPlugin.Scan(target, "", in, client)
             
-            output.OutChannel <- output.VulMessage{
+            client.Report(output.VulMessage{
                 DataType: "web_vul",
                 Plugin:   "Swagger unauthorized",
                 VulnData: output.VulnData{
@@ -552,12 +552,12 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
                     Response:   res.Body,
                 },
                 Level: output.Low,
-            }
+            })
             
             if util.Contains(res.Body, "<title>百度一下，你就知道</title>") {
                 
                 logging.Logger.Infof("存在SSRF漏洞漏洞: %s ", payload)
-                output.OutChannel <- output.VulMessage{
+                client.Report(output.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "Swagger SSRF",
                     VulnData: output.VulnData{
@@ -570,13 +570,13 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
                         Response:   res.Body,
                     },
                     Level: output.Critical,
-                }
+                })
             }
             
             if util.Contains(res.Body, "root:x:0:0:root:/root:") {
                 logging.Logger.Infof("存在任意文件读取漏洞: %s ", payload)
                 
-                output.OutChannel <- output.VulMessage{
+                client.Report(output.VulMessage{
                     DataType: "web_vul",
                     Plugin:   "Swagger File Reading",
                     VulnData: output.VulnData{
@@ -589,7 +589,7 @@ func scanSwagger(method, target, bodyParams string, header map[string]string, cl
                         Response:   res.Body,
                     },
                     Level: output.Critical,
-                }
+                })
             }
         }
     }
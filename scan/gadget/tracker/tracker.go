@@ -0,0 +1,157 @@
+package tracker
+
+import (
+    "fmt"
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/Jie/pkg/util"
+    "github.com/yhy0/logging"
+    "strings"
+    "sync"
+    "time"
+)
+
+/**
+   @author yhy
+   @since 2024/7/3
+   @desc 存储型、二阶注入跟踪
+        插件注入时使用 Token 生成唯一的标记放入 payload，发送后使用 Register 记录注入的请求
+        之后所有的响应(爬虫、被动代理、插件发送的请求)都会经过 Check 查找标记，标记出现在请求中不包含它的响应里，说明输入被存储后在这个页面输出
+        再根据注入时提供的上下文检查判断输出的位置是否可以利用，可以利用时报告为对应的漏洞，否则只记录为存储的输入
+**/
+
+const (
+    prefix    = "jzq"          // 所有标记的前缀，先查找前缀，减少开销
+    tokenLen  = 10             // 前缀之后随机部分的长度
+    ttl       = 12 * time.Hour // 标记的有效时间
+    maxTokens = 50000          // 最多记录的标记数量，超过时丢弃最早的
+)
+
+// Origin 注入的请求
+type Origin struct {
+    Plugin  string // 注入的插件
+    Kind    string // 可以利用时的漏洞类型，如 stored xss
+    Level   string // 可以利用时的等级
+    Url     string
+    Method  string
+    Param   string
+    Payload string
+    Request string
+    Sink    *output.Sink // 注入的扫描任务，结果只输出给它，为空时发送到 output.OutChannel
+    
+    // Executable 输出的页面中标记所在的上下文是否可以利用，为空时 payload 原样输出就认为可以利用
+    Executable func(response, token string) bool
+}
+
+type entry struct {
+    Origin
+    created time.Time
+}
+
+var (
+    lock     sync.RWMutex
+    tokens   = make(map[string]*entry)
+    order    []string
+    reported sync.Map // 标记 + 输出页面，只报告一次
+)
+
+// Token 生成一个新的标记，只包含小写字母和数字，可以放在各种上下文的 payload 中
+func Token() string {
+    return prefix + util.RandomLowLetterNumber(tokenLen)
+}
+
+// Register 记录标记对应的注入请求
+func Register(token string, origin Origin) {
+    lock.Lock()
+    defer lock.Unlock()
+    if _, ok := tokens[token]; !ok {
+        order = append(order, token)
+    }
+    tokens[token] = &entry{Origin: origin, created: time.Now()}
+    for len(order) > maxTokens {
+        delete(tokens, order[0])
+        order = order[1:]
+    }
+}
+
+// Check 查找响应中已经记录的标记，请求中包含标记时是注入请求本身的回显，不算存储
+func Check(url, request, response string) {
+    if !strings.Contains(response, prefix) {
+        return
+    }
+    for _, token := range find(response) {
+        lock.RLock()
+        e := tokens[token]
+        lock.RUnlock()
+        if e == nil || time.Since(e.created) > ttl || strings.Contains(request, token) {
+            continue
+        }
+        if _, ok := reported.LoadOrStore(token+"|"+url, true); ok {
+            continue
+        }
+        report(url, response, token, e)
+    }
+}
+
+// find 响应中所有的标记
+func find(response string) []string {
+    var result []string
+    seen := make(map[string]bool)
+    for i := 0; ; {
+        j := strings.Index(response[i:], prefix)
+        if j < 0 {
+            break
+        }
+        start := i + j
+        end := start + len(prefix) + tokenLen
+        i = start + len(prefix)
+        if end > len(response) || !lowLetterNumber(response[start+len(prefix):end]) {
+            continue
+        }
+        if token := response[start:end]; !seen[token] {
+            seen[token] = true
+            result = append(result, token)
+        }
+    }
+    return result
+}
+
+func lowLetterNumber(s string) bool {
+    for _, c := range s {
+        if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+            return false
+        }
+    }
+    return true
+}
+
+func report(url, response, token string, e *entry) {
+    executable := strings.Contains(response, e.Payload)
+    if e.Executable != nil {
+        executable = e.Executable(response, token)
+    }
+    
+    vulnType, level := "stored input", output.Low
+    description := fmt.Sprintf("The value injected into %s at [%s] %s is stored and rendered at %s, the rendering context does not look exploitable.", e.Param, e.Method, e.Url, url)
+    if executable {
+        vulnType, level = e.Kind, e.Level
+        description = fmt.Sprintf("%s: the value injected into %s at [%s] %s is stored and rendered at %s in an exploitable context.", e.Kind, e.Param, e.Method, e.Url, url)
+    }
+    logging.Logger.Infoln("[tracker]", vulnType, e.Url, e.Param, "=>", url)
+    
+    e.Sink.Report(output.VulMessage{
+        DataType: "web_vul",
+        Plugin:   e.Plugin,
+        VulnData: output.VulnData{
+            CreateTime:  time.Now().Format("2006-01-02 15:04:05"),
+            VulnType:    vulnType,
+            Target:      url,
+            Method:      e.Method,
+            Param:       e.Param,
+            Payload:     e.Payload,
+            Request:     e.Request,
+            Response:    response,
+            Description: description,
+        },
+        Level: level,
+    })
+}
@@ -0,0 +1,100 @@
+package tracker
+
+import (
+    "github.com/yhy0/Jie/pkg/output"
+    "github.com/yhy0/logging"
+    "os"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestMain(m *testing.M) {
+    logging.Logger = logging.New(false, os.TempDir(), "tracker", false)
+    os.Exit(m.Run())
+}
+
+func TestFind(t *testing.T) {
+    tests := []struct {
+        response string
+        want     []string
+    }{
+        {"no tokens here", nil},
+        {"<p>jzqabcde12345</p>", []string{"jzqabcde12345"}},
+        // 重复的只返回一次，相邻的标记都能找到
+        {"jzqabcde12345 jzqabcde12345jzq0123456789", []string{"jzqabcde12345", "jzq0123456789"}},
+        // 截断的、包含大写字母的不是标记
+        {"jzqabc jzqABCDE12345 jzqabcde1234", nil},
+        // 前缀重叠时从下一个位置继续查找
+        {"jzqjzqabcde12345", []string{"jzqjzqabcde12", "jzqabcde12345"}},
+    }
+    for _, tt := range tests {
+        if got := find(tt.response); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("find(%q) = %v, want %v", tt.response, got, tt.want)
+        }
+    }
+}
+
+// checked 调用 Check，返回输出的漏洞，没有输出时为空
+func checked(url, request, response string) *output.VulMessage {
+    got := make(chan output.VulMessage, 1)
+    done := make(chan struct{})
+    go func() {
+        select {
+        case msg := <-output.OutChannel:
+            got <- msg
+        case <-done:
+        }
+    }()
+    Check(url, request, response)
+    select {
+    case msg := <-got:
+        return &msg
+    case <-time.After(100 * time.Millisecond):
+        close(done)
+        return nil
+    }
+}
+
+func TestCheck(t *testing.T) {
+    token := Token()
+    if len(token) != len(prefix)+tokenLen || !strings.HasPrefix(token, prefix) {
+        t.Fatalf("Token() = %q", token)
+    }
+    Register(token, Origin{
+        Plugin:  "XSS",
+        Kind:    "stored xss",
+        Level:   output.High,
+        Url:     "http://example.com/comment",
+        Method:  "POST",
+        Param:   "body",
+        Payload: "<b>" + token,
+        Executable: func(response, token string) bool {
+            return strings.Contains(response, "<b>"+token)
+        },
+    })
+    
+    // 注入请求本身的回显
+    if msg := checked("http://example.com/comment", "body=%3Cb%3E"+token, "<b>"+token); msg != nil {
+        t.Errorf("reflection reported as stored: %+v", msg)
+    }
+    
+    msg := checked("http://example.com/list", "GET /list", "<li>&lt;b&gt;"+token+"</li>")
+    if msg == nil || msg.Level != output.Low || msg.VulnData.VulnType != "stored input" {
+        t.Fatalf("escaped output: %+v", msg)
+    }
+    
+    msg = checked("http://example.com/admin", "GET /admin", "<li><b>"+token+"</li>")
+    if msg == nil || msg.Level != output.High || msg.VulnData.VulnType != "stored xss" || msg.VulnData.Target != "http://example.com/admin" {
+        t.Fatalf("exploitable output: %+v", msg)
+    }
+    
+    // 同一个页面只报告一次，未知的标记不报告
+    if msg = checked("http://example.com/admin", "GET /admin", "<li><b>"+token+"</li>"); msg != nil {
+        t.Errorf("reported twice: %+v", msg)
+    }
+    if msg = checked("http://example.com/other", "GET /other", Token()); msg != nil {
+        t.Errorf("unknown token reported: %+v", msg)
+    }
+}
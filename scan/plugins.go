@@ -1,12 +1,27 @@
 package scan
 
 import (
+    "github.com/yhy0/Jie/scan/PerFile/authz"
+    "github.com/yhy0/Jie/scan/PerFile/blindxss"
     "github.com/yhy0/Jie/scan/PerFile/cmdinject"
+    "github.com/yhy0/Jie/scan/PerFile/cors"
+    "github.com/yhy0/Jie/scan/PerFile/csrf"
+    "github.com/yhy0/Jie/scan/PerFile/deserialization"
+    "github.com/yhy0/Jie/scan/PerFile/domxss"
     "github.com/yhy0/Jie/scan/PerFile/fastjson"
+    "github.com/yhy0/Jie/scan/PerFile/graphql"
     "github.com/yhy0/Jie/scan/PerFile/jsonp"
+    "github.com/yhy0/Jie/scan/PerFile/lfi"
+    "github.com/yhy0/Jie/scan/PerFile/nosql"
+    "github.com/yhy0/Jie/scan/PerFile/prototype"
+    "github.com/yhy0/Jie/scan/PerFile/redirect"
     "github.com/yhy0/Jie/scan/PerFile/sql"
     "github.com/yhy0/Jie/scan/PerFile/sql/sqlmap"
     "github.com/yhy0/Jie/scan/PerFile/ssrf"
+    "github.com/yhy0/Jie/scan/PerFile/ssti"
+    "github.com/yhy0/Jie/scan/PerFile/upload"
+    "github.com/yhy0/Jie/scan/PerFile/viewstate"
+    "github.com/yhy0/Jie/scan/PerFile/websocket"
     "github.com/yhy0/Jie/scan/PerFile/xss"
     "github.com/yhy0/Jie/scan/PerFile/xxe"
     "github.com/yhy0/Jie/scan/PerFolder/crlf"
@@ -14,6 +29,8 @@ import (
     "github.com/yhy0/Jie/scan/PerFolder/traversal"
     "github.com/yhy0/Jie/scan/PerServer"
     "github.com/yhy0/Jie/scan/PerServer/portScan"
+    "github.com/yhy0/Jie/scan/PerServer/smuggling"
+    "github.com/yhy0/Jie/scan/PerServer/ssl"
     "github.com/yhy0/Jie/scan/bbscan"
     "github.com/yhy0/Jie/scan/gadget/bypass403"
     "github.com/yhy0/Jie/scan/gadget/collection"
@@ -34,28 +51,70 @@ var PerFolderPlugins = make(map[string]Addon)
 // PerServerPlugins 每个网站只测试一次的插件
 var PerServerPlugins = make(map[string]Addon)
 
-// 注册插件 , 每新增一个插件，这里都要注册一下
-func init() {
-    PerFilePlugins["xss"] = &xss.Plugin{}
-    PerFilePlugins["sql"] = &sql.Plugin{}
-    PerFilePlugins["sqlmapApi"] = &sqlmap.Plugin{}
-    PerFilePlugins["ssrf"] = &ssrf.Plugin{}
-    PerFilePlugins["jsonp"] = &jsonp.Plugin{}
-    PerFilePlugins["cmd"] = &cmdinject.Plugin{}
-    PerFilePlugins["xxe"] = &xxe.Plugin{}
-    PerFilePlugins["fastjson"] = &fastjson.Plugin{}
-    PerFilePlugins["bypass403"] = &bypass403.Plugin{}
+// PluginSet 一组插件实例，插件内部保存了是否扫描过的状态，嵌入使用时每个扫描器使用单独的一组，互不影响
+type PluginSet struct {
+    PerFile   map[string]Addon
+    PerFolder map[string]Addon
+    PerServer map[string]Addon
+}
+
+// DefaultPlugins 命令行下使用的插件实例，和 PerFilePlugins 等是同一组
+var DefaultPlugins *PluginSet
+
+// NewPluginSet 创建一组新的插件实例, 每新增一个插件，这里都要注册一下
+func NewPluginSet() *PluginSet {
+    s := &PluginSet{
+        PerFile:   make(map[string]Addon),
+        PerFolder: make(map[string]Addon),
+        PerServer: make(map[string]Addon),
+    }
     
-    PerFilePlugins["SensitiveParameters"] = &collection.Plugin{} // 这个不受开关控制
+    s.PerFile["xss"] = &xss.Plugin{}
+    s.PerFile["sql"] = &sql.Plugin{}
+    s.PerFile["sqlmapApi"] = &sqlmap.Plugin{}
+    s.PerFile["ssrf"] = &ssrf.Plugin{}
+    s.PerFile["jsonp"] = &jsonp.Plugin{}
+    s.PerFile["cmd"] = &cmdinject.Plugin{}
+    s.PerFile["xxe"] = &xxe.Plugin{}
+    s.PerFile["fastjson"] = &fastjson.Plugin{}
+    s.PerFile["bypass403"] = &bypass403.Plugin{}
     
-    PerFolderPlugins["crlf"] = &crlf.Plugin{}
-    PerFolderPlugins["iis"] = &crlf.Plugin{}
-    PerFolderPlugins["nginx-alias-traversal"] = &traversal.Plugin{}
-    PerFolderPlugins["log4j"] = &log4j.Plugin{}
-    PerFolderPlugins["bbscan"] = &bbscan.Plugin{} // 扫描规则路径不是 root 的需要扫描
+    s.PerFile["SensitiveParameters"] = &collection.Plugin{} // 这个不受开关控制
+    s.PerFile["cors"] = &cors.Plugin{}
+    s.PerFile["redirect"] = &redirect.Plugin{}
+    s.PerFile["ssti"] = &ssti.Plugin{}
+    s.PerFile["lfi"] = &lfi.Plugin{}
+    s.PerFile["nosql"] = &nosql.Plugin{}
+    s.PerFile["graphql"] = &graphql.Plugin{}
+    s.PerFile["websocket"] = &websocket.Plugin{}
+    s.PerFile["upload"] = &upload.Plugin{}
+    s.PerFile["authz"] = &authz.Plugin{}
+    s.PerFile["csrf"] = &csrf.Plugin{}
+    s.PerFile["deserialization"] = &deserialization.Plugin{}
+    s.PerFile["viewstate"] = &viewstate.Plugin{}
+    s.PerFile["prototype"] = &prototype.Plugin{}
+    s.PerFile["blindxss"] = &blindxss.Plugin{}
+    s.PerFile["domxss"] = &domxss.Plugin{}
     
-    PerServerPlugins["bbscan"] = &bbscan.Plugin{}
-    PerServerPlugins["portScan"] = &portScan.Plugin{}
-    PerServerPlugins["nuclei"] = &PerServer.NucleiPlugin{}
-    PerServerPlugins["archive"] = &PerServer.ArchivePlugin{}
+    s.PerFolder["crlf"] = &crlf.Plugin{}
+    s.PerFolder["iis"] = &crlf.Plugin{}
+    s.PerFolder["nginx-alias-traversal"] = &traversal.Plugin{}
+    s.PerFolder["log4j"] = &log4j.Plugin{}
+    s.PerFolder["bbscan"] = &bbscan.Plugin{} // 扫描规则路径不是 root 的需要扫描
+    
+    s.PerServer["bbscan"] = &bbscan.Plugin{}
+    s.PerServer["portScan"] = &portScan.Plugin{}
+    s.PerServer["nuclei"] = &PerServer.NucleiPlugin{}
+    s.PerServer["archive"] = &PerServer.ArchivePlugin{}
+    s.PerServer["smuggling"] = &smuggling.Plugin{}
+    s.PerServer["ssl"] = &ssl.Plugin{}
+    return s
+}
+
+// 注册插件
+func init() {
+    DefaultPlugins = NewPluginSet()
+    PerFilePlugins = DefaultPlugins.PerFile
+    PerFolderPlugins = DefaultPlugins.PerFolder
+    PerServerPlugins = DefaultPlugins.PerServer
 }
@@ -16,4 +16,12 @@ type Addon interface {
     Scan(target string, path string, in *input.CrawlResult, client *httpx.Client) // 扫描, target\path 扫描目标单独传入，不从 in 中获取，这样就不用修改 in 中的 url 导致出现错误
     IsScanned(uniqueId string) bool                                               // 是否已经扫描过
     Name() string                                                                 // 插件名称
+    Risk() string                                                                 // 风险等级 conf.RiskReadOnly 等，安全模式下只运行 read-only、intrusive 的插件
+}
+
+// Expander 可以把一个请求展开为多个新请求的插件，例如 GraphQL 把每个操作的变量展开为普通的 JSON 参数
+// Scan 结束后调用 Expand，展开的请求交给 Plugins 中的插件继续检测
+type Expander interface {
+    Expand(in *input.CrawlResult, client *httpx.Client) []*input.CrawlResult
+    Plugins() []string // 继续检测展开请求的 PerFile 插件
 }
@@ -0,0 +1,97 @@
+package test
+
+import (
+    "github.com/yhy0/Jie/pkg/input"
+    "github.com/yhy0/logging"
+    "os"
+    "reflect"
+    "testing"
+)
+
+func TestParseTargets(t *testing.T) {
+    logging.Logger = logging.New(false, os.TempDir(), "target", false)
+    targets := input.ParseTargets([]string{
+        "example.com",
+        "example.com:80",
+        "https://Example.com:443/",
+        "10.0.0.0/31",
+        "10.0.0.5-6:8080,8443",
+        "# comment",
+        "10.0.0.0/33",
+        "example.com:abc",
+        "[::1",
+        "[::1]:8080",
+        "[fd00::2]",
+        "fd00::3",
+        "fd00::10/127",
+        "10.0.0.7/admin",
+    })
+    
+    want := []string{
+        "http://example.com",
+        "https://example.com",
+        "http://10.0.0.0",
+        "http://10.0.0.1",
+        "http://10.0.0.5:8080",
+        "https://10.0.0.5:8443",
+        "http://10.0.0.6:8080",
+        "https://10.0.0.6:8443",
+        "http://[::1]:8080",
+        "http://[fd00::2]",
+        "http://[fd00::3]",
+        "http://[fd00::10]",
+        "http://[fd00::11]",
+        "http://10.0.0.7/admin",
+    }
+    if !reflect.DeepEqual(targets, want) {
+        t.Errorf("got %v, want %v", targets, want)
+    }
+}
+
+func TestParseNmapXML(t *testing.T) {
+    data := `<?xml version="1.0"?>
+<nmaprun scanner="nmap">
+<host>
+<address addr="10.0.0.1" addrtype="ipv4"/>
+<address addr="00:11:22:33:44:55" addrtype="mac"/>
+<ports>
+<port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port>
+<port protocol="tcp" portid="443"><state state="open"/><service name="http" tunnel="ssl"/></port>
+<port protocol="tcp" portid="8080"><state state="open"/><service name="http-proxy"/></port>
+<port protocol="tcp" portid="8081"><state state="closed"/><service name="http"/></port>
+</ports>
+</host>
+<host>
+<address addr="10.0.0.9" addrtype="ipv4"/>
+<hostnames><hostname name="www.example.com" type="user"/><hostname name="ptr.example.com" type="PTR"/></hostnames>
+<ports>
+<port protocol="tcp" portid="80"><state state="open"/><service name="http"/></port>
+</ports>
+</host>
+</nmaprun>`
+    
+    targets, err := input.ParseNmapXML([]byte(data))
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []string{"https://10.0.0.1", "http://10.0.0.1:8080", "http://www.example.com"}
+    if !reflect.DeepEqual(targets, want) {
+        t.Errorf("got %v, want %v", targets, want)
+    }
+}
+
+func TestParseMasscanJSON(t *testing.T) {
+    data := `[
+{   "ip": "10.0.0.2",   "timestamp": "1718000000", "ports": [ {"port": 8443, "proto": "tcp", "status": "open", "reason": "syn-ack", "ttl": 52} ] },
+{   "ip": "10.0.0.3",   "timestamp": "1718000000", "ports": [ {"port": 3306, "proto": "tcp", "status": "open", "reason": "syn-ack", "ttl": 52} ] },
+]`
+    
+    targets, err := input.ParseMasscanJSON([]byte(data))
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []string{"https://10.0.0.2:8443"}
+    if !reflect.DeepEqual(targets, want) {
+        t.Errorf("got %v, want %v", targets, want)
+    }
+}
@@ -74,5 +74,5 @@ func TestDom1Xss(t *testing.T) {
         return
     }
     
-    dom.Dom("https://public-firing-range.appspot.com/dom/toxicdom/document/cookie_set/eval", response.Body)
+    dom.Dom("https://public-firing-range.appspot.com/dom/toxicdom/document/cookie_set/eval", response.Body, nil)
 }